@@ -0,0 +1,53 @@
+// argon2calibrate recommends argon2id parameters for the current host.
+// Its output can be loaded as environment of cmd/authorization, for example
+// with `docker run --env-file` or `set -a; . ./argon2.env`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+)
+
+func main() {
+	var (
+		target      = flag.Duration("target", 500*time.Millisecond, "maximum time hashing a single password may take")
+		maxMemory   = flag.Uint("max-memory", 256*1024, "maximum memory in KiB a single hash may use")
+		parallelism = flag.Uint("parallelism", uint(defaultParallelism()), "number of threads used per hash")
+		samples     = flag.Int("samples", 3, "number of hashes timed per candidate")
+	)
+	flag.Parse()
+
+	if *parallelism < 1 || *parallelism > 255 {
+		fmt.Fprintln(os.Stderr, "parallelism must be between 1 and 255")
+		os.Exit(2)
+	}
+	if *maxMemory > 1<<32-1 {
+		fmt.Fprintln(os.Stderr, "max-memory must fit in 32 bits")
+		os.Exit(2)
+	}
+
+	c, err := argon2id.Calibrate(*target, uint32(*maxMemory), uint8(*parallelism), *samples)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	host, _ := os.Hostname()
+	fmt.Printf("# argon2id calibrated on %s (%d CPUs) for %s, hashing takes %s\n", host, runtime.NumCPU(), *target, c.Duration.Round(time.Millisecond))
+	fmt.Printf("ARGON2_MEMORY_KIB=%d\n", c.Params.Memory())
+	fmt.Printf("ARGON2_ITERATIONS=%d\n", c.Params.Iterations())
+	fmt.Printf("ARGON2_PARALLELISM=%d\n", c.Params.Parallelism())
+}
+
+// defaultParallelism uses every CPU, bounded by what argon2id accepts
+func defaultParallelism() int {
+	if n := runtime.NumCPU(); n < 255 {
+		return n
+	}
+	return 255
+}
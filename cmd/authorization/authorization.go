@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/mailer"
+	"github.com/demeesterdev/todo-service/internal/password"
+	"github.com/demeesterdev/todo-service/internal/throttle"
+	"github.com/demeesterdev/todo-service/internal/validate"
 	"github.com/demeesterdev/todo-service/pkg/authorization"
 	authorizationEps "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+	"github.com/demeesterdev/todo-service/pkg/authorization/ldapauth"
+	"github.com/demeesterdev/todo-service/pkg/authorization/pb"
 	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
 	"github.com/go-kit/log"
+	"google.golang.org/grpc"
 )
 
 const (
 	defaultHTTPPort = "8082"
+	defaultGRPCPort = "9082"
 	defaultDBtarget = ":memory:"
 )
 
@@ -24,21 +38,194 @@ func main() {
 	var (
 		logger   log.Logger
 		httpAddr = net.JoinHostPort("localhost", envString("HTTP_PORT", defaultHTTPPort))
+		grpcAddr = net.JoinHostPort("localhost", envString("GRPC_PORT", defaultGRPCPort))
 		dbTarget = envString("DB_PATH_AUTH", defaultDBtarget)
+		limits   = authorizationEps.Limits{
+			UsernameMinLength: envInt("USERNAME_MIN_LENGTH", authorizationEps.DefaultLimits.UsernameMinLength),
+			UsernameMaxLength: envInt("USERNAME_MAX_LENGTH", authorizationEps.DefaultLimits.UsernameMaxLength),
+			MaxBodyBytes:      int64(envInt("MAX_BODY_BYTES", int(authorizationEps.DefaultLimits.MaxBodyBytes))),
+		}
 	)
 
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 
-	service, err := authorization.NewSqliteDBService(dbTarget, argon2id.DefaultConfig)
+	policy := password.Policy{
+		MinLength:      envInt("PASSWORD_MIN_LENGTH", password.DefaultPolicy.MinLength),
+		MaxLength:      envInt("PASSWORD_MAX_LENGTH", password.DefaultPolicy.MaxLength),
+		RequireLower:   envBool("PASSWORD_REQUIRE_LOWER", password.DefaultPolicy.RequireLower),
+		RequireUpper:   envBool("PASSWORD_REQUIRE_UPPER", password.DefaultPolicy.RequireUpper),
+		RequireDigit:   envBool("PASSWORD_REQUIRE_DIGIT", password.DefaultPolicy.RequireDigit),
+		RequireSymbol:  envBool("PASSWORD_REQUIRE_SYMBOL", password.DefaultPolicy.RequireSymbol),
+		ForbidUsername: envBool("PASSWORD_FORBID_USERNAME", password.DefaultPolicy.ForbidUsername),
+	}
+	if path := envString("BREACHED_PASSWORDS_FILE", ""); path != "" {
+		breached, err := password.LoadBreachedList(path)
+		if err != nil {
+			panic(err)
+		}
+		logger.Log("breached_passwords", breached.Len(), "file", path)
+		policy.Breached = breached
+	}
+
+	hashParams, err := argon2id.NewParams(
+		uint32(envInt("ARGON2_MEMORY_KIB", argon2id.DefaultMemory)),
+		uint32(envInt("ARGON2_ITERATIONS", argon2id.DefaultIterations)),
+		uint8(envInt("ARGON2_PARALLELISM", argon2id.DefaultParallelism)),
+		uint32(envInt("ARGON2_SALT_LENGTH", argon2id.DefaultSaltLength)),
+		uint32(envInt("ARGON2_KEY_LENGTH", argon2id.DefaultKeyLength)),
+	)
 	if err != nil {
 		panic(err)
 	}
+	if path := envString("PASSWORD_PEPPER_FILE", ""); path != "" {
+		keyring, err := argon2id.LoadKeyring(path)
+		if err != nil {
+			panic(err)
+		}
+		hashParams = hashParams.WithPepper(keyring)
+	}
+	logger.Log("argon2id", hashParams)
+
+	throttleConfig := func(prefix string, defaults throttle.Config) throttle.Config {
+		return throttle.Config{
+			Threshold: envInt(prefix+"_THRESHOLD", defaults.Threshold),
+			BaseDelay: envDuration(prefix+"_BASE_DELAY", defaults.BaseDelay),
+			MaxDelay:  envDuration(prefix+"_MAX_DELAY", defaults.MaxDelay),
+			Window:    envDuration(prefix+"_WINDOW", defaults.Window),
+		}
+	}
+	loginThrottle := authorization.WithLoginThrottle(
+		throttleConfig("LOGIN_USER", authorization.DefaultUserThrottle),
+		throttleConfig("LOGIN_IP", authorization.DefaultIPThrottle),
+	)
+
+	mfa := authorization.DefaultMFAConfig
+	mfa.Issuer = envString("TOTP_ISSUER", mfa.Issuer)
+	mfa.ChallengeTTL = envDuration("MFA_CHALLENGE_TTL", mfa.ChallengeTTL)
+
+	// mails go to SMTP_ADDR, to MAIL_FILE or to stderr for local development
+	from := envString("MAIL_FROM", "todo-service@localhost")
+	var mail mailer.Mailer
+	switch {
+	case envString("SMTP_ADDR", "") != "":
+		mail, err = mailer.NewSMTP(mailer.SMTPConfig{
+			Addr:     envString("SMTP_ADDR", ""),
+			From:     from,
+			Username: envString("SMTP_USERNAME", ""),
+			Password: envString("SMTP_PASSWORD", ""),
+		})
+		if err != nil {
+			panic(err)
+		}
+	case envString("MAIL_FILE", "") != "":
+		mail = mailer.NewFile(envString("MAIL_FILE", ""), from)
+	default:
+		mail = mailer.NewWriter(os.Stderr, from)
+	}
+	mailConfig := authorization.MailConfig{
+		ResetURL:  envString("PASSWORD_RESET_URL", ""),
+		VerifyURL: envString("EMAIL_VERIFY_URL", ""),
+		ResetTTL:  envDuration("PASSWORD_RESET_TTL", authorization.DefaultMailConfig.ResetTTL),
+		VerifyTTL: envDuration("EMAIL_VERIFY_TTL", authorization.DefaultMailConfig.VerifyTTL),
+	}
+
+	// the issuer is the URL clients reach the HTTP transport at
+	oidc := authorization.OIDCConfig{
+		Issuer:      envString("OIDC_ISSUER", "http://"+httpAddr),
+		IDTokenTTL:  envDuration("ID_TOKEN_TTL", authorization.DefaultOIDCConfig.IDTokenTTL),
+		KeyRotation: envDuration("SIGNING_KEY_ROTATION", authorization.DefaultOIDCConfig.KeyRotation),
+	}
+
+	// identity providers are listed in IDP_PROVIDERS and configured by
+	// IDP_<NAME>_* variables, they redirect back to the HTTP transport
+	federation := authorization.DefaultFederationConfig
+	federation.JITProvisioning = envBool("IDP_JIT_PROVISIONING", federation.JITProvisioning)
+	federation.LoginTTL = envDuration("IDP_LOGIN_TTL", federation.LoginTTL)
+	for _, name := range strings.Fields(strings.ReplaceAll(envString("IDP_PROVIDERS", ""), ",", " ")) {
+		prefix := "IDP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		idp := authorization.IdentityProvider{Name: name}
+		idp.Issuer = envString(prefix+"_ISSUER", "")
+		idp.ClientID = envString(prefix+"_CLIENT_ID", "")
+		idp.ClientSecret = envString(prefix+"_CLIENT_SECRET", "")
+		idp.RedirectURL = envString(prefix+"_REDIRECT_URL", strings.TrimSuffix(oidc.Issuer, "/")+"/login/"+name+"/callback")
+		idp.Scopes = strings.Fields(envString(prefix+"_SCOPES", "profile email"))
+		if idp.Issuer == "" || idp.ClientID == "" {
+			panic(fmt.Errorf("identity provider %s: %s_ISSUER and %s_CLIENT_ID are required", name, prefix, prefix))
+		}
+		federation.Providers = append(federation.Providers, idp)
+		logger.Log("identity_provider", name, "issuer", idp.Issuer)
+	}
+
+	// passwords are checked against the local hashes and, with LDAP_URL, at
+	// a directory which creates the users signing in there
+	verifiers := []authorization.Verifier{authorization.PasswordHashes}
+	if url := envString("LDAP_URL", ""); url != "" {
+		directory := ldapauth.Config{
+			URL:               url,
+			StartTLS:          envBool("LDAP_START_TLS", false),
+			BindDN:            envString("LDAP_BIND_DN", ""),
+			BindPassword:      envString("LDAP_BIND_PASSWORD", ""),
+			BaseDN:            envString("LDAP_BASE_DN", ""),
+			Filter:            envString("LDAP_FILTER", ldapauth.DefaultConfig.Filter),
+			UsernameAttribute: envString("LDAP_USERNAME_ATTRIBUTE", ldapauth.DefaultConfig.UsernameAttribute),
+			EmailAttribute:    envString("LDAP_EMAIL_ATTRIBUTE", ldapauth.DefaultConfig.EmailAttribute),
+			Timeout:           envDuration("LDAP_TIMEOUT", ldapauth.DefaultConfig.Timeout),
+		}
+		if caFile := envString("LDAP_CA_FILE", ""); caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				panic(err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				panic(fmt.Errorf("LDAP_CA_FILE %s: no certificates", caFile))
+			}
+			directory.TLS = &tls.Config{RootCAs: roots}
+		}
+		if envBool("LDAP_BEFORE_LOCAL", false) {
+			verifiers = append([]authorization.Verifier{ldapauth.New(directory)}, verifiers...)
+		} else {
+			verifiers = append(verifiers, ldapauth.New(directory))
+		}
+		logger.Log("ldap", url, "base_dn", directory.BaseDN)
+	}
+
+	// the provisioning client only creates members unless PROVISIONING_ROLES
+	// lists the roles it may grant
+	provisioning := authorization.ProvisioningConfig{Token: envString("PROVISIONING_TOKEN", "")}
+	for _, role := range strings.Fields(strings.ReplaceAll(envString("PROVISIONING_ROLES", ""), ",", " ")) {
+		if !authorization.Role(role).Valid() {
+			panic(fmt.Errorf("PROVISIONING_ROLES: unknown role %s", role))
+		}
+		provisioning.Roles = append(provisioning.Roles, authorization.Role(role))
+	}
+
+	service, err := authorization.NewSqliteDBService(dbTarget, hashParams, authorization.WithPasswordPolicy(policy), loginThrottle, authorization.WithMFA(mfa), authorization.WithMailer(mail, mailConfig),
+		authorization.WithSessionTTL(envDuration("SESSION_TTL", authorization.DefaultSessionTTL)), authorization.WithOIDC(oidc), authorization.WithFederation(federation),
+		authorization.WithVerifiers(verifiers...), authorization.WithProvisioning(provisioning),
+		authorization.WithLogger(log.With(logger, "component", "service")))
+	if err != nil {
+		panic(err)
+	}
+
+	// the first admin is created on the first start, it manages the other users
+	if username := envString("BOOTSTRAP_ADMIN_USERNAME", ""); username != "" {
+		created, err := authorization.BootstrapAdmin(context.Background(), service, username, envString("BOOTSTRAP_ADMIN_PASSWORD", ""))
+		if err != nil {
+			panic(fmt.Errorf("bootstrap admin: %w", err))
+		}
+		if created {
+			logger.Log("bootstrap_admin", username)
+		}
+	}
 
 	var (
-		eps         = authorizationEps.MakeServerEndpoints(service)
-		httpHandler = authorizationTrsp.MakeHTTPHandler(eps, log.With(logger, "component", "HTTP"))
+		eps         = authorizationEps.MakeServerEndpoints(service, authorizationEps.AuthorizationMiddleware(service), authorizationEps.ValidationMiddleware(limits))
+		httpHandler = validate.MaxBodyBytes(limits.MaxBodyBytes)(authorizationTrsp.MakeHTTPHandler(eps, log.With(logger, "component", "HTTP")))
+		grpcServer  = grpc.NewServer()
 	)
+	pb.RegisterUsersServer(grpcServer, authorizationTrsp.MakeGRPCServer(eps, log.With(logger, "component", "gRPC")))
 
 	errs := make(chan error)
 	go func() {
@@ -53,6 +240,16 @@ func main() {
 		errs <- http.ListenAndServe(httpAddr, httpHandler)
 	}()
 
+	go func() {
+		logger.Log("transport", "gRPC", "addr", grpcAddr)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			errs <- err
+			return
+		}
+		errs <- grpcServer.Serve(listener)
+	}()
+
 	logger.Log("exit", <-errs)
 }
 
@@ -63,3 +260,39 @@ func envString(env, fallback string) string {
 	}
 	return e
 }
+
+func envInt(env string, fallback int) int {
+	e := os.Getenv(env)
+	if e == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(e)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", env, err))
+	}
+	return i
+}
+
+func envBool(env string, fallback bool) bool {
+	e := os.Getenv(env)
+	if e == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(e)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", env, err))
+	}
+	return b
+}
+
+func envDuration(env string, fallback time.Duration) time.Duration {
+	e := os.Getenv(env)
+	if e == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(e)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", env, err))
+	}
+	return d
+}
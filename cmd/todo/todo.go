@@ -1,42 +1,99 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/validate"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
+	"github.com/demeesterdev/todo-service/pkg/events"
+	"github.com/demeesterdev/todo-service/pkg/graph"
 	"github.com/demeesterdev/todo-service/pkg/todo"
+	"github.com/demeesterdev/todo-service/pkg/todo/pb"
+	"github.com/demeesterdev/todo-service/pkg/webhook"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/driver/sqlite"
 )
 
 const (
-	defaultHTTPPort = "8081"
-	defaultDBtarget = ":memory:"
+	defaultHTTPPort  = "8081"
+	defaultGRPCPort  = "9081"
+	defaultDBtarget  = ":memory:"
+	defaultAuthAddr  = "localhost:9082"
+	defaultRelayPoll = time.Second
 )
 
 func main() {
 	var (
 		logger   log.Logger
 		httpAddr = net.JoinHostPort("localhost", envString("HTTP_PORT", defaultHTTPPort))
+		grpcAddr = net.JoinHostPort("localhost", envString("GRPC_PORT", defaultGRPCPort))
 		dbTarget = envString("DB_PATH_TODO", defaultDBtarget)
+		authAddr = envString("AUTH_GRPC_ADDR", defaultAuthAddr)
+		limits   = todo.Limits{
+			TitleMaxLength:      envInt("TODO_TITLE_MAX_LENGTH", todo.DefaultLimits.TitleMaxLength),
+			DescriptionMaxBytes: envInt("TODO_DESCRIPTION_MAX_BYTES", todo.DefaultLimits.DescriptionMaxBytes),
+			MaxBodyBytes:        int64(envInt("MAX_BODY_BYTES", int(todo.DefaultLimits.MaxBodyBytes))),
+		}
+		graphLimits = graph.Limits{
+			MaxDepth:      envInt("GRAPHQL_MAX_DEPTH", graph.DefaultLimits.MaxDepth),
+			MaxComplexity: envInt("GRAPHQL_MAX_COMPLEXITY", graph.DefaultLimits.MaxComplexity),
+			ListCost:      envInt("GRAPHQL_LIST_COST", graph.DefaultLimits.ListCost),
+		}
 	)
 
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 
-	service, err := todo.NewSqliteDBService(dbTarget)
+	// background work stops when the server shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// webhooks get their own file, the outbox relay and the webhook
+	// dispatcher both write in the background and sqlite serializes writers
+	// per file
+	webhookDBTarget := envString("DB_PATH_WEBHOOK", defaultDBtarget)
+	if webhookDBTarget == dbTarget && dbTarget != defaultDBtarget {
+		panic(fmt.Errorf("DB_PATH_WEBHOOK and DB_PATH_TODO must be different files"))
+	}
+	webhooks, err := webhook.NewSqliteDBService(ctx, webhookDBTarget, webhook.DefaultConfig, log.With(logger, "component", "webhook"))
 	if err != nil {
 		panic(err)
 	}
 
+	authConn, err := grpc.Dial(authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		panic(err)
+	}
+	users := authorizationTrsp.NewGRPCClient(authConn)
+
+	broker := events.NewBroker()
+	service, relay, err := todo.NewOutboxDBService(sqlite.Open(dbTarget), events.Publishers{webhooks, broker}, defaultRelayPoll, log.With(logger, "component", "outbox"), todo.WithTeams(users))
+	if err != nil {
+		panic(err)
+	}
+	go relay.Run(ctx)
+
 	var (
-		eps         = todo.MakeServerEndpoints(service)
-		httpHandler = todo.MakeHTTPHandler(eps, log.With(logger, "component", "HTTP"))
+		eps         = todo.MakeServerEndpoints(service, todo.AuthorizationMiddleware(users), todo.ValidationMiddleware(limits))
+		webhookEps  = webhook.MakeServerEndpoints(webhooks, webhook.AuthorizationMiddleware(users))
+		httpHandler = makeHTTPHandler(eps, webhookEps, graph.MakeHTTPHandler(eps, users, broker, graphLimits, log.With(logger, "component", "GraphQL")), limits, logger)
 	)
 
+	grpcServer := grpc.NewServer()
+	pb.RegisterTodosServer(grpcServer, todo.MakeGRPCServer(eps, log.With(logger, "component", "gRPC")))
+
 	errs := make(chan error)
 	go func() {
 		c := make(chan os.Signal, 1)
@@ -46,13 +103,47 @@ func main() {
 
 	go func() {
 		logger.Log("transport", "SQL", "addr", dbTarget)
+		logger.Log("transport", "SQL", "webhooks", webhookDBTarget)
+		logger.Log("transport", "gRPC", "authorization", authAddr)
 		logger.Log("transport", "HTTP", "addr", httpAddr)
 		errs <- http.ListenAndServe(httpAddr, httpHandler)
 	}()
 
+	go func() {
+		logger.Log("transport", "gRPC", "addr", grpcAddr)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			errs <- err
+			return
+		}
+		errs <- grpcServer.Serve(listener)
+	}()
+
 	logger.Log("exit", <-errs)
 }
 
+// makeHTTPHandler serves the todos, webhooks below /webhooks and GraphQL
+// below /graphql. Its OpenAPI document, see openAPI, replaces the one of the
+// todo handler.
+func makeHTTPHandler(eps todo.Endpoints, webhookEps webhook.Endpoints, graphql http.Handler, limits todo.Limits, logger log.Logger) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(validate.MaxBodyBytes(limits.MaxBodyBytes))
+	openapi.Mount(r, openAPI())
+	r.Mount("/graphql", graphql)
+	r.Mount("/webhooks", webhook.MakeHTTPHandler(webhookEps, log.With(logger, "component", "HTTP")))
+	r.Mount("/", todo.MakeHTTPHandler(eps, log.With(logger, "component", "HTTP")))
+	return r
+}
+
+// openAPI documents every route of makeHTTPHandler
+func openAPI() openapi.Document {
+	doc := todo.OpenAPI()
+	doc.Info.Description += " Webhooks are managed below /webhooks, GraphQL is served at /graphql."
+	doc.Include("/webhooks", webhook.OpenAPI())
+	doc.Include("/graphql", graph.OpenAPI())
+	return doc
+}
+
 func envString(env, fallback string) string {
 	e := os.Getenv(env)
 	if e == "" {
@@ -60,3 +151,15 @@ func envString(env, fallback string) string {
 	}
 	return e
 }
+
+func envInt(env string, fallback int) int {
+	e := os.Getenv(env)
+	if e == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(e)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", env, err))
+	}
+	return i
+}
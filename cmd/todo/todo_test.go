@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/events"
+	"github.com/demeesterdev/todo-service/pkg/graph"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+	"github.com/demeesterdev/todo-service/pkg/webhook"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	todos, _ := todo.NewInMemService()
+	webhooks, _ := webhook.NewInMemService(ctx, webhook.DefaultConfig, log.NewNopLogger())
+
+	eps := todo.MakeServerEndpoints(todos)
+	h := makeHTTPHandler(eps, webhook.MakeServerEndpoints(webhooks), graph.MakeHTTPHandler(eps, users, events.NewBroker(), graph.DefaultLimits, log.NewNopLogger()), todo.DefaultLimits, log.NewNopLogger())
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + openapi.SpecPath)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var doc openapi.Document
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Contains(t, doc.Paths, "/webhooks/{id}")
+	assert.Contains(t, doc.Paths, "/graphql")
+
+	undocumented, unrouted, err := openapi.Diff(doc, h)
+	assert.NoError(t, err)
+	assert.Empty(t, undocumented, "routes missing from the OpenAPI document")
+	assert.Empty(t, unrouted, "OpenAPI operations without a route")
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+func (a *app) loginCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login [username]",
+		Short: "Log in and cache the session in the user config dir",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src := cmd.InOrStdin()
+			in := bufio.NewReader(src)
+
+			var username string
+			if len(args) == 1 {
+				username = args[0]
+			} else {
+				fmt.Fprint(cmd.ErrOrStderr(), "Username: ")
+				line, err := in.ReadString('\n')
+				if err != nil {
+					return err
+				}
+				username = strings.TrimSpace(line)
+			}
+
+			fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+			password, err := readPassword(src, in)
+			fmt.Fprintln(cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			s, err := a.users.Login(cmd.Context(), authorization.User{Username: username, Password: password})
+			var mfa *authorization.MFARequiredError
+			if errors.As(err, &mfa) {
+				fmt.Fprint(cmd.ErrOrStderr(), "Code: ")
+				line, err := in.ReadString('\n')
+				if err != nil {
+					return err
+				}
+				s, err = a.users.CompleteMFA(cmd.Context(), mfa.Challenge, strings.TrimSpace(line))
+				if err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+			if err := saveSession(session{Token: s.Token, User: s.User}); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Logged in as %s\n", s.User.Username)
+			return nil
+		},
+	}
+}
+
+func (a *app) logoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "End the session and remove it from the user config dir",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := loadSession()
+			if err != nil {
+				return err
+			}
+			// an expired session is gone already
+			err = a.users.RevokeSession(cmd.Context(), s.Token)
+			if err != nil && err != authorization.ErrInvalidToken {
+				return err
+			}
+			return removeSession()
+		},
+	}
+}
+
+// readPassword reads without echo when src is a terminal and reads a line
+// from in otherwise, in buffers src. Input buffered already, like a line
+// typed ahead, is read from in as well.
+func readPassword(src io.Reader, in *bufio.Reader) (string, error) {
+	if f, ok := src.(*os.File); ok && in.Buffered() == 0 && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		return string(password), err
+	}
+	line, err := in.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (a *app) whoamiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the logged in user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := loadSession()
+			if err != nil {
+				return err
+			}
+			u, err := a.users.GetUser(s.context(cmd.Context()), s.User.ID)
+			if err != nil {
+				return err
+			}
+			return a.printUser(cmd.OutOrStdout(), u)
+		},
+	}
+}
+
+func (a *app) addCmd() *cobra.Command {
+	var description, team string
+	cmd := &cobra.Command{
+		Use:   "add <title>",
+		Short: "Add a todo",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := loadSession()
+			if err != nil {
+				return err
+			}
+			var teamID uuid.UUID
+			if team != "" {
+				if teamID, err = parseID(team); err != nil {
+					return err
+				}
+			}
+			t, err := a.todos.AddTodo(s.context(cmd.Context()), todo.Todo{
+				Title:       strings.Join(args, " "),
+				Description: description,
+				OwnerID:     s.User.ID,
+				TeamID:      teamID,
+			})
+			if err != nil {
+				return err
+			}
+			return a.printTodo(cmd.OutOrStdout(), t)
+		},
+	}
+	cmd.Flags().StringVarP(&description, "description", "d", "", "description of the todo")
+	cmd.Flags().StringVar(&team, "team", "", "id of the team sharing the todo")
+	return cmd
+}
+
+func (a *app) lsCmd() *cobra.Command {
+	var (
+		all    bool
+		owner  string
+		done   bool
+		open   bool
+		search string
+	)
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List todos, by default those of the logged in user",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if done && open {
+				return errors.New("--done and --open are mutually exclusive")
+			}
+
+			s, err := loadSession()
+			if err != nil {
+				return err
+			}
+			ctx := s.context(cmd.Context())
+
+			var todos []todo.Todo
+			switch {
+			case all:
+				todos, err = a.todos.GetTodos(ctx)
+			case owner != "":
+				var id uuid.UUID
+				id, err = parseID(owner)
+				if err != nil {
+					return err
+				}
+				todos, err = a.todos.GetTodosOwned(ctx, authorization.User{ID: id})
+			default:
+				todos, err = a.todos.GetTodosOwned(ctx, s.User)
+			}
+			if err != nil {
+				return err
+			}
+
+			filtered := todos[:0]
+			for _, t := range todos {
+				if (done && !t.Done) || (open && t.Done) {
+					continue
+				}
+				if search != "" && !strings.Contains(strings.ToLower(t.Title+" "+t.Description), strings.ToLower(search)) {
+					continue
+				}
+				filtered = append(filtered, t)
+			}
+			return a.printTodos(cmd.OutOrStdout(), filtered)
+		},
+	}
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "list the todos of all users, for admins")
+	cmd.Flags().StringVar(&owner, "owner", "", "list the todos of this user id")
+	cmd.Flags().BoolVar(&done, "done", false, "only list done todos")
+	cmd.Flags().BoolVar(&open, "open", false, "only list open todos")
+	cmd.Flags().StringVarP(&search, "search", "s", "", "only list todos containing this text")
+	return cmd
+}
+
+func (a *app) showCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show <id>",
+		Short:             "Show a todo",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := sessionContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			t, err := a.todos.GetTodo(ctx, id)
+			if err != nil {
+				return err
+			}
+			return a.printTodo(cmd.OutOrStdout(), t)
+		},
+	}
+}
+
+// editable are the fields of a todo presented in the editor
+type editable struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Done        bool   `yaml:"done"`
+}
+
+func (a *app) editCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "edit <id>",
+		Short:             "Edit a todo in $EDITOR",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := sessionContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			t, err := a.todos.GetTodo(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			before, err := yaml.Marshal(editable{Title: t.Title, Description: t.Description, Done: t.Done})
+			if err != nil {
+				return err
+			}
+			after, err := editInEditor(before)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(before, after) {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no changes")
+				return nil
+			}
+
+			var e editable
+			if err := yaml.Unmarshal(after, &e); err != nil {
+				return fmt.Errorf("parsing edited todo: %w", err)
+			}
+			t.Title, t.Description, t.Done = e.Title, e.Description, e.Done
+
+			t, err = a.todos.UpdateTodo(ctx, id, t)
+			if err != nil {
+				return err
+			}
+			return a.printTodo(cmd.OutOrStdout(), t)
+		},
+	}
+}
+
+// editInEditor opens content in $EDITOR, or vi when unset, and returns the result
+func editInEditor(content []byte) ([]byte, error) {
+	f, err := os.CreateTemp("", "todoctl-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := envString("EDITOR", "vi")
+	// $EDITOR may contain arguments, like "code --wait"
+	parts := strings.Fields(editor)
+	c := exec.Command(parts[0], append(parts[1:], f.Name())...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("running %s: %w", editor, err)
+	}
+	return os.ReadFile(f.Name())
+}
+
+func (a *app) doneCmd() *cobra.Command {
+	var undo bool
+	cmd := &cobra.Command{
+		Use:               "done <id>...",
+		Short:             "Mark todos as done",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := sessionContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return a.eachTodo(cmd, args, func(id uuid.UUID) (todo.Todo, error) {
+				t, err := a.todos.GetTodo(ctx, id)
+				if err != nil {
+					return todo.Todo{}, err
+				}
+				t.Done = !undo
+				return a.todos.UpdateTodo(ctx, id, t)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&undo, "undo", false, "reopen the todos instead")
+	return cmd
+}
+
+func (a *app) rmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm <id>...",
+		Short:             "Move todos to the trash",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := sessionContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				id, err := parseID(arg)
+				if err != nil {
+					return err
+				}
+				if err := a.todos.DeleteTodo(ctx, id); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s moved to the trash, use todoctl restore to bring it back\n", id)
+			}
+			return nil
+		},
+	}
+}
+
+func (a *app) restoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <id>...",
+		Short: "Restore todos from the trash",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := sessionContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return a.eachTodo(cmd, args, func(id uuid.UUID) (todo.Todo, error) {
+				return a.todos.RestoreTodo(ctx, id)
+			})
+		},
+	}
+}
+
+// eachTodo applies fn to every id in args and prints the resulting todos
+func (a *app) eachTodo(cmd *cobra.Command, args []string, fn func(id uuid.UUID) (todo.Todo, error)) error {
+	todos := make([]todo.Todo, 0, len(args))
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		t, err := fn(id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		todos = append(todos, t)
+	}
+	return a.printTodos(cmd.OutOrStdout(), todos)
+}
+
+func parseID(raw string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%q is not a valid id", raw)
+	}
+	return id, nil
+}
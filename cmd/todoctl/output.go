@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+var outputFormats = []string{"table", "json", "yaml"}
+
+func validOutput(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// todoView is how a todo is printed, yaml has no use for the json tags of todo.Todo
+type todoView struct {
+	ID          string `json:"id" yaml:"id"`
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Done        bool   `json:"done" yaml:"done"`
+	OwnerID     string `json:"owner_id" yaml:"owner_id"`
+	TeamID      string `json:"team_id,omitempty" yaml:"team_id,omitempty"`
+}
+
+func viewTodo(t todo.Todo) todoView {
+	var teamID string
+	if t.TeamID != uuid.Nil {
+		teamID = t.TeamID.String()
+	}
+	return todoView{
+		ID:          t.ID.String(),
+		Title:       t.Title,
+		Description: t.Description,
+		Done:        t.Done,
+		OwnerID:     t.OwnerID.String(),
+		TeamID:      teamID,
+	}
+}
+
+type userView struct {
+	ID       string `json:"id" yaml:"id"`
+	Username string `json:"username" yaml:"username"`
+}
+
+func viewUser(u authorization.User) userView {
+	return userView{ID: u.ID.String(), Username: u.Username}
+}
+
+func (a *app) printTodos(w io.Writer, todos []todo.Todo) error {
+	views := make([]todoView, len(todos))
+	for i := range todos {
+		views[i] = viewTodo(todos[i])
+	}
+
+	if a.output != "table" {
+		return encode(w, a.output, views)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDONE\tTITLE")
+	for _, v := range views {
+		done := " "
+		if v.Done {
+			done = "x"
+		}
+		fmt.Fprintf(tw, "%s\t[%s]\t%s\n", v.ID, done, v.Title)
+	}
+	return tw.Flush()
+}
+
+func (a *app) printTodo(w io.Writer, t todo.Todo) error {
+	v := viewTodo(t)
+	if a.output != "table" {
+		return encode(w, a.output, v)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", v.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", v.Title)
+	fmt.Fprintf(tw, "Description:\t%s\n", v.Description)
+	fmt.Fprintf(tw, "Done:\t%t\n", v.Done)
+	fmt.Fprintf(tw, "Owner:\t%s\n", v.OwnerID)
+	return tw.Flush()
+}
+
+func (a *app) printUser(w io.Writer, u authorization.User) error {
+	v := viewUser(u)
+	if a.output != "table" {
+		return encode(w, a.output, v)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", v.ID)
+	fmt.Fprintf(tw, "Username:\t%s\n", v.Username)
+	return tw.Flush()
+}
+
+func encode(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+var errNotLoggedIn = errors.New("not logged in, run todoctl login first")
+
+// session is cached in the user config dir after a successful login.
+// Token is sent along with the requests to the todo and authorization
+// services.
+type session struct {
+	Token string             `json:"token"`
+	User  authorization.User `json:"user"`
+}
+
+// context returns ctx carrying the token of s
+func (s session) context(ctx context.Context) context.Context {
+	return authorization.WithSessionToken(ctx, s.Token)
+}
+
+// sessionContext returns ctx carrying the token of the cached session
+func sessionContext(ctx context.Context) (context.Context, error) {
+	s, err := loadSession()
+	if err != nil {
+		return nil, err
+	}
+	return s.context(ctx), nil
+}
+
+func sessionPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "todoctl", "session.json"), nil
+}
+
+func loadSession() (session, error) {
+	path, err := sessionPath()
+	if err != nil {
+		return session{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return session{}, errNotLoggedIn
+	}
+	if err != nil {
+		return session{}, err
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return session{}, err
+	}
+	return s, nil
+}
+
+func saveSession(s session) error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func removeSession() error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
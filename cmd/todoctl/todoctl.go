@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+const (
+	defaultTodoAddr = "localhost:8081"
+	defaultAuthAddr = "localhost:8082"
+)
+
+// app holds the state shared by all commands
+type app struct {
+	todoAddr string
+	authAddr string
+	output   string
+
+	todos todo.Service
+	users authorization.Service
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+
+	root := &cobra.Command{
+		Use:           "todoctl",
+		Short:         "Manage todos from the terminal",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return a.connect()
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.todoAddr, "todo-addr", envString("TODOCTL_TODO_ADDR", defaultTodoAddr), "address of the todo service")
+	flags.StringVar(&a.authAddr, "auth-addr", envString("TODOCTL_AUTH_ADDR", defaultAuthAddr), "address of the authorization service")
+	flags.StringVarP(&a.output, "output", "o", "table", "output format: table, json or yaml")
+	root.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return outputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	root.AddCommand(
+		a.loginCmd(),
+		a.logoutCmd(),
+		a.whoamiCmd(),
+		a.addCmd(),
+		a.lsCmd(),
+		a.showCmd(),
+		a.editCmd(),
+		a.doneCmd(),
+		a.rmCmd(),
+		a.restoreCmd(),
+	)
+	return root
+}
+
+// connect creates the clients of both services
+func (a *app) connect() error {
+	if !validOutput(a.output) {
+		return fmt.Errorf("unknown output format %q", a.output)
+	}
+
+	todos, err := todo.MakeClientEndpoints(a.todoAddr)
+	if err != nil {
+		return err
+	}
+	users, err := authorizationTrsp.MakeClientEndpoints(a.authAddr)
+	if err != nil {
+		return err
+	}
+	a.todos = todos
+	a.users = users
+	return nil
+}
+
+// completeTodoIDs completes the ids of the todos of the logged in user
+func (a *app) completeTodoIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := a.connect(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	s, err := loadSession()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	todos, err := a.todos.GetTodosOwned(s.context(context.Background()), s.User)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	ids := make([]string, 0, len(todos))
+	for _, t := range todos {
+		ids = append(ids, t.ID.String()+"\t"+t.Title)
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func envString(env, fallback string) string {
+	e := os.Getenv(env)
+	if e == "" {
+		return fallback
+	}
+	return e
+}
@@ -11,14 +11,20 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
+// Params are the argon2id parameters passwords are hashed with,
+// use NewParams or DefaultConfig to create them
 type Params struct {
 	memory      uint32
 	iterations  uint32
 	parallelism uint8
 	saltLength  uint32
 	keyLength   uint32
+	pepper      *Keyring
 }
 
+// Prefix starts every hash created by HashPassword
+const Prefix = "$argon2id$"
+
 const (
 	DefaultMemory      = 64 * 1024
 	DefaultIterations  = 3
@@ -30,6 +36,7 @@ const (
 var (
 	ErrInvalidHash         = errors.New("the encoded hash is not in the correct format")
 	ErrIncompatibleVersion = errors.New("incompatible version of argon2")
+	ErrInvalidParams       = errors.New("invalid argon2id parameters")
 	DefaultConfig          = Params{
 		memory:      DefaultMemory,
 		iterations:  DefaultIterations,
@@ -39,34 +46,144 @@ var (
 	}
 )
 
+// NewParams returns parameters using memory KiB of memory, iterations passes
+// over it and parallelism threads, creating keyLength byte keys from
+// saltLength byte salts
+func NewParams(memory, iterations uint32, parallelism uint8, saltLength, keyLength uint32) (Params, error) {
+	switch {
+	case iterations < 1:
+		return Params{}, fmt.Errorf("%w: iterations must be at least 1", ErrInvalidParams)
+	case parallelism < 1:
+		return Params{}, fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidParams)
+	case memory < 8*uint32(parallelism):
+		return Params{}, fmt.Errorf("%w: memory must be at least 8 KiB per thread", ErrInvalidParams)
+	case saltLength < 8:
+		return Params{}, fmt.Errorf("%w: salt length must be at least 8 bytes", ErrInvalidParams)
+	case keyLength < 16:
+		return Params{}, fmt.Errorf("%w: key length must be at least 16 bytes", ErrInvalidParams)
+	}
+	return Params{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+		saltLength:  saltLength,
+		keyLength:   keyLength,
+	}, nil
+}
+
+// ParseParams returns the parameters encodedHash was created with
+func ParseParams(encodedHash string) (Params, error) {
+	p, _, _, _, err := decodeHash(encodedHash)
+	if err != nil {
+		return Params{}, err
+	}
+	return *p, nil
+}
+
+// Memory returns the memory in KiB
+func (p Params) Memory() uint32 { return p.memory }
+
+// Iterations returns the number of passes over the memory
+func (p Params) Iterations() uint32 { return p.iterations }
+
+// Parallelism returns the number of threads
+func (p Params) Parallelism() uint8 { return p.parallelism }
+
+// SaltLength returns the length of the salt in bytes
+func (p Params) SaltLength() uint32 { return p.saltLength }
+
+// KeyLength returns the length of the key in bytes
+func (p Params) KeyLength() uint32 { return p.keyLength }
+
+// WithPepper returns a copy of p peppering new hashes with the current key
+// of k. Hashes peppered with the other keys of k are still verified.
+func (p Params) WithPepper(k *Keyring) Params {
+	p.pepper = k
+	return p
+}
+
+// Pepper returns the keyring of p, or nil when passwords aren't peppered
+func (p Params) Pepper() *Keyring { return p.pepper }
+
+func (p Params) String() string {
+	s := fmt.Sprintf("m=%d,t=%d,p=%d,salt=%d,key=%d", p.memory, p.iterations, p.parallelism, p.saltLength, p.keyLength)
+	if p.pepper != nil {
+		s += ",pepper=" + p.pepper.current
+	}
+	return s
+}
+
+// NeedsRehash reports whether encodedHash was created with parameters weaker
+// than p: less memory, fewer iterations, or a shorter salt or key.
+// Parallelism is not compared as it doesn't add to the cost of an attack.
+// Hashes not peppered with the current key of p need a rehash as well.
+func NeedsRehash(encodedHash string, p Params) (bool, error) {
+	current, _, _, keyID, err := decodeHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	if p.pepper != nil && keyID != p.pepper.current {
+		return true, nil
+	}
+	return current.memory < p.memory ||
+		current.iterations < p.iterations ||
+		current.saltLength < p.saltLength ||
+		current.keyLength < p.keyLength, nil
+}
+
 func HashPassword(password string, p Params) (encodedHash string, err error) {
 	salt, err := generateRandomBytes(p.saltLength)
 	if err != nil {
 		return "", err
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	input := []byte(password)
+	var keyID string
+	if p.pepper != nil {
+		keyID = p.pepper.current
+		if input, err = p.pepper.pepper(keyID, input); err != nil {
+			return "", err
+		}
+	}
+
+	hash := argon2.IDKey(input, salt, p.iterations, p.memory, p.parallelism, p.keyLength)
 
 	// Base64 encode the salt and hashed password.
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
+	// The id of the pepper key is recorded as keyid parameter of the PHC string format.
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", p.memory, p.iterations, p.parallelism)
+	if keyID != "" {
+		params += ",keyid=" + keyID
+	}
+
 	// Return a string using the standard encoded hash representation.
-	encodedHash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism, b64Salt, b64Hash)
+	encodedHash = fmt.Sprintf("$argon2id$v=%d$%s$%s$%s", argon2.Version, params, b64Salt, b64Hash)
 
 	return encodedHash, nil
 }
 
-func ComparePasswordAndHash(password, encodedHash string) (match bool, err error) {
+// ComparePasswordAndHash reports whether password matches encodedHash.
+// pepper holds the key encodedHash was peppered with, it may be nil for
+// hashes without pepper.
+func ComparePasswordAndHash(password, encodedHash string, pepper *Keyring) (match bool, err error) {
 	// Extract the parameters, salt and derived key from the encoded password
 	// hash.
-	p, salt, hash, err := decodeHash(encodedHash)
+	p, salt, hash, keyID, err := decodeHash(encodedHash)
 	if err != nil {
 		return false, err
 	}
 
+	input := []byte(password)
+	if keyID != "" {
+		if input, err = pepper.pepper(keyID, input); err != nil {
+			return false, err
+		}
+	}
+
 	// Derive the key from the other password using the same parameters.
-	otherHash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	otherHash := argon2.IDKey(input, salt, p.iterations, p.memory, p.parallelism, p.keyLength)
 
 	// Check that the contents of the hashed passwords are identical. Note
 	// that we are using the subtle.ConstantTimeCompare() function for this
@@ -77,40 +194,47 @@ func ComparePasswordAndHash(password, encodedHash string) (match bool, err error
 	return false, nil
 }
 
-func decodeHash(encodedHash string) (p *Params, salt, hash []byte, err error) {
+func decodeHash(encodedHash string) (p *Params, salt, hash []byte, keyID string, err error) {
 	vals := strings.Split(encodedHash, "$")
-	if len(vals) != 6 {
-		return nil, nil, nil, ErrInvalidHash
+	if len(vals) != 6 || vals[1] != "argon2id" {
+		return nil, nil, nil, "", ErrInvalidHash
 	}
 
 	var version int
 	_, err = fmt.Sscanf(vals[2], "v=%d", &version)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, "", err
 	}
 	if version != argon2.Version {
-		return nil, nil, nil, ErrIncompatibleVersion
+		return nil, nil, nil, "", ErrIncompatibleVersion
 	}
 
+	params := strings.Split(vals[3], ",")
+	switch {
+	case len(params) == 4 && strings.HasPrefix(params[3], "keyid="):
+		keyID = strings.TrimPrefix(params[3], "keyid=")
+	case len(params) != 3:
+		return nil, nil, nil, "", ErrInvalidHash
+	}
 	p = &Params{}
-	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
+	_, err = fmt.Sscanf(strings.Join(params[:3], ","), "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, "", err
 	}
 
 	salt, err = base64.RawStdEncoding.Strict().DecodeString(vals[4])
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, "", err
 	}
 	p.saltLength = uint32(len(salt))
 
 	hash, err = base64.RawStdEncoding.Strict().DecodeString(vals[5])
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, "", err
 	}
 	p.keyLength = uint32(len(hash))
 
-	return p, salt, hash, nil
+	return p, salt, hash, keyID, nil
 }
 
 func generateRandomBytes(n uint32) ([]byte, error) {
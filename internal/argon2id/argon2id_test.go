@@ -0,0 +1,168 @@
+package argon2id
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewParams(t *testing.T) {
+	p, err := NewParams(64, 1, 2, 16, 32)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(64), p.Memory())
+	assert.Equal(t, uint32(1), p.Iterations())
+	assert.Equal(t, uint8(2), p.Parallelism())
+	assert.Equal(t, uint32(16), p.SaltLength())
+	assert.Equal(t, uint32(32), p.KeyLength())
+
+	for _, invalid := range [][5]uint32{
+		{64, 0, 1, 16, 32},
+		{64, 1, 0, 16, 32},
+		{8, 1, 2, 16, 32},
+		{64, 1, 1, 4, 32},
+		{64, 1, 1, 16, 8},
+	} {
+		_, err := NewParams(invalid[0], invalid[1], uint8(invalid[2]), invalid[3], invalid[4])
+		assert.ErrorIs(t, err, ErrInvalidParams, "%v", invalid)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak, _ := NewParams(64, 1, 1, 16, 32)
+	hash, err := HashPassword("secret", weak)
+	assert.NoError(t, err)
+
+	parsed, err := ParseParams(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, weak, parsed)
+
+	testCases := []struct {
+		name        string
+		params      Params
+		needsRehash bool
+	}{
+		{name: "same parameters", params: weak},
+		{name: "more memory", params: Params{memory: 128, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}, needsRehash: true},
+		{name: "more iterations", params: Params{memory: 64, iterations: 2, parallelism: 1, saltLength: 16, keyLength: 32}, needsRehash: true},
+		{name: "longer key", params: Params{memory: 64, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 64}, needsRehash: true},
+		{name: "more parallelism", params: Params{memory: 64, iterations: 1, parallelism: 4, saltLength: 16, keyLength: 32}},
+		{name: "less memory", params: Params{memory: 32, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			needsRehash, err := NeedsRehash(hash, tc.params)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.needsRehash, needsRehash)
+		})
+	}
+
+	_, err = NeedsRehash("not a hash", weak)
+	assert.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestCalibrate(t *testing.T) {
+	// a host taking a millisecond per MiB and iteration
+	measure := func(p Params) time.Duration {
+		return time.Duration(p.memory) * time.Duration(p.iterations) * time.Millisecond / 1024
+	}
+
+	testCases := []struct {
+		name        string
+		target      time.Duration
+		maxMemory   uint32
+		memory      uint32
+		iterations  uint32
+		expectError bool
+	}{
+		{name: "should add iterations when memory is cheap", target: 100 * time.Millisecond, maxMemory: 16 * 1024, memory: 16 * 1024, iterations: 6},
+		{name: "should lower memory when a single iteration is too slow", target: 100 * time.Millisecond, maxMemory: 1024 * 1024, memory: 64 * 1024, iterations: 1},
+		{name: "should fail when nothing fits", target: time.Microsecond, maxMemory: 1024, expectError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := calibrate(tc.target, tc.maxMemory, 1, measure)
+			if tc.expectError {
+				assert.ErrorIs(t, err, ErrInvalidParams)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.memory, c.Params.Memory())
+			assert.Equal(t, tc.iterations, c.Params.Iterations())
+			assert.LessOrEqual(t, c.Duration, tc.target)
+		})
+	}
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, MinPepperLength)
+}
+
+func TestPepper(t *testing.T) {
+	params, _ := NewParams(64, 1, 1, 16, 32)
+	old, err := NewKeyring("old", map[string][]byte{"old": testKey(1)})
+	assert.NoError(t, err)
+	rotated, err := NewKeyring("new", map[string][]byte{"old": testKey(1), "new": testKey(2)})
+	assert.NoError(t, err)
+
+	hash, err := HashPassword("secret", params.WithPepper(old))
+	assert.NoError(t, err)
+	assert.Contains(t, hash, ",keyid=old$")
+
+	match, err := ComparePasswordAndHash("secret", hash, old)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = ComparePasswordAndHash("wrong", hash, old)
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	// the hash can't be verified without the key
+	_, err = ComparePasswordAndHash("secret", hash, nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = ComparePasswordAndHash("secret", hash, &Keyring{current: "other", keys: map[string][]byte{"other": testKey(1)}})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// after a rotation old hashes are verified and need a rehash
+	match, err = ComparePasswordAndHash("secret", hash, rotated)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	needsRehash, err := NeedsRehash(hash, params.WithPepper(rotated))
+	assert.NoError(t, err)
+	assert.True(t, needsRehash)
+	needsRehash, err = NeedsRehash(hash, params.WithPepper(old))
+	assert.NoError(t, err)
+	assert.False(t, needsRehash)
+
+	// unpeppered hashes get peppered once a keyring is configured
+	plain, _ := HashPassword("secret", params)
+	match, err = ComparePasswordAndHash("secret", plain, rotated)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	needsRehash, err = NeedsRehash(plain, params.WithPepper(rotated))
+	assert.NoError(t, err)
+	assert.True(t, needsRehash)
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, err := NewKeyring("missing", map[string][]byte{"k": testKey(1)})
+	assert.ErrorIs(t, err, ErrInvalidKeyring)
+	_, err = NewKeyring("k", map[string][]byte{"k": []byte("short")})
+	assert.ErrorIs(t, err, ErrInvalidKeyring)
+	_, err = NewKeyring("k$1", map[string][]byte{"k$1": testKey(1)})
+	assert.ErrorIs(t, err, ErrInvalidKeyring)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pepper.json")
+	key := base64.StdEncoding.EncodeToString(testKey(1))
+	assert.NoError(t, os.WriteFile(path, []byte(`{"current": "k1", "keys": {"k1": "`+key+`"}}`), 0600))
+
+	k, err := LoadKeyring(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", k.Current())
+	assert.Equal(t, []string{"k1"}, k.IDs())
+}
@@ -0,0 +1,71 @@
+package argon2id
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Calibration is the result of Calibrate
+type Calibration struct {
+	Params Params
+	// Duration is the median time hashing a password with Params took
+	Duration time.Duration
+}
+
+// Calibrate finds the strongest parameters hashing a password within target
+// on the current host using at most maxMemory KiB and parallelism threads.
+// Memory is preferred over iterations: memory is raised to maxMemory first,
+// and only lowered when a single iteration already exceeds target.
+// Every candidate is hashed samples times and its median duration is used.
+func Calibrate(target time.Duration, maxMemory uint32, parallelism uint8, samples int) (Calibration, error) {
+	if samples < 1 {
+		samples = 1
+	}
+	return calibrate(target, maxMemory, parallelism, func(p Params) time.Duration {
+		durations := make([]time.Duration, samples)
+		for i := range durations {
+			salt := make([]byte, p.saltLength)
+			start := time.Now()
+			argon2.IDKey([]byte("calibration"), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+			durations[i] = time.Since(start)
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		return durations[len(durations)/2]
+	})
+}
+
+func calibrate(target time.Duration, maxMemory uint32, parallelism uint8, measure func(Params) time.Duration) (Calibration, error) {
+	p, err := NewParams(maxMemory, 1, parallelism, DefaultSaltLength, DefaultKeyLength)
+	if err != nil {
+		return Calibration{}, err
+	}
+	minMemory := 8 * uint32(parallelism)
+
+	// lower the memory until a single iteration fits
+	d := measure(p)
+	for d > target {
+		if p.memory/2 < minMemory {
+			return Calibration{}, fmt.Errorf("%w: hashing with %d KiB already takes %s, more than %s", ErrInvalidParams, p.memory, d, target)
+		}
+		p.memory /= 2
+		d = measure(p)
+	}
+
+	// duration is about linear in the iterations, estimate and correct
+	best := Calibration{Params: p, Duration: d}
+	if d > 0 {
+		p.iterations = uint32(target / d)
+	}
+	for p.iterations > best.Params.iterations {
+		d = measure(p)
+		if d <= target {
+			best = Calibration{Params: p, Duration: d}
+			break
+		}
+		p.iterations--
+	}
+	return best, nil
+}
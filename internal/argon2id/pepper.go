@@ -0,0 +1,99 @@
+package argon2id
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// MinPepperLength is the minimum length of a pepper key in bytes
+const MinPepperLength = 32
+
+var (
+	ErrUnknownKey     = errors.New("the hash was peppered with an unknown key")
+	ErrInvalidKeyring = errors.New("invalid pepper keyring")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Keyring holds the secret pepper keys by id.
+// New hashes are peppered with the current key, the others are only used to
+// verify hashes created before a rotation.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring returns a keyring peppering new hashes with the key with id current
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q is missing", ErrInvalidKeyring, current)
+	}
+	k := &Keyring{current: current, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("%w: key id %q may only contain letters, digits, '_' and '-'", ErrInvalidKeyring, id)
+		}
+		if len(key) < MinPepperLength {
+			return nil, fmt.Errorf("%w: key %q must be at least %d bytes", ErrInvalidKeyring, id, MinPepperLength)
+		}
+		k.keys[id] = append([]byte(nil), key...)
+	}
+	return k, nil
+}
+
+// keyringFile is the format read by LoadKeyring, keys are base64 encoded
+type keyringFile struct {
+	Current string            `json:"current"`
+	Keys    map[string][]byte `json:"keys"`
+}
+
+// LoadKeyring reads a keyring from a JSON file like
+//
+//	{"current": "2024-06", "keys": {"2024-01": "<base64>", "2024-06": "<base64>"}}
+//
+// To rotate, add a key and make it current. Keep the old key until no hash
+// refers to it anymore, hashes are moved to the current key on login.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyringFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyring, err)
+	}
+	return NewKeyring(f.Current, f.Keys)
+}
+
+// Current returns the id of the key peppering new hashes
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+// IDs returns the ids of all keys in the keyring
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// pepper returns the HMAC-SHA256 of password with the key with id
+func (k *Keyring) pepper(id string, password []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(password)
+	return mac.Sum(nil), nil
+}
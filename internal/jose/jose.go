@@ -0,0 +1,163 @@
+// Package jose implements the parts of JSON Web Signature (RFC 7515) and
+// JSON Web Key (RFC 7517) OpenID Connect needs: compact RS256 signed tokens
+// and sets of RSA public keys.
+package jose
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	// Algorithm is the only signing algorithm, RFC 7518 RS256
+	Algorithm = "RS256"
+	// KeySize is the size in bits of generated keys
+	KeySize = 2048
+)
+
+var (
+	ErrMalformed            = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrSignature            = errors.New("invalid signature")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Header is the protected header of a token
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// Key is a public RSA key as JSON Web Key
+type Key struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// KeySet is a JSON Web Key Set as published at a jwks_uri
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+// GenerateKey returns a new RSA key of KeySize bits
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, KeySize)
+}
+
+// PublicKey returns the signing key pub as JSON Web Key with id kid
+func PublicKey(kid string, pub *rsa.PublicKey) Key {
+	return Key{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: Algorithm,
+		KeyID:     kid,
+		N:         encoding.EncodeToString(pub.N.Bytes()),
+		E:         encoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// RSA returns the public key of k
+func (k Key) RSA() (*rsa.PublicKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	n, err := encoding.DecodeString(k.N)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	e, err := encoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, ErrMalformed
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// Find returns the key with id kid
+func (s KeySet) Find(kid string) (Key, bool) {
+	for _, k := range s.Keys {
+		if k.KeyID == kid {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Sign returns claims as compact token signed by key, kid identifies the
+// key in the published KeySet
+func Sign(key *rsa.PrivateKey, kid string, claims interface{}) (string, error) {
+	header, err := json.Marshal(Header{Algorithm: Algorithm, KeyID: kid, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + encoding.EncodeToString(sig), nil
+}
+
+// Verify checks the signature of token with the key of keys named in its
+// header and decodes its payload into claims. The claims themselves, like
+// the expiry, are left to the caller.
+func Verify(token string, keys KeySet, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrMalformed
+	}
+	var header Header
+	if err := decodePart(parts[0], &header); err != nil {
+		return err
+	}
+	// the algorithm is fixed, a token can't downgrade it to none or HMAC
+	if header.Algorithm != Algorithm {
+		return ErrUnsupportedAlgorithm
+	}
+	k, ok := keys.Find(header.KeyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+	pub, err := k.RSA()
+	if err != nil {
+		return err
+	}
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+		return ErrSignature
+	}
+	return decodePart(parts[1], claims)
+}
+
+func decodePart(part string, v interface{}) error {
+	b, err := encoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
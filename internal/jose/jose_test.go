@@ -0,0 +1,54 @@
+package jose
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type claims struct {
+	Subject string `json:"sub"`
+}
+
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey()
+	assert.NoError(t, err)
+	keys := KeySet{Keys: []Key{PublicKey("k1", &key.PublicKey)}}
+
+	token, err := Sign(key, "k1", claims{Subject: "alice"})
+	assert.NoError(t, err)
+	var got claims
+	assert.NoError(t, Verify(token, keys, &got))
+	assert.Equal(t, "alice", got.Subject)
+
+	// the key is looked up by its id
+	assert.Equal(t, ErrUnknownKey, Verify(token, KeySet{Keys: []Key{PublicKey("k2", &key.PublicKey)}}, &got))
+	other, _ := GenerateKey()
+	assert.Equal(t, ErrSignature, Verify(token, KeySet{Keys: []Key{PublicKey("k1", &other.PublicKey)}}, &got))
+
+	// the payload can't be changed
+	parts := strings.Split(token, ".")
+	payload, _ := json.Marshal(claims{Subject: "mallory"})
+	assert.Equal(t, ErrSignature, Verify(parts[0]+"."+encoding.EncodeToString(payload)+"."+parts[2], keys, &got))
+
+	// unsigned tokens are rejected
+	header, _ := json.Marshal(Header{Algorithm: "none", KeyID: "k1"})
+	assert.Equal(t, ErrUnsupportedAlgorithm, Verify(encoding.EncodeToString(header)+"."+parts[1]+".", keys, &got))
+	assert.Equal(t, ErrMalformed, Verify("not a token", keys, &got))
+}
+
+func TestKeyJSON(t *testing.T) {
+	key, _ := GenerateKey()
+	b, err := json.Marshal(KeySet{Keys: []Key{PublicKey("k1", &key.PublicKey)}})
+	assert.NoError(t, err)
+
+	var keys KeySet
+	assert.NoError(t, json.Unmarshal(b, &keys))
+	assert.Equal(t, "RSA", keys.Keys[0].KeyType)
+	assert.Equal(t, "AQAB", keys.Keys[0].E)
+	pub, err := keys.Keys[0].RSA()
+	assert.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(pub))
+}
@@ -0,0 +1,162 @@
+// Package legacyhash verifies password hashes imported from other systems.
+// New passwords are always hashed with argon2id, legacy hashes are only
+// compared until the user logs in and is migrated.
+//
+// Supported are bcrypt ($2a$, $2b$, $2y$) and the passlib formats of scrypt
+// ($scrypt$ln=,r=,p=$salt$hash) and PBKDF2-SHA256 ($pbkdf2-sha256$rounds$salt$hash)
+// with salt and hash in adapted base64, using "." instead of "+" without padding.
+package legacyhash
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	ScryptPrefix = "$scrypt$"
+	PBKDF2Prefix = "$pbkdf2-sha256$"
+
+	// the maximum costs accepted, imported hashes must not be a way to make
+	// logins arbitrarily expensive
+	maxBcryptCost   = 16
+	maxScryptLogN   = 20
+	maxScryptRP     = 64
+	maxPBKDF2Rounds = 10_000_000
+)
+
+var (
+	ErrUnsupportedHash = errors.New("unsupported password hash")
+	ErrInvalidHash     = errors.New("the encoded hash is not in the correct format")
+)
+
+// Supported reports whether encodedHash is of a supported legacy scheme
+func Supported(encodedHash string) bool {
+	return isBcrypt(encodedHash) ||
+		strings.HasPrefix(encodedHash, ScryptPrefix) ||
+		strings.HasPrefix(encodedHash, PBKDF2Prefix)
+}
+
+// Validate checks that encodedHash is a well-formed hash of a supported scheme
+// with an acceptable cost
+func Validate(encodedHash string) error {
+	switch {
+	case isBcrypt(encodedHash):
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+		if cost > maxBcryptCost {
+			return fmt.Errorf("%w: bcrypt cost %d exceeds %d", ErrInvalidHash, cost, maxBcryptCost)
+		}
+		return nil
+	case strings.HasPrefix(encodedHash, ScryptPrefix):
+		_, err := decodeScrypt(encodedHash)
+		return err
+	case strings.HasPrefix(encodedHash, PBKDF2Prefix):
+		_, err := decodePBKDF2(encodedHash)
+		return err
+	}
+	return ErrUnsupportedHash
+}
+
+// Compare reports whether password matches encodedHash
+func Compare(password, encodedHash string) (match bool, err error) {
+	switch {
+	case isBcrypt(encodedHash):
+		if err := Validate(encodedHash); err != nil {
+			return false, err
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(encodedHash, ScryptPrefix):
+		h, err := decodeScrypt(encodedHash)
+		if err != nil {
+			return false, err
+		}
+		other, err := scrypt.Key([]byte(password), h.salt, 1<<h.logN, h.r, h.p, len(h.hash))
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare(h.hash, other) == 1, nil
+	case strings.HasPrefix(encodedHash, PBKDF2Prefix):
+		h, err := decodePBKDF2(encodedHash)
+		if err != nil {
+			return false, err
+		}
+		other := pbkdf2.Key([]byte(password), h.salt, h.rounds, len(h.hash), sha256.New)
+		return subtle.ConstantTimeCompare(h.hash, other) == 1, nil
+	}
+	return false, ErrUnsupportedHash
+}
+
+func isBcrypt(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+type scryptHash struct {
+	logN, r, p int
+	salt, hash []byte
+}
+
+func decodeScrypt(encodedHash string) (h scryptHash, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) != 5 {
+		return h, ErrInvalidHash
+	}
+	_, err = fmt.Sscanf(vals[2], "ln=%d,r=%d,p=%d", &h.logN, &h.r, &h.p)
+	if err != nil {
+		return h, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	if h.logN < 1 || h.logN > maxScryptLogN || h.r < 1 || h.p < 1 || h.r*h.p > maxScryptRP {
+		return h, fmt.Errorf("%w: scrypt parameters out of range", ErrInvalidHash)
+	}
+	if h.salt, err = decodeAB64(vals[3]); err != nil {
+		return h, err
+	}
+	h.hash, err = decodeAB64(vals[4])
+	return h, err
+}
+
+type pbkdf2Hash struct {
+	rounds     int
+	salt, hash []byte
+}
+
+func decodePBKDF2(encodedHash string) (h pbkdf2Hash, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) != 5 {
+		return h, ErrInvalidHash
+	}
+	h.rounds, err = strconv.Atoi(vals[2])
+	if err != nil || h.rounds < 1 || h.rounds > maxPBKDF2Rounds {
+		return h, fmt.Errorf("%w: pbkdf2 rounds out of range", ErrInvalidHash)
+	}
+	if h.salt, err = decodeAB64(vals[3]); err != nil {
+		return h, err
+	}
+	h.hash, err = decodeAB64(vals[4])
+	return h, err
+}
+
+// decodeAB64 decodes the adapted base64 used by passlib
+func decodeAB64(s string) ([]byte, error) {
+	b, err := base64.RawStdEncoding.DecodeString(strings.ReplaceAll(s, ".", "+"))
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidHash
+	}
+	return b, nil
+}
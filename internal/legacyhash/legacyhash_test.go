@@ -0,0 +1,55 @@
+package legacyhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+func TestCompare(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+
+	hashes := map[string]string{
+		"bcrypt": string(bcryptHash),
+		"scrypt": "$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0MTIzNA$kmVMPslrNcE8hRKEeDS6wH8VgAPKYosGdhexpSGkMYk",
+		"pbkdf2": "$pbkdf2-sha256$29000$c2FsdHNhbHRzYWx0MTIzNA$hhE82ZNkxt.Z9xuW7xpmjjLDAPx/Y559u8/hxrvZs30",
+	}
+	for scheme, hash := range hashes {
+		t.Run(scheme, func(t *testing.T) {
+			assert.True(t, Supported(hash))
+			assert.NoError(t, Validate(hash))
+
+			match, err := Compare(testPassword, hash)
+			assert.NoError(t, err)
+			assert.True(t, match)
+
+			match, err = Compare("wrong", hash)
+			assert.NoError(t, err)
+			assert.False(t, match)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name string
+		hash string
+		err  error
+	}{
+		{name: "unknown scheme", hash: "$1$abc$def", err: ErrUnsupportedHash},
+		{name: "plain text", hash: "hunter2", err: ErrUnsupportedHash},
+		{name: "malformed bcrypt", hash: "$2b$10$tooshort", err: ErrInvalidHash},
+		{name: "too expensive bcrypt", hash: "$2b$20$" + "abcdefghijklmnopqrstuvabcdefghijklmnopqrstuvwxyz01234", err: ErrInvalidHash},
+		{name: "too expensive scrypt", hash: "$scrypt$ln=30,r=8,p=1$c2FsdA$c2FsdA", err: ErrInvalidHash},
+		{name: "missing pbkdf2 hash", hash: "$pbkdf2-sha256$1000$c2FsdA", err: ErrInvalidHash},
+		{name: "invalid pbkdf2 salt", hash: "$pbkdf2-sha256$1000$!!$c2FsdA", err: ErrInvalidHash},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, Validate(tc.hash), tc.err)
+		})
+	}
+}
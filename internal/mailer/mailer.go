@@ -0,0 +1,201 @@
+// Package mailer sends the mails of the services, like password reset links.
+// SMTP delivers them, Writer and File keep them local for development.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Func adapts a function to a Mailer
+type Func func(ctx context.Context, m Message) error
+
+func (f Func) Send(ctx context.Context, m Message) error { return f(ctx, m) }
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// SMTPConfig configures the SMTP mailer.
+// Username and Password are optional, STARTTLS is used when the server
+// offers it.
+type SMTPConfig struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+type smtpMailer struct {
+	cfg  SMTPConfig
+	host string
+}
+
+// NewSMTP returns a Mailer delivering through the SMTP server at cfg.Addr
+func NewSMTP(cfg SMTPConfig) (Mailer, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp address: %w", err)
+	}
+	if cfg.From == "" {
+		return nil, errors.New("smtp: from address is required")
+	}
+	return &smtpMailer{cfg: cfg, host: host}, nil
+}
+
+func (s *smtpMailer) Send(ctx context.Context, m Message) error {
+	msg, err := format(s.cfg.From, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth refuses to send the password unencrypted to other hosts than localhost
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+type writerMailer struct {
+	mu   sync.Mutex
+	from string
+	w    io.Writer
+}
+
+// NewWriter returns a Mailer writing every message to w, separated by a blank line
+func NewWriter(w io.Writer, from string) Mailer {
+	return &writerMailer{from: from, w: w}
+}
+
+func (wm *writerMailer) Send(_ context.Context, m Message) error {
+	msg, err := format(wm.from, m, time.Now())
+	if err != nil {
+		return err
+	}
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	_, err = wm.w.Write(append(msg, "\r\n"...))
+	return err
+}
+
+type fileMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+// NewFile returns a Mailer appending every message to the file at path
+func NewFile(path, from string) Mailer {
+	return &fileMailer{from: from, path: path}
+}
+
+func (fm *fileMailer) Send(ctx context.Context, m Message) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	f, err := os.OpenFile(fm.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := NewWriter(f, fm.from).Send(ctx, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// format returns m as RFC 5322 message
+func format(from string, m Message, now time.Time) ([]byte, error) {
+	for _, h := range []string{from, m.To, m.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, fmt.Errorf("%w: line breaks in header", ErrInvalidMessage)
+		}
+	}
+	if m.To == "" {
+		return nil, fmt.Errorf("%w: recipient is required", ErrInvalidMessage)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	if !strings.HasSuffix(m.Body, "\n") {
+		b.WriteString("\r\n")
+	}
+	return b.Bytes(), nil
+}
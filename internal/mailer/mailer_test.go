@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpStandIn accepts a single mail and sends the envelope and data on the channel
+func smtpStandIn(t *testing.T) (addr string, mails <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	out := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var got []string
+		reply("220 localhost ESMTP stand-in")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 end with .")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				got = append(got, data.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				out <- got
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), out
+}
+
+func TestSMTP(t *testing.T) {
+	addr, mails := smtpStandIn(t)
+	m, err := NewSMTP(SMTPConfig{Addr: addr, From: "todo@example.com"})
+	assert.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{To: "jane@example.com", Subject: "Réinitialiser", Body: "line one\nline two"})
+	assert.NoError(t, err)
+
+	got := <-mails
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, "MAIL FROM:<todo@example.com>", strings.SplitN(got[0], " BODY", 2)[0])
+		assert.Equal(t, "RCPT TO:<jane@example.com>", got[1])
+		assert.Contains(t, got[2], "To: jane@example.com\r\n")
+		assert.Contains(t, got[2], "Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n")
+		assert.True(t, strings.HasSuffix(got[2], "\r\n\r\nline one\r\nline two\r\n"))
+	}
+
+	_, err = NewSMTP(SMTPConfig{Addr: "localhost", From: "todo@example.com"})
+	assert.Error(t, err)
+}
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWriter(&b, "todo@example.com")
+
+	assert.NoError(t, m.Send(context.Background(), Message{To: "jane@example.com", Subject: "hi", Body: "body\n"}))
+	assert.Contains(t, b.String(), "From: todo@example.com\r\n")
+	assert.True(t, strings.HasSuffix(b.String(), "\r\nbody\r\n\r\n"))
+
+	err := m.Send(context.Background(), Message{To: "jane@example.com\r\nBcc: eve@example.com", Subject: "hi"})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+	err = m.Send(context.Background(), Message{Subject: "hi"})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m := NewFile(path, "todo@example.com")
+
+	assert.NoError(t, m.Send(context.Background(), Message{To: "jane@example.com", Subject: "one", Body: "first"}))
+	assert.NoError(t, m.Send(context.Background(), Message{To: "john@example.com", Subject: "two", Body: "second"}))
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(b), "MIME-Version: 1.0"))
+	assert.Contains(t, string(b), "first")
+	assert.Contains(t, string(b), "second")
+}
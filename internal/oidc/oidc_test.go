@@ -0,0 +1,100 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/oidc"
+	"github.com/demeesterdev/todo-service/internal/oidc/oidctest"
+)
+
+func TestAuthCodeFlow(t *testing.T) {
+	idp := oidctest.NewProvider(
+		oidctest.Client{ID: "todo", Secret: "s3cret", RedirectURL: "http://localhost/callback"},
+		oidc.Claims{Subject: "alice-at-idp", PreferredUsername: "alice", Email: "alice@example.com", EmailVerified: true},
+	)
+	defer idp.Close()
+	ctx := context.Background()
+	p := oidc.NewProvider(idp.Config(), nil)
+
+	authURL, err := p.AuthCodeURL(ctx, "st", "n0nce", "verifier-of-at-least-forty-three-characters")
+	assert.NoError(t, err)
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(authURL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	assert.Equal(t, "st", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+
+	// the verifier must match the challenge
+	_, err = p.Exchange(ctx, code, "another-verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+
+	resp, _ = noRedirect.Get(authURL)
+	resp.Body.Close()
+	callback, _ = url.Parse(resp.Header.Get("Location"))
+	idToken, err := p.Exchange(ctx, callback.Query().Get("code"), "verifier-of-at-least-forty-three-characters")
+	assert.NoError(t, err)
+
+	claims, err := p.Verify(ctx, idToken, "n0nce", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "alice-at-idp", claims.Subject)
+	assert.Equal(t, "alice", claims.PreferredUsername)
+	assert.True(t, claims.EmailVerified)
+
+	_, err = p.Verify(ctx, idToken, "other", time.Now())
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	_, err = p.Verify(ctx, idToken, "n0nce", time.Now().Add(2*time.Hour))
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestVerify(t *testing.T) {
+	idp := oidctest.NewProvider(oidctest.Client{ID: "todo", RedirectURL: "http://localhost/callback"}, oidc.Claims{})
+	defer idp.Close()
+	ctx := context.Background()
+	p := oidc.NewProvider(idp.Config(), nil)
+	now := time.Now()
+	valid := oidc.Claims{Issuer: idp.URL, Subject: "bob", Audience: oidc.Audience{"todo"}, ExpiresAt: now.Add(time.Hour).Unix()}
+
+	_, err := p.Verify(ctx, idp.Sign(valid), "", now)
+	assert.NoError(t, err)
+
+	// keys are refetched after a rotation
+	idp.RotateKey()
+	_, err = p.Verify(ctx, idp.Sign(valid), "", now)
+	assert.NoError(t, err)
+
+	for name, c := range map[string]oidc.Claims{
+		"issuer":   {Issuer: "https://evil.example", Subject: "bob", Audience: oidc.Audience{"todo"}, ExpiresAt: valid.ExpiresAt},
+		"audience": {Issuer: idp.URL, Subject: "bob", Audience: oidc.Audience{"other", "clients"}, ExpiresAt: valid.ExpiresAt},
+		"expired":  {Issuer: idp.URL, Subject: "bob", Audience: oidc.Audience{"todo"}, ExpiresAt: now.Add(-time.Hour).Unix()},
+		"subject":  {Issuer: idp.URL, Audience: oidc.Audience{"todo"}, ExpiresAt: valid.ExpiresAt},
+	} {
+		_, err := p.Verify(ctx, idp.Sign(c), "", now)
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken, name)
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	var c oidc.Claims
+	assert.NoError(t, json.Unmarshal([]byte(`{"aud":"todo"}`), &c))
+	assert.Equal(t, oidc.Audience{"todo"}, c.Audience)
+	assert.NoError(t, json.Unmarshal([]byte(`{"aud":["todo","other"]}`), &c))
+	assert.True(t, c.Audience.Contains("other"))
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewProvider(oidctest.Client{ID: "todo"}, oidc.Claims{})
+	defer idp.Close()
+	cfg := idp.Config()
+	cfg.Issuer += "/"
+	_, err := oidc.NewProvider(cfg, nil).Metadata(context.Background())
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
@@ -0,0 +1,189 @@
+// Package oidctest runs an OpenID Connect provider in process for tests of
+// relying parties. Its authorization endpoint signs in the configured user
+// without asking.
+package oidctest
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/jose"
+	"github.com/demeesterdev/todo-service/internal/oidc"
+)
+
+// Client is the client registered at the provider
+type Client struct {
+	ID          string
+	Secret      string
+	RedirectURL string
+}
+
+// Provider is a running test provider, it must be closed after the test
+type Provider struct {
+	*httptest.Server
+	Client Client
+
+	mu     sync.Mutex
+	claims oidc.Claims
+	kid    string
+	key    *rsa.PrivateKey
+	codes  map[string]grant
+}
+
+type grant struct {
+	claims    oidc.Claims
+	challenge string
+}
+
+// NewProvider starts a provider with client registered that signs in the
+// user with claims
+func NewProvider(client Client, claims oidc.Claims) *Provider {
+	key, err := jose.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	p := &Provider{Client: client, claims: claims, kid: uuid.NewString(), key: key, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Config returns the relying party configuration of the registered client
+func (p *Provider) Config() oidc.Config {
+	return oidc.Config{
+		Issuer:       p.URL,
+		ClientID:     p.Client.ID,
+		ClientSecret: p.Client.Secret,
+		RedirectURL:  p.Client.RedirectURL,
+	}
+}
+
+// SetClaims changes the user signed in from now on
+func (p *Provider) SetClaims(claims oidc.Claims) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+// RotateKey replaces the signing key
+func (p *Provider) RotateKey() {
+	key, err := jose.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.kid, p.key = uuid.NewString(), key
+}
+
+// Sign returns claims as ID token signed with the current key
+func (p *Provider) Sign(claims oidc.Claims) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	token, err := jose.Sign(p.key, p.kid, claims)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                p.URL,
+		AuthorizationEndpoint: p.URL + "/authorize",
+		TokenEndpoint:         p.URL + "/token",
+		JWKSURI:               p.URL + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	writeJSON(w, http.StatusOK, jose.KeySet{Keys: []jose.Key{jose.PublicKey(p.kid, &p.key.PublicKey)}})
+}
+
+// authorize redirects back with a code for the configured claims
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.Client.ID || q.Get("redirect_uri") != p.Client.RedirectURL {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	redirect, _ := url.Parse(p.Client.RedirectURL)
+	params := redirect.Query()
+	params.Set("state", q.Get("state"))
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		params.Set("error", "invalid_request")
+		redirect.RawQuery = params.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+		return
+	}
+
+	p.mu.Lock()
+	claims := p.claims
+	claims.Nonce = q.Get("nonce")
+	code := uuid.NewString()
+	p.codes[code] = grant{claims: claims, challenge: q.Get("code_challenge")}
+	p.mu.Unlock()
+
+	params.Set("code", code)
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token redeems a code once for an ID token
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id = r.PostFormValue("client_id")
+	}
+	if id != p.Client.ID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.Client.Secret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != p.Client.RedirectURL ||
+		oidc.Challenge(r.PostFormValue("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	g.claims.Issuer = p.URL
+	g.claims.Audience = oidc.Audience{p.Client.ID}
+	g.claims.IssuedAt = now.Unix()
+	g.claims.ExpiresAt = now.Add(time.Hour).Unix()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.Sign(g.claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
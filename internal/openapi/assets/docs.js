@@ -0,0 +1,211 @@
+// Renders the OpenAPI document of the service, see openapi.Mount. Only the
+// parts of OpenAPI modelled by the openapi package are shown. Operations can
+// be tried out against the service itself.
+(function () {
+  "use strict";
+
+  var docs = document.getElementById("docs");
+  var specURL = docs.getAttribute("data-spec");
+  var spec;
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      if (k === "text") {
+        e.textContent = attrs[k];
+      } else {
+        e.setAttribute(k, attrs[k]);
+      }
+    });
+    (children || []).forEach(function (c) {
+      if (c) {
+        e.appendChild(c);
+      }
+    });
+    return e;
+  }
+
+  // example builds a sample value of schema, references are resolved
+  // against the components of the document
+  function example(schema, seen) {
+    if (!schema) {
+      return null;
+    }
+    seen = seen || {};
+    if (schema.$ref) {
+      var name = schema.$ref.replace("#/components/schemas/", "");
+      if (seen[name]) {
+        return {};
+      }
+      seen[name] = true;
+      var v = example((spec.components.schemas || {})[name], seen);
+      delete seen[name];
+      return v;
+    }
+    if (schema.enum && schema.enum.length) {
+      return schema.enum[0];
+    }
+    switch (schema.type) {
+      case "object":
+        var o = {};
+        Object.keys(schema.properties || {}).forEach(function (p) {
+          o[p] = example(schema.properties[p], seen);
+        });
+        return o;
+      case "array":
+        return [example(schema.items, seen)];
+      case "integer":
+      case "number":
+        return 0;
+      case "boolean":
+        return false;
+      case "string":
+        return schema.format ? "<" + schema.format + ">" : "string";
+      default:
+        return null;
+    }
+  }
+
+  function content(c) {
+    var list = el("div", { class: "content" });
+    Object.keys(c || {}).forEach(function (type) {
+      list.appendChild(el("div", { class: "media-type", text: type }));
+      if (c[type].schema) {
+        list.appendChild(el("pre", { text: JSON.stringify(example(c[type].schema), null, 2) }));
+      }
+    });
+    return list;
+  }
+
+  function parameters(op) {
+    if (!op.parameters || !op.parameters.length) {
+      return null;
+    }
+    var rows = op.parameters.map(function (p) {
+      return el("tr", {}, [
+        el("td", { text: p.name + (p.required ? " *" : "") }),
+        el("td", { text: p.in }),
+        el("td", { text: p.description || "" }),
+        el("td", {}, [el("input", { "data-param": p.name, "data-in": p.in })]),
+      ]);
+    });
+    return el("table", {}, [
+      el("thead", {}, [el("tr", {}, ["name", "in", "description", "value"].map(function (h) {
+        return el("th", { text: h });
+      }))]),
+      el("tbody", {}, rows),
+    ]);
+  }
+
+  function responses(op) {
+    var list = el("dl", { class: "responses" });
+    Object.keys(op.responses || {}).sort().forEach(function (status) {
+      var r = op.responses[status];
+      list.appendChild(el("dt", { text: status }));
+      list.appendChild(el("dd", {}, [el("span", { text: r.description }), r.content ? content(r.content) : null]));
+    });
+    return list;
+  }
+
+  // tryIt sends the request described by the form of an operation to the
+  // service, with the bearer token of the header when one is set
+  function tryIt(method, path, form, out) {
+    var query = [];
+    var url = path;
+    form.querySelectorAll("[data-param]").forEach(function (input) {
+      var name = input.getAttribute("data-param");
+      if (input.value === "") {
+        return;
+      }
+      if (input.getAttribute("data-in") === "path") {
+        url = url.replace("{" + name + "}", encodeURIComponent(input.value));
+      } else if (input.getAttribute("data-in") === "query") {
+        query.push(encodeURIComponent(name) + "=" + encodeURIComponent(input.value));
+      }
+    });
+    if (query.length) {
+      url += "?" + query.join("&");
+    }
+    var init = { method: method.toUpperCase(), headers: {} };
+    var token = document.getElementById("token").value;
+    if (token) {
+      init.headers.Authorization = "Bearer " + token;
+    }
+    var body = form.querySelector("textarea");
+    if (body && body.value.trim() !== "") {
+      init.headers["Content-Type"] = "application/json";
+      init.body = body.value;
+    }
+    // relative to the spec, the service may be mounted below a prefix
+    var target = new URL(specURL, window.location.href);
+    target = new URL("." + url, target);
+    out.textContent = "...";
+    fetch(target, init).then(function (resp) {
+      return resp.text().then(function (text) {
+        out.textContent = resp.status + " " + resp.statusText + "\n\n" + text;
+      });
+    }).catch(function (err) {
+      out.textContent = String(err);
+    });
+  }
+
+  function operation(method, path, op) {
+    var form = el("form", {}, [parameters(op)]);
+    if (op.requestBody) {
+      var media = op.requestBody.content["application/json"];
+      var body = el("textarea", { rows: "8" });
+      body.value = media && media.schema ? JSON.stringify(example(media.schema), null, 2) : "";
+      form.appendChild(el("label", { text: "request body" + (op.requestBody.required ? " *" : "") }));
+      form.appendChild(body);
+    }
+    var out = el("pre", { class: "result" });
+    form.appendChild(el("button", { type: "submit", text: "Try it" }));
+    form.addEventListener("submit", function (e) {
+      e.preventDefault();
+      tryIt(method, path, form, out);
+    });
+    return el("details", { class: "operation " + method }, [
+      el("summary", {}, [
+        el("span", { class: "method", text: method.toUpperCase() }),
+        el("code", { text: path }),
+        el("span", { class: "summary", text: op.summary || "" }),
+      ]),
+      op.description ? el("p", { text: op.description }) : null,
+      form,
+      out,
+      el("h4", { text: "Responses" }),
+      responses(op),
+    ]);
+  }
+
+  function render() {
+    document.title = spec.info.title;
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    document.getElementById("description").textContent = spec.info.description || "";
+
+    var tags = {};
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      Object.keys(spec.paths[path]).sort().forEach(function (method) {
+        var op = spec.paths[path][method];
+        var tag = (op.tags && op.tags[0]) || "default";
+        (tags[tag] = tags[tag] || []).push(operation(method, path, op));
+      });
+    });
+    Object.keys(tags).sort().forEach(function (tag) {
+      docs.appendChild(el("section", {}, [el("h2", { text: tag })].concat(tags[tag])));
+    });
+  }
+
+  fetch(specURL).then(function (resp) {
+    if (!resp.ok) {
+      throw new Error(specURL + ": " + resp.status);
+    }
+    return resp.json();
+  }).then(function (s) {
+    spec = s;
+    spec.components = spec.components || {};
+    render();
+  }).catch(function (err) {
+    docs.textContent = String(err);
+  });
+})();
@@ -0,0 +1,120 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// docsAssets are the scripts and styles of the docs UI, they are part of
+// the binary so the docs work offline and load nothing from elsewhere
+//
+//go:embed assets
+var docsAssets embed.FS
+
+// docsPolicy keeps the docs UI to the assets and API of the service
+const docsPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; img-src 'self' data:; form-action 'none'; base-uri 'none'; frame-ancestors 'none'"
+
+// Mount serves doc at SpecPath and a docs UI rendering it at DocsPath, its
+// assets are served below DocsPath/assets/. The spec and the page are added
+// to doc so the document describes itself.
+func Mount(r chi.Router, doc Document) {
+	doc.Add(http.MethodGet, SpecPath, Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "OpenAPI document of this service",
+		Tags:        []string{"docs"},
+		Responses: map[string]Response{
+			"200": {
+				Description: "the OpenAPI document",
+				Content:     map[string]MediaType{"application/json": {}},
+			},
+		},
+	})
+	doc.Add(http.MethodGet, DocsPath, Operation{
+		OperationID: "getDocs",
+		Summary:     "Interactive documentation",
+		Tags:        []string{"docs"},
+		Responses: map[string]Response{
+			"200": {
+				Description: "docs UI",
+				Content:     map[string]MediaType{"text/html": {}},
+			},
+		},
+	})
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		// documents are built in code, failing to marshal is a programming error
+		panic(err)
+	}
+
+	r.Get(SpecPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(spec)
+	})
+	r.Get(DocsPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", docsPolicy)
+		w.Write(docsPage)
+	})
+	assets, err := fs.Sub(docsAssets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	r.Handle(DocsPath+"/assets/*", http.StripPrefix(DocsPath+"/assets/", http.FileServer(http.FS(assets))))
+}
+
+// Diff compares the routes registered on a chi router with the paths in doc.
+// undocumented lists the routes without an operation in doc, unrouted lists the
+// operations without a route. Entries are formatted as "METHOD /path".
+// Wildcard routes of mounted handlers are not compared.
+func Diff(doc Document, routes chi.Routes) (undocumented, unrouted []string, err error) {
+	routed := map[string]bool{}
+	err = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasSuffix(route, "/*") {
+			return nil
+		}
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[strings.ToUpper(method)+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range routed {
+		if !documented[route] {
+			undocumented = append(undocumented, route)
+		}
+	}
+	for route := range documented {
+		if !routed[route] {
+			unrouted = append(unrouted, route)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unrouted)
+	return undocumented, unrouted, nil
+}
@@ -0,0 +1,43 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMountServesEmbeddedDocs(t *testing.T) {
+	r := chi.NewRouter()
+	Mount(r, New("test", "1", ""))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, page := get(DocsPath)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, docsPolicy, resp.Header.Get("Content-Security-Policy"))
+	assert.NotRegexp(t, regexp.MustCompile(`(src|href)="(https?:)?//`), page, "the docs load nothing from elsewhere")
+
+	for _, asset := range regexp.MustCompile(`(?:src|href)="(docs/assets/[^"]+)"`).FindAllStringSubmatch(page, -1) {
+		resp, body := get("/" + asset[1])
+		assert.Equal(t, http.StatusOK, resp.StatusCode, asset[1])
+		assert.NotEmpty(t, body, asset[1])
+	}
+	resp, _ = get(DocsPath + "/assets/missing.js")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,289 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+)
+
+const Version = "3.1.0"
+
+// Document is the root of an OpenAPI 3.1 specification.
+// Only the parts used by the services in this repository are modelled.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case http methods to their operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// New returns an empty document
+func New(title, version, description string) Document {
+	return Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       title,
+			Version:     version,
+			Description: description,
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+		},
+	}
+}
+
+// Add documents the operation for method on path.
+// path uses the same {param} syntax as chi.
+func (d *Document) Add(method, path string, op Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = &op
+}
+
+// Include adds the paths of other below prefix, like a handler mounted at
+// prefix, and its component schemas. Components of the same name must be
+// the same schema.
+func (d *Document) Include(prefix string, other Document) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	for path, item := range other.Paths {
+		full := prefix + path
+		if path == "/" && prefix != "" {
+			full = prefix
+		}
+		for method, op := range item {
+			d.Add(method, full, *op)
+		}
+	}
+	for name, schema := range other.Components.Schemas {
+		if existing, ok := d.Components.Schemas[name]; ok && !reflect.DeepEqual(existing, schema) {
+			// documents are built in code, a clash is a programming error
+			panic(fmt.Sprintf("openapi: component %s differs between documents", name))
+		}
+		d.Components.Schemas[name] = schema
+	}
+}
+
+// Component adds a named schema and returns a reference to it
+func (d *Document) Component(name string, s *Schema) *Schema {
+	d.Components.Schemas[name] = s
+	return Ref(name)
+}
+
+// Ref returns a reference to a component schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// JSON returns the content of a JSON request or response body
+func JSON(s *Schema) map[string]MediaType {
+	return map[string]MediaType{
+		"application/json": {Schema: s},
+	}
+}
+
+// PathParam documents a required path parameter
+func PathParam(name, description string, s *Schema) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      s,
+	}
+}
+
+// QueryParam documents an optional query parameter
+func QueryParam(name, description string, s *Schema) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      s,
+	}
+}
+
+// UUID is the schema of every id in the services
+var UUID = &Schema{Type: "string", Format: "uuid"}
+
+var (
+	uuidType  = reflect.TypeOf(uuid.UUID{})
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// SchemaOf generates the schema of the JSON encoding of v.
+// Fields tagged json:"-" are skipped, fields without omitempty are required.
+// Error fields are skipped as well, errors never reach the client in a
+// response envelope but are written by the transports error encoder.
+func SchemaOf(v interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		// interfaces and other dynamic values can hold anything
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || f.Type == errorType {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := schemaOf(f.Type)
+			for n, p := range embedded.Properties {
+				s.Properties[n] = p
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// Responses returns the responses of an operation: ok as 200 and the problem
+// of every error in errs, grouped by status. Internal errors are always
+// documented as every operation can fail.
+func (d *Document) Responses(reg *problem.Registry, ok Response, errs ...error) map[string]Response {
+	if _, found := d.Components.Schemas["Problem"]; !found {
+		d.Component("Problem", SchemaOf(problem.Problem{}))
+	}
+
+	codes := map[int][]string{}
+	for _, err := range append(errs, problem.ErrInternal) {
+		e, found := reg.Lookup(err)
+		if !found {
+			panic(fmt.Sprintf("openapi: %v is not registered", err))
+		}
+		codes[e.Status] = append(codes[e.Status], e.Code)
+	}
+
+	responses := map[string]Response{"200": ok}
+	for status, c := range codes {
+		responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status) + ": " + strings.Join(c, ", "),
+			Content: map[string]MediaType{
+				problem.ContentType: {Schema: Ref("Problem")},
+			},
+		}
+	}
+	return responses
+}
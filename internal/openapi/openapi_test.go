@@ -0,0 +1,28 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInclude(t *testing.T) {
+	doc := New("root", "1", "")
+	doc.Component("Shared", &Schema{Type: "string"})
+
+	other := New("other", "1", "")
+	other.Component("Shared", &Schema{Type: "string"})
+	other.Component("Item", &Schema{Type: "object"})
+	other.Add(http.MethodGet, "/", Operation{OperationID: "list"})
+	other.Add(http.MethodGet, "/{id}", Operation{OperationID: "get"})
+
+	doc.Include("/items/", other)
+	assert.Equal(t, "list", doc.Paths["/items"]["get"].OperationID)
+	assert.Equal(t, "get", doc.Paths["/items/{id}"]["get"].OperationID)
+	assert.Contains(t, doc.Components.Schemas, "Item")
+
+	clash := New("clash", "1", "")
+	clash.Component("Shared", &Schema{Type: "integer"})
+	assert.Panics(t, func() { doc.Include("/clash", clash) })
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PrefixLength is the number of hex characters of a SHA-1 hash identifying
+// its range, as in the k-anonymity model of the Pwned Passwords API
+const PrefixLength = 5
+
+// BreachedList holds the SHA-1 hashes of breached passwords grouped by prefix.
+// Lookups only fetch the range of the prefix of a password, so the list can
+// be replaced by a remote range source without exposing full hashes.
+type BreachedList struct {
+	ranges map[string][]string
+}
+
+// LoadBreachedList reads a breached password list from the file at path,
+// see ParseBreachedList for the format
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseBreachedList(f)
+}
+
+// ParseBreachedList reads one upper or lower case hex SHA-1 hash per line,
+// optionally followed by ":" and a count, as in the downloadable Pwned
+// Passwords files. Empty lines and lines starting with "#" are skipped.
+func ParseBreachedList(r io.Reader) (*BreachedList, error) {
+	l := &BreachedList{ranges: map[string][]string{}}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d: %q is not a SHA-1 hash", n, hash)
+		}
+		prefix := hash[:PrefixLength]
+		l.ranges[prefix] = append(l.ranges[prefix], hash[PrefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Range returns the suffixes of the hashes starting with prefix
+func (l *BreachedList) Range(prefix string) []string {
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether password is in the list
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for _, suffix := range l.Range(hash[:PrefixLength]) {
+		if suffix == hash[PrefixLength:] {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of hashes in the list
+func (l *BreachedList) Len() int {
+	n := 0
+	for _, r := range l.ranges {
+		n += len(r)
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sha1 of "password1" and "hunter2"
+const testList = `# breached passwords
+E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2413945
+f3bbbd66a63d4bf1747940578ec3d0103530e21d
+
+`
+
+func codes(t *testing.T, p Policy, password, username string) []string {
+	t.Helper()
+	var codes []string
+	for _, f := range p.Check(password, username) {
+		assert.Equal(t, "password", f.Field)
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+func TestPolicy(t *testing.T) {
+	breached, err := ParseBreachedList(strings.NewReader(testList))
+	assert.NoError(t, err)
+
+	strict := Policy{
+		MinLength:      8,
+		MaxLength:      16,
+		RequireLower:   true,
+		RequireUpper:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		ForbidUsername: true,
+		Breached:       breached,
+	}
+
+	testCases := []struct {
+		name     string
+		policy   Policy
+		password string
+		username string
+		codes    []string
+	}{
+		{name: "should accept passwords following the policy", policy: strict, password: "Tr0ub4dor&3", username: "hans"},
+		{name: "should require a password", policy: strict, password: "", codes: []string{"required"}},
+		{name: "should only report oversized passwords", policy: strict, password: strings.Repeat("a", 17), codes: []string{"too_long"}},
+		{
+			name:     "should report every violation",
+			policy:   strict,
+			password: "hans",
+			username: "Hans",
+			codes:    []string{"too_short", "missing_uppercase", "missing_digit", "missing_symbol", "contains_username"},
+		},
+		{name: "should report breached passwords", policy: Policy{Breached: breached}, password: "password1", codes: []string{"breached"}},
+		{name: "should accept anything with an empty policy", policy: Policy{}, password: "a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.codes, codes(t, tc.policy, tc.password, tc.username))
+		})
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	l, err := ParseBreachedList(strings.NewReader(testList))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, l.Len())
+	assert.True(t, l.Contains("password1"))
+	assert.True(t, l.Contains("hunter2"))
+	assert.False(t, l.Contains("correct horse battery staple"))
+	assert.Equal(t, []string{"214943DAAD1D64C102FAEC29DE4AFE9DA3D"}, l.Range("e38ad"))
+
+	_, err = ParseBreachedList(strings.NewReader("not a hash\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Package password checks new passwords against a configurable policy and a
+// list of breached passwords.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+)
+
+// Policy describes the passwords accepted for new and changed users.
+// A limit of 0 disables it.
+type Policy struct {
+	MinLength int
+	// MaxLength guards against denial of service through hashing huge passwords
+	MaxLength int
+
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// ForbidUsername rejects passwords containing the username, ignoring case
+	ForbidUsername bool
+
+	// Breached is consulted when set
+	Breached *BreachedList
+}
+
+var DefaultPolicy = Policy{
+	MinLength:      12,
+	MaxLength:      256,
+	ForbidUsername: true,
+}
+
+// Check returns every rule password violates for the user with username,
+// or nil when password is accepted.
+// The violations are reported on the field "password".
+func (p Policy) Check(password, username string) problem.FieldErrors {
+	var errs problem.FieldErrors
+	fail := func(code, format string, a ...interface{}) {
+		errs = append(errs, problem.FieldError{Field: "password", Code: code, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if password == "" {
+		fail("required", "is required")
+		return errs
+	}
+	length := utf8.RuneCountInString(password)
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// don't spend any more time on oversized passwords
+		fail("too_long", "must be at most %d characters", p.MaxLength)
+		return errs
+	}
+	if p.MinLength > 0 && length < p.MinLength {
+		fail("too_short", "must be at least %d characters", p.MinLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.RequireLower && !lower {
+		fail("missing_lowercase", "must contain a lowercase letter")
+	}
+	if p.RequireUpper && !upper {
+		fail("missing_uppercase", "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail("missing_digit", "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		fail("missing_symbol", "must contain a symbol")
+	}
+
+	if p.ForbidUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		fail("contains_username", "must not contain the username")
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		fail("breached", "appears in a list of breached passwords")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
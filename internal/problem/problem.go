@@ -0,0 +1,218 @@
+// Package problem writes errors as RFC 7807 problem details and turns them
+// back into the sentinel errors they were created from.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ContentType = "application/problem+json"
+
+// TypePrefix is prepended to the code of a problem to form its type
+const TypePrefix = "urn:todo-service:problem:"
+
+// Problem is the body of an error response
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Error allows problems without a registered sentinel to be returned as error
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// FieldError describes why a single field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is returned when one or more fields of a request are invalid
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid fields: " + strings.Join(msgs, ", ")
+}
+
+// WithField marks the field of the request that caused err.
+// It takes precedence over the field of the registered entry.
+func WithField(err error, field string) error {
+	return &fieldErr{err: err, field: field}
+}
+
+type fieldErr struct {
+	err   error
+	field string
+}
+
+func (e *fieldErr) Error() string { return e.field + ": " + e.err.Error() }
+func (e *fieldErr) Unwrap() error { return e.err }
+
+var (
+	ErrMalformedBody = errors.New("malformed request body")
+	ErrBodyTooLarge  = errors.New("request body too large")
+	ErrInvalidFields = errors.New("invalid fields")
+	ErrInternal      = errors.New("internal error")
+)
+
+// DecodeError wraps an error from decoding a request body in
+// ErrBodyTooLarge when the body exceeded its limit, and in ErrMalformedBody
+// otherwise
+func DecodeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, tooLarge.Limit)
+	}
+	return fmt.Errorf("%w: %v", ErrMalformedBody, err)
+}
+
+// Entry registers how a sentinel error is presented.
+// Field is set for errors about a single field of the request.
+type Entry struct {
+	Err    error
+	Status int
+	Code   string
+	Title  string
+	Field  string
+}
+
+// Registry maps sentinel errors to problems and back.
+// Every service has its own registry as codes are only unique per service.
+type Registry struct {
+	entries []Entry
+	codes   map[string]Entry
+}
+
+// defaults are part of every registry
+var defaults = []Entry{
+	{Err: ErrMalformedBody, Status: http.StatusBadRequest, Code: "malformed_body", Title: "Malformed request body"},
+	{Err: ErrBodyTooLarge, Status: http.StatusRequestEntityTooLarge, Code: "body_too_large", Title: "Request body too large"},
+	{Err: ErrInvalidFields, Status: http.StatusBadRequest, Code: "invalid_fields", Title: "Invalid fields"},
+	{Err: ErrInternal, Status: http.StatusInternalServerError, Code: "internal", Title: "Internal error"},
+}
+
+// NewRegistry returns a registry with the default entries and entries.
+// It panics on duplicate codes as registries are built at init.
+func NewRegistry(entries ...Entry) *Registry {
+	r := &Registry{codes: map[string]Entry{}}
+	for _, e := range append(defaults, entries...) {
+		if _, ok := r.codes[e.Code]; ok {
+			panic(fmt.Sprintf("problem: duplicate code %q", e.Code))
+		}
+		r.entries = append(r.entries, e)
+		r.codes[e.Code] = e
+	}
+	return r
+}
+
+// Entries returns the registered entries, defaults first
+func (r *Registry) Entries() []Entry {
+	return append([]Entry(nil), r.entries...)
+}
+
+// Lookup returns the entry registered for err
+func (r *Registry) Lookup(err error) (Entry, bool) {
+	for _, e := range r.entries {
+		if errors.Is(err, e.Err) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Problem returns the problem describing err.
+// Unregistered errors become an internal error without details, so internals
+// don't leak to clients.
+func (r *Registry) Problem(err error) Problem {
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		e := r.codes["invalid_fields"]
+		return Problem{
+			Type:   TypePrefix + e.Code,
+			Title:  e.Title,
+			Status: e.Status,
+			Detail: err.Error(),
+			Code:   e.Code,
+			Errors: fields,
+		}
+	}
+
+	e, ok := r.Lookup(err)
+	if !ok {
+		e = r.codes["internal"]
+		return Problem{Type: TypePrefix + e.Code, Title: e.Title, Status: e.Status, Code: e.Code}
+	}
+
+	p := Problem{
+		Type:   TypePrefix + e.Code,
+		Title:  e.Title,
+		Status: e.Status,
+		Detail: err.Error(),
+		Code:   e.Code,
+	}
+	field := e.Field
+	var fe *fieldErr
+	if errors.As(err, &fe) {
+		field = fe.field
+	}
+	if field != "" {
+		p.Errors = []FieldError{{Field: field, Code: e.Code, Message: e.Err.Error()}}
+	}
+	return p
+}
+
+// Write writes err as problem to w
+func (r *Registry) Write(w http.ResponseWriter, err error) {
+	p := r.Problem(err)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error returns the sentinel registered for the code of p.
+// Field errors are returned as FieldErrors, unknown codes as the problem itself.
+func (r *Registry) Error(p Problem) error {
+	if p.Code == "invalid_fields" {
+		return FieldErrors(p.Errors)
+	}
+	if e, ok := r.codes[p.Code]; ok {
+		return e.Err
+	}
+	return &p
+}
+
+// Decode returns the error of a failed response, or nil for a successful one
+func (r *Registry) Decode(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != ContentType {
+		return &Problem{Title: resp.Status, Status: resp.StatusCode}
+	}
+
+	var p Problem
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return &Problem{Title: resp.Status, Status: resp.StatusCode}
+	}
+	return r.Error(p)
+}
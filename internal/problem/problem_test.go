@@ -0,0 +1,105 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTest = errors.New("test failed")
+
+func TestRoundTrip(t *testing.T) {
+	r := NewRegistry(Entry{Err: errTest, Status: http.StatusConflict, Code: "test_failed", Title: "Test failed", Field: "test"})
+
+	testCases := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		fields  []FieldError
+		decoded error
+	}{
+		{
+			name:    "should map sentinels",
+			err:     errTest,
+			status:  http.StatusConflict,
+			code:    "test_failed",
+			fields:  []FieldError{{Field: "test", Code: "test_failed", Message: "test failed"}},
+			decoded: errTest,
+		},
+		{
+			name:    "should map wrapped sentinels",
+			err:     fmt.Errorf("%w: unexpected EOF", ErrMalformedBody),
+			status:  http.StatusBadRequest,
+			code:    "malformed_body",
+			decoded: ErrMalformedBody,
+		},
+		{
+			name:    "should override the field",
+			err:     WithField(errTest, "other"),
+			status:  http.StatusConflict,
+			code:    "test_failed",
+			fields:  []FieldError{{Field: "other", Code: "test_failed", Message: "test failed"}},
+			decoded: errTest,
+		},
+		{
+			name:    "should keep field errors",
+			err:     FieldErrors{{Field: "title", Code: "required", Message: "is required"}},
+			status:  http.StatusBadRequest,
+			code:    "invalid_fields",
+			fields:  []FieldError{{Field: "title", Code: "required", Message: "is required"}},
+			decoded: FieldErrors{{Field: "title", Code: "required", Message: "is required"}},
+		},
+		{
+			name:    "should hide unknown errors",
+			err:     errors.New("database is on fire"),
+			status:  http.StatusInternalServerError,
+			code:    "internal",
+			decoded: ErrInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := r.Problem(tc.err)
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.code, p.Code)
+			assert.Equal(t, TypePrefix+tc.code, p.Type)
+			assert.Equal(t, tc.fields, p.Errors)
+			if tc.code == "internal" {
+				assert.Empty(t, p.Detail)
+			}
+
+			w := httptest.NewRecorder()
+			r.Write(w, tc.err)
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.decoded, r.Decode(w.Result()))
+		})
+	}
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	r := NewRegistry()
+
+	w := httptest.NewRecorder()
+	NewRegistry(Entry{Err: errTest, Status: http.StatusTeapot, Code: "test_failed", Title: "Test failed"}).Write(w, errTest)
+	err := r.Decode(w.Result())
+
+	var p *Problem
+	assert.ErrorAs(t, err, &p)
+	assert.Equal(t, http.StatusTeapot, p.Status)
+	assert.Equal(t, "test failed", err.Error())
+
+	w = httptest.NewRecorder()
+	w.WriteHeader(http.StatusBadGateway)
+	err = r.Decode(w.Result())
+	assert.ErrorAs(t, err, &p)
+	assert.Equal(t, http.StatusBadGateway, p.Status)
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, r.Decode(w.Result()))
+}
@@ -0,0 +1,163 @@
+// Package throttle counts failures per key and locks keys out for an
+// exponentially growing duration once they fail too often.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Config of a Limiter
+type Config struct {
+	// Threshold is the number of failures before a key is locked,
+	// 0 disables the limiter
+	Threshold int
+	// BaseDelay is the first lockout, it doubles with every further failure
+	// up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window after the last failure in which failures are remembered
+	Window time.Duration
+}
+
+// maxEntries bounds the memory of a limiter, expired entries are swept when
+// it is exceeded
+const maxEntries = 100_000
+
+type entry struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// Limiter keeps the failures of keys in memory. It is safe for concurrent use.
+type Limiter struct {
+	cfg     Config
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg, entries: map[string]*entry{}, now: time.Now}
+}
+
+// Check returns how long key is still locked out, or 0 when it isn't
+func (l *Limiter) Check(key string) time.Duration {
+	if l.cfg.Threshold <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	if wait := e.lockedUntil.Sub(l.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failure of key and returns how long it is locked out now
+func (l *Limiter) Fail(key string) time.Duration {
+	if l.cfg.Threshold <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e, ok := l.entries[key]
+	if !ok || l.expired(e, now) {
+		if len(l.entries) >= maxEntries {
+			l.sweep(now)
+		}
+		e = &entry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	if e.failures < l.cfg.Threshold {
+		return 0
+	}
+
+	delay := l.cfg.BaseDelay
+	for i := l.cfg.Threshold; i < e.failures && delay < l.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if l.cfg.MaxDelay > 0 && delay > l.cfg.MaxDelay {
+		delay = l.cfg.MaxDelay
+	}
+	e.lockedUntil = now.Add(delay)
+	return delay
+}
+
+// Reset forgets the failures of key, unlocking it
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// expired reports whether e is neither locked nor within the window
+func (l *Limiter) expired(e *entry, now time.Time) bool {
+	return now.After(e.lockedUntil) && now.Sub(e.lastFailure) > l.cfg.Window
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if l.expired(e, now) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// maxSources bounds the sources remembered per key
+const maxSources = 32
+
+// Sources remembers where the failures of keys came from, like the client
+// ip addresses failing logins of a user, so lifting the lockout of a key
+// can lift the lockout of its sources too. It is safe for concurrent use.
+type Sources struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+func NewSources() *Sources {
+	return &Sources{keys: map[string]map[string]struct{}{}}
+}
+
+// Add remembers that key failed from source
+func (s *Sources) Add(key, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sources, ok := s.keys[key]
+	if !ok {
+		if len(s.keys) >= maxEntries {
+			// the sources only help lifting lockouts early, forgetting
+			// them is safe
+			s.keys = map[string]map[string]struct{}{}
+		}
+		sources = map[string]struct{}{}
+		s.keys[key] = sources
+	}
+	if len(sources) < maxSources {
+		sources[source] = struct{}{}
+	}
+}
+
+// Take returns the sources of key and forgets them
+func (s *Sources) Take(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var taken []string
+	for source := range s.keys[key] {
+		taken = append(taken, source)
+	}
+	delete(s.keys, key)
+	return taken
+}
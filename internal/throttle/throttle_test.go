@@ -0,0 +1,65 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := New(Config{Threshold: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second, Window: time.Minute})
+	l.now = func() time.Time { return now }
+
+	assert.Zero(t, l.Fail("key"))
+	assert.Zero(t, l.Fail("key"))
+	assert.Zero(t, l.Check("key"))
+
+	// locked from the threshold on, doubling up to the maximum
+	assert.Equal(t, time.Second, l.Fail("key"))
+	assert.Equal(t, time.Second, l.Check("key"))
+	assert.Zero(t, l.Check("other"))
+	assert.Equal(t, 2*time.Second, l.Fail("key"))
+	assert.Equal(t, 4*time.Second, l.Fail("key"))
+	assert.Equal(t, 5*time.Second, l.Fail("key"))
+
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, 2*time.Second, l.Check("key"))
+
+	// failures are forgotten after the window
+	now = now.Add(2 * time.Minute)
+	assert.Zero(t, l.Check("key"))
+	assert.Zero(t, l.Fail("key"))
+
+	l.Fail("key")
+	l.Fail("key")
+	assert.NotZero(t, l.Check("key"))
+	l.Reset("key")
+	assert.Zero(t, l.Check("key"))
+}
+
+func TestDisabledLimiter(t *testing.T) {
+	l := New(Config{})
+	for i := 0; i < 100; i++ {
+		assert.Zero(t, l.Fail("key"))
+	}
+	assert.Zero(t, l.Check("key"))
+}
+
+func TestSources(t *testing.T) {
+	s := NewSources()
+	s.Add("alice", "192.0.2.1")
+	s.Add("alice", "192.0.2.2")
+	s.Add("alice", "192.0.2.1")
+	s.Add("bob", "192.0.2.3")
+
+	assert.ElementsMatch(t, []string{"192.0.2.1", "192.0.2.2"}, s.Take("alice"))
+	assert.Empty(t, s.Take("alice"))
+	assert.Equal(t, []string{"192.0.2.3"}, s.Take("bob"))
+
+	for i := 0; i < 2*maxSources; i++ {
+		s.Add("carol", time.Duration(i).String())
+	}
+	assert.Len(t, s.Take("carol"), maxSources)
+}
@@ -0,0 +1,149 @@
+// Package validate checks requests before they reach a service and reports
+// every invalid field at once.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+)
+
+// StringRule checks a string and returns a violation, or nil when s is valid
+type StringRule func(s string) *problem.FieldError
+
+// Required rejects empty strings
+func Required() StringRule {
+	return func(s string) *problem.FieldError {
+		if s == "" {
+			return &problem.FieldError{Code: "required", Message: "is required"}
+		}
+		return nil
+	}
+}
+
+// MinLength rejects strings shorter than n characters.
+// A limit of 0 disables the rule, as do the other limits.
+func MinLength(n int) StringRule {
+	return func(s string) *problem.FieldError {
+		if n > 0 && utf8.RuneCountInString(s) < n {
+			return &problem.FieldError{Code: "too_short", Message: fmt.Sprintf("must be at least %d characters", n)}
+		}
+		return nil
+	}
+}
+
+// MaxLength rejects strings longer than n characters
+func MaxLength(n int) StringRule {
+	return func(s string) *problem.FieldError {
+		if n > 0 && utf8.RuneCountInString(s) > n {
+			return &problem.FieldError{Code: "too_long", Message: fmt.Sprintf("must be at most %d characters", n)}
+		}
+		return nil
+	}
+}
+
+// MaxBytes rejects strings larger than n bytes
+func MaxBytes(n int) StringRule {
+	return func(s string) *problem.FieldError {
+		if n > 0 && len(s) > n {
+			return &problem.FieldError{Code: "too_large", Message: fmt.Sprintf("must be at most %d bytes", n)}
+		}
+		return nil
+	}
+}
+
+// Matches rejects strings not matching re, description explains the pattern
+func Matches(re *regexp.Regexp, description string) StringRule {
+	return func(s string) *problem.FieldError {
+		if !re.MatchString(s) {
+			return &problem.FieldError{Code: "invalid_format", Message: description}
+		}
+		return nil
+	}
+}
+
+// Email rejects strings that are not a bare email address, display names
+// like "Jane <jane@example.com>" are rejected as well
+func Email() StringRule {
+	return func(s string) *problem.FieldError {
+		if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+			return &problem.FieldError{Code: "invalid_email", Message: "must be an email address"}
+		}
+		return nil
+	}
+}
+
+// UTF8 rejects strings that are not valid UTF-8
+func UTF8() StringRule {
+	return func(s string) *problem.FieldError {
+		if !utf8.ValidString(s) {
+			return &problem.FieldError{Code: "invalid_encoding", Message: "must be valid UTF-8"}
+		}
+		return nil
+	}
+}
+
+// Checker collects the violations of a request.
+// Only the first violation of every field is reported.
+type Checker struct {
+	errs problem.FieldErrors
+}
+
+// String checks value of field against rules
+func (c *Checker) String(field, value string, rules ...StringRule) {
+	for _, rule := range rules {
+		if v := rule(value); v != nil {
+			v.Field = field
+			c.errs = append(c.errs, *v)
+			return
+		}
+	}
+}
+
+// Optional checks value only when it is set
+func (c *Checker) Optional(field, value string, rules ...StringRule) {
+	if value != "" {
+		c.String(field, value, rules...)
+	}
+}
+
+// Err returns the violations as problem.FieldErrors, or nil when there are none
+func (c *Checker) Err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+// Middleware rejects requests for which check returns an error before they
+// reach the endpoint
+func Middleware(check func(request interface{}) error) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := check(request); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// MaxBodyBytes limits the size of request bodies to n bytes.
+// Decoders see the limit as error while reading, see problem.DecodeError.
+func MaxBodyBytes(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if n > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+)
+
+func TestRules(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rule  StringRule
+		value string
+		code  string
+	}{
+		{name: "required accepts values", rule: Required(), value: "a"},
+		{name: "required rejects empty strings", rule: Required(), value: "", code: "required"},
+		{name: "min length counts characters", rule: MinLength(3), value: "héé"},
+		{name: "min length rejects short strings", rule: MinLength(3), value: "ab", code: "too_short"},
+		{name: "max length counts characters", rule: MaxLength(3), value: "héé"},
+		{name: "max length rejects long strings", rule: MaxLength(3), value: "abcd", code: "too_long"},
+		{name: "max bytes counts bytes", rule: MaxBytes(3), value: "héé", code: "too_large"},
+		{name: "a limit of 0 disables the rule", rule: MaxBytes(0), value: "abcd"},
+		{name: "matches accepts matching strings", rule: Matches(regexp.MustCompile(`^a+$`), "only a"), value: "aaa"},
+		{name: "matches rejects other strings", rule: Matches(regexp.MustCompile(`^a+$`), "only a"), value: "ab", code: "invalid_format"},
+		{name: "email accepts addresses", rule: Email(), value: "jane@example.com"},
+		{name: "email rejects display names", rule: Email(), value: "Jane <jane@example.com>", code: "invalid_email"},
+		{name: "email rejects other strings", rule: Email(), value: "jane", code: "invalid_email"},
+		{name: "utf8 rejects invalid encodings", rule: UTF8(), value: "\xff", code: "invalid_encoding"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := tc.rule(tc.value)
+			if tc.code == "" {
+				assert.Nil(t, v)
+				return
+			}
+			if assert.NotNil(t, v) {
+				assert.Equal(t, tc.code, v.Code)
+			}
+		})
+	}
+}
+
+func TestChecker(t *testing.T) {
+	var c Checker
+	assert.NoError(t, c.Err())
+
+	c.String("title", "", Required(), MaxLength(1))
+	c.Optional("description", "")
+	c.Optional("owner", "abc", MaxLength(2))
+
+	var fields problem.FieldErrors
+	assert.True(t, errors.As(c.Err(), &fields))
+	assert.Equal(t, problem.FieldErrors{
+		{Field: "title", Code: "required", Message: "is required"},
+		{Field: "owner", Code: "too_long", Message: "must be at most 2 characters"},
+	}, fields)
+}
+
+func TestMiddleware(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	called := false
+	e := Middleware(func(request interface{}) error {
+		if request == "bad" {
+			return errInvalid
+		}
+		return nil
+	})(func(ctx context.Context, request interface{}) (interface{}, error) {
+		called = true
+		return request, nil
+	})
+
+	_, err := e(context.Background(), "bad")
+	assert.ErrorIs(t, err, errInvalid)
+	assert.False(t, called)
+
+	resp, err := e(context.Background(), "good")
+	assert.NoError(t, err)
+	assert.Equal(t, "good", resp)
+	assert.True(t, called)
+}
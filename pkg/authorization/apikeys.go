@@ -0,0 +1,204 @@
+package authorization
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scope limits what an API key may be used for
+type Scope string
+
+const (
+	// ScopeTodosRead reads todos
+	ScopeTodosRead Scope = "todos:read"
+	// ScopeTodosWrite adds, changes and deletes todos
+	ScopeTodosWrite Scope = "todos:write"
+)
+
+// Scopes lists the known scopes
+var Scopes = []Scope{ScopeTodosRead, ScopeTodosWrite}
+
+// Valid reports whether s is one of Scopes
+func (s Scope) Valid() bool {
+	for _, known := range Scopes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// APIKeyPrefix starts every API key so they can be told apart from
+	// session tokens
+	APIKeyPrefix = "tdk_"
+	// DefaultAPIKeyTTL is the lifetime of an API key created without expiry
+	DefaultAPIKeyTTL = 90 * 24 * time.Hour
+)
+
+// IsAPIKey reports whether token looks like an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// APIKey lets scripts act as a user with a limited set of scopes.
+// Key is only returned by CreateAPIKey, only its hash is stored.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// Has reports whether k grants scope
+func (k APIKey) Has(scope Scope) bool {
+	return hasScope(k.Scopes, scope)
+}
+
+// storedAPIKey is removed, not soft deleted, when revoked. Scopes are
+// separated by spaces like OAuth scopes.
+type storedAPIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;primarykey"`
+	UserID     uuid.UUID `gorm:"type:uuid;index"`
+	Name       string
+	Scopes     string
+	Hash       string `gorm:"uniqueIndex"`
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (storedAPIKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *storedAPIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return
+}
+
+func (k storedAPIKey) toAPIKey() APIKey {
+	key := APIKey{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Scopes:     []Scope{},
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+	}
+	for _, s := range strings.Fields(k.Scopes) {
+		key.Scopes = append(key.Scopes, Scope(s))
+	}
+	return key
+}
+
+func (s *dbSvc) CreateAPIKey(ctx context.Context, userID uuid.UUID, K APIKey) (APIKey, error) {
+	if K.Name == "" || len(K.Scopes) == 0 {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	scopes := make([]string, len(K.Scopes))
+	for i, scope := range K.Scopes {
+		if !scope.Valid() {
+			return APIKey{}, ErrInvalidScope
+		}
+		scopes[i] = string(scope)
+	}
+	now := s.now()
+	if K.ExpiresAt.IsZero() {
+		K.ExpiresAt = now.Add(DefaultAPIKeyTTL)
+	}
+	if !now.Before(K.ExpiresAt) {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	if _, err := s.storedUser(userID); err != nil {
+		return APIKey{}, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return APIKey{}, err
+	}
+	token = APIKeyPrefix + token
+	k := storedAPIKey{
+		UserID:    userID,
+		Name:      K.Name,
+		Scopes:    strings.Join(scopes, " "),
+		Hash:      hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: K.ExpiresAt,
+	}
+	if err := s.db.Create(&k).Error; err != nil {
+		return APIKey{}, err
+	}
+	key := k.toAPIKey()
+	key.Key = token
+	return key, nil
+}
+
+func (s *dbSvc) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	if _, err := s.storedUser(userID); err != nil {
+		return []APIKey{}, err
+	}
+	var stored []storedAPIKey
+	if err := s.db.Where(&storedAPIKey{UserID: userID}).Order("created_at").Find(&stored).Error; err != nil {
+		return []APIKey{}, err
+	}
+	keys := make([]APIKey, len(stored))
+	for i := range stored {
+		keys[i] = stored[i].toAPIKey()
+	}
+	return keys, nil
+}
+
+func (s *dbSvc) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	result := s.db.Where(&storedAPIKey{ID: id, UserID: userID}).Delete(&storedAPIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *dbSvc) ValidateAPIKey(ctx context.Context, key string) (APIKey, error) {
+	if !IsAPIKey(key) {
+		return APIKey{}, ErrInvalidToken
+	}
+	var k storedAPIKey
+	result := s.db.Where(&storedAPIKey{Hash: hashToken(key)}).First(&k)
+	if result.Error == gorm.ErrRecordNotFound {
+		return APIKey{}, ErrInvalidToken
+	}
+	if result.Error != nil {
+		return APIKey{}, result.Error
+	}
+	now := s.now()
+	if !now.Before(k.ExpiresAt) {
+		return APIKey{}, ErrInvalidToken
+	}
+	u, err := s.storedUser(k.UserID)
+	if err == ErrNotFound || (err == nil && u.Disabled) {
+		return APIKey{}, ErrInvalidToken
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	if err := s.db.Model(&k).Update("last_used_at", now).Error; err != nil {
+		return APIKey{}, err
+	}
+	k.LastUsedAt = &now
+	return k.toAPIKey(), nil
+}
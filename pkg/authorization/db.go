@@ -2,13 +2,26 @@ package authorization
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-kit/log"
 	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/legacyhash"
+	"github.com/demeesterdev/todo-service/internal/mailer"
+	"github.com/demeesterdev/todo-service/internal/oidc"
+	"github.com/demeesterdev/todo-service/internal/password"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/internal/throttle"
 )
 
 // user presents a single user object as stored in the database
@@ -18,6 +31,24 @@ type storedUser struct {
 	ID           uuid.UUID `gorm:"type:uuid;primarykey"`
 	Username     string    `gorm:"unique;index"`
 	PasswordHash string
+
+	// TOTPSecret is set by EnrollTOTP, the second factor is only required
+	// once TOTPEnabled is set by ConfirmTOTP. TOTPCounter is the time step
+	// of the last accepted code, codes can't be used twice.
+	TOTPSecret  string
+	TOTPEnabled bool
+	TOTPCounter int64
+
+	Email         string `gorm:"index"`
+	EmailVerified bool
+
+	Role string
+
+	// Directory is the name of the Verifier that created the user, it is
+	// empty for local users
+	Directory string
+
+	Disabled bool
 }
 
 // TableName overrides the table name used by User to `profiles` (GORM specifics)
@@ -37,11 +68,29 @@ func (u *storedUser) BeforeCreate(tx *gorm.DB) (err error) {
 func (u storedUser) ToUser() (U User) {
 	U.ID = u.ID
 	U.Username = u.Username
+	U.MFAEnabled = u.TOTPEnabled
+	U.Email = u.Email
+	U.EmailVerified = u.EmailVerified
+	// users from before roles were added are members
+	U.Role = Role(u.Role)
+	if U.Role == "" {
+		U.Role = RoleMember
+	}
+	U.Disabled = u.Disabled
 	return U
 }
 
-func (u *storedUser) ComparePassword(password string) (match bool, err error) {
-	return argon2id.ComparePasswordAndHash(password, u.PasswordHash)
+// ComparePassword dispatches on the prefix of the hash, users imported from
+// other systems keep their legacy hash until they log in. Users created by
+// an identity provider have no password.
+func (u *storedUser) ComparePassword(password string, pepper *argon2id.Keyring) (match bool, err error) {
+	if u.PasswordHash == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(u.PasswordHash, argon2id.Prefix) {
+		return argon2id.ComparePasswordAndHash(password, u.PasswordHash, pepper)
+	}
+	return legacyhash.Compare(password, u.PasswordHash)
 }
 
 func newStoredUser(U User, passwordHashParameters argon2id.Params) (u storedUser, err error) {
@@ -53,41 +102,164 @@ func newStoredUser(U User, passwordHashParameters argon2id.Params) (u storedUser
 		ID:           U.ID,
 		Username:     U.Username,
 		PasswordHash: passwordHash,
+		Email:        U.Email,
+		Role:         string(U.Role),
 	}, err
 }
 
 type dbSvc struct {
 	hashParams argon2id.Params
+	policy     password.Policy
 	db         *gorm.DB
+
+	// failed logins per username and per client ip, loginIPs are the ips
+	// the failed logins of a username came from
+	userLogins *throttle.Limiter
+	ipLogins   *throttle.Limiter
+	loginIPs   *throttle.Sources
+
+	// dummyHash is verified for unknown users, so they take as long as known ones
+	dummyOnce sync.Once
+	dummyHash string
+
+	mfa        MFAConfig
+	challenges *challengeStore
+	now        func() time.Time
+
+	mailer mailer.Mailer
+	mail   MailConfig
+	// mails tracks password reset mails sent in the background
+	mails sync.WaitGroup
+
+	sessionTTL time.Duration
+	oauth      OAuthConfig
+	oidc       OIDCConfig
+
+	federation FederationConfig
+	providers  map[string]*oidc.Provider
+
+	verifiers []Verifier
+
+	provisioning ProvisioningConfig
+
+	logger log.Logger
+}
+
+var (
+	DefaultUserThrottle = throttle.Config{Threshold: 5, BaseDelay: 5 * time.Second, MaxDelay: 15 * time.Minute, Window: 15 * time.Minute}
+	// many clients may share an ip address behind a NAT or proxy
+	DefaultIPThrottle = throttle.Config{Threshold: 50, BaseDelay: time.Second, MaxDelay: 15 * time.Minute, Window: 15 * time.Minute}
+)
+
+// Option configures the service created by NewDBService
+type Option func(*dbSvc)
+
+// WithPasswordPolicy sets the policy new passwords are checked against,
+// password.DefaultPolicy is used otherwise
+func WithPasswordPolicy(p password.Policy) Option {
+	return func(s *dbSvc) {
+		s.policy = p
+	}
+}
+
+// WithLoginThrottle sets how failed logins lock out users and client ip
+// addresses, DefaultUserThrottle and DefaultIPThrottle are used otherwise
+func WithLoginThrottle(user, ip throttle.Config) Option {
+	return func(s *dbSvc) {
+		s.userLogins = throttle.New(user)
+		s.ipLogins = throttle.New(ip)
+	}
+}
+
+// WithMFA configures TOTP second factors, DefaultMFAConfig is used otherwise
+func WithMFA(cfg MFAConfig) Option {
+	return func(s *dbSvc) {
+		s.mfa = cfg
+	}
+}
+
+// WithMailer sends password reset and email verification tokens with m,
+// the methods sending mail fail with ErrMailDisabled otherwise
+func WithMailer(m mailer.Mailer, cfg MailConfig) Option {
+	return func(s *dbSvc) {
+		s.mailer = m
+		s.mail = cfg
+	}
+}
+
+// WithLogger logs failures of work done in the background, like sending
+// password reset mails
+func WithLogger(l log.Logger) Option {
+	return func(s *dbSvc) {
+		s.logger = l
+	}
 }
 
 // NewService creates a new user service based on a sqlite database with a target file
-func NewDBService(dbconnection gorm.Dialector, passwordHashParameters argon2id.Params) (Service, error) {
+func NewDBService(dbconnection gorm.Dialector, passwordHashParameters argon2id.Params, opts ...Option) (Service, error) {
 	db, err := gorm.Open(dbconnection, &gorm.Config{})
-	db.AutoMigrate(&storedUser{})
+	db.AutoMigrate(&storedUser{}, &storedRecoveryCode{}, &storedToken{},
+		&storedTeam{}, &storedMembership{}, &storedInvitation{}, &storedAPIKey{},
+		&storedOAuthClient{}, &storedAuthCode{}, &storedOAuthToken{}, &storedSigningKey{},
+		&storedIdentity{}, &storedFederatedLogin{})
 	if err != nil {
 		return &dbSvc{}, err
 	}
 
-	return &dbSvc{
+	s := &dbSvc{
 		db:         db,
 		hashParams: passwordHashParameters,
-	}, nil
+		policy:     password.DefaultPolicy,
+		userLogins: throttle.New(DefaultUserThrottle),
+		ipLogins:   throttle.New(DefaultIPThrottle),
+		loginIPs:   throttle.NewSources(),
+		mfa:        DefaultMFAConfig,
+		mail:       DefaultMailConfig,
+		sessionTTL: DefaultSessionTTL,
+		oauth:      DefaultOAuthConfig,
+		oidc:       DefaultOIDCConfig,
+		federation: DefaultFederationConfig,
+		verifiers:  []Verifier{PasswordHashes},
+		now:        time.Now,
+		logger:     log.NewNopLogger(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.challenges = newChallengeStore(s.mfa.ChallengeTTL, s.mfa.MaxAttempts)
+	return s, nil
 }
 
 // NewSqliteDBService creates a new user service based on a sqlite database with a target file
-func NewSqliteDBService(target string, passwordHashParameters argon2id.Params) (Service, error) {
-	return NewDBService(sqlite.Open(target), passwordHashParameters)
+func NewSqliteDBService(target string, passwordHashParameters argon2id.Params, opts ...Option) (Service, error) {
+	return NewDBService(sqlite.Open(target), passwordHashParameters, opts...)
 }
 
-func NewInMemService(passwordHashParameters argon2id.Params) (Service, error) {
-	return NewSqliteDBService(":memory:", passwordHashParameters)
+func NewInMemService(passwordHashParameters argon2id.Params, opts ...Option) (Service, error) {
+	return NewSqliteDBService(":memory:", passwordHashParameters, opts...)
+}
+
+// checkPassword returns the policy violations of password as problem.FieldErrors
+func (s *dbSvc) checkPassword(password, username string) error {
+	if errs := s.policy.Check(password, username); errs != nil {
+		return errs
+	}
+	return nil
 }
 
 func (s *dbSvc) AddUser(ctx context.Context, u User) (User, error) {
 	if u.Username == "" || u.Password == "" {
 		return User{}, ErrInvalidUserObject
 	}
+	if err := s.checkPassword(u.Password, u.Username); err != nil {
+		return User{}, err
+	}
+	if u.Role == "" {
+		u.Role = RoleMember
+	}
+	if !u.Role.Valid() {
+		return User{}, ErrInvalidRole
+	}
 
 	newUser, err := newStoredUser(u, s.hashParams)
 	if err != nil {
@@ -106,7 +278,7 @@ func (s *dbSvc) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 
 	// get first user where storedUser.ID = id
 	var u storedUser
-	result := s.db.Model(&storedUser{ID: id}).First(&u)
+	result := s.db.Where(&storedUser{ID: id}).First(&u)
 
 	switch result.Error {
 	case gorm.ErrRecordNotFound:
@@ -137,16 +309,34 @@ func (s *dbSvc) UpdateUser(ctx context.Context, id uuid.UUID, U User) (User, err
 	if U.ID != id {
 		return User{}, ErrInconsistentIDs
 	}
+	if U.Role != "" && !U.Role.Valid() {
+		return User{}, ErrInvalidRole
+	}
 
 	// get first user where storedUser.ID = id
 	var u storedUser
-	result := s.db.Model(&storedUser{ID: id}).First(&u)
+	result := s.db.Where(&storedUser{ID: id}).First(&u)
+	if result.Error == gorm.ErrRecordNotFound {
+		return User{}, ErrNotFound
+	}
 	if result.Error != nil {
 		return User{}, result.Error
 	}
 
+	if U.Password != "" {
+		username := U.Username
+		if username == "" {
+			username = u.Username
+		}
+		if err := s.checkPassword(U.Password, username); err != nil {
+			return User{}, err
+		}
+	}
+
+	emailChanged := U.Email != "" && U.Email != u.Email
+
 	u, err := newStoredUser(U, s.hashParams)
-	if result.Error != nil {
+	if err != nil {
 		return User{}, err
 	}
 
@@ -154,8 +344,14 @@ func (s *dbSvc) UpdateUser(ctx context.Context, id uuid.UUID, U User) (User, err
 	if result.Error != nil {
 		return User{}, result.Error
 	}
+	if emailChanged {
+		// Updates skips false, the new address is verified on its own
+		if err := s.db.Model(&u).Update("email_verified", false).Error; err != nil {
+			return User{}, err
+		}
+	}
 
-	s.db.Model(&storedUser{ID: id}).First(&u)
+	s.db.Where(&storedUser{ID: id}).First(&u)
 	return u.ToUser(), nil
 }
 
@@ -164,33 +360,101 @@ func (s *dbSvc) AuthenticateUser(ctx context.Context, U User) (User, error) {
 		return User{}, ErrInvalidUserObject
 	}
 
-	// get first user where storedUser.Username = username
-	var u storedUser
-	result := s.db.Model(&storedUser{Username: U.Username}).First(&u)
-	if result.Error == gorm.ErrRecordNotFound {
-		return User{}, ErrNotFound
+	// rejecting locked out logins before hashing keeps them cheap
+	ip := ClientIP(ctx)
+	wait := s.userLogins.Check(U.Username)
+	if ip != "" {
+		if ipWait := s.ipLogins.Check(ip); ipWait > wait {
+			wait = ipWait
+		}
 	}
-	if result.Error != nil {
-		return User{}, result.Error
+	if wait > 0 {
+		return User{}, &LockedError{RetryAfter: wait}
+	}
+	fail := func() (User, error) {
+		s.userLogins.Fail(U.Username)
+		if ip != "" {
+			s.ipLogins.Fail(ip)
+			s.loginIPs.Add(U.Username, ip)
+		}
+		return User{}, ErrAuthenticationFailed
 	}
 
-	match, err := u.ComparePassword(U.Password)
+	u, err := s.verify(ctx, U.Username, U.Password)
+	if err == ErrAuthenticationFailed {
+		return fail()
+	}
 	if err != nil {
 		return User{}, err
 	}
+	s.userLogins.Reset(U.Username)
+	s.loginIPs.Take(U.Username)
+	if u.Disabled {
+		return User{}, ErrUserDisabled
+	}
+	if u.TOTPEnabled {
+		return User{}, s.challenge(u)
+	}
+	return u.ToUser(), nil
+}
 
-	if match {
-		return u.ToUser(), nil
+// dummy returns a hash of a random password with the configured parameters
+func (s *dbSvc) dummy() string {
+	s.dummyOnce.Do(func() {
+		b := make([]byte, 16)
+		rand.Read(b)
+		s.dummyHash, _ = argon2id.HashPassword(hex.EncodeToString(b), s.hashParams)
+	})
+	return s.dummyHash
+}
+
+// rehash stores a new hash of password when the hash of u was created with
+// parameters weaker than the configured ones.
+// It is best effort, a failed upgrade is retried on the next login.
+// Legacy hashes are always replaced.
+func (s *dbSvc) rehash(u storedUser, password string) {
+	if strings.HasPrefix(u.PasswordHash, argon2id.Prefix) {
+		needsRehash, err := argon2id.NeedsRehash(u.PasswordHash, s.hashParams)
+		if err != nil || !needsRehash {
+			return
+		}
+	}
+	hash, err := argon2id.HashPassword(password, s.hashParams)
+	if err != nil {
+		return
 	}
-	return User{}, ErrAuthenticationFailed
+	s.db.Model(&u).Update("password_hash", hash)
 }
 
 func (s *dbSvc) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	u := storedUser{}
 	result := s.db.Delete(&u, "id = ?", id.String())
+	if result.Error != nil {
+		return result.Error
+	}
 
-	return result.Error
-
+	if err := s.db.Unscoped().Where("user_id = ?", id).Delete(&storedToken{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Unscoped().Where("user_id = ?", id).Delete(&storedRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&storedMembership{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&storedAPIKey{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&storedOAuthToken{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&storedAuthCode{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", id).Delete(&storedIdentity{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("owner_id = ?", id).Delete(&storedOAuthClient{}).Error
 }
 func (s *dbSvc) GetUsers(ctx context.Context) ([]User, error) {
 
@@ -208,6 +472,123 @@ func (s *dbSvc) GetUsers(ctx context.Context) ([]User, error) {
 	return Users, nil
 }
 
+func (s *dbSvc) ListUsers(ctx context.Context, q ListQuery) (UserList, error) {
+	query := s.db.Model(&storedUser{})
+	if q.Name != "" {
+		query = query.Where("LOWER(username) = LOWER(?)", q.Name)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return UserList{}, err
+	}
+	var stored []storedUser
+	if err := query.Order("username").Offset(q.Offset).Limit(q.limit()).Find(&stored).Error; err != nil {
+		return UserList{}, err
+	}
+	list := UserList{Users: make([]User, len(stored)), Total: int(total)}
+	for i, u := range stored {
+		list.Users[i] = u.ToUser()
+	}
+	return list, nil
+}
+
+func (s *dbSvc) ImportUsers(ctx context.Context, users []ImportedUser) ([]User, error) {
+	if len(users) == 0 {
+		return []User{}, nil
+	}
+
+	var errs problem.FieldErrors
+	fail := func(i int, field, code, message string) {
+		errs = append(errs, problem.FieldError{Field: fmt.Sprintf("users[%d].%s", i, field), Code: code, Message: message})
+	}
+
+	stored := make([]storedUser, len(users))
+	seen := map[string]bool{}
+	for i, u := range users {
+		switch {
+		case u.Username == "":
+			fail(i, "username", "required", "is required")
+		case seen[u.Username]:
+			fail(i, "username", "duplicate", "is imported more than once")
+		default:
+			var count int64
+			if err := s.db.Model(&storedUser{}).Where(&storedUser{Username: u.Username}).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				fail(i, "username", "taken", "is already taken")
+			}
+		}
+		seen[u.Username] = true
+
+		if strings.HasPrefix(u.PasswordHash, argon2id.Prefix) {
+			if err := s.checkImportedParams(u.PasswordHash); err != nil {
+				fail(i, "password_hash", "invalid_hash", err.Error())
+			}
+		} else if err := legacyhash.Validate(u.PasswordHash); err != nil {
+			fail(i, "password_hash", "invalid_hash", err.Error())
+		}
+
+		stored[i] = storedUser{ID: u.ID, Username: u.Username, PasswordHash: u.PasswordHash, Role: string(RoleMember)}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&stored).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make([]User, len(stored))
+	for i := range stored {
+		imported[i] = stored[i].ToUser()
+	}
+	return imported, nil
+}
+
+// maxImportedCost is how many times the configured memory, iterations and
+// parallelism an imported argon2id hash may use. Like the caps on legacy
+// hashes it keeps imports from making logins arbitrarily expensive.
+const maxImportedCost = 4
+
+// checkImportedParams rejects argon2id hashes that can't be parsed or cost
+// more than maxImportedCost times the configured parameters
+func (s *dbSvc) checkImportedParams(encodedHash string) error {
+	p, err := argon2id.ParseParams(encodedHash)
+	if err != nil {
+		return err
+	}
+	for _, c := range []struct {
+		name       string
+		got, limit uint64
+	}{
+		{"memory", uint64(p.Memory()), uint64(s.hashParams.Memory())},
+		{"iterations", uint64(p.Iterations()), uint64(s.hashParams.Iterations())},
+		{"parallelism", uint64(p.Parallelism()), uint64(s.hashParams.Parallelism())},
+	} {
+		if c.got > c.limit*maxImportedCost {
+			return fmt.Errorf("%w: %s %d exceeds %d", argon2id.ErrInvalidParams, c.name, c.got, c.limit*maxImportedCost)
+		}
+	}
+	return nil
+}
+
+func (s *dbSvc) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.userLogins.Reset(u.Username)
+	// the user is still refused from the ips it failed from otherwise
+	for _, ip := range s.loginIPs.Take(u.Username) {
+		s.ipLogins.Reset(ip)
+	}
+	return nil
+}
+
 func (s *dbSvc) ServiceStatus(ctx context.Context) (int, error) {
 	db, err := s.db.DB()
 	if err != nil {
@@ -0,0 +1,1219 @@
+package authorization
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/jose"
+	"github.com/demeesterdev/todo-service/internal/mailer"
+	"github.com/demeesterdev/todo-service/internal/oidc"
+	"github.com/demeesterdev/todo-service/internal/oidc/oidctest"
+	"github.com/demeesterdev/todo-service/internal/password"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/internal/throttle"
+	"github.com/demeesterdev/todo-service/internal/totp"
+)
+
+func fieldCodes(t *testing.T, err error) []string {
+	t.Helper()
+	var fields problem.FieldErrors
+	if !assert.True(t, errors.As(err, &fields), "expected field errors, got %v", err) {
+		return nil
+	}
+	var codes []string
+	for _, f := range fields {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+	s, _ := NewInMemService(argon2id.DefaultConfig, WithPasswordPolicy(password.Policy{
+		MinLength:      10,
+		RequireDigit:   true,
+		ForbidUsername: true,
+	}))
+
+	_, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "hanshandjes"})
+	assert.Equal(t, []string{"missing_digit", "contains_username"}, fieldCodes(t, err))
+
+	u, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "4 long enough password"})
+	assert.NoError(t, err)
+
+	// the stored username is used when the update doesn't change it
+	_, err = s.UpdateUser(ctx, u.ID, User{Password: "HansHandjes1"})
+	assert.Equal(t, []string{"contains_username"}, fieldCodes(t, err))
+
+	_, err = s.UpdateUser(ctx, u.ID, User{Password: "short1"})
+	assert.Equal(t, []string{"too_short"}, fieldCodes(t, err))
+
+	_, err = s.UpdateUser(ctx, u.ID, User{Password: "another 4 long password"})
+	assert.NoError(t, err)
+}
+
+func TestRehashOnLogin(t *testing.T) {
+	ctx := context.Background()
+	weak, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	strong, _ := argon2id.NewParams(128, 2, 1, 16, 32)
+	s, _ := NewInMemService(weak)
+	svc := s.(*dbSvc)
+
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	storedHash := func() string {
+		var u storedUser
+		svc.db.Where(&storedUser{ID: added.ID}).First(&u)
+		return u.PasswordHash
+	}
+	before := storedHash()
+
+	// failed logins never rehash
+	svc.hashParams = strong
+	_, err = s.AuthenticateUser(ctx, User{Username: "hanshandjes", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+	assert.Equal(t, before, storedHash())
+
+	_, err = s.AuthenticateUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	after := storedHash()
+	assert.NotEqual(t, before, after)
+	params, err := argon2id.ParseParams(after)
+	assert.NoError(t, err)
+	assert.Equal(t, strong, params)
+
+	_, err = s.AuthenticateUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, after, storedHash())
+}
+
+func TestImportUsers(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+	svc := s.(*dbSvc)
+
+	_, err := s.AddUser(ctx, User{Username: "existing", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	_, err = s.ImportUsers(ctx, []ImportedUser{
+		{Username: "existing", PasswordHash: "$2b$04$abcdefghijklmnopqrstuu5TvoldX7Oc0Sc4FWwH6ZMpjp7xUvVGq"},
+		{Username: "twice", PasswordHash: "plain text"},
+		{Username: "twice", PasswordHash: "$pbkdf2-sha256$29000$c2FsdHNhbHRzYWx0MTIzNA$hhE82ZNkxt.Z9xuW7xpmjjLDAPx/Y559u8/hxrvZs30"},
+	})
+	assert.Equal(t, []string{"taken", "invalid_hash", "duplicate"}, fieldCodes(t, err))
+	users, _ := s.GetUsers(ctx)
+	assert.Len(t, users, 1, "failed imports must not add any user")
+
+	// argon2id hashes are capped at a multiple of the configured cost
+	cheap, err := argon2id.HashPassword("correct horse battery staple", params)
+	assert.NoError(t, err)
+	_, err = s.ImportUsers(ctx, []ImportedUser{
+		{Username: "memory", PasswordHash: strings.Replace(cheap, "m=64,", "m=4194304,", 1)},
+		{Username: "iterations", PasswordHash: strings.Replace(cheap, "t=1,", "t=100,", 1)},
+		{Username: "parallelism", PasswordHash: strings.Replace(cheap, "p=1", "p=255", 1)},
+		{Username: "cheap", PasswordHash: cheap},
+	})
+	assert.Equal(t, []string{"invalid_hash", "invalid_hash", "invalid_hash"}, fieldCodes(t, err))
+
+	imported, err := s.ImportUsers(ctx, []ImportedUser{
+		{Username: "scrypt", PasswordHash: "$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0MTIzNA$kmVMPslrNcE8hRKEeDS6wH8VgAPKYosGdhexpSGkMYk"},
+		{Username: "pbkdf2", PasswordHash: "$pbkdf2-sha256$29000$c2FsdHNhbHRzYWx0MTIzNA$hhE82ZNkxt.Z9xuW7xpmjjLDAPx/Y559u8/hxrvZs30"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, imported, 2)
+
+	for _, u := range imported {
+		_, err = s.AuthenticateUser(ctx, User{Username: u.Username, Password: "wrong"})
+		assert.ErrorIs(t, err, ErrAuthenticationFailed)
+
+		authenticated, err := s.AuthenticateUser(ctx, User{Username: u.Username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, authenticated.ID)
+
+		// the legacy hash is replaced on login
+		var stored storedUser
+		svc.db.Where(&storedUser{ID: u.ID}).First(&stored)
+		assert.True(t, strings.HasPrefix(stored.PasswordHash, argon2id.Prefix), stored.PasswordHash)
+	}
+}
+
+func TestPepperRotation(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	key := func(b byte) []byte { return []byte(strings.Repeat(string(rune('a'+b)), argon2id.MinPepperLength)) }
+	old, _ := argon2id.NewKeyring("old", map[string][]byte{"old": key(1)})
+	rotated, _ := argon2id.NewKeyring("new", map[string][]byte{"old": key(1), "new": key(2)})
+
+	s, _ := NewInMemService(params.WithPepper(old))
+	svc := s.(*dbSvc)
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	storedHash := func() string {
+		var u storedUser
+		svc.db.Where(&storedUser{ID: added.ID}).First(&u)
+		return u.PasswordHash
+	}
+	assert.Contains(t, storedHash(), "keyid=old")
+
+	svc.hashParams = params.WithPepper(rotated)
+	_, err = s.AuthenticateUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Contains(t, storedHash(), "keyid=new")
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params, WithLoginThrottle(
+		throttle.Config{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour},
+		throttle.Config{Threshold: 5, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour},
+	))
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	right := User{Username: "hanshandjes", Password: "correct horse battery staple"}
+	wrong := User{Username: "hanshandjes", Password: "wrong"}
+
+	// unknown users are indistinguishable from wrong passwords
+	_, err = s.AuthenticateUser(ctx, User{Username: "nobody", Password: "wrong"})
+	assert.Equal(t, ErrAuthenticationFailed, err)
+
+	_, err = s.AuthenticateUser(ctx, wrong)
+	assert.Equal(t, ErrAuthenticationFailed, err)
+	_, err = s.AuthenticateUser(ctx, wrong)
+	assert.Equal(t, ErrAuthenticationFailed, err)
+
+	// even the right password is rejected while locked
+	_, err = s.AuthenticateUser(ctx, right)
+	var locked *LockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.InDelta(t, time.Minute, locked.RetryAfter, float64(time.Second))
+
+	assert.NoError(t, s.UnlockUser(ctx, added.ID))
+	_, err = s.AuthenticateUser(ctx, right)
+	assert.NoError(t, err)
+
+	// a client is locked out after failing for many users
+	client := WithClientIP(ctx, "192.0.2.1")
+	for i := 0; i < 5; i++ {
+		_, err = s.AuthenticateUser(client, User{Username: fmt.Sprintf("user%d", i), Password: "wrong"})
+		assert.Equal(t, ErrAuthenticationFailed, err)
+	}
+	_, err = s.AuthenticateUser(client, right)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	_, err = s.AuthenticateUser(WithClientIP(ctx, "192.0.2.2"), right)
+	assert.NoError(t, err)
+
+	// unlocking a user lifts the lockout of the ips it failed from
+	owner, elsewhere := WithClientIP(ctx, "192.0.2.3"), WithClientIP(ctx, "192.0.2.4")
+	for i := 0; i < 5; i++ {
+		_, err = s.AuthenticateUser(owner, wrong)
+		assert.Equal(t, ErrAuthenticationFailed, err)
+		// keeps the user below its threshold until the ip is locked
+		s.(*dbSvc).userLogins.Reset("hanshandjes")
+	}
+	_, err = s.AuthenticateUser(owner, right)
+	assert.ErrorIs(t, err, ErrTooManyAttempts, "locked by ip")
+	for i := 0; i < 2; i++ {
+		_, err = s.AuthenticateUser(elsewhere, wrong)
+		assert.Equal(t, ErrAuthenticationFailed, err)
+	}
+	_, err = s.AuthenticateUser(elsewhere, right)
+	assert.ErrorIs(t, err, ErrTooManyAttempts, "locked by user")
+	assert.NoError(t, s.UnlockUser(ctx, added.ID))
+	_, err = s.AuthenticateUser(owner, right)
+	assert.NoError(t, err)
+	_, err = s.AuthenticateUser(elsewhere, right)
+	assert.NoError(t, err)
+	// other ips stay locked
+	_, err = s.AuthenticateUser(client, right)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	assert.Equal(t, ErrNotFound, s.UnlockUser(ctx, uuid.New()))
+}
+
+func TestTOTP(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params, WithMFA(MFAConfig{Issuer: "test", ChallengeTTL: time.Minute, MaxAttempts: 2, Skew: 1}))
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	right := User{Username: "hanshandjes", Password: "correct horse battery staple"}
+
+	_, err = s.ConfirmTOTP(ctx, added.ID, "123456")
+	assert.Equal(t, ErrMFANotEnrolled, err)
+
+	enrollment, err := s.EnrollTOTP(ctx, added.ID)
+	assert.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/test:hanshandjes?")
+	secret, err := totp.Encoding.DecodeString(enrollment.Secret)
+	assert.NoError(t, err)
+	code := func() string { return totp.Code(secret, totp.Counter(now)) }
+
+	// not required before it is confirmed
+	u, err := s.AuthenticateUser(ctx, right)
+	assert.NoError(t, err)
+	assert.False(t, u.MFAEnabled)
+
+	_, err = s.ConfirmTOTP(ctx, added.ID, "000000")
+	assert.Equal(t, ErrInvalidMFACode, err)
+	recovery, err := s.ConfirmTOTP(ctx, added.ID, code())
+	assert.NoError(t, err)
+	assert.Len(t, recovery, RecoveryCodes)
+	_, err = s.EnrollTOTP(ctx, added.ID)
+	assert.Equal(t, ErrMFAEnabled, err)
+
+	login := func() string {
+		_, err := s.AuthenticateUser(ctx, right)
+		var mfa *MFARequiredError
+		assert.True(t, errors.As(err, &mfa))
+		return mfa.Challenge
+	}
+
+	// the code used to confirm can't be used again
+	challenge := login()
+	_, err = s.CompleteMFA(ctx, challenge, code())
+	assert.Equal(t, ErrInvalidMFACode, err)
+
+	now = now.Add(totp.Period)
+	session, err := s.CompleteMFA(ctx, challenge, code())
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, session.User.ID)
+	assert.True(t, session.User.MFAEnabled)
+	assert.NotEmpty(t, session.Token)
+
+	// challenges are single use and expire
+	_, err = s.CompleteMFA(ctx, challenge, code())
+	assert.Equal(t, ErrInvalidMFAChallenge, err)
+	challenge = login()
+	now = now.Add(2 * time.Minute)
+	_, err = s.CompleteMFA(ctx, challenge, code())
+	assert.Equal(t, ErrInvalidMFAChallenge, err)
+
+	// and are dropped after too many wrong codes
+	challenge = login()
+	for i := 0; i < 2; i++ {
+		_, err = s.CompleteMFA(ctx, challenge, "000000")
+		assert.Equal(t, ErrInvalidMFACode, err)
+	}
+	_, err = s.CompleteMFA(ctx, challenge, code())
+	assert.Equal(t, ErrInvalidMFAChallenge, err)
+
+	// recovery codes work once, however they are typed
+	challenge = login()
+	session, err = s.CompleteMFA(ctx, challenge, strings.ToUpper(strings.ReplaceAll(recovery[0], "-", " ")))
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, session.User.ID)
+	challenge = login()
+	_, err = s.CompleteMFA(ctx, challenge, recovery[0])
+	assert.Equal(t, ErrInvalidMFACode, err)
+
+	// disabling for oneself takes a second factor as well
+	assert.Equal(t, ErrInvalidMFACode, s.DisableMFA(ctx, added.ID, "000000"))
+	assert.Equal(t, ErrInvalidMFACode, s.DisableMFA(ctx, added.ID, recovery[0]))
+	assert.NoError(t, s.DisableMFA(ctx, added.ID, recovery[1]))
+	u, err = s.AuthenticateUser(ctx, right)
+	assert.NoError(t, err)
+	assert.False(t, u.MFAEnabled)
+	assert.Equal(t, ErrMFANotEnrolled, s.DisableMFA(ctx, added.ID, recovery[2]))
+	assert.NoError(t, s.DisableMFA(ctx, added.ID, ""))
+	assert.Equal(t, ErrNotFound, s.DisableMFA(ctx, uuid.New(), ""))
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	var sent []mailer.Message
+	capture := mailer.Func(func(ctx context.Context, m mailer.Message) error {
+		sent = append(sent, m)
+		return nil
+	})
+	s, _ := NewInMemService(params, WithMailer(capture, MailConfig{ResetURL: "https://todo.example/reset", ResetTTL: time.Hour}))
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+	token := func() string {
+		return regexp.MustCompile(`token=([\w-]+)`).FindStringSubmatch(sent[len(sent)-1].Body)[1]
+	}
+
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple", Email: "hans@example.com"})
+	assert.NoError(t, err)
+	assert.False(t, added.EmailVerified)
+
+	// mails are sent in the background
+	forgot := func(email string) {
+		assert.NoError(t, s.ForgotPassword(ctx, email))
+		s.(*dbSvc).mails.Wait()
+	}
+
+	// unknown addresses succeed without mail
+	forgot("nobody@example.com")
+	assert.Empty(t, sent)
+
+	forgot("hans@example.com")
+	assert.Len(t, sent, 1)
+	assert.Equal(t, "hans@example.com", sent[0].To)
+	first := token()
+
+	// a new mail revokes the previous token
+	forgot("hans@example.com")
+	assert.Equal(t, ErrInvalidToken, s.ResetPassword(ctx, first, "a new password for hans"))
+
+	// the reset ends every credential made with the old password
+	session, err := s.Login(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	key, err := s.CreateAPIKey(ctx, added.ID, APIKey{Name: "backup", Scopes: []Scope{ScopeTodosRead}})
+	assert.NoError(t, err)
+	client, err := s.RegisterClient(ctx, OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}, Scopes: []Scope{ScopeTodosRead}}, added.ID)
+	assert.NoError(t, err)
+	verifier := strings.Repeat("v", 43)
+	code, err := s.Authorize(ctx, AuthorizationRequest{ClientID: client.ID, UserID: added.ID, RedirectURI: "https://cal.example/cb", CodeChallenge: pkceChallenge(verifier), CodeChallengeMethod: CodeChallengeS256})
+	assert.NoError(t, err)
+	grant, err := s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeAuthorizationCode, Client: ClientCredentials{ID: client.ID, Secret: client.Secret}, Code: code, RedirectURI: "https://cal.example/cb", CodeVerifier: verifier})
+	assert.NoError(t, err)
+
+	second := token()
+	assert.Equal(t, []string{"too_short"}, fieldCodes(t, s.ResetPassword(ctx, second, "short")))
+	assert.NoError(t, s.ResetPassword(ctx, second, "a new password for hans"))
+	assert.Equal(t, ErrInvalidToken, s.ResetPassword(ctx, second, "another password for hans"))
+
+	_, err = s.ValidateSession(ctx, session.Token)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.ValidateAPIKey(ctx, key.Key)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.ValidateAccessToken(ctx, grant.AccessToken)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeRefreshToken, Client: ClientCredentials{ID: client.ID, Secret: client.Secret}, RefreshToken: grant.RefreshToken})
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	u, err := s.AuthenticateUser(ctx, User{Username: "hanshandjes", Password: "a new password for hans"})
+	assert.NoError(t, err)
+	assert.True(t, u.EmailVerified)
+
+	// tokens expire
+	forgot("hans@example.com")
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, ErrInvalidToken, s.ResetPassword(ctx, token(), "a new password for hans"))
+
+	// failed mails look like unknown addresses
+	failing, _ := NewInMemService(params, WithMailer(mailer.Func(func(ctx context.Context, m mailer.Message) error {
+		return errors.New("connection refused")
+	}), DefaultMailConfig))
+	failing.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple", Email: "hans@example.com"})
+	assert.NoError(t, failing.ForgotPassword(ctx, "hans@example.com"))
+	failing.(*dbSvc).mails.Wait()
+
+	disabled, _ := NewInMemService(params)
+	assert.Equal(t, ErrMailDisabled, disabled.ForgotPassword(ctx, "hans@example.com"))
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	var sent []mailer.Message
+	capture := mailer.Func(func(ctx context.Context, m mailer.Message) error {
+		sent = append(sent, m)
+		return nil
+	})
+	s, _ := NewInMemService(params, WithMailer(capture, MailConfig{VerifyURL: "https://todo.example/verify", VerifyTTL: time.Hour}))
+	token := func() string {
+		return regexp.MustCompile(`token=([\w-]+)`).FindStringSubmatch(sent[len(sent)-1].Body)[1]
+	}
+
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEmailMissing, s.SendVerificationEmail(ctx, added.ID))
+	assert.Equal(t, ErrNotFound, s.SendVerificationEmail(ctx, uuid.New()))
+
+	_, err = s.UpdateUser(ctx, added.ID, User{Email: "hans@example.com"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.SendVerificationEmail(ctx, added.ID))
+	u, err := s.VerifyEmail(ctx, token())
+	assert.NoError(t, err)
+	assert.True(t, u.EmailVerified)
+	assert.Equal(t, "hans@example.com", u.Email)
+
+	// a new address has to be verified again and old tokens don't apply
+	assert.NoError(t, s.SendVerificationEmail(ctx, added.ID))
+	old := token()
+	u, err = s.UpdateUser(ctx, added.ID, User{Email: "hans@example.org"})
+	assert.NoError(t, err)
+	assert.False(t, u.EmailVerified)
+	_, err = s.VerifyEmail(ctx, old)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.VerifyEmail(ctx, "made-up")
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params, WithSessionTTL(time.Hour))
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	added, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	right := User{Username: "hanshandjes", Password: "correct horse battery staple"}
+
+	_, err = s.Login(ctx, User{Username: "hanshandjes", Password: "wrong"})
+	assert.Equal(t, ErrAuthenticationFailed, err)
+
+	first, err := s.Login(ctx, right)
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, first.User.ID)
+	assert.Equal(t, now.Add(time.Hour), first.ExpiresAt)
+
+	// sessions on other devices stay valid
+	second, err := s.Login(ctx, right)
+	assert.NoError(t, err)
+	u, err := s.ValidateSession(ctx, first.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, u.ID)
+
+	assert.NoError(t, s.RevokeSession(ctx, first.Token))
+	_, err = s.ValidateSession(ctx, first.Token)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Equal(t, ErrInvalidToken, s.RevokeSession(ctx, first.Token))
+	_, err = s.ValidateSession(ctx, second.Token)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = s.ValidateSession(ctx, second.Token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// deleted users are logged out
+	third, err := s.Login(ctx, right)
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteUser(ctx, added.ID))
+	_, err = s.ValidateSession(ctx, third.Token)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestRoles(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+
+	member, err := s.AddUser(ctx, User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, RoleMember, member.Role)
+	_, err = s.AddUser(ctx, User{Username: "root", Password: "correct horse battery staple", Role: "root"})
+	assert.Equal(t, ErrInvalidRole, err)
+
+	updated, err := s.UpdateUser(ctx, member.ID, User{ID: member.ID, Role: RoleReadOnly})
+	assert.NoError(t, err)
+	assert.Equal(t, RoleReadOnly, updated.Role)
+	_, err = s.UpdateUser(ctx, member.ID, User{ID: member.ID, Role: "root"})
+	assert.Equal(t, ErrInvalidRole, err)
+
+	assert.True(t, RoleAdmin.Can(PermWriteUsers))
+	assert.False(t, RoleMember.Can(PermReadUsers))
+	assert.False(t, RoleReadOnly.Can(PermWriteSelf))
+
+	// the admin is only created once, the username of a member isn't taken over
+	created, err := BootstrapAdmin(ctx, s, "hanshandjes", "correct horse battery staple")
+	assert.Error(t, err)
+	assert.False(t, created)
+	created, err = BootstrapAdmin(ctx, s, "admin", "correct horse battery staple")
+	assert.NoError(t, err)
+	assert.True(t, created)
+	created, err = BootstrapAdmin(ctx, s, "admin2", "correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	admin, err := s.FindUser(ctx, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, admin.Role)
+}
+
+func TestTeams(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	owner, _ := s.AddUser(ctx, User{Username: "owner", Password: "correct horse battery staple"})
+	guest, _ := s.AddUser(ctx, User{Username: "guest", Password: "correct horse battery staple"})
+
+	_, err := s.CreateTeam(ctx, Team{}, owner.ID)
+	assert.Equal(t, ErrInvalidTeam, err)
+	team, err := s.CreateTeam(ctx, Team{Name: "kitchen"}, owner.ID)
+	assert.NoError(t, err)
+	role, ok := team.Role(owner.ID)
+	assert.True(t, ok)
+	assert.Equal(t, TeamRoleOwner, role)
+
+	_, err = s.InviteMember(ctx, team.ID, "boss")
+	assert.Equal(t, ErrInvalidTeamRole, err)
+	token, err := s.InviteMember(ctx, team.ID, TeamRoleGuest)
+	assert.NoError(t, err)
+	team, err = s.AcceptInvitation(ctx, token, guest.ID)
+	assert.NoError(t, err)
+	role, _ = team.Role(guest.ID)
+	assert.Equal(t, TeamRoleGuest, role)
+
+	// invitations are single use and expire
+	_, err = s.AcceptInvitation(ctx, token, guest.ID)
+	assert.Equal(t, ErrInvalidToken, err)
+	expired, _ := s.InviteMember(ctx, team.ID, TeamRoleMember)
+	now = now.Add(InvitationTTL)
+	_, err = s.AcceptInvitation(ctx, expired, guest.ID)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	teams, err := s.GetTeams(ctx, guest.ID)
+	assert.NoError(t, err)
+	assert.Len(t, teams, 1)
+
+	// a team always keeps an owner
+	_, err = s.UpdateMember(ctx, team.ID, owner.ID, TeamRoleAdmin)
+	assert.Equal(t, ErrLastOwner, err)
+	assert.Equal(t, ErrLastOwner, s.RemoveMember(ctx, team.ID, owner.ID))
+	team, err = s.UpdateMember(ctx, team.ID, guest.ID, TeamRoleOwner)
+	assert.NoError(t, err)
+	assert.NoError(t, s.RemoveMember(ctx, team.ID, owner.ID))
+	assert.Equal(t, ErrNotMember, s.RemoveMember(ctx, team.ID, owner.ID))
+
+	teams, err = s.GetTeams(ctx, owner.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, teams)
+
+	assert.NoError(t, s.DeleteTeam(ctx, team.ID))
+	_, err = s.GetTeam(ctx, team.ID)
+	assert.Equal(t, ErrTeamNotFound, err)
+	teams, _ = s.GetTeams(ctx, guest.ID)
+	assert.Empty(t, teams)
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	u, _ := s.AddUser(ctx, User{Username: "scripter", Password: "correct horse battery staple"})
+
+	_, err := s.CreateAPIKey(ctx, u.ID, APIKey{Name: "backup", Scopes: []Scope{"todos:admin"}})
+	assert.Equal(t, ErrInvalidScope, err)
+	_, err = s.CreateAPIKey(ctx, u.ID, APIKey{Name: "backup"})
+	assert.Equal(t, ErrInvalidAPIKey, err)
+	_, err = s.CreateAPIKey(ctx, uuid.New(), APIKey{Name: "backup", Scopes: []Scope{ScopeTodosRead}})
+	assert.Equal(t, ErrNotFound, err)
+
+	created, err := s.CreateAPIKey(ctx, u.ID, APIKey{Name: "backup", Scopes: []Scope{ScopeTodosRead}})
+	assert.NoError(t, err)
+	assert.True(t, IsAPIKey(created.Key))
+	assert.Equal(t, now.Add(DefaultAPIKeyTTL), created.ExpiresAt)
+	assert.Nil(t, created.LastUsedAt)
+
+	// the key is only shown once
+	keys, err := s.GetAPIKeys(ctx, u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key)
+	assert.Equal(t, []Scope{ScopeTodosRead}, keys[0].Scopes)
+
+	now = now.Add(time.Hour)
+	k, err := s.ValidateAPIKey(ctx, created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, k.UserID)
+	assert.True(t, k.Has(ScopeTodosRead))
+	assert.False(t, k.Has(ScopeTodosWrite))
+	keys, _ = s.GetAPIKeys(ctx, u.ID)
+	assert.Equal(t, now, keys[0].LastUsedAt.UTC())
+
+	_, err = s.ValidateAPIKey(ctx, APIKeyPrefix+"unknown")
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// keys expire
+	short, _ := s.CreateAPIKey(ctx, u.ID, APIKey{Name: "ci", Scopes: []Scope{ScopeTodosWrite}, ExpiresAt: now.Add(time.Minute)})
+	now = now.Add(time.Minute)
+	_, err = s.ValidateAPIKey(ctx, short.Key)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	assert.Equal(t, ErrAPIKeyNotFound, s.RevokeAPIKey(ctx, uuid.New(), created.ID))
+	assert.NoError(t, s.RevokeAPIKey(ctx, u.ID, created.ID))
+	_, err = s.ValidateAPIKey(ctx, created.Key)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// keys end with their user
+	other, _ := s.CreateAPIKey(ctx, u.ID, APIKey{Name: "other", Scopes: []Scope{ScopeTodosRead}})
+	assert.NoError(t, s.DeleteUser(ctx, u.ID))
+	_, err = s.ValidateAPIKey(ctx, other.Key)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOAuth(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	dev, _ := s.AddUser(ctx, User{Username: "developer", Password: "correct horse battery staple"})
+	u, _ := s.AddUser(ctx, User{Username: "planner", Password: "correct horse battery staple"})
+
+	_, err := s.RegisterClient(ctx, OAuthClient{Name: "calendar"}, dev.ID)
+	assert.Equal(t, ErrInvalidClientMetadata, err)
+	for _, uri := range []string{"/callback", "http://cal.example/cb", "chrome-extension://abc/cb", "https://cal.example/cb#token", "https://user@cal.example/cb"} {
+		_, err = s.RegisterClient(ctx, OAuthClient{Name: "calendar", RedirectURIs: []string{uri}}, dev.ID)
+		assert.Equal(t, ErrInvalidRedirectURI, err, uri)
+	}
+	_, err = s.RegisterClient(ctx, OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}, Scopes: []Scope{"todos:admin"}}, dev.ID)
+	assert.Equal(t, ErrInvalidScope, err)
+
+	calendar, err := s.RegisterClient(ctx, OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}, Scopes: []Scope{ScopeTodosRead}}, dev.ID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, calendar.Secret)
+	assert.False(t, calendar.Public)
+	extension, err := s.RegisterClient(ctx, OAuthClient{Name: "extension", RedirectURIs: []string{"http://127.0.0.1:8400/cb", "http://localhost:8400/cb"}, Public: true}, dev.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, extension.Secret)
+	assert.Equal(t, OAuthScopes, extension.Scopes)
+
+	// the secret is only shown once
+	got, err := s.GetClient(ctx, calendar.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, got.Secret)
+	_, err = s.GetClient(ctx, uuid.New())
+	assert.Equal(t, ErrClientNotFound, err)
+
+	verifier := strings.Repeat("v", 43)
+	auth := AuthorizationRequest{ClientID: calendar.ID, UserID: u.ID, RedirectURI: "https://cal.example/cb", CodeChallenge: pkceChallenge(verifier), CodeChallengeMethod: CodeChallengeS256}
+	_, err = s.Authorize(ctx, AuthorizationRequest{ClientID: calendar.ID, UserID: u.ID, RedirectURI: "https://evil.example/cb", CodeChallenge: auth.CodeChallenge, CodeChallengeMethod: CodeChallengeS256})
+	assert.Equal(t, ErrInvalidRedirectURI, err)
+	_, err = s.Authorize(ctx, AuthorizationRequest{ClientID: calendar.ID, UserID: u.ID, RedirectURI: auth.RedirectURI, CodeChallenge: verifier, CodeChallengeMethod: "plain"})
+	assert.Equal(t, ErrInvalidOAuthRequest, err)
+	_, err = s.Authorize(ctx, AuthorizationRequest{ClientID: calendar.ID, UserID: u.ID, RedirectURI: auth.RedirectURI, Scopes: []Scope{ScopeTodosWrite}, CodeChallenge: auth.CodeChallenge, CodeChallengeMethod: CodeChallengeS256})
+	assert.Equal(t, ErrInvalidScope, err)
+
+	code, err := s.Authorize(ctx, auth)
+	assert.NoError(t, err)
+	exchange := TokenRequest{GrantType: GrantTypeAuthorizationCode, Client: ClientCredentials{ID: calendar.ID, Secret: calendar.Secret}, Code: code, RedirectURI: auth.RedirectURI, CodeVerifier: verifier}
+
+	wrongSecret := exchange
+	wrongSecret.Client.Secret = "guess"
+	_, err = s.ExchangeToken(ctx, wrongSecret)
+	assert.Equal(t, ErrInvalidClient, err)
+	unsupported := exchange
+	unsupported.GrantType = "password"
+	_, err = s.ExchangeToken(ctx, unsupported)
+	assert.Equal(t, ErrUnsupportedGrantType, err)
+
+	token, err := s.ExchangeToken(ctx, exchange)
+	assert.NoError(t, err)
+	assert.True(t, IsAccessToken(token.AccessToken))
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, int64(3600), token.ExpiresIn)
+	assert.Equal(t, "todos:read", token.Scope)
+
+	// codes are single use
+	_, err = s.ExchangeToken(ctx, exchange)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	at, err := s.ValidateAccessToken(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, at.UserID)
+	assert.Equal(t, calendar.ID, at.ClientID)
+	assert.True(t, at.Has(ScopeTodosRead))
+	assert.False(t, at.Has(ScopeTodosWrite))
+	_, err = s.ValidateAccessToken(ctx, token.RefreshToken)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// PKCE binds the code to the client that asked for it
+	code, _ = s.Authorize(ctx, auth)
+	stolen := exchange
+	stolen.Code, stolen.CodeVerifier = code, strings.Repeat("x", 43)
+	_, err = s.ExchangeToken(ctx, stolen)
+	assert.Equal(t, ErrInvalidGrant, err)
+	stolen.CodeVerifier = verifier
+	_, err = s.ExchangeToken(ctx, stolen)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	// codes expire
+	code, _ = s.Authorize(ctx, auth)
+	now = now.Add(DefaultOAuthConfig.CodeTTL)
+	exchange.Code = code
+	_, err = s.ExchangeToken(ctx, exchange)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	i, err := s.IntrospectToken(ctx, token.AccessToken, exchange.Client)
+	assert.NoError(t, err)
+	assert.Equal(t, Introspection{Active: true, Scope: "todos:read", ClientID: calendar.ID.String(), Username: "planner", TokenType: "Bearer",
+		Exp: now.Add(-DefaultOAuthConfig.CodeTTL).Add(time.Hour).Unix(), Iat: now.Add(-DefaultOAuthConfig.CodeTTL).Unix(), Sub: u.ID.String()}, i)
+	i, err = s.IntrospectToken(ctx, AccessTokenPrefix+"unknown", exchange.Client)
+	assert.NoError(t, err)
+	assert.Equal(t, Introspection{}, i)
+	// public clients can't introspect
+	_, err = s.IntrospectToken(ctx, token.AccessToken, ClientCredentials{ID: extension.ID})
+	assert.Equal(t, ErrInvalidClient, err)
+
+	// refresh tokens rotate and can narrow the scopes
+	refresh := TokenRequest{GrantType: GrantTypeRefreshToken, Client: exchange.Client, RefreshToken: token.RefreshToken, Scopes: []Scope{ScopeTodosWrite}}
+	_, err = s.ExchangeToken(ctx, refresh)
+	assert.Equal(t, ErrInvalidScope, err)
+	refresh.Scopes = nil
+	refreshed, err := s.ExchangeToken(ctx, refresh)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token.RefreshToken, refreshed.RefreshToken)
+	_, err = s.ExchangeToken(ctx, refresh)
+	assert.Equal(t, ErrInvalidGrant, err)
+
+	// access tokens expire
+	now = now.Add(time.Hour)
+	_, err = s.ValidateAccessToken(ctx, refreshed.AccessToken)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// revoking the refresh token revokes the grant, other clients and unknown
+	// tokens are ignored
+	refresh.RefreshToken = refreshed.RefreshToken
+	again, err := s.ExchangeToken(ctx, refresh)
+	assert.NoError(t, err)
+	assert.NoError(t, s.RevokeToken(ctx, again.RefreshToken, ClientCredentials{ID: extension.ID}))
+	_, err = s.ValidateAccessToken(ctx, again.AccessToken)
+	assert.NoError(t, err)
+	assert.NoError(t, s.RevokeToken(ctx, "unknown", exchange.Client))
+	assert.Equal(t, ErrInvalidClient, s.RevokeToken(ctx, again.RefreshToken, wrongSecret.Client))
+	assert.NoError(t, s.RevokeToken(ctx, again.RefreshToken, exchange.Client))
+	_, err = s.ValidateAccessToken(ctx, again.AccessToken)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// public clients only need PKCE
+	auth.ClientID, auth.RedirectURI = extension.ID, "http://127.0.0.1:8400/cb"
+	code, _ = s.Authorize(ctx, auth)
+	public, err := s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeAuthorizationCode, Client: ClientCredentials{ID: extension.ID}, Code: code, RedirectURI: auth.RedirectURI, CodeVerifier: verifier})
+	assert.NoError(t, err)
+	assert.Equal(t, "openid todos:read todos:write", public.Scope)
+
+	// disabled users can't refresh and their tokens are inactive
+	assert.NoError(t, s.(*dbSvc).db.Model(&storedUser{}).Where("id = ?", u.ID).Update("disabled", true).Error)
+	_, err = s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeRefreshToken, Client: ClientCredentials{ID: extension.ID}, RefreshToken: public.RefreshToken})
+	assert.Equal(t, ErrInvalidGrant, err)
+	i, err = s.IntrospectToken(ctx, public.AccessToken, exchange.Client)
+	assert.NoError(t, err)
+	assert.False(t, i.Active)
+	assert.NoError(t, s.(*dbSvc).db.Model(&storedUser{}).Where("id = ?", u.ID).Update("disabled", false).Error)
+
+	// tokens end with their user
+	assert.NoError(t, s.DeleteUser(ctx, u.ID))
+	_, err = s.ValidateAccessToken(ctx, public.AccessToken)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.NoError(t, s.DeleteUser(ctx, dev.ID))
+	_, err = s.GetClient(ctx, extension.ID)
+	assert.Equal(t, ErrClientNotFound, err)
+}
+
+func TestOIDC(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params, WithOIDC(OIDCConfig{Issuer: "https://auth.example", IDTokenTTL: time.Hour, KeyRotation: 24 * time.Hour}))
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.(*dbSvc).now = func() time.Time { return now }
+
+	m, err := s.OpenIDConfiguration(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://auth.example", m.Issuer)
+	assert.Equal(t, "https://auth.example/jwks.json", m.JWKSURI)
+	assert.Contains(t, m.ScopesSupported, "openid")
+
+	u, _ := s.AddUser(ctx, User{Username: "planner", Password: "correct horse battery staple"})
+	client, _ := s.RegisterClient(ctx, OAuthClient{Name: "wiki", RedirectURIs: []string{"https://wiki.example/cb"}, Public: true}, u.ID)
+	verifier := strings.Repeat("v", 43)
+	signIn := func(nonce string, scopes ...Scope) OAuthToken {
+		code, err := s.Authorize(ctx, AuthorizationRequest{ClientID: client.ID, UserID: u.ID, RedirectURI: "https://wiki.example/cb", Scopes: scopes,
+			CodeChallenge: pkceChallenge(verifier), CodeChallengeMethod: CodeChallengeS256, Nonce: nonce})
+		assert.NoError(t, err)
+		token, err := s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeAuthorizationCode, Client: ClientCredentials{ID: client.ID},
+			Code: code, RedirectURI: "https://wiki.example/cb", CodeVerifier: verifier})
+		assert.NoError(t, err)
+		return token
+	}
+	verify := func(token string) (IDToken, error) {
+		keys, err := s.GetJWKS(ctx)
+		assert.NoError(t, err)
+		var claims IDToken
+		return claims, jose.Verify(token, keys, &claims)
+	}
+
+	// only openid grants get an ID token
+	plain := signIn("", ScopeTodosRead)
+	assert.Empty(t, plain.IDToken)
+	_, err = s.UserInfo(ctx, plain.AccessToken)
+	assert.Equal(t, ErrForbidden, err)
+
+	token := signIn("n-0S6_WzA2Mj", ScopeOpenID)
+	claims, err := verify(token.IDToken)
+	assert.NoError(t, err)
+	assert.Equal(t, IDToken{Issuer: "https://auth.example", Audience: client.ID.String(), ExpiresAt: now.Add(time.Hour).Unix(), IssuedAt: now.Unix(),
+		Nonce: "n-0S6_WzA2Mj", UserInfo: UserInfo{Subject: u.ID.String(), PreferredUsername: "planner"}}, claims)
+
+	info, err := s.UserInfo(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, UserInfo{Subject: u.ID.String(), PreferredUsername: "planner"}, info)
+	_, err = s.UserInfo(ctx, AccessTokenPrefix+"unknown")
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// refreshed grants get a new ID token without nonce
+	refreshed, err := s.ExchangeToken(ctx, TokenRequest{GrantType: GrantTypeRefreshToken, Client: ClientCredentials{ID: client.ID}, RefreshToken: token.RefreshToken})
+	assert.NoError(t, err)
+	claims, err = verify(refreshed.IDToken)
+	assert.NoError(t, err)
+	assert.Empty(t, claims.Nonce)
+
+	// a rotated key is published until the tokens it signed expired
+	keys, _ := s.GetJWKS(ctx)
+	assert.Len(t, keys.Keys, 1)
+	now = now.Add(24 * time.Hour)
+	rotated := signIn("", ScopeOpenID)
+	keys, _ = s.GetJWKS(ctx)
+	assert.Len(t, keys.Keys, 2)
+	_, err = verify(rotated.IDToken)
+	assert.NoError(t, err)
+	_, err = verify(token.IDToken)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	keys, _ = s.GetJWKS(ctx)
+	assert.Len(t, keys.Keys, 1)
+	_, err = verify(token.IDToken)
+	assert.Equal(t, jose.ErrUnknownKey, err)
+	_, err = verify(rotated.IDToken)
+	assert.NoError(t, err)
+}
+
+// federatedSignIn signs in at idp after StartFederatedLogin and returns the
+// state and code of the redirect back
+func federatedSignIn(t *testing.T, authURL string) (state, code string) {
+	t.Helper()
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(authURL)
+	if !assert.NoError(t, err) {
+		return "", ""
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	return callback.Query().Get("state"), callback.Query().Get("code")
+}
+
+func TestFederatedLogin(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	idp := oidctest.NewProvider(
+		oidctest.Client{ID: "todo", Secret: "s3cret", RedirectURL: "http://localhost:8082/login/corp/callback"},
+		oidc.Claims{Subject: "00u1", PreferredUsername: "alice", Email: "alice@corp.example", EmailVerified: true},
+	)
+	defer idp.Close()
+	cfg := DefaultFederationConfig
+	cfg.Providers = []IdentityProvider{{Name: "corp", Config: idp.Config()}}
+	s, _ := NewInMemService(params, WithFederation(cfg))
+
+	_, err := s.StartFederatedLogin(ctx, "other", uuid.Nil)
+	assert.Equal(t, ErrProviderNotFound, err)
+
+	signIn := func(userID uuid.UUID) (Session, error) {
+		authURL, err := s.StartFederatedLogin(ctx, "corp", userID)
+		assert.NoError(t, err)
+		state, code := federatedSignIn(t, authURL)
+		return s.CompleteFederatedLogin(ctx, "corp", state, code)
+	}
+
+	// the first sign in creates the user without a password
+	session, err := signIn(uuid.Nil)
+	assert.NoError(t, err)
+	alice := session.User
+	assert.Equal(t, "alice", alice.Username)
+	assert.Equal(t, "alice@corp.example", alice.Email)
+	assert.True(t, alice.EmailVerified)
+	assert.Equal(t, RoleMember, alice.Role)
+	u, err := s.ValidateSession(ctx, session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, u.ID)
+	_, err = s.AuthenticateUser(ctx, User{Username: "alice", Password: ""})
+	assert.Equal(t, ErrAuthenticationFailed, err)
+
+	// the next sign in finds the linked user
+	session, err = signIn(uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, session.User.ID)
+	ids, err := s.GetIdentities(ctx, alice.ID)
+	assert.NoError(t, err)
+	assert.Len(t, ids, 1)
+	assert.Equal(t, "corp", ids[0].Provider)
+	assert.Equal(t, "00u1", ids[0].Subject)
+
+	// states are used once
+	authURL, _ := s.StartFederatedLogin(ctx, "corp", uuid.Nil)
+	state, code := federatedSignIn(t, authURL)
+	_, err = s.CompleteFederatedLogin(ctx, "other", state, code)
+	assert.Equal(t, ErrProviderNotFound, err)
+	_, err = s.CompleteFederatedLogin(ctx, "corp", state, code)
+	assert.NoError(t, err)
+	_, err = s.CompleteFederatedLogin(ctx, "corp", state, code)
+	assert.Equal(t, ErrInvalidState, err)
+
+	// codes are checked by the provider
+	authURL, _ = s.StartFederatedLogin(ctx, "corp", uuid.Nil)
+	state, _ = federatedSignIn(t, authURL)
+	_, err = s.CompleteFederatedLogin(ctx, "corp", state, "forged")
+	assert.ErrorIs(t, err, ErrFederationFailed)
+
+	// a local user with the same name is not taken over
+	bob, _ := s.AddUser(ctx, User{Username: "bob", Password: "correct horse battery staple"})
+	idp.SetClaims(oidc.Claims{Subject: "00u2", PreferredUsername: "bob", Email: "bob@corp.example"})
+	session, err = signIn(uuid.Nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, bob.ID, session.User.ID)
+	assert.Equal(t, "corp-", session.User.Username[:5])
+	assert.Empty(t, session.User.Email, "unverified emails are not taken")
+
+	// bob links an identity of his own
+	idp.SetClaims(oidc.Claims{Subject: "00u3", PreferredUsername: "robert"})
+	session, err = signIn(bob.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, session.User.ID)
+	session, err = signIn(uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, session.User.ID)
+	// an identity belongs to one user, a user has one identity per provider
+	idp.SetClaims(oidc.Claims{Subject: "00u1"})
+	_, err = signIn(bob.ID)
+	assert.Equal(t, ErrIdentityLinked, err)
+	idp.SetClaims(oidc.Claims{Subject: "00u4"})
+	_, err = signIn(bob.ID)
+	assert.Equal(t, ErrIdentityLinked, err)
+
+	// the provider replaces the password, not the second factor
+	s.(*dbSvc).db.Model(&storedUser{}).Where("id = ?", bob.ID).Update("totp_enabled", true)
+	idp.SetClaims(oidc.Claims{Subject: "00u3"})
+	_, err = signIn(uuid.Nil)
+	var mfa *MFARequiredError
+	assert.ErrorAs(t, err, &mfa)
+
+	// logins expire
+	authURL, _ = s.StartFederatedLogin(ctx, "corp", uuid.Nil)
+	state, code = federatedSignIn(t, authURL)
+	s.(*dbSvc).now = func() time.Time { return time.Now().Add(cfg.LoginTTL) }
+	_, err = s.CompleteFederatedLogin(ctx, "corp", state, code)
+	assert.Equal(t, ErrInvalidState, err)
+	s.(*dbSvc).now = time.Now
+
+	// deleting a user unlinks its identities
+	assert.NoError(t, s.DeleteUser(ctx, alice.ID))
+	idp.SetClaims(oidc.Claims{Subject: "00u1", PreferredUsername: "alice"})
+	session, err = signIn(uuid.Nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, alice.ID, session.User.ID)
+}
+
+func TestFederatedLoginWithoutJIT(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	idp := oidctest.NewProvider(oidctest.Client{ID: "todo", RedirectURL: "http://localhost:8082/login/corp/callback"}, oidc.Claims{Subject: "00u1"})
+	defer idp.Close()
+	s, _ := NewInMemService(params, WithFederation(FederationConfig{
+		Providers: []IdentityProvider{{Name: "corp", Config: idp.Config()}},
+		LoginTTL:  time.Minute,
+	}))
+
+	authURL, err := s.StartFederatedLogin(ctx, "corp", uuid.Nil)
+	assert.NoError(t, err)
+	state, code := federatedSignIn(t, authURL)
+	_, err = s.CompleteFederatedLogin(ctx, "corp", state, code)
+	assert.Equal(t, ErrIdentityNotLinked, err)
+	users, _ := s.GetUsers(ctx)
+	assert.Empty(t, users)
+}
+
+// directory is a Verifier of fixed passwords
+type directory map[string]string
+
+func (directory) Name() string {
+	return "directory"
+}
+
+func (d directory) Verify(ctx context.Context, username, password string) (VerifiedUser, error) {
+	p, ok := d[username]
+	switch {
+	case !ok:
+		return VerifiedUser{}, ErrUnknownUser
+	case p != password:
+		return VerifiedUser{}, ErrAuthenticationFailed
+	}
+	return VerifiedUser{Username: username, Email: username + "@example.com"}, nil
+}
+
+func TestVerifierChain(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	dir := directory{"alice": "alice in the directory", "bob": "bob in the directory"}
+	s, _ := NewInMemService(params, WithVerifiers(PasswordHashes, dir))
+	svc := s.(*dbSvc)
+
+	_, err := s.AddUser(ctx, User{Username: "bob", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	// the first verifier knowing a user decides
+	_, err = s.AuthenticateUser(ctx, User{Username: "bob", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	_, err = s.AuthenticateUser(ctx, User{Username: "bob", Password: "bob in the directory"})
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+
+	// directory users are created on their first login
+	alice, err := s.AuthenticateUser(ctx, User{Username: "alice", Password: "alice in the directory"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", alice.Email)
+	assert.Equal(t, RoleMember, alice.Role)
+	_, err = s.AuthenticateUser(ctx, User{Username: "alice", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+	_, err = s.AuthenticateUser(ctx, User{Username: "carol", Password: "anything"})
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+
+	// and updated on the next ones
+	svc.db.Model(&storedUser{}).Where("id = ?", alice.ID).Update("email", "old@example.com")
+	again, err := s.AuthenticateUser(ctx, User{Username: "alice", Password: "alice in the directory"})
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, again.ID)
+	assert.Equal(t, "alice@example.com", again.Email)
+
+	// a directory never takes over local users
+	s, _ = NewInMemService(params, WithVerifiers(dir, PasswordHashes))
+	_, err = s.AddUser(ctx, User{Username: "bob", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	_, err = s.AuthenticateUser(ctx, User{Username: "bob", Password: "bob in the directory"})
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+}
+
+func TestProvisioning(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params, WithProvisioning(ProvisioningConfig{Token: "s3cret"}))
+
+	_, err := s.ValidateProvisioningToken(ctx, "wrong")
+	assert.Equal(t, ErrInvalidToken, err)
+	p, err := s.ValidateProvisioningToken(ctx, "s3cret")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleProvisioner, p.Role)
+
+	// provisioned users may come without a password
+	alice, err := s.ProvisionUser(ctx, User{Username: "alice", Email: "alice@example.com"})
+	assert.NoError(t, err)
+	_, err = s.ProvisionUser(ctx, User{Username: "alice"})
+	assert.Equal(t, ErrUsernameTaken, err)
+	bob, err := s.ProvisionUser(ctx, User{Username: "bob", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteUser(ctx, alice.ID))
+	_, err = s.ProvisionUser(ctx, User{Username: "alice"})
+	assert.Equal(t, ErrUsernameTaken, err)
+
+	// only members are provisioned unless more roles are allowed
+	_, err = s.ProvisionUser(ctx, User{Username: "carol", Role: RoleAdmin})
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.ProvisionUser(ctx, User{Username: "carol", Role: RoleReadOnly})
+	assert.Equal(t, ErrForbidden, err)
+	allowing, _ := NewInMemService(params, WithProvisioning(ProvisioningConfig{Token: "s3cret", Roles: []Role{RoleMember, RoleReadOnly}}))
+	carol, err := allowing.ProvisionUser(ctx, User{Username: "carol", Role: RoleReadOnly})
+	assert.NoError(t, err)
+	assert.Equal(t, RoleReadOnly, carol.Role)
+	_, err = allowing.ProvisionUser(ctx, User{Username: "dave", Role: RoleAdmin})
+	assert.Equal(t, ErrForbidden, err)
+
+	// disabling logs the user out and keeps it out
+	right := User{Username: "bob", Password: "correct horse battery staple"}
+	session, err := s.Login(ctx, right)
+	assert.NoError(t, err)
+	disabled, err := s.SetUserActive(ctx, bob.ID, false)
+	assert.NoError(t, err)
+	assert.True(t, disabled.Disabled)
+	_, err = s.ValidateSession(ctx, session.Token)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.Login(ctx, right)
+	assert.Equal(t, ErrUserDisabled, err)
+	enabled, err := s.SetUserActive(ctx, bob.ID, true)
+	assert.NoError(t, err)
+	assert.False(t, enabled.Disabled)
+	_, err = s.Login(ctx, right)
+	assert.NoError(t, err)
+	_, err = s.SetUserActive(ctx, uuid.New(), false)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestListing(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := NewInMemService(params)
+
+	var ids []uuid.UUID
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := s.ProvisionUser(ctx, User{Username: name})
+		assert.NoError(t, err)
+		ids = append(ids, u.ID)
+	}
+
+	page, err := s.ListUsers(ctx, ListQuery{Offset: 1, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, "bob", page.Users[0].Username)
+	}
+	page, err = s.ListUsers(ctx, ListQuery{Name: "carol"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	page, err = s.ListUsers(ctx, ListQuery{Name: "dave"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+	assert.Empty(t, page.Users)
+
+	// provisioned teams start empty and may lose all members
+	team, err := s.CreateTeam(ctx, Team{Name: "kitchen"}, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Empty(t, team.Members)
+	team, err = s.SetMembers(ctx, team.ID, []TeamMember{{UserID: ids[0], Role: TeamRoleMember}, {UserID: ids[1], Role: TeamRoleAdmin}})
+	assert.NoError(t, err)
+	assert.Len(t, team.Members, 2)
+	_, err = s.SetMembers(ctx, team.ID, []TeamMember{{UserID: ids[0], Role: TeamRoleMember}, {UserID: ids[0], Role: TeamRoleAdmin}})
+	assert.Equal(t, ErrInvalidTeam, err)
+	_, err = s.SetMembers(ctx, team.ID, []TeamMember{{UserID: uuid.New(), Role: TeamRoleMember}})
+	assert.Equal(t, ErrNotFound, err)
+	team, err = s.SetMembers(ctx, team.ID, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, team.Members)
+
+	// a team with an owner keeps one
+	owned, err := s.CreateTeam(ctx, Team{Name: "garden"}, ids[2])
+	assert.NoError(t, err)
+	_, err = s.SetMembers(ctx, owned.ID, []TeamMember{{UserID: ids[0], Role: TeamRoleMember}})
+	assert.Equal(t, ErrLastOwner, err)
+
+	renamed, err := s.UpdateTeam(ctx, owned.ID, Team{Name: "yard"})
+	assert.NoError(t, err)
+	assert.Equal(t, "yard", renamed.Name)
+	teams, err := s.ListTeams(ctx, ListQuery{Name: "yard"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, teams.Total)
+	teams, err = s.ListTeams(ctx, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, teams.Total)
+	assert.Len(t, teams.Teams, 2)
+}
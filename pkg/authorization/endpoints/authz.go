@@ -0,0 +1,298 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// AuthorizationMiddleware rejects requests the caller may not make before they
+// reach the service. The caller is the user of the session token in the
+// context, see authorization.WithSessionToken, and is added to the context
+// with authorization.WithCaller. Logging in, password resets and the status
+// need no session, users are restricted to their own record and API keys
+// unless their role allows managing all users. Teams are managed by their
+// owners and admins, see allowTeam. API keys and access tokens with a todos
+// scope may list the teams of their user. The OAuth2 token endpoints authenticate
+// clients instead of users, only the user itself consents to a client.
+// OpenID Connect discovery, the signing keys and userinfo, which takes an
+// access token, are public. So is signing in with an identity provider,
+// only users themselves link an identity to their account. The
+// provisioning token is only accepted from the SCIM transport, see
+// authorization.WithProvisioningToken, its caller authorization.Provisioner
+// makes the SCIM requests and nothing else, see provisions.
+func AuthorizationMiddleware(s authorization.Service) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			var (
+				caller *authorization.User
+				u      authorization.User
+				err    = authorization.ErrInvalidToken
+			)
+			if token := authorization.ProvisioningToken(ctx); token != "" {
+				u, err = s.ValidateProvisioningToken(ctx, token)
+			} else if token := authorization.SessionToken(ctx); token != "" {
+				u, err = s.ValidateSession(ctx, token)
+			}
+			switch err {
+			case nil:
+				caller = &u
+				ctx = authorization.WithCaller(ctx, u)
+			case authorization.ErrInvalidToken:
+				// requests needing no session still go through
+			default:
+				return nil, err
+			}
+			if caller != nil && caller.Role.Can(authorization.PermProvision) {
+				err = provisions(ctx, s, request)
+			} else {
+				err = authorize(ctx, s, caller, request)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// provisions returns nil when the provisioning client may make request, it
+// manages users and teams over SCIM but can't grant roles or touch admins
+func provisions(ctx context.Context, s authorization.Service, request interface{}) error {
+	switch req := request.(type) {
+	case ListUsersRequest, GetUserRequest, ProvisionUserRequest,
+		ListTeamsRequest, GetTeamRequest, CreateTeamRequest, UpdateTeamRequest,
+		DeleteTeamRequest, SetMembersRequest, ServiceStatusRequest:
+		return nil
+	case UpdateUserRequest:
+		if req.User.Role != "" {
+			return authorization.ErrForbidden
+		}
+		return provisioned(ctx, s, req.ID)
+	case SetUserActiveRequest:
+		return provisioned(ctx, s, req.ID)
+	default:
+		return authorization.ErrForbidden
+	}
+}
+
+// provisioned returns nil when the provisioning client may change the user
+// with id, admins are managed by admins only
+func provisioned(ctx context.Context, s authorization.Service, id uuid.UUID) error {
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u.Role.Can(authorization.PermWriteUsers) {
+		return authorization.ErrForbidden
+	}
+	return nil
+}
+
+// authorize returns nil when caller may make request, caller is nil without
+// a valid session
+func authorize(ctx context.Context, s authorization.Service, caller *authorization.User, request interface{}) error {
+	switch req := request.(type) {
+	case AuthenticateUserRequest, LoginRequest, CompleteMFARequest,
+		ValidateSessionRequest, RevokeSessionRequest,
+		ForgotPasswordRequest, ResetPasswordRequest, VerifyEmailRequest,
+		ValidateAPIKeyRequest, GetClientRequest, ExchangeTokenRequest,
+		RevokeTokenRequest, IntrospectTokenRequest, ValidateAccessTokenRequest,
+		OpenIDConfigurationRequest, GetJWKSRequest, UserInfoRequest,
+		CompleteFederatedLoginRequest, ValidateProvisioningTokenRequest,
+		ServiceStatusRequest:
+		return nil
+	case AddUserRequest:
+		// anyone may sign up, only as member
+		if req.User.Role == "" || req.User.Role == authorization.RoleMember {
+			return nil
+		}
+		return allow(caller, authorization.PermWriteUsers)
+	case GetUserRequest:
+		self := isSelf(caller, req.User.ID)
+		if req.User.ID == uuid.Nil && caller != nil {
+			self = req.User.Username == caller.Username
+		}
+		return allowSelf(caller, self, authorization.PermReadSelf, authorization.PermReadUsers)
+	case UpdateUserRequest:
+		// users can't change their own role
+		if req.User.Role != "" && (caller == nil || req.User.Role != caller.Role) {
+			return allow(caller, authorization.PermWriteUsers)
+		}
+		return allowSelf(caller, isSelf(caller, req.ID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case DeleteUserRequest:
+		return allowSelf(caller, isSelf(caller, req.ID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case EnrollTOTPRequest:
+		return allowSelf(caller, isSelf(caller, req.ID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case ConfirmTOTPRequest:
+		return allowSelf(caller, isSelf(caller, req.ID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case DisableMFARequest:
+		// a session alone can't remove its own second factor
+		return allowSelf(caller, isSelf(caller, req.ID) && req.Code != "", authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case SendVerificationEmailRequest:
+		return allowSelf(caller, isSelf(caller, req.ID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case GetUsersRequest:
+		return allow(caller, authorization.PermReadUsers)
+	case CreateTeamRequest:
+		self := isSelf(caller, req.OwnerID) || (caller != nil && req.OwnerID == uuid.Nil)
+		return allowSelf(caller, self, authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case AcceptInvitationRequest:
+		self := isSelf(caller, req.UserID) || (caller != nil && req.UserID == uuid.Nil)
+		return allowSelf(caller, self, authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case GetTeamsRequest:
+		// the todo service looks up the teams of the user of a key or token
+		if caller == nil && todosDelegate(ctx, s, req.UserID) {
+			return nil
+		}
+		return allowSelf(caller, isSelf(caller, req.UserID), authorization.PermReadSelf, authorization.PermReadUsers)
+	case CreateAPIKeyRequest:
+		return allowSelf(caller, isSelf(caller, req.UserID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case GetAPIKeysRequest:
+		return allowSelf(caller, isSelf(caller, req.UserID), authorization.PermReadSelf, authorization.PermReadUsers)
+	case RevokeAPIKeyRequest:
+		return allowSelf(caller, isSelf(caller, req.UserID), authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case RegisterClientRequest:
+		self := isSelf(caller, req.OwnerID) || (caller != nil && req.OwnerID == uuid.Nil)
+		return allowSelf(caller, self, authorization.PermWriteSelf, authorization.PermWriteUsers)
+	case AuthorizeRequest:
+		// only users themselves consent, not even admins consent for them
+		if caller == nil {
+			return authorization.ErrUnauthenticated
+		}
+		self := isSelf(caller, req.Request.UserID) || req.Request.UserID == uuid.Nil
+		if !self || !caller.Role.Can(authorization.PermWriteSelf) {
+			return authorization.ErrForbidden
+		}
+		return nil
+	case StartFederatedLoginRequest:
+		if req.UserID == uuid.Nil {
+			return nil
+		}
+		// whoever controls the identity can sign in as the user
+		if caller == nil {
+			return authorization.ErrUnauthenticated
+		}
+		if !isSelf(caller, req.UserID) || !caller.Role.Can(authorization.PermWriteSelf) {
+			return authorization.ErrForbidden
+		}
+		return nil
+	case GetIdentitiesRequest:
+		return allowSelf(caller, isSelf(caller, req.UserID), authorization.PermReadSelf, authorization.PermReadUsers)
+	case ListUsersRequest:
+		return allow(caller, authorization.PermReadUsers)
+	case ListTeamsRequest:
+		return allow(caller, authorization.PermReadUsers)
+	case UpdateTeamRequest:
+		return allowTeam(ctx, s, caller, req.ID, authorization.PermWriteSelf, authorization.PermWriteUsers, func(r authorization.TeamRole) bool {
+			return r == authorization.TeamRoleOwner
+		})
+	case GetTeamRequest:
+		return allowTeam(ctx, s, caller, req.ID, authorization.PermReadSelf, authorization.PermReadUsers, func(authorization.TeamRole) bool {
+			return true
+		})
+	case DeleteTeamRequest:
+		return allowTeam(ctx, s, caller, req.ID, authorization.PermWriteSelf, authorization.PermWriteUsers, func(r authorization.TeamRole) bool {
+			return r == authorization.TeamRoleOwner
+		})
+	case InviteMemberRequest:
+		// only owners make owners
+		return allowTeam(ctx, s, caller, req.TeamID, authorization.PermWriteSelf, authorization.PermWriteUsers, func(r authorization.TeamRole) bool {
+			return r == authorization.TeamRoleOwner || (r.CanManage() && req.Role != authorization.TeamRoleOwner)
+		})
+	case UpdateMemberRequest:
+		return allowTeam(ctx, s, caller, req.TeamID, authorization.PermWriteSelf, authorization.PermWriteUsers, func(r authorization.TeamRole) bool {
+			if r == authorization.TeamRoleOwner {
+				return true
+			}
+			current, _ := memberRole(ctx, s, req.TeamID, req.UserID)
+			return r.CanManage() && req.Role != authorization.TeamRoleOwner && current != authorization.TeamRoleOwner
+		})
+	case RemoveMemberRequest:
+		// members may leave a team
+		if isSelf(caller, req.UserID) && caller.Role.Can(authorization.PermWriteSelf) {
+			return nil
+		}
+		return allowTeam(ctx, s, caller, req.TeamID, authorization.PermWriteSelf, authorization.PermWriteUsers, func(r authorization.TeamRole) bool {
+			if r == authorization.TeamRoleOwner {
+				return true
+			}
+			current, _ := memberRole(ctx, s, req.TeamID, req.UserID)
+			return r.CanManage() && current != authorization.TeamRoleOwner
+		})
+	default:
+		// importing, unlocking, provisioning and disabling users, replacing
+		// the members of teams, and requests added later without a rule
+		return allow(caller, authorization.PermWriteUsers)
+	}
+}
+
+// todosDelegate reports whether the bearer token in ctx is an API key or
+// OAuth2 access token of userID with a todos scope
+func todosDelegate(ctx context.Context, s authorization.Service, userID uuid.UUID) bool {
+	token := authorization.SessionToken(ctx)
+	switch {
+	case authorization.IsAPIKey(token):
+		k, err := s.ValidateAPIKey(ctx, token)
+		return err == nil && k.UserID == userID && (k.Has(authorization.ScopeTodosRead) || k.Has(authorization.ScopeTodosWrite))
+	case authorization.IsAccessToken(token):
+		t, err := s.ValidateAccessToken(ctx, token)
+		return err == nil && t.UserID == userID && (t.Has(authorization.ScopeTodosRead) || t.Has(authorization.ScopeTodosWrite))
+	default:
+		return false
+	}
+}
+
+func isSelf(caller *authorization.User, id uuid.UUID) bool {
+	return caller != nil && id != uuid.Nil && caller.ID == id
+}
+
+func allow(caller *authorization.User, p authorization.Permission) error {
+	if caller == nil {
+		return authorization.ErrUnauthenticated
+	}
+	if !caller.Role.Can(p) {
+		return authorization.ErrForbidden
+	}
+	return nil
+}
+
+// allowSelf is allow for a request on a single user, own is enough when
+// that user is the caller
+func allowSelf(caller *authorization.User, self bool, own, p authorization.Permission) error {
+	if self && caller.Role.Can(own) {
+		return nil
+	}
+	return allow(caller, p)
+}
+
+// allowTeam is allowSelf for a request on a single team, the role of the
+// caller in the team is enough when ok accepts it. Unknown teams are left to the
+// service so they are reported as not found.
+func allowTeam(ctx context.Context, s authorization.Service, caller *authorization.User, teamID uuid.UUID, own, p authorization.Permission, ok func(authorization.TeamRole) bool) error {
+	if caller == nil {
+		return authorization.ErrUnauthenticated
+	}
+	t, err := s.GetTeam(ctx, teamID)
+	if err == authorization.ErrTeamNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r, member := t.Role(caller.ID); member && caller.Role.Can(own) && ok(r) {
+		return nil
+	}
+	return allow(caller, p)
+}
+
+func memberRole(ctx context.Context, s authorization.Service, teamID, userID uuid.UUID) (authorization.TeamRole, error) {
+	t, err := s.GetTeam(ctx, teamID)
+	if err != nil {
+		return "", err
+	}
+	r, _ := t.Role(userID)
+	return r, nil
+}
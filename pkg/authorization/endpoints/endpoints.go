@@ -2,35 +2,681 @@ package endpoints
 
 import (
 	"context"
+	"errors"
 
+	"github.com/demeesterdev/todo-service/internal/jose"
 	"github.com/demeesterdev/todo-service/pkg/authorization"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/google/uuid"
 )
 
 type Endpoints struct {
-	AddUserEndpoint          endpoint.Endpoint
-	GetUserEndpoint          endpoint.Endpoint
-	UpdateUserEndpoint       endpoint.Endpoint
-	AuthenticateUserEndpoint endpoint.Endpoint
-	DeleteUserEndpoint       endpoint.Endpoint
-	GetUsersEndpoint         endpoint.Endpoint
-	ServiceStatusEndpoint    endpoint.Endpoint
+	AddUserEndpoint                   endpoint.Endpoint
+	GetUserEndpoint                   endpoint.Endpoint
+	UpdateUserEndpoint                endpoint.Endpoint
+	AuthenticateUserEndpoint          endpoint.Endpoint
+	LoginEndpoint                     endpoint.Endpoint
+	ValidateSessionEndpoint           endpoint.Endpoint
+	RevokeSessionEndpoint             endpoint.Endpoint
+	DeleteUserEndpoint                endpoint.Endpoint
+	GetUsersEndpoint                  endpoint.Endpoint
+	ImportUsersEndpoint               endpoint.Endpoint
+	UnlockUserEndpoint                endpoint.Endpoint
+	CompleteMFAEndpoint               endpoint.Endpoint
+	EnrollTOTPEndpoint                endpoint.Endpoint
+	ConfirmTOTPEndpoint               endpoint.Endpoint
+	DisableMFAEndpoint                endpoint.Endpoint
+	ForgotPasswordEndpoint            endpoint.Endpoint
+	ResetPasswordEndpoint             endpoint.Endpoint
+	SendVerificationEmailEndpoint     endpoint.Endpoint
+	VerifyEmailEndpoint               endpoint.Endpoint
+	CreateTeamEndpoint                endpoint.Endpoint
+	GetTeamEndpoint                   endpoint.Endpoint
+	GetTeamsEndpoint                  endpoint.Endpoint
+	DeleteTeamEndpoint                endpoint.Endpoint
+	InviteMemberEndpoint              endpoint.Endpoint
+	AcceptInvitationEndpoint          endpoint.Endpoint
+	UpdateMemberEndpoint              endpoint.Endpoint
+	RemoveMemberEndpoint              endpoint.Endpoint
+	CreateAPIKeyEndpoint              endpoint.Endpoint
+	GetAPIKeysEndpoint                endpoint.Endpoint
+	RevokeAPIKeyEndpoint              endpoint.Endpoint
+	ValidateAPIKeyEndpoint            endpoint.Endpoint
+	RegisterClientEndpoint            endpoint.Endpoint
+	GetClientEndpoint                 endpoint.Endpoint
+	AuthorizeEndpoint                 endpoint.Endpoint
+	ExchangeTokenEndpoint             endpoint.Endpoint
+	RevokeTokenEndpoint               endpoint.Endpoint
+	IntrospectTokenEndpoint           endpoint.Endpoint
+	ValidateAccessTokenEndpoint       endpoint.Endpoint
+	OpenIDConfigurationEndpoint       endpoint.Endpoint
+	GetJWKSEndpoint                   endpoint.Endpoint
+	UserInfoEndpoint                  endpoint.Endpoint
+	StartFederatedLoginEndpoint       endpoint.Endpoint
+	CompleteFederatedLoginEndpoint    endpoint.Endpoint
+	GetIdentitiesEndpoint             endpoint.Endpoint
+	ListUsersEndpoint                 endpoint.Endpoint
+	ValidateProvisioningTokenEndpoint endpoint.Endpoint
+	ProvisionUserEndpoint             endpoint.Endpoint
+	SetUserActiveEndpoint             endpoint.Endpoint
+	ListTeamsEndpoint                 endpoint.Endpoint
+	UpdateTeamEndpoint                endpoint.Endpoint
+	SetMembersEndpoint                endpoint.Endpoint
+	ServiceStatusEndpoint             endpoint.Endpoint
 }
 
 // MakeServerEndpoints returns an Endpoints struct where each endpoint invokes
 // the corresponding method on the provided service. Useful in a authorization svc
-// server
-func MakeServerEndpoints(s authorization.Service) Endpoints {
+// server. The middlewares wrap every endpoint, the first one outermost.
+func MakeServerEndpoints(s authorization.Service, mws ...endpoint.Middleware) Endpoints {
+	mw := func(e endpoint.Endpoint) endpoint.Endpoint {
+		for i := len(mws) - 1; i >= 0; i-- {
+			e = mws[i](e)
+		}
+		return e
+	}
 	return Endpoints{
-		AddUserEndpoint:          MakeAddUserEndpoint(s),
-		GetUserEndpoint:          MakeGetUserEndpoint(s),
-		UpdateUserEndpoint:       MakeUpdateUserEndpoint(s),
-		AuthenticateUserEndpoint: MakeAuthenticateUserEndpoint(s),
-		DeleteUserEndpoint:       MakeDeleteUserEndpoint(s),
-		GetUsersEndpoint:         MakeGetUsersEndpoint(s),
-		ServiceStatusEndpoint:    MakeServiceStatusEndpoint(s),
+		AddUserEndpoint:                   mw(MakeAddUserEndpoint(s)),
+		GetUserEndpoint:                   mw(MakeGetUserEndpoint(s)),
+		UpdateUserEndpoint:                mw(MakeUpdateUserEndpoint(s)),
+		AuthenticateUserEndpoint:          mw(MakeAuthenticateUserEndpoint(s)),
+		LoginEndpoint:                     mw(MakeLoginEndpoint(s)),
+		ValidateSessionEndpoint:           mw(MakeValidateSessionEndpoint(s)),
+		RevokeSessionEndpoint:             mw(MakeRevokeSessionEndpoint(s)),
+		DeleteUserEndpoint:                mw(MakeDeleteUserEndpoint(s)),
+		GetUsersEndpoint:                  mw(MakeGetUsersEndpoint(s)),
+		ImportUsersEndpoint:               mw(MakeImportUsersEndpoint(s)),
+		UnlockUserEndpoint:                mw(MakeUnlockUserEndpoint(s)),
+		CompleteMFAEndpoint:               mw(MakeCompleteMFAEndpoint(s)),
+		EnrollTOTPEndpoint:                mw(MakeEnrollTOTPEndpoint(s)),
+		ConfirmTOTPEndpoint:               mw(MakeConfirmTOTPEndpoint(s)),
+		DisableMFAEndpoint:                mw(MakeDisableMFAEndpoint(s)),
+		ForgotPasswordEndpoint:            mw(MakeForgotPasswordEndpoint(s)),
+		ResetPasswordEndpoint:             mw(MakeResetPasswordEndpoint(s)),
+		SendVerificationEmailEndpoint:     mw(MakeSendVerificationEmailEndpoint(s)),
+		VerifyEmailEndpoint:               mw(MakeVerifyEmailEndpoint(s)),
+		CreateTeamEndpoint:                mw(MakeCreateTeamEndpoint(s)),
+		GetTeamEndpoint:                   mw(MakeGetTeamEndpoint(s)),
+		GetTeamsEndpoint:                  mw(MakeGetTeamsEndpoint(s)),
+		DeleteTeamEndpoint:                mw(MakeDeleteTeamEndpoint(s)),
+		InviteMemberEndpoint:              mw(MakeInviteMemberEndpoint(s)),
+		AcceptInvitationEndpoint:          mw(MakeAcceptInvitationEndpoint(s)),
+		UpdateMemberEndpoint:              mw(MakeUpdateMemberEndpoint(s)),
+		RemoveMemberEndpoint:              mw(MakeRemoveMemberEndpoint(s)),
+		CreateAPIKeyEndpoint:              mw(MakeCreateAPIKeyEndpoint(s)),
+		GetAPIKeysEndpoint:                mw(MakeGetAPIKeysEndpoint(s)),
+		RevokeAPIKeyEndpoint:              mw(MakeRevokeAPIKeyEndpoint(s)),
+		ValidateAPIKeyEndpoint:            mw(MakeValidateAPIKeyEndpoint(s)),
+		RegisterClientEndpoint:            mw(MakeRegisterClientEndpoint(s)),
+		GetClientEndpoint:                 mw(MakeGetClientEndpoint(s)),
+		AuthorizeEndpoint:                 mw(MakeAuthorizeEndpoint(s)),
+		ExchangeTokenEndpoint:             mw(MakeExchangeTokenEndpoint(s)),
+		RevokeTokenEndpoint:               mw(MakeRevokeTokenEndpoint(s)),
+		IntrospectTokenEndpoint:           mw(MakeIntrospectTokenEndpoint(s)),
+		ValidateAccessTokenEndpoint:       mw(MakeValidateAccessTokenEndpoint(s)),
+		OpenIDConfigurationEndpoint:       mw(MakeOpenIDConfigurationEndpoint(s)),
+		GetJWKSEndpoint:                   mw(MakeGetJWKSEndpoint(s)),
+		UserInfoEndpoint:                  mw(MakeUserInfoEndpoint(s)),
+		StartFederatedLoginEndpoint:       mw(MakeStartFederatedLoginEndpoint(s)),
+		CompleteFederatedLoginEndpoint:    mw(MakeCompleteFederatedLoginEndpoint(s)),
+		GetIdentitiesEndpoint:             mw(MakeGetIdentitiesEndpoint(s)),
+		ListUsersEndpoint:                 mw(MakeListUsersEndpoint(s)),
+		ValidateProvisioningTokenEndpoint: mw(MakeValidateProvisioningTokenEndpoint(s)),
+		ProvisionUserEndpoint:             mw(MakeProvisionUserEndpoint(s)),
+		SetUserActiveEndpoint:             mw(MakeSetUserActiveEndpoint(s)),
+		ListTeamsEndpoint:                 mw(MakeListTeamsEndpoint(s)),
+		UpdateTeamEndpoint:                mw(MakeUpdateTeamEndpoint(s)),
+		SetMembersEndpoint:                mw(MakeSetMembersEndpoint(s)),
+		ServiceStatusEndpoint:             mw(MakeServiceStatusEndpoint(s)),
+	}
+}
+
+// AddUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) AddUser(ctx context.Context, u authorization.User) (authorization.User, error) {
+	response, err := e.AddUserEndpoint(ctx, AddUserRequest{User: u})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(AddUserResponse)
+	return resp.User, resp.Err
+}
+
+// GetUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetUser(ctx context.Context, id uuid.UUID) (authorization.User, error) {
+	response, err := e.GetUserEndpoint(ctx, GetUserRequest{User: authorization.User{ID: id}})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(GetUserResponse)
+	return resp.User, resp.Err
+}
+
+// FindUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) FindUser(ctx context.Context, username string) (authorization.User, error) {
+	response, err := e.GetUserEndpoint(ctx, GetUserRequest{User: authorization.User{Username: username}})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(GetUserResponse)
+	return resp.User, resp.Err
+}
+
+// UpdateUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) UpdateUser(ctx context.Context, id uuid.UUID, u authorization.User) (authorization.User, error) {
+	response, err := e.UpdateUserEndpoint(ctx, UpdateUserRequest{ID: id, User: u})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(UpdateUserResponse)
+	return resp.User, resp.Err
+}
+
+// AuthenticateUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) AuthenticateUser(ctx context.Context, u authorization.User) (authorization.User, error) {
+	response, err := e.AuthenticateUserEndpoint(ctx, AuthenticateUserRequest{User: u})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(AuthenticateUserResponse)
+	if resp.MFAChallenge != "" {
+		return authorization.User{}, &authorization.MFARequiredError{Challenge: resp.MFAChallenge}
+	}
+	if resp.User == nil {
+		return authorization.User{}, resp.Err
+	}
+	return *resp.User, resp.Err
+}
+
+// Login implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) Login(ctx context.Context, u authorization.User) (authorization.Session, error) {
+	response, err := e.LoginEndpoint(ctx, LoginRequest{User: u})
+	if err != nil {
+		return authorization.Session{}, err
+	}
+	resp := response.(LoginResponse)
+	if resp.MFAChallenge != "" {
+		return authorization.Session{}, &authorization.MFARequiredError{Challenge: resp.MFAChallenge}
+	}
+	if resp.Session == nil {
+		return authorization.Session{}, resp.Err
+	}
+	return *resp.Session, resp.Err
+}
+
+// ValidateSession implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ValidateSession(ctx context.Context, token string) (authorization.User, error) {
+	response, err := e.ValidateSessionEndpoint(ctx, ValidateSessionRequest{Token: token})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(ValidateSessionResponse)
+	return resp.User, resp.Err
+}
+
+// RevokeSession implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) RevokeSession(ctx context.Context, token string) error {
+	response, err := e.RevokeSessionEndpoint(ctx, RevokeSessionRequest{Token: token})
+	if err != nil {
+		return err
+	}
+	resp := response.(RevokeSessionResponse)
+	return resp.Err
+}
+
+// DeleteUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	response, err := e.DeleteUserEndpoint(ctx, DeleteUserRequest{ID: id})
+	if err != nil {
+		return err
+	}
+	resp := response.(DeleteUserResponse)
+	return resp.Err
+}
+
+// GetUsers implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetUsers(ctx context.Context) ([]authorization.User, error) {
+	response, err := e.GetUsersEndpoint(ctx, GetUsersRequest{})
+	if err != nil {
+		return []authorization.User{}, err
+	}
+	resp := response.(GetUsersResponse)
+	return resp.Users, resp.Err
+}
+
+// ImportUsers implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ImportUsers(ctx context.Context, users []authorization.ImportedUser) ([]authorization.User, error) {
+	response, err := e.ImportUsersEndpoint(ctx, ImportUsersRequest{Users: users})
+	if err != nil {
+		return []authorization.User{}, err
+	}
+	resp := response.(ImportUsersResponse)
+	return resp.Users, resp.Err
+}
+
+// UnlockUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	response, err := e.UnlockUserEndpoint(ctx, UnlockUserRequest{ID: id})
+	if err != nil {
+		return err
+	}
+	resp := response.(UnlockUserResponse)
+	return resp.Err
+}
+
+// CompleteMFA implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) CompleteMFA(ctx context.Context, challenge, code string) (authorization.Session, error) {
+	response, err := e.CompleteMFAEndpoint(ctx, CompleteMFARequest{Challenge: challenge, Code: code})
+	if err != nil {
+		return authorization.Session{}, err
 	}
+	resp := response.(CompleteMFAResponse)
+	return resp.Session, resp.Err
+}
+
+// EnrollTOTP implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) EnrollTOTP(ctx context.Context, id uuid.UUID) (authorization.TOTPEnrollment, error) {
+	response, err := e.EnrollTOTPEndpoint(ctx, EnrollTOTPRequest{ID: id})
+	if err != nil {
+		return authorization.TOTPEnrollment{}, err
+	}
+	resp := response.(EnrollTOTPResponse)
+	return resp.Enrollment, resp.Err
+}
+
+// ConfirmTOTP implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ConfirmTOTP(ctx context.Context, id uuid.UUID, code string) ([]string, error) {
+	response, err := e.ConfirmTOTPEndpoint(ctx, ConfirmTOTPRequest{ID: id, Code: code})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(ConfirmTOTPResponse)
+	return resp.RecoveryCodes, resp.Err
+}
+
+// DisableMFA implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) DisableMFA(ctx context.Context, id uuid.UUID, code string) error {
+	response, err := e.DisableMFAEndpoint(ctx, DisableMFARequest{ID: id, Code: code})
+	if err != nil {
+		return err
+	}
+	resp := response.(DisableMFAResponse)
+	return resp.Err
+}
+
+// ForgotPassword implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ForgotPassword(ctx context.Context, email string) error {
+	response, err := e.ForgotPasswordEndpoint(ctx, ForgotPasswordRequest{Email: email})
+	if err != nil {
+		return err
+	}
+	resp := response.(ForgotPasswordResponse)
+	return resp.Err
+}
+
+// ResetPassword implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ResetPassword(ctx context.Context, token, password string) error {
+	response, err := e.ResetPasswordEndpoint(ctx, ResetPasswordRequest{Token: token, Password: password})
+	if err != nil {
+		return err
+	}
+	resp := response.(ResetPasswordResponse)
+	return resp.Err
+}
+
+// SendVerificationEmail implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) SendVerificationEmail(ctx context.Context, id uuid.UUID) error {
+	response, err := e.SendVerificationEmailEndpoint(ctx, SendVerificationEmailRequest{ID: id})
+	if err != nil {
+		return err
+	}
+	resp := response.(SendVerificationEmailResponse)
+	return resp.Err
+}
+
+// VerifyEmail implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) VerifyEmail(ctx context.Context, token string) (authorization.User, error) {
+	response, err := e.VerifyEmailEndpoint(ctx, VerifyEmailRequest{Token: token})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(VerifyEmailResponse)
+	return resp.User, resp.Err
+}
+
+// ServiceStatus implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ServiceStatus(ctx context.Context) (int, error) {
+	response, err := e.ServiceStatusEndpoint(ctx, ServiceStatusRequest{})
+	if err != nil {
+		return 0, err
+	}
+	resp := response.(ServiceStatusResponse)
+	return resp.Code, resp.Err
+}
+
+// CreateTeam implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) CreateTeam(ctx context.Context, t authorization.Team, owner uuid.UUID) (authorization.Team, error) {
+	response, err := e.CreateTeamEndpoint(ctx, CreateTeamRequest{Team: t, OwnerID: owner})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(CreateTeamResponse)
+	return resp.Team, resp.Err
+}
+
+// GetTeam implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetTeam(ctx context.Context, id uuid.UUID) (authorization.Team, error) {
+	response, err := e.GetTeamEndpoint(ctx, GetTeamRequest{ID: id})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(GetTeamResponse)
+	return resp.Team, resp.Err
+}
+
+// GetTeams implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetTeams(ctx context.Context, userID uuid.UUID) ([]authorization.Team, error) {
+	response, err := e.GetTeamsEndpoint(ctx, GetTeamsRequest{UserID: userID})
+	if err != nil {
+		return []authorization.Team{}, err
+	}
+	resp := response.(GetTeamsResponse)
+	return resp.Teams, resp.Err
+}
+
+// DeleteTeam implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) DeleteTeam(ctx context.Context, id uuid.UUID) error {
+	response, err := e.DeleteTeamEndpoint(ctx, DeleteTeamRequest{ID: id})
+	if err != nil {
+		return err
+	}
+	resp := response.(DeleteTeamResponse)
+	return resp.Err
+}
+
+// InviteMember implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) InviteMember(ctx context.Context, teamID uuid.UUID, role authorization.TeamRole) (string, error) {
+	response, err := e.InviteMemberEndpoint(ctx, InviteMemberRequest{TeamID: teamID, Role: role})
+	if err != nil {
+		return "", err
+	}
+	resp := response.(InviteMemberResponse)
+	return resp.Token, resp.Err
+}
+
+// AcceptInvitation implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) AcceptInvitation(ctx context.Context, token string, userID uuid.UUID) (authorization.Team, error) {
+	response, err := e.AcceptInvitationEndpoint(ctx, AcceptInvitationRequest{Token: token, UserID: userID})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(AcceptInvitationResponse)
+	return resp.Team, resp.Err
+}
+
+// UpdateMember implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) UpdateMember(ctx context.Context, teamID, userID uuid.UUID, role authorization.TeamRole) (authorization.Team, error) {
+	response, err := e.UpdateMemberEndpoint(ctx, UpdateMemberRequest{TeamID: teamID, UserID: userID, Role: role})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(UpdateMemberResponse)
+	return resp.Team, resp.Err
+}
+
+// RemoveMember implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) RemoveMember(ctx context.Context, teamID, userID uuid.UUID) error {
+	response, err := e.RemoveMemberEndpoint(ctx, RemoveMemberRequest{TeamID: teamID, UserID: userID})
+	if err != nil {
+		return err
+	}
+	resp := response.(RemoveMemberResponse)
+	return resp.Err
+}
+
+// CreateAPIKey implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) CreateAPIKey(ctx context.Context, userID uuid.UUID, k authorization.APIKey) (authorization.APIKey, error) {
+	response, err := e.CreateAPIKeyEndpoint(ctx, CreateAPIKeyRequest{UserID: userID, APIKey: k})
+	if err != nil {
+		return authorization.APIKey{}, err
+	}
+	resp := response.(CreateAPIKeyResponse)
+	return resp.APIKey, resp.Err
+}
+
+// GetAPIKeys implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]authorization.APIKey, error) {
+	response, err := e.GetAPIKeysEndpoint(ctx, GetAPIKeysRequest{UserID: userID})
+	if err != nil {
+		return []authorization.APIKey{}, err
+	}
+	resp := response.(GetAPIKeysResponse)
+	return resp.APIKeys, resp.Err
+}
+
+// RevokeAPIKey implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	response, err := e.RevokeAPIKeyEndpoint(ctx, RevokeAPIKeyRequest{UserID: userID, ID: id})
+	if err != nil {
+		return err
+	}
+	resp := response.(RevokeAPIKeyResponse)
+	return resp.Err
+}
+
+// ValidateAPIKey implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ValidateAPIKey(ctx context.Context, key string) (authorization.APIKey, error) {
+	response, err := e.ValidateAPIKeyEndpoint(ctx, ValidateAPIKeyRequest{Key: key})
+	if err != nil {
+		return authorization.APIKey{}, err
+	}
+	resp := response.(ValidateAPIKeyResponse)
+	return resp.APIKey, resp.Err
+}
+
+// RegisterClient implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) RegisterClient(ctx context.Context, c authorization.OAuthClient, owner uuid.UUID) (authorization.OAuthClient, error) {
+	response, err := e.RegisterClientEndpoint(ctx, RegisterClientRequest{Client: c, OwnerID: owner})
+	if err != nil {
+		return authorization.OAuthClient{}, err
+	}
+	resp := response.(RegisterClientResponse)
+	return resp.Client, resp.Err
+}
+
+// GetClient implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetClient(ctx context.Context, id uuid.UUID) (authorization.OAuthClient, error) {
+	response, err := e.GetClientEndpoint(ctx, GetClientRequest{ID: id})
+	if err != nil {
+		return authorization.OAuthClient{}, err
+	}
+	resp := response.(GetClientResponse)
+	return resp.Client, resp.Err
+}
+
+// Authorize implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) Authorize(ctx context.Context, r authorization.AuthorizationRequest) (string, error) {
+	response, err := e.AuthorizeEndpoint(ctx, AuthorizeRequest{Request: r})
+	if err != nil {
+		return "", err
+	}
+	resp := response.(AuthorizeResponse)
+	return resp.Code, resp.Err
+}
+
+// ExchangeToken implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ExchangeToken(ctx context.Context, r authorization.TokenRequest) (authorization.OAuthToken, error) {
+	response, err := e.ExchangeTokenEndpoint(ctx, ExchangeTokenRequest{Request: r})
+	if err != nil {
+		return authorization.OAuthToken{}, err
+	}
+	resp := response.(ExchangeTokenResponse)
+	return resp.Token, resp.Err
+}
+
+// RevokeToken implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) RevokeToken(ctx context.Context, token string, client authorization.ClientCredentials) error {
+	response, err := e.RevokeTokenEndpoint(ctx, RevokeTokenRequest{Token: token, Client: client})
+	if err != nil {
+		return err
+	}
+	resp := response.(RevokeTokenResponse)
+	return resp.Err
+}
+
+// IntrospectToken implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) IntrospectToken(ctx context.Context, token string, client authorization.ClientCredentials) (authorization.Introspection, error) {
+	response, err := e.IntrospectTokenEndpoint(ctx, IntrospectTokenRequest{Token: token, Client: client})
+	if err != nil {
+		return authorization.Introspection{}, err
+	}
+	resp := response.(IntrospectTokenResponse)
+	return resp.Introspection, resp.Err
+}
+
+// ValidateAccessToken implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ValidateAccessToken(ctx context.Context, token string) (authorization.AccessToken, error) {
+	response, err := e.ValidateAccessTokenEndpoint(ctx, ValidateAccessTokenRequest{Token: token})
+	if err != nil {
+		return authorization.AccessToken{}, err
+	}
+	resp := response.(ValidateAccessTokenResponse)
+	return resp.AccessToken, resp.Err
+}
+
+// OpenIDConfiguration implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) OpenIDConfiguration(ctx context.Context) (authorization.ProviderMetadata, error) {
+	response, err := e.OpenIDConfigurationEndpoint(ctx, OpenIDConfigurationRequest{})
+	if err != nil {
+		return authorization.ProviderMetadata{}, err
+	}
+	resp := response.(OpenIDConfigurationResponse)
+	return resp.Metadata, resp.Err
+}
+
+// GetJWKS implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetJWKS(ctx context.Context) (jose.KeySet, error) {
+	response, err := e.GetJWKSEndpoint(ctx, GetJWKSRequest{})
+	if err != nil {
+		return jose.KeySet{}, err
+	}
+	resp := response.(GetJWKSResponse)
+	return resp.Keys, resp.Err
+}
+
+// UserInfo implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) UserInfo(ctx context.Context, accessToken string) (authorization.UserInfo, error) {
+	response, err := e.UserInfoEndpoint(ctx, UserInfoRequest{Token: accessToken})
+	if err != nil {
+		return authorization.UserInfo{}, err
+	}
+	resp := response.(UserInfoResponse)
+	return resp.UserInfo, resp.Err
+}
+
+// StartFederatedLogin implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) StartFederatedLogin(ctx context.Context, provider string, userID uuid.UUID) (string, error) {
+	response, err := e.StartFederatedLoginEndpoint(ctx, StartFederatedLoginRequest{Provider: provider, UserID: userID})
+	if err != nil {
+		return "", err
+	}
+	resp := response.(StartFederatedLoginResponse)
+	return resp.URL, resp.Err
+}
+
+// CompleteFederatedLogin implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) CompleteFederatedLogin(ctx context.Context, provider, state, code string) (authorization.Session, error) {
+	response, err := e.CompleteFederatedLoginEndpoint(ctx, CompleteFederatedLoginRequest{Provider: provider, State: state, Code: code})
+	if err != nil {
+		return authorization.Session{}, err
+	}
+	resp := response.(CompleteFederatedLoginResponse)
+	if resp.MFAChallenge != "" {
+		return authorization.Session{}, &authorization.MFARequiredError{Challenge: resp.MFAChallenge}
+	}
+	if resp.Session == nil {
+		return authorization.Session{}, resp.Err
+	}
+	return *resp.Session, resp.Err
+}
+
+// GetIdentities implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) GetIdentities(ctx context.Context, userID uuid.UUID) ([]authorization.Identity, error) {
+	response, err := e.GetIdentitiesEndpoint(ctx, GetIdentitiesRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(GetIdentitiesResponse)
+	return resp.Identities, resp.Err
+}
+
+// ListUsers implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ListUsers(ctx context.Context, q authorization.ListQuery) (authorization.UserList, error) {
+	response, err := e.ListUsersEndpoint(ctx, ListUsersRequest{Query: q})
+	if err != nil {
+		return authorization.UserList{}, err
+	}
+	resp := response.(ListUsersResponse)
+	return resp.Page, resp.Err
+}
+
+// ValidateProvisioningToken implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ValidateProvisioningToken(ctx context.Context, token string) (authorization.User, error) {
+	response, err := e.ValidateProvisioningTokenEndpoint(ctx, ValidateProvisioningTokenRequest{Token: token})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(ValidateProvisioningTokenResponse)
+	return resp.User, resp.Err
+}
+
+// ProvisionUser implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ProvisionUser(ctx context.Context, u authorization.User) (authorization.User, error) {
+	response, err := e.ProvisionUserEndpoint(ctx, ProvisionUserRequest{User: u})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(ProvisionUserResponse)
+	return resp.User, resp.Err
+}
+
+// SetUserActive implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) SetUserActive(ctx context.Context, id uuid.UUID, active bool) (authorization.User, error) {
+	response, err := e.SetUserActiveEndpoint(ctx, SetUserActiveRequest{ID: id, Active: active})
+	if err != nil {
+		return authorization.User{}, err
+	}
+	resp := response.(SetUserActiveResponse)
+	return resp.User, resp.Err
+}
+
+// ListTeams implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) ListTeams(ctx context.Context, q authorization.ListQuery) (authorization.TeamList, error) {
+	response, err := e.ListTeamsEndpoint(ctx, ListTeamsRequest{Query: q})
+	if err != nil {
+		return authorization.TeamList{}, err
+	}
+	resp := response.(ListTeamsResponse)
+	return resp.Page, resp.Err
+}
+
+// UpdateTeam implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) UpdateTeam(ctx context.Context, id uuid.UUID, t authorization.Team) (authorization.Team, error) {
+	response, err := e.UpdateTeamEndpoint(ctx, UpdateTeamRequest{ID: id, Team: t})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(UpdateTeamResponse)
+	return resp.Team, resp.Err
+}
+
+// SetMembers implements authorization.Service interface. Primarily useful in a client.
+func (e Endpoints) SetMembers(ctx context.Context, teamID uuid.UUID, members []authorization.TeamMember) (authorization.Team, error) {
+	response, err := e.SetMembersEndpoint(ctx, SetMembersRequest{TeamID: teamID, Members: members})
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	resp := response.(SetMembersResponse)
+	return resp.Team, resp.Err
 }
 
 // MakeAddUserEndpoint returns an enpoint via the passed service.
@@ -73,7 +719,42 @@ func MakeAuthenticateUserEndpoint(s authorization.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(AuthenticateUserRequest)
 		u, e := s.AuthenticateUser(ctx, req.User)
-		return AuthenticateUserResponse{User: u, Err: e}, nil
+		var mfa *authorization.MFARequiredError
+		if errors.As(e, &mfa) {
+			return AuthenticateUserResponse{MFAChallenge: mfa.Challenge}, nil
+		}
+		if e != nil {
+			return AuthenticateUserResponse{Err: e}, nil
+		}
+		return AuthenticateUserResponse{User: &u}, nil
+	}
+}
+func MakeLoginEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(LoginRequest)
+		session, e := s.Login(ctx, req.User)
+		var mfa *authorization.MFARequiredError
+		if errors.As(e, &mfa) {
+			return LoginResponse{MFAChallenge: mfa.Challenge}, nil
+		}
+		if e != nil {
+			return LoginResponse{Err: e}, nil
+		}
+		return LoginResponse{Session: &session}, nil
+	}
+}
+func MakeValidateSessionEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ValidateSessionRequest)
+		u, e := s.ValidateSession(ctx, req.Token)
+		return ValidateSessionResponse{User: u, Err: e}, nil
+	}
+}
+func MakeRevokeSessionEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(RevokeSessionRequest)
+		e := s.RevokeSession(ctx, req.Token)
+		return RevokeSessionResponse{Err: e}, nil
 	}
 }
 func MakeDeleteUserEndpoint(s authorization.Service) endpoint.Endpoint {
@@ -91,6 +772,366 @@ func MakeGetUsersEndpoint(s authorization.Service) endpoint.Endpoint {
 	}
 }
 
+func MakeImportUsersEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ImportUsersRequest)
+		us, e := s.ImportUsers(ctx, req.Users)
+		return ImportUsersResponse{Users: us, Err: e}, nil
+	}
+}
+
+func MakeUnlockUserEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(UnlockUserRequest)
+		e := s.UnlockUser(ctx, req.ID)
+		return UnlockUserResponse{Err: e}, nil
+	}
+}
+
+func MakeCompleteMFAEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(CompleteMFARequest)
+		session, e := s.CompleteMFA(ctx, req.Challenge, req.Code)
+		return CompleteMFAResponse{Session: session, Err: e}, nil
+	}
+}
+
+func MakeEnrollTOTPEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(EnrollTOTPRequest)
+		enrollment, e := s.EnrollTOTP(ctx, req.ID)
+		return EnrollTOTPResponse{Enrollment: enrollment, Err: e}, nil
+	}
+}
+
+func MakeConfirmTOTPEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ConfirmTOTPRequest)
+		codes, e := s.ConfirmTOTP(ctx, req.ID, req.Code)
+		return ConfirmTOTPResponse{RecoveryCodes: codes, Err: e}, nil
+	}
+}
+
+func MakeDisableMFAEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(DisableMFARequest)
+		e := s.DisableMFA(ctx, req.ID, req.Code)
+		return DisableMFAResponse{Err: e}, nil
+	}
+}
+
+func MakeForgotPasswordEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ForgotPasswordRequest)
+		e := s.ForgotPassword(ctx, req.Email)
+		return ForgotPasswordResponse{Err: e}, nil
+	}
+}
+
+func MakeResetPasswordEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ResetPasswordRequest)
+		e := s.ResetPassword(ctx, req.Token, req.Password)
+		return ResetPasswordResponse{Err: e}, nil
+	}
+}
+
+func MakeSendVerificationEmailEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(SendVerificationEmailRequest)
+		e := s.SendVerificationEmail(ctx, req.ID)
+		return SendVerificationEmailResponse{Err: e}, nil
+	}
+}
+
+func MakeVerifyEmailEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(VerifyEmailRequest)
+		u, e := s.VerifyEmail(ctx, req.Token)
+		return VerifyEmailResponse{User: u, Err: e}, nil
+	}
+}
+
+// the caller owns a team created without an owner
+func MakeCreateTeamEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(CreateTeamRequest)
+		owner := req.OwnerID
+		if caller, ok := authorization.Caller(ctx); ok && owner == uuid.Nil {
+			owner = caller.ID
+		}
+		t, e := s.CreateTeam(ctx, req.Team, owner)
+		return CreateTeamResponse{Team: t, Err: e}, nil
+	}
+}
+
+func MakeGetTeamEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetTeamRequest)
+		t, e := s.GetTeam(ctx, req.ID)
+		return GetTeamResponse{Team: t, Err: e}, nil
+	}
+}
+
+func MakeGetTeamsEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetTeamsRequest)
+		ts, e := s.GetTeams(ctx, req.UserID)
+		return GetTeamsResponse{Teams: ts, Err: e}, nil
+	}
+}
+
+func MakeDeleteTeamEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(DeleteTeamRequest)
+		e := s.DeleteTeam(ctx, req.ID)
+		return DeleteTeamResponse{Err: e}, nil
+	}
+}
+
+func MakeInviteMemberEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(InviteMemberRequest)
+		token, e := s.InviteMember(ctx, req.TeamID, req.Role)
+		return InviteMemberResponse{Token: token, Err: e}, nil
+	}
+}
+
+// the caller joins when the invitation names no user
+func MakeAcceptInvitationEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(AcceptInvitationRequest)
+		user := req.UserID
+		if caller, ok := authorization.Caller(ctx); ok && user == uuid.Nil {
+			user = caller.ID
+		}
+		t, e := s.AcceptInvitation(ctx, req.Token, user)
+		return AcceptInvitationResponse{Team: t, Err: e}, nil
+	}
+}
+
+func MakeUpdateMemberEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(UpdateMemberRequest)
+		t, e := s.UpdateMember(ctx, req.TeamID, req.UserID, req.Role)
+		return UpdateMemberResponse{Team: t, Err: e}, nil
+	}
+}
+
+func MakeRemoveMemberEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(RemoveMemberRequest)
+		e := s.RemoveMember(ctx, req.TeamID, req.UserID)
+		return RemoveMemberResponse{Err: e}, nil
+	}
+}
+
+func MakeCreateAPIKeyEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(CreateAPIKeyRequest)
+		k, e := s.CreateAPIKey(ctx, req.UserID, req.APIKey)
+		return CreateAPIKeyResponse{APIKey: k, Err: e}, nil
+	}
+}
+
+func MakeGetAPIKeysEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetAPIKeysRequest)
+		ks, e := s.GetAPIKeys(ctx, req.UserID)
+		return GetAPIKeysResponse{APIKeys: ks, Err: e}, nil
+	}
+}
+
+func MakeRevokeAPIKeyEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(RevokeAPIKeyRequest)
+		e := s.RevokeAPIKey(ctx, req.UserID, req.ID)
+		return RevokeAPIKeyResponse{Err: e}, nil
+	}
+}
+
+func MakeValidateAPIKeyEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ValidateAPIKeyRequest)
+		k, e := s.ValidateAPIKey(ctx, req.Key)
+		return ValidateAPIKeyResponse{APIKey: k, Err: e}, nil
+	}
+}
+
+func MakeRegisterClientEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(RegisterClientRequest)
+		owner := req.OwnerID
+		if caller, ok := authorization.Caller(ctx); ok && owner == uuid.Nil {
+			owner = caller.ID
+		}
+		c, e := s.RegisterClient(ctx, req.Client, owner)
+		return RegisterClientResponse{Client: c, Err: e}, nil
+	}
+}
+
+func MakeGetClientEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetClientRequest)
+		c, e := s.GetClient(ctx, req.ID)
+		return GetClientResponse{Client: c, Err: e}, nil
+	}
+}
+
+func MakeAuthorizeEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(AuthorizeRequest)
+		if caller, ok := authorization.Caller(ctx); ok && req.Request.UserID == uuid.Nil {
+			req.Request.UserID = caller.ID
+		}
+		code, e := s.Authorize(ctx, req.Request)
+		return AuthorizeResponse{Code: code, Err: e}, nil
+	}
+}
+
+func MakeExchangeTokenEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ExchangeTokenRequest)
+		t, e := s.ExchangeToken(ctx, req.Request)
+		return ExchangeTokenResponse{Token: t, Err: e}, nil
+	}
+}
+
+func MakeRevokeTokenEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(RevokeTokenRequest)
+		e := s.RevokeToken(ctx, req.Token, req.Client)
+		return RevokeTokenResponse{Err: e}, nil
+	}
+}
+
+func MakeIntrospectTokenEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(IntrospectTokenRequest)
+		i, e := s.IntrospectToken(ctx, req.Token, req.Client)
+		return IntrospectTokenResponse{Introspection: i, Err: e}, nil
+	}
+}
+
+func MakeValidateAccessTokenEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ValidateAccessTokenRequest)
+		t, e := s.ValidateAccessToken(ctx, req.Token)
+		return ValidateAccessTokenResponse{AccessToken: t, Err: e}, nil
+	}
+}
+
+func MakeOpenIDConfigurationEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		_ = request.(OpenIDConfigurationRequest)
+		m, e := s.OpenIDConfiguration(ctx)
+		return OpenIDConfigurationResponse{Metadata: m, Err: e}, nil
+	}
+}
+
+func MakeGetJWKSEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		_ = request.(GetJWKSRequest)
+		keys, e := s.GetJWKS(ctx)
+		return GetJWKSResponse{Keys: keys, Err: e}, nil
+	}
+}
+
+func MakeUserInfoEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(UserInfoRequest)
+		u, e := s.UserInfo(ctx, req.Token)
+		return UserInfoResponse{UserInfo: u, Err: e}, nil
+	}
+}
+
+func MakeStartFederatedLoginEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(StartFederatedLoginRequest)
+		url, e := s.StartFederatedLogin(ctx, req.Provider, req.UserID)
+		return StartFederatedLoginResponse{URL: url, Err: e}, nil
+	}
+}
+
+func MakeCompleteFederatedLoginEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(CompleteFederatedLoginRequest)
+		session, e := s.CompleteFederatedLogin(ctx, req.Provider, req.State, req.Code)
+		var mfa *authorization.MFARequiredError
+		if errors.As(e, &mfa) {
+			return CompleteFederatedLoginResponse{MFAChallenge: mfa.Challenge}, nil
+		}
+		if e != nil {
+			return CompleteFederatedLoginResponse{Err: e}, nil
+		}
+		return CompleteFederatedLoginResponse{Session: &session}, nil
+	}
+}
+
+func MakeGetIdentitiesEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetIdentitiesRequest)
+		ids, e := s.GetIdentities(ctx, req.UserID)
+		return GetIdentitiesResponse{Identities: ids, Err: e}, nil
+	}
+}
+
+func MakeListUsersEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ListUsersRequest)
+		page, e := s.ListUsers(ctx, req.Query)
+		return ListUsersResponse{Page: page, Err: e}, nil
+	}
+}
+
+func MakeValidateProvisioningTokenEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ValidateProvisioningTokenRequest)
+		u, e := s.ValidateProvisioningToken(ctx, req.Token)
+		return ValidateProvisioningTokenResponse{User: u, Err: e}, nil
+	}
+}
+
+func MakeProvisionUserEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ProvisionUserRequest)
+		u, e := s.ProvisionUser(ctx, req.User)
+		return ProvisionUserResponse{User: u, Err: e}, nil
+	}
+}
+
+func MakeSetUserActiveEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(SetUserActiveRequest)
+		u, e := s.SetUserActive(ctx, req.ID, req.Active)
+		return SetUserActiveResponse{User: u, Err: e}, nil
+	}
+}
+
+func MakeListTeamsEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ListTeamsRequest)
+		page, e := s.ListTeams(ctx, req.Query)
+		return ListTeamsResponse{Page: page, Err: e}, nil
+	}
+}
+
+func MakeUpdateTeamEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(UpdateTeamRequest)
+		t, e := s.UpdateTeam(ctx, req.ID, req.Team)
+		return UpdateTeamResponse{Team: t, Err: e}, nil
+	}
+}
+
+func MakeSetMembersEndpoint(s authorization.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(SetMembersRequest)
+		t, e := s.SetMembers(ctx, req.TeamID, req.Members)
+		return SetMembersResponse{Team: t, Err: e}, nil
+	}
+}
+
 // MakeServicestatusEndpoint returns an enpoint via the passed service.
 // Primarily useful in a server
 func MakeServiceStatusEndpoint(s authorization.Service) endpoint.Endpoint {
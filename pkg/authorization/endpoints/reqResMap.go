@@ -1,6 +1,7 @@
 package endpoints
 
 import (
+	"github.com/demeesterdev/todo-service/internal/jose"
 	"github.com/demeesterdev/todo-service/pkg/authorization"
 	"github.com/google/uuid"
 )
@@ -67,13 +68,57 @@ type AuthenticateUserRequest struct {
 	User authorization.User
 }
 
+// when the user has a second factor only MFAChallenge is set,
+// the login is completed with a CompleteMFARequest
 type AuthenticateUserResponse struct {
+	User         *authorization.User `json:"user,omitempty"`
+	MFAChallenge string              `json:"mfa_challenge,omitempty"`
+	Err          error               `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r AuthenticateUserResponse) Error() error { return r.Err }
+
+// LoginRequest and LoginResponse
+// same as AuthenticateUser but the response starts a session
+type LoginRequest struct {
+	User authorization.User
+}
+
+type LoginResponse struct {
+	Session      *authorization.Session `json:"session,omitempty"`
+	MFAChallenge string                 `json:"mfa_challenge,omitempty"`
+	Err          error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r LoginResponse) Error() error { return r.Err }
+
+// ValidateSessionRequest and ValidateSessionResponse
+// the response holds the user of the session
+type ValidateSessionRequest struct {
+	Token string `json:"token"`
+}
+
+type ValidateSessionResponse struct {
 	User authorization.User `json:"user,omitempty"`
 	Err  error              `json:"err,omitempty"`
 }
 
 //lint:ignore U1000 used to satisfy error interface in transport
-func (r AuthenticateUserResponse) Error() error { return r.Err }
+func (r ValidateSessionResponse) Error() error { return r.Err }
+
+// RevokeSessionRequest and RevokeSessionResponse
+type RevokeSessionRequest struct {
+	Token string `json:"token"`
+}
+
+type RevokeSessionResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r RevokeSessionResponse) Error() error { return r.Err }
 
 // DeleteUserRequestRequest and DeleteUserRequestRequestResponse
 // only id needede to delete
@@ -99,3 +144,574 @@ type ServiceStatusResponse struct {
 
 //lint:ignore U1000 used to satisfy error interface in transport
 func (r ServiceStatusResponse) Error() error { return r.Err }
+
+// UnlockUserRequest and UnlockUserResponse
+type UnlockUserRequest struct {
+	ID uuid.UUID
+}
+
+type UnlockUserResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r UnlockUserResponse) Error() error { return r.Err }
+
+// ImportUsersRequest and ImportUsersResponse
+// returns the imported users without password hashes
+type ImportUsersRequest struct {
+	Users []authorization.ImportedUser `json:"users"`
+}
+
+type ImportUsersResponse struct {
+	Users []authorization.User `json:"users,omitempty"`
+	Err   error                `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ImportUsersResponse) Error() error { return r.Err }
+
+// CompleteMFARequest and CompleteMFAResponse
+// the challenge returned by AuthenticateUser and a TOTP or recovery code
+type CompleteMFARequest struct {
+	Challenge string `json:"mfa_challenge"`
+	Code      string `json:"code"`
+}
+
+type CompleteMFAResponse struct {
+	Session authorization.Session `json:"session"`
+	Err     error                 `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r CompleteMFAResponse) Error() error { return r.Err }
+
+// EnrollTOTPRequest and EnrollTOTPResponse
+type EnrollTOTPRequest struct {
+	ID uuid.UUID
+}
+
+type EnrollTOTPResponse struct {
+	Enrollment authorization.TOTPEnrollment `json:"totp,omitempty"`
+	Err        error                        `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r EnrollTOTPResponse) Error() error { return r.Err }
+
+// ConfirmTOTPRequest and ConfirmTOTPResponse
+// returns the recovery codes of the user
+type ConfirmTOTPRequest struct {
+	ID   uuid.UUID `json:"-"`
+	Code string    `json:"code"`
+}
+
+type ConfirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Err           error    `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ConfirmTOTPResponse) Error() error { return r.Err }
+
+// DisableMFARequest and DisableMFAResponse
+type DisableMFARequest struct {
+	ID   uuid.UUID `json:"-"`
+	Code string    `json:"code,omitempty"`
+}
+
+type DisableMFAResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r DisableMFAResponse) Error() error { return r.Err }
+
+// ForgotPasswordRequest and ForgotPasswordResponse
+// the response is the same whether the address is known or not
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ForgotPasswordResponse) Error() error { return r.Err }
+
+// ResetPasswordRequest and ResetPasswordResponse
+// the mailed token and the new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ResetPasswordResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ResetPasswordResponse) Error() error { return r.Err }
+
+// SendVerificationEmailRequest and SendVerificationEmailResponse
+type SendVerificationEmailRequest struct {
+	ID uuid.UUID
+}
+
+type SendVerificationEmailResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r SendVerificationEmailResponse) Error() error { return r.Err }
+
+// VerifyEmailRequest and VerifyEmailResponse
+// returns the user with the verified address
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type VerifyEmailResponse struct {
+	User authorization.User `json:"user,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r VerifyEmailResponse) Error() error { return r.Err }
+
+// CreateTeamRequest and CreateTeamResponse
+// the owner defaults to the caller
+type CreateTeamRequest struct {
+	Team    authorization.Team `json:"team"`
+	OwnerID uuid.UUID          `json:"owner_id,omitempty"`
+}
+
+type CreateTeamResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r CreateTeamResponse) Error() error { return r.Err }
+
+// GetTeamRequest and GetTeamResponse
+type GetTeamRequest struct {
+	ID uuid.UUID
+}
+
+type GetTeamResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetTeamResponse) Error() error { return r.Err }
+
+// GetTeamsRequest and GetTeamsResponse
+// the teams the user is a member of
+type GetTeamsRequest struct {
+	UserID uuid.UUID
+}
+
+type GetTeamsResponse struct {
+	Teams []authorization.Team `json:"teams"`
+	Err   error                `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetTeamsResponse) Error() error { return r.Err }
+
+// DeleteTeamRequest and DeleteTeamResponse
+type DeleteTeamRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteTeamResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r DeleteTeamResponse) Error() error { return r.Err }
+
+// InviteMemberRequest and InviteMemberResponse
+// the token is only returned once
+type InviteMemberRequest struct {
+	TeamID uuid.UUID              `json:"-"`
+	Role   authorization.TeamRole `json:"role"`
+}
+
+type InviteMemberResponse struct {
+	Token string `json:"token,omitempty"`
+	Err   error  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r InviteMemberResponse) Error() error { return r.Err }
+
+// AcceptInvitationRequest and AcceptInvitationResponse
+// the user defaults to the caller
+type AcceptInvitationRequest struct {
+	Token  string    `json:"token"`
+	UserID uuid.UUID `json:"user_id,omitempty"`
+}
+
+type AcceptInvitationResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r AcceptInvitationResponse) Error() error { return r.Err }
+
+// UpdateMemberRequest and UpdateMemberResponse
+// changes the role of a member
+type UpdateMemberRequest struct {
+	TeamID uuid.UUID              `json:"-"`
+	UserID uuid.UUID              `json:"-"`
+	Role   authorization.TeamRole `json:"role"`
+}
+
+type UpdateMemberResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r UpdateMemberResponse) Error() error { return r.Err }
+
+// RemoveMemberRequest and RemoveMemberResponse
+type RemoveMemberRequest struct {
+	TeamID uuid.UUID
+	UserID uuid.UUID
+}
+
+type RemoveMemberResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r RemoveMemberResponse) Error() error { return r.Err }
+
+// CreateAPIKeyRequest and CreateAPIKeyResponse
+// the key is only returned once
+type CreateAPIKeyRequest struct {
+	UserID uuid.UUID            `json:"-"`
+	APIKey authorization.APIKey `json:"api_key"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKey authorization.APIKey `json:"api_key,omitempty"`
+	Err    error                `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r CreateAPIKeyResponse) Error() error { return r.Err }
+
+// GetAPIKeysRequest and GetAPIKeysResponse
+type GetAPIKeysRequest struct {
+	UserID uuid.UUID
+}
+
+type GetAPIKeysResponse struct {
+	APIKeys []authorization.APIKey `json:"api_keys"`
+	Err     error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetAPIKeysResponse) Error() error { return r.Err }
+
+// RevokeAPIKeyRequest and RevokeAPIKeyResponse
+type RevokeAPIKeyRequest struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+type RevokeAPIKeyResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r RevokeAPIKeyResponse) Error() error { return r.Err }
+
+// ValidateAPIKeyRequest and ValidateAPIKeyResponse
+type ValidateAPIKeyRequest struct {
+	Key string `json:"key"`
+}
+
+type ValidateAPIKeyResponse struct {
+	APIKey authorization.APIKey `json:"api_key,omitempty"`
+	Err    error                `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ValidateAPIKeyResponse) Error() error { return r.Err }
+
+// RegisterClientRequest and RegisterClientResponse
+// the owner defaults to the caller, the secret is only returned once
+type RegisterClientRequest struct {
+	Client  authorization.OAuthClient `json:"client"`
+	OwnerID uuid.UUID                 `json:"owner_id,omitempty"`
+}
+
+type RegisterClientResponse struct {
+	Client authorization.OAuthClient `json:"client,omitempty"`
+	Err    error                     `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r RegisterClientResponse) Error() error { return r.Err }
+
+// GetClientRequest and GetClientResponse
+type GetClientRequest struct {
+	ID uuid.UUID
+}
+
+type GetClientResponse struct {
+	Client authorization.OAuthClient `json:"client,omitempty"`
+	Err    error                     `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetClientResponse) Error() error { return r.Err }
+
+// AuthorizeRequest and AuthorizeResponse
+// the user defaults to the caller
+type AuthorizeRequest struct {
+	Request authorization.AuthorizationRequest `json:"request"`
+}
+
+type AuthorizeResponse struct {
+	Code string `json:"code,omitempty"`
+	Err  error  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r AuthorizeResponse) Error() error { return r.Err }
+
+// ExchangeTokenRequest and ExchangeTokenResponse
+type ExchangeTokenRequest struct {
+	Request authorization.TokenRequest
+}
+
+type ExchangeTokenResponse struct {
+	Token authorization.OAuthToken `json:"token,omitempty"`
+	Err   error                    `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ExchangeTokenResponse) Error() error { return r.Err }
+
+// RevokeTokenRequest and RevokeTokenResponse
+type RevokeTokenRequest struct {
+	Token  string
+	Client authorization.ClientCredentials
+}
+
+type RevokeTokenResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r RevokeTokenResponse) Error() error { return r.Err }
+
+// IntrospectTokenRequest and IntrospectTokenResponse
+type IntrospectTokenRequest struct {
+	Token  string
+	Client authorization.ClientCredentials
+}
+
+type IntrospectTokenResponse struct {
+	Introspection authorization.Introspection `json:"introspection"`
+	Err           error                       `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r IntrospectTokenResponse) Error() error { return r.Err }
+
+// ValidateAccessTokenRequest and ValidateAccessTokenResponse
+type ValidateAccessTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type ValidateAccessTokenResponse struct {
+	AccessToken authorization.AccessToken `json:"access_token,omitempty"`
+	Err         error                     `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ValidateAccessTokenResponse) Error() error { return r.Err }
+
+// OpenIDConfigurationRequest and OpenIDConfigurationResponse
+type OpenIDConfigurationRequest struct{}
+
+type OpenIDConfigurationResponse struct {
+	Metadata authorization.ProviderMetadata `json:"metadata,omitempty"`
+	Err      error                          `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r OpenIDConfigurationResponse) Error() error { return r.Err }
+
+// GetJWKSRequest and GetJWKSResponse
+type GetJWKSRequest struct{}
+
+type GetJWKSResponse struct {
+	Keys jose.KeySet `json:"keys,omitempty"`
+	Err  error       `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetJWKSResponse) Error() error { return r.Err }
+
+// UserInfoRequest and UserInfoResponse
+type UserInfoRequest struct {
+	Token string `json:"token"`
+}
+
+type UserInfoResponse struct {
+	UserInfo authorization.UserInfo `json:"userinfo,omitempty"`
+	Err      error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r UserInfoResponse) Error() error { return r.Err }
+
+// StartFederatedLoginRequest and StartFederatedLoginResponse
+type StartFederatedLoginRequest struct {
+	Provider string    `json:"provider"`
+	UserID   uuid.UUID `json:"user_id,omitempty"`
+}
+
+type StartFederatedLoginResponse struct {
+	URL string `json:"url,omitempty"`
+	Err error  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r StartFederatedLoginResponse) Error() error { return r.Err }
+
+// CompleteFederatedLoginRequest and CompleteFederatedLoginResponse
+type CompleteFederatedLoginRequest struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Code     string `json:"code"`
+}
+
+// like LoginResponse only MFAChallenge is set when the user has a second
+// factor
+type CompleteFederatedLoginResponse struct {
+	Session      *authorization.Session `json:"session,omitempty"`
+	MFAChallenge string                 `json:"mfa_challenge,omitempty"`
+	Err          error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r CompleteFederatedLoginResponse) Error() error { return r.Err }
+
+// GetIdentitiesRequest and GetIdentitiesResponse
+type GetIdentitiesRequest struct {
+	UserID uuid.UUID
+}
+
+type GetIdentitiesResponse struct {
+	Identities []authorization.Identity `json:"identities"`
+	Err        error                    `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r GetIdentitiesResponse) Error() error { return r.Err }
+
+// ListUsersRequest and ListUsersResponse
+type ListUsersRequest struct {
+	Query authorization.ListQuery `json:"query"`
+}
+
+type ListUsersResponse struct {
+	Page authorization.UserList `json:"page"`
+	Err  error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ListUsersResponse) Error() error { return r.Err }
+
+// ValidateProvisioningTokenRequest and ValidateProvisioningTokenResponse
+type ValidateProvisioningTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type ValidateProvisioningTokenResponse struct {
+	User authorization.User `json:"user,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ValidateProvisioningTokenResponse) Error() error { return r.Err }
+
+// ProvisionUserRequest and ProvisionUserResponse
+type ProvisionUserRequest struct {
+	User authorization.User `json:"user"`
+}
+
+type ProvisionUserResponse struct {
+	User authorization.User `json:"user,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ProvisionUserResponse) Error() error { return r.Err }
+
+// SetUserActiveRequest and SetUserActiveResponse
+type SetUserActiveRequest struct {
+	ID     uuid.UUID `json:"-"`
+	Active bool      `json:"active"`
+}
+
+type SetUserActiveResponse struct {
+	User authorization.User `json:"user,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r SetUserActiveResponse) Error() error { return r.Err }
+
+// ListTeamsRequest and ListTeamsResponse
+type ListTeamsRequest struct {
+	Query authorization.ListQuery `json:"query"`
+}
+
+type ListTeamsResponse struct {
+	Page authorization.TeamList `json:"page"`
+	Err  error                  `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r ListTeamsResponse) Error() error { return r.Err }
+
+// UpdateTeamRequest and UpdateTeamResponse
+type UpdateTeamRequest struct {
+	ID   uuid.UUID          `json:"-"`
+	Team authorization.Team `json:"team"`
+}
+
+type UpdateTeamResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r UpdateTeamResponse) Error() error { return r.Err }
+
+// SetMembersRequest and SetMembersResponse
+type SetMembersRequest struct {
+	TeamID  uuid.UUID                  `json:"-"`
+	Members []authorization.TeamMember `json:"members"`
+}
+
+type SetMembersResponse struct {
+	Team authorization.Team `json:"team,omitempty"`
+	Err  error              `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r SetMembersResponse) Error() error { return r.Err }
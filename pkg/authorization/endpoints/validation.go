@@ -0,0 +1,173 @@
+package endpoints
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/demeesterdev/todo-service/internal/validate"
+)
+
+// Limits bounds the users accepted by the service.
+// A limit of 0 disables it.
+type Limits struct {
+	UsernameMinLength int
+	UsernameMaxLength int
+	// MaxBodyBytes is enforced by the http server, see validate.MaxBodyBytes
+	MaxBodyBytes int64
+}
+
+var DefaultLimits = Limits{
+	UsernameMinLength: 3,
+	UsernameMaxLength: 64,
+	MaxBodyBytes:      16 << 10,
+}
+
+// email addresses are limited to the 254 bytes SMTP accepts
+var email = []validate.StringRule{validate.MaxBytes(254), validate.Email()}
+
+var role = validate.Matches(regexp.MustCompile(`^(admin|member|read-only)$`), "must be admin, member or read-only")
+
+var teamRole = validate.Matches(regexp.MustCompile(`^(owner|admin|member|guest)$`), "must be owner, admin, member or guest")
+
+var scope = validate.Matches(regexp.MustCompile(`^todos:(read|write)$`), "must be todos:read or todos:write")
+
+var oauthScope = validate.Matches(regexp.MustCompile(`^(openid|todos:(read|write))$`), "must be openid, todos:read or todos:write")
+
+var challengeMethod = validate.Matches(regexp.MustCompile(`^S256$`), "must be S256")
+
+var usernameCharset = regexp.MustCompile(`^[a-zA-Z0-9._-]*$`)
+
+// ValidationMiddleware rejects requests violating l before they reach the service
+func ValidationMiddleware(l Limits) endpoint.Middleware {
+	return validate.Middleware(l.validate)
+}
+
+func (l Limits) validate(request interface{}) error {
+	var c validate.Checker
+	switch req := request.(type) {
+	case AddUserRequest:
+		c.String("username", req.User.Username, l.username()...)
+		c.String("password", req.User.Password, validate.Required())
+		c.Optional("email", req.User.Email, email...)
+		c.Optional("role", string(req.User.Role), role)
+	case UpdateUserRequest:
+		c.Optional("username", req.User.Username, l.username()...)
+		c.Optional("email", req.User.Email, email...)
+		c.Optional("role", string(req.User.Role), role)
+	case AuthenticateUserRequest:
+		// usernames from before the current limits must still be able to log in
+		c.String("username", req.User.Username, validate.Required(), validate.MaxBytes(1<<10))
+		c.String("password", req.User.Password, validate.Required())
+	case LoginRequest:
+		c.String("username", req.User.Username, validate.Required(), validate.MaxBytes(1<<10))
+		c.String("password", req.User.Password, validate.Required())
+	case ValidateSessionRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case RevokeSessionRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case CompleteMFARequest:
+		c.String("mfa_challenge", req.Challenge, validate.Required(), validate.MaxBytes(128))
+		c.String("code", req.Code, validate.Required(), validate.MaxBytes(64))
+	case ConfirmTOTPRequest:
+		c.String("code", req.Code, validate.Required(), validate.MaxBytes(64))
+	case DisableMFARequest:
+		c.String("code", req.Code, validate.MaxBytes(64))
+	case ForgotPasswordRequest:
+		c.String("email", req.Email, append([]validate.StringRule{validate.Required()}, email...)...)
+	case ResetPasswordRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+		c.String("password", req.Password, validate.Required())
+	case VerifyEmailRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case CreateTeamRequest:
+		c.String("name", req.Team.Name, validate.Required(), validate.MaxLength(128))
+	case InviteMemberRequest:
+		c.String("role", string(req.Role), validate.Required(), teamRole)
+	case AcceptInvitationRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case UpdateMemberRequest:
+		c.String("role", string(req.Role), validate.Required(), teamRole)
+	case CreateAPIKeyRequest:
+		c.String("name", req.APIKey.Name, validate.Required(), validate.MaxLength(128))
+		if len(req.APIKey.Scopes) == 0 {
+			c.String("scopes", "", validate.Required())
+		}
+		for i, s := range req.APIKey.Scopes {
+			c.String(fmt.Sprintf("scopes[%d]", i), string(s), scope)
+		}
+	case ValidateAPIKeyRequest:
+		c.String("key", req.Key, validate.Required(), validate.MaxBytes(128))
+	case RegisterClientRequest:
+		c.String("name", req.Client.Name, validate.Required(), validate.MaxLength(128))
+		if len(req.Client.RedirectURIs) == 0 {
+			c.String("redirect_uris", "", validate.Required())
+		}
+		for i, uri := range req.Client.RedirectURIs {
+			c.String(fmt.Sprintf("redirect_uris[%d]", i), uri, validate.Required(), validate.MaxBytes(2048))
+		}
+		for i, s := range req.Client.Scopes {
+			c.String(fmt.Sprintf("scopes[%d]", i), string(s), oauthScope)
+		}
+	case AuthorizeRequest:
+		c.String("redirect_uri", req.Request.RedirectURI, validate.Required(), validate.MaxBytes(2048))
+		c.String("code_challenge", req.Request.CodeChallenge, validate.Required(), validate.MaxBytes(128))
+		c.String("code_challenge_method", req.Request.CodeChallengeMethod, validate.Required(), challengeMethod)
+		c.Optional("nonce", req.Request.Nonce, validate.MaxBytes(256))
+		for i, s := range req.Request.Scopes {
+			c.String(fmt.Sprintf("scope[%d]", i), string(s), oauthScope)
+		}
+	case ExchangeTokenRequest:
+		c.String("grant_type", req.Request.GrantType, validate.Required(), validate.MaxBytes(64))
+		c.Optional("code", req.Request.Code, validate.MaxBytes(128))
+		c.Optional("code_verifier", req.Request.CodeVerifier, validate.MaxBytes(128))
+		c.Optional("refresh_token", req.Request.RefreshToken, validate.MaxBytes(128))
+		c.Optional("redirect_uri", req.Request.RedirectURI, validate.MaxBytes(2048))
+	case RevokeTokenRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case IntrospectTokenRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case ValidateAccessTokenRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case UserInfoRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(128))
+	case StartFederatedLoginRequest:
+		c.String("provider", req.Provider, validate.Required(), validate.MaxBytes(64))
+	case CompleteFederatedLoginRequest:
+		c.String("provider", req.Provider, validate.Required(), validate.MaxBytes(64))
+		c.String("state", req.State, validate.Required(), validate.MaxBytes(128))
+		c.String("code", req.Code, validate.Required(), validate.MaxBytes(2048))
+	case ProvisionUserRequest:
+		c.String("username", req.User.Username, l.username()...)
+		c.Optional("email", req.User.Email, email...)
+		c.Optional("role", string(req.User.Role), role)
+	case ValidateProvisioningTokenRequest:
+		c.String("token", req.Token, validate.Required(), validate.MaxBytes(1<<10))
+	case ListUsersRequest:
+		c.Optional("name", req.Query.Name, validate.MaxBytes(1<<10))
+	case ListTeamsRequest:
+		c.Optional("name", req.Query.Name, validate.MaxBytes(1<<10))
+	case UpdateTeamRequest:
+		c.String("name", req.Team.Name, validate.Required(), validate.MaxLength(128))
+	case SetMembersRequest:
+		for i, m := range req.Members {
+			c.String(fmt.Sprintf("members[%d].role", i), string(m.Role), validate.Required(), teamRole)
+		}
+	case ImportUsersRequest:
+		for i, u := range req.Users {
+			c.String(fmt.Sprintf("users[%d].username", i), u.Username, l.username()...)
+			c.String(fmt.Sprintf("users[%d].password_hash", i), u.PasswordHash, validate.Required(), validate.MaxBytes(1<<10))
+		}
+	}
+	return c.Err()
+}
+
+func (l Limits) username() []validate.StringRule {
+	return []validate.StringRule{
+		validate.Required(),
+		validate.MinLength(l.UsernameMinLength),
+		validate.MaxLength(l.UsernameMaxLength),
+		validate.Matches(usernameCharset, "may only contain letters, digits, '.', '_' and '-'"),
+	}
+}
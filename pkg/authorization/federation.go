@@ -0,0 +1,283 @@
+package authorization
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/internal/oidc"
+)
+
+// IdentityProvider is an external OpenID Connect provider users sign in
+// with. Name identifies it in the login URLs, RedirectURL is the callback
+// of the HTTP transport registered at the provider.
+type IdentityProvider struct {
+	Name string
+	oidc.Config
+}
+
+// FederationConfig configures sign in with external identity providers.
+// With JITProvisioning the first sign in of an unknown identity creates a
+// user, identities are only linked to existing users by the users
+// themselves otherwise. LoginTTL is the time a user has to sign in at the
+// provider. HTTPClient calls the providers, http.DefaultClient is used
+// when it is nil.
+type FederationConfig struct {
+	Providers       []IdentityProvider
+	JITProvisioning bool
+	LoginTTL        time.Duration
+	HTTPClient      *http.Client
+}
+
+var DefaultFederationConfig = FederationConfig{JITProvisioning: true, LoginTTL: 10 * time.Minute}
+
+// WithFederation configures the external identity providers, there are
+// none otherwise
+func WithFederation(cfg FederationConfig) Option {
+	return func(s *dbSvc) {
+		s.federation = cfg
+		s.providers = make(map[string]*oidc.Provider, len(cfg.Providers))
+		for _, p := range cfg.Providers {
+			s.providers[p.Name] = oidc.NewProvider(p.Config, cfg.HTTPClient)
+		}
+	}
+}
+
+// Identity is a user at an external identity provider linked to a local
+// user, Subject is the sub claim of its ID tokens
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// storedIdentity links the sub claim of a provider to a user, a user has a
+// single identity per provider
+type storedIdentity struct {
+	Provider  string    `gorm:"primarykey;uniqueIndex:idx_identity_user"`
+	Subject   string    `gorm:"primarykey"`
+	UserID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_identity_user"`
+	CreatedAt time.Time
+}
+
+func (storedIdentity) TableName() string {
+	return "identities"
+}
+
+func (i storedIdentity) toIdentity() Identity {
+	return Identity{Provider: i.Provider, Subject: i.Subject, UserID: i.UserID, CreatedAt: i.CreatedAt}
+}
+
+// storedFederatedLogin is a sign in waiting for the callback of the
+// provider, it is found by the hash of the state parameter. The nonce and
+// PKCE verifier are checked against the ID token and the token endpoint.
+// UserID is set when a signed in user links an identity.
+type storedFederatedLogin struct {
+	Hash      string `gorm:"primarykey"`
+	Provider  string
+	Nonce     string
+	Verifier  string
+	UserID    uuid.UUID `gorm:"type:uuid"`
+	ExpiresAt time.Time
+}
+
+func (storedFederatedLogin) TableName() string {
+	return "federated_logins"
+}
+
+func (s *dbSvc) provider(name string) (*oidc.Provider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return p, nil
+}
+
+func (s *dbSvc) StartFederatedLogin(ctx context.Context, provider string, userID uuid.UUID) (string, error) {
+	p, err := s.provider(provider)
+	if err != nil {
+		return "", err
+	}
+	if userID != uuid.Nil {
+		if _, err := s.storedUser(userID); err != nil {
+			return "", err
+		}
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		if secrets[i], err = randomToken(); err != nil {
+			return "", err
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFederationFailed, err)
+	}
+	err = s.db.Create(&storedFederatedLogin{
+		Hash:      hashToken(state),
+		Provider:  provider,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		UserID:    userID,
+		ExpiresAt: s.now().Add(s.federation.LoginTTL),
+	}).Error
+	return authURL, err
+}
+
+func (s *dbSvc) CompleteFederatedLogin(ctx context.Context, provider, state, code string) (Session, error) {
+	p, err := s.provider(provider)
+	if err != nil {
+		return Session{}, err
+	}
+
+	// the state is used up even when the sign in fails
+	var login storedFederatedLogin
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where(&storedFederatedLogin{Hash: hashToken(state)}).First(&login)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrInvalidState
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Delete(&login).Error
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if login.Provider != provider || !s.now().Before(login.ExpiresAt) {
+		return Session{}, ErrInvalidState
+	}
+
+	idToken, err := p.Exchange(ctx, code, login.Verifier)
+	if err != nil {
+		return Session{}, fmt.Errorf("%w: %v", ErrFederationFailed, err)
+	}
+	claims, err := p.Verify(ctx, idToken, login.Nonce, s.now())
+	if err != nil {
+		return Session{}, fmt.Errorf("%w: %v", ErrFederationFailed, err)
+	}
+
+	var u storedUser
+	err = s.db.Transaction(func(tx *gorm.DB) (err error) {
+		u, err = s.federatedUser(tx, provider, claims, login.UserID)
+		return err
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	// the provider replaces the password, not the second factor
+	if u.TOTPEnabled {
+		return Session{}, s.challenge(u)
+	}
+	return s.startSession(u.ToUser())
+}
+
+// federatedUser returns the user linked to the identity of claims. The
+// identity is linked to linkUser when set, or to a new user with
+// JITProvisioning. Users are never linked by a matching username or email,
+// whoever controls an account at the provider could take over local users.
+func (s *dbSvc) federatedUser(tx *gorm.DB, provider string, claims oidc.Claims, linkUser uuid.UUID) (storedUser, error) {
+	var identity storedIdentity
+	result := tx.Where(&storedIdentity{Provider: provider, Subject: claims.Subject}).First(&identity)
+	found := result.Error == nil
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		return storedUser{}, result.Error
+	}
+
+	switch {
+	case found && linkUser != uuid.Nil && identity.UserID != linkUser:
+		return storedUser{}, ErrIdentityLinked
+	case found:
+		u, ok := grantUser(tx, identity.UserID)
+		if !ok {
+			return storedUser{}, ErrIdentityNotLinked
+		}
+		return u, nil
+	case linkUser != uuid.Nil:
+		u, ok := grantUser(tx, linkUser)
+		if !ok {
+			return storedUser{}, ErrNotFound
+		}
+		return u, s.linkIdentity(tx, provider, claims.Subject, u.ID)
+	case !s.federation.JITProvisioning:
+		return storedUser{}, ErrIdentityNotLinked
+	}
+
+	username, err := s.federatedUsername(tx, provider, claims)
+	if err != nil {
+		return storedUser{}, err
+	}
+	// users created by a provider sign in there, they have no password
+	u := storedUser{Username: username, Role: string(RoleMember)}
+	if claims.EmailVerified {
+		u.Email, u.EmailVerified = claims.Email, true
+	}
+	if err := tx.Create(&u).Error; err != nil {
+		return storedUser{}, err
+	}
+	return u, s.linkIdentity(tx, provider, claims.Subject, u.ID)
+}
+
+func (s *dbSvc) linkIdentity(tx *gorm.DB, provider, subject string, userID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&storedIdentity{}).Where(&storedIdentity{Provider: provider, UserID: userID}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrIdentityLinked
+	}
+	return tx.Create(&storedIdentity{Provider: provider, Subject: subject, UserID: userID, CreatedAt: s.now()}).Error
+}
+
+var notUsername = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// federatedUsername returns the preferred_username of claims when it is a
+// free and valid username, a name derived from the identity otherwise
+func (s *dbSvc) federatedUsername(tx *gorm.DB, provider string, claims oidc.Claims) (string, error) {
+	sum := sha256.Sum256([]byte(claims.Subject))
+	candidates := []string{
+		notUsername.ReplaceAllString(claims.PreferredUsername, ""),
+		notUsername.ReplaceAllString(provider, "") + "-" + hex.EncodeToString(sum[:6]),
+	}
+	for _, name := range candidates {
+		if len(name) < 3 || len(name) > 64 {
+			continue
+		}
+		var count int64
+		if err := tx.Unscoped().Model(&storedUser{}).Where("username = ?", name).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return name, nil
+		}
+	}
+	return "", errors.New("no free username for identity " + claims.Subject)
+}
+
+func (s *dbSvc) GetIdentities(ctx context.Context, userID uuid.UUID) ([]Identity, error) {
+	if _, err := s.storedUser(userID); err != nil {
+		return nil, err
+	}
+	var stored []storedIdentity
+	if err := s.db.Where(&storedIdentity{UserID: userID}).Order("provider").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	identities := make([]Identity, len(stored))
+	for i, id := range stored {
+		identities[i] = id.toIdentity()
+	}
+	return identities, nil
+}
@@ -0,0 +1,167 @@
+// Package ldapauth verifies passwords at an LDAP directory with a search and
+// bind: a service account searches the entry of the user, which is then
+// bound with the password of the user.
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+var ErrAmbiguousUser = errors.New("ldap filter matches several entries")
+
+// Config is the directory users are searched in.
+// URL is ldap://host:389 or ldaps://host:636, StartTLS upgrades ldap://
+// connections before binding. TLS configures both, the system roots are
+// trusted when it is nil.
+// BindDN and BindPassword are the service account searching for users, the
+// search is anonymous when BindDN is empty.
+// Filter finds the entry below BaseDN, %s is replaced by the escaped
+// username, e.g. (&(objectClass=inetOrgPerson)(uid=%s)).
+// UsernameAttribute and EmailAttribute are copied to the local user.
+type Config struct {
+	Name              string
+	URL               string
+	StartTLS          bool
+	TLS               *tls.Config
+	BindDN            string
+	BindPassword      string
+	BaseDN            string
+	Filter            string
+	UsernameAttribute string
+	EmailAttribute    string
+	Timeout           time.Duration
+}
+
+var DefaultConfig = Config{
+	Name:              "ldap",
+	Filter:            "(uid=%s)",
+	UsernameAttribute: "uid",
+	EmailAttribute:    "mail",
+	Timeout:           10 * time.Second,
+}
+
+// Verifier is an authorization.Verifier asking a directory
+type Verifier struct {
+	cfg Config
+}
+
+var _ authorization.Verifier = (*Verifier)(nil)
+
+// New returns the verifier of cfg, empty fields are taken from DefaultConfig
+func New(cfg Config) *Verifier {
+	if cfg.Name == "" {
+		cfg.Name = DefaultConfig.Name
+	}
+	if cfg.Filter == "" {
+		cfg.Filter = DefaultConfig.Filter
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = DefaultConfig.UsernameAttribute
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = DefaultConfig.EmailAttribute
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	return &Verifier{cfg: cfg}
+}
+
+func (v *Verifier) Name() string {
+	return v.cfg.Name
+}
+
+func (v *Verifier) Verify(ctx context.Context, username, password string) (authorization.VerifiedUser, error) {
+	// a simple bind without password is an unauthenticated bind and
+	// succeeds on most servers, see RFC 4513 section 5.1.2
+	if username == "" || password == "" {
+		return authorization.VerifiedUser{}, authorization.ErrUnknownUser
+	}
+
+	conn, err := v.dial(ctx)
+	if err != nil {
+		return authorization.VerifiedUser{}, err
+	}
+	defer conn.Close()
+
+	if v.cfg.BindDN != "" {
+		if err := conn.Bind(v.cfg.BindDN, v.cfg.BindPassword); err != nil {
+			return authorization.VerifiedUser{}, fmt.Errorf("ldap bind as %s: %w", v.cfg.BindDN, err)
+		}
+	}
+	entry, err := v.search(conn, username)
+	if err != nil {
+		return authorization.VerifiedUser{}, err
+	}
+
+	if err := conn.Bind(entry.DN, password); ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return authorization.VerifiedUser{}, authorization.ErrAuthenticationFailed
+	} else if err != nil {
+		return authorization.VerifiedUser{}, fmt.Errorf("ldap bind as %s: %w", entry.DN, err)
+	}
+	return authorization.VerifiedUser{
+		Username: entry.GetEqualFoldAttributeValue(v.cfg.UsernameAttribute),
+		Email:    entry.GetEqualFoldAttributeValue(v.cfg.EmailAttribute),
+	}, nil
+}
+
+func (v *Verifier) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: v.cfg.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := ldap.DialURL(v.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(v.tlsConfig()))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(v.cfg.Timeout)
+	if v.cfg.StartTLS {
+		if err := conn.StartTLS(v.tlsConfig()); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// tlsConfig returns the configured TLS settings with the server name of URL
+func (v *Verifier) tlsConfig() *tls.Config {
+	cfg := &tls.Config{}
+	if v.cfg.TLS != nil {
+		cfg = v.cfg.TLS.Clone()
+	}
+	if cfg.ServerName == "" {
+		if u, err := url.Parse(v.cfg.URL); err == nil {
+			cfg.ServerName = u.Hostname()
+		}
+	}
+	return cfg
+}
+
+// search returns the single entry of username
+func (v *Verifier) search(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(v.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(v.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(v.cfg.Filter, ldap.EscapeFilter(username)),
+		[]string{v.cfg.UsernameAttribute, v.cfg.EmailAttribute}, nil)
+	result, err := conn.Search(req)
+	if result != nil && len(result.Entries) > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousUser, username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap search: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, authorization.ErrUnknownUser
+	}
+	return result.Entries[0], nil
+}
@@ -0,0 +1,124 @@
+package ldapauth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/authorization/ldapauth"
+	"github.com/demeesterdev/todo-service/pkg/authorization/ldapauth/ldaptest"
+)
+
+var entries = []ldaptest.Entry{
+	{DN: "cn=todo,ou=services,dc=example,dc=com", Password: "service secret"},
+	{DN: "uid=alice,ou=people,dc=example,dc=com", Password: "alice secret", Attributes: map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"alice"}, "mail": {"alice@example.com"},
+	}},
+	{DN: "uid=bob,ou=people,dc=example,dc=com", Password: "bob secret", Attributes: map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"bob"},
+	}},
+	{DN: "uid=bob,ou=former,dc=example,dc=com", Password: "bob secret", Attributes: map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"bob"},
+	}},
+}
+
+func config(srv *ldaptest.Server) ldapauth.Config {
+	return ldapauth.Config{
+		URL:          srv.URL,
+		BindDN:       "cn=todo,ou=services,dc=example,dc=com",
+		BindPassword: "service secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		Filter:       "(&(objectClass=inetOrgPerson)(uid=%s))",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	srv := ldaptest.NewServer(entries...)
+	defer srv.Close()
+	ctx := context.Background()
+	v := ldapauth.New(config(srv))
+
+	u, err := v.Verify(ctx, "alice", "alice secret")
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.VerifiedUser{Username: "alice", Email: "alice@example.com"}, u)
+
+	_, err = v.Verify(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, authorization.ErrAuthenticationFailed)
+	_, err = v.Verify(ctx, "carol", "carol secret")
+	assert.ErrorIs(t, err, authorization.ErrUnknownUser)
+
+	// an empty password would be an unauthenticated bind
+	_, err = v.Verify(ctx, "alice", "")
+	assert.ErrorIs(t, err, authorization.ErrUnknownUser)
+
+	// usernames can't change the filter
+	_, err = v.Verify(ctx, "*", "alice secret")
+	assert.ErrorIs(t, err, authorization.ErrUnknownUser)
+	_, err = v.Verify(ctx, "alice)(uid=*", "alice secret")
+	assert.ErrorIs(t, err, authorization.ErrUnknownUser)
+
+	cfg := config(srv)
+	cfg.BaseDN = "dc=example,dc=com"
+	_, err = ldapauth.New(cfg).Verify(ctx, "bob", "bob secret")
+	assert.ErrorIs(t, err, ldapauth.ErrAmbiguousUser)
+
+	cfg = config(srv)
+	cfg.BindPassword = "wrong"
+	_, err = ldapauth.New(cfg).Verify(ctx, "alice", "alice secret")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, authorization.ErrAuthenticationFailed)
+}
+
+func TestVerifyTLS(t *testing.T) {
+	ctx := context.Background()
+	srv := ldaptest.NewServer(entries...)
+	defer srv.Close()
+	cfg := config(srv)
+	cfg.StartTLS = true
+	cfg.TLS = &tls.Config{RootCAs: srv.CertPool()}
+	_, err := ldapauth.New(cfg).Verify(ctx, "alice", "alice secret")
+	assert.NoError(t, err)
+
+	// the certificate is checked
+	cfg.TLS = nil
+	_, err = ldapauth.New(cfg).Verify(ctx, "alice", "alice secret")
+	assert.Error(t, err)
+
+	ldaps := ldaptest.NewTLSServer(entries...)
+	defer ldaps.Close()
+	cfg = config(ldaps)
+	cfg.TLS = &tls.Config{RootCAs: ldaps.CertPool()}
+	_, err = ldapauth.New(cfg).Verify(ctx, "alice", "alice secret")
+	assert.NoError(t, err)
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	srv := ldaptest.NewServer(entries...)
+	defer srv.Close()
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params, authorization.WithVerifiers(authorization.PasswordHashes, ldapauth.New(config(srv))))
+
+	alice, err := s.AuthenticateUser(ctx, authorization.User{Username: "alice", Password: "alice secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", alice.Email)
+	found, err := s.FindUser(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+
+	// the directory keeps the email address up to date
+	srv.SetEntries(entries[0], ldaptest.Entry{DN: entries[1].DN, Password: "alice secret", Attributes: map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"alice"}, "mail": {"alice@example.org"},
+	}})
+	again, err := s.AuthenticateUser(ctx, authorization.User{Username: "alice", Password: "alice secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, again.ID)
+	assert.Equal(t, "alice@example.org", again.Email)
+
+	_, err = s.AuthenticateUser(ctx, authorization.User{Username: "alice", Password: "wrong"})
+	assert.ErrorIs(t, err, authorization.ErrAuthenticationFailed)
+}
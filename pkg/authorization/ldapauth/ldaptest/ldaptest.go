@@ -0,0 +1,342 @@
+// Package ldaptest runs an LDAP server in process for tests of directory
+// clients. It answers simple binds, searches with and, or, not, equality
+// and presence filters, and StartTLS.
+package ldaptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// result codes and operations of RFC 4511
+const (
+	resultSuccess           = 0
+	resultProtocolError     = 2
+	resultSizeLimitExceeded = 4
+	resultInvalidCredential = 49
+	resultInsufficientRight = 50
+
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchEntry       = 4
+	opSearchDone        = 5
+	opExtendedRequest   = 23
+	opExtendedResponse  = 24
+	startTLSOID         = "1.3.6.1.4.1.1466.20037"
+	filterAnd           = 0
+	filterOr            = 1
+	filterNot           = 2
+	filterEqualityMatch = 3
+	filterPresent       = 7
+)
+
+// Entry is an entry of the directory, it binds with Password
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a running test server, it must be closed after the test. URL
+// is ldap:// with StartTLS, or ldaps:// for servers of NewTLSServer.
+type Server struct {
+	URL string
+
+	listener net.Listener
+	tls      *tls.Config
+	certPool *x509.CertPool
+
+	mu      sync.Mutex
+	entries []Entry
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewServer starts a server with entries listening for ldap://
+func NewServer(entries ...Entry) *Server {
+	return newServer(false, entries)
+}
+
+// NewTLSServer starts a server with entries listening for ldaps://
+func NewTLSServer(entries ...Entry) *Server {
+	return newServer(true, entries)
+}
+
+func newServer(ldaps bool, entries []Entry) *Server {
+	s := &Server{entries: entries, conns: map[net.Conn]struct{}{}}
+	s.tls, s.certPool = selfSigned()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s.URL = "ldap://" + l.Addr().String()
+	if ldaps {
+		l = tls.NewListener(l, s.tls)
+		s.URL = "ldaps://" + l.Addr().String()
+	}
+	s.listener = l
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes its connections
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// CertPool trusts the certificate of the server
+func (s *Server) CertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// SetEntries replaces the entries of the directory
+func (s *Server) SetEntries(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// handle answers the requests on conn until it is closed or unbound
+func (s *Server) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+	bound := false
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess && len(op.Children) > 1 && str(op.Children[1]) != ""
+			write(conn, id, result(opBindResponse, code))
+		case opSearchRequest:
+			if !bound {
+				write(conn, id, result(opSearchDone, resultInsufficientRight))
+				continue
+			}
+			s.search(conn, id, op)
+		case opExtendedRequest:
+			if len(op.Children) == 0 || str(op.Children[0]) != startTLSOID {
+				write(conn, id, result(opExtendedResponse, resultProtocolError))
+				continue
+			}
+			write(conn, id, result(opExtendedResponse, resultSuccess))
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+// bind checks a simple bind, a bind without password is unauthenticated
+// and succeeds like on most servers
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocolError
+	}
+	dn, password := str(op.Children[1]), str(op.Children[2])
+	if password == "" {
+		return resultSuccess
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) && e.Password != "" && e.Password == password {
+			return resultSuccess
+		}
+	}
+	return resultInvalidCredential
+}
+
+func (s *Server) search(conn net.Conn, id int64, op *ber.Packet) {
+	if len(op.Children) < 7 {
+		write(conn, id, result(opSearchDone, resultProtocolError))
+		return
+	}
+	base := strings.ToLower(str(op.Children[0]))
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	s.mu.Lock()
+	var found []Entry
+	for _, e := range s.entries {
+		if strings.HasSuffix(strings.ToLower(e.DN), base) && match(filter, e) {
+			found = append(found, e)
+		}
+	}
+	s.mu.Unlock()
+
+	code := resultSuccess
+	if sizeLimit > 0 && int64(len(found)) > sizeLimit {
+		found, code = found[:sizeLimit], resultSizeLimitExceeded
+	}
+	for _, e := range found {
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+		entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+		attributes := ber.NewSequence("Attributes")
+		for name, values := range e.Attributes {
+			attribute := ber.NewSequence("Attribute")
+			attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attribute.AppendChild(set)
+			attributes.AppendChild(attribute)
+		}
+		entry.AppendChild(attributes)
+		write(conn, id, entry)
+	}
+	write(conn, id, result(opSearchDone, code))
+}
+
+// match evaluates filter against e, attribute names and values are case
+// insensitive
+func match(filter *ber.Packet, e Entry) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, f := range filter.Children {
+			if !match(f, e) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, f := range filter.Children {
+			if match(f, e) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !match(filter.Children[0], e)
+	case filterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, v := range values(e, str(filter.Children[0])) {
+			if strings.EqualFold(v, str(filter.Children[1])) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		return len(values(e, str(filter))) > 0
+	}
+	return false
+}
+
+func values(e Entry, attribute string) []string {
+	for name, vs := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return vs
+		}
+	}
+	return nil
+}
+
+func str(p *ber.Packet) string {
+	if v, ok := p.Value.(string); ok {
+		return v
+	}
+	if p.Data != nil {
+		return p.Data.String()
+	}
+	return ""
+}
+
+func result(op ber.Tag, code int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return p
+}
+
+func write(conn net.Conn, id int64, op *ber.Packet) {
+	envelope := ber.NewSequence("LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	envelope.AppendChild(op)
+	conn.Write(envelope.Bytes())
+}
+
+// selfSigned returns a server configuration with a certificate for
+// 127.0.0.1 and a pool trusting it
+func selfSigned() (*tls.Config, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldaptest"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
@@ -0,0 +1,356 @@
+package authorization
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/totp"
+)
+
+// MFAConfig configures TOTP second factors.
+// Issuer names the service in authenticator apps, a challenge returned by
+// AuthenticateUser expires after ChallengeTTL or MaxAttempts wrong codes.
+type MFAConfig struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+	MaxAttempts  int
+	// Skew is the number of 30 second steps a code may be early or late
+	Skew int
+}
+
+var DefaultMFAConfig = MFAConfig{Issuer: "todo-service", ChallengeTTL: 5 * time.Minute, MaxAttempts: 5, Skew: 1}
+
+const (
+	// RecoveryCodes is the number of recovery codes created by ConfirmTOTP
+	RecoveryCodes = 10
+	// recovery codes are 12 base32 characters, the first 4 are stored in the
+	// clear to find the hash to verify
+	recoveryCodeLen    = 12
+	recoveryLookupLen  = 4
+	recoveryGroupLen   = 4
+	recoveryCodeBytes  = 8
+	challengeTokenSize = 32
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// storedRecoveryCode is a single use recovery code of a user
+type storedRecoveryCode struct {
+	gorm.Model
+	UserID uuid.UUID `gorm:"type:uuid;index"`
+	Lookup string
+	Hash   string
+}
+
+func (storedRecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+type mfaChallenge struct {
+	userID   uuid.UUID
+	username string
+	expires  time.Time
+	attempts int
+}
+
+// challengeStore keeps the pending second factor logins in memory,
+// they are short lived and a restart only means logging in again
+type challengeStore struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxAttempts int
+	pending     map[string]*mfaChallenge
+}
+
+func newChallengeStore(ttl time.Duration, maxAttempts int) *challengeStore {
+	return &challengeStore{ttl: ttl, maxAttempts: maxAttempts, pending: map[string]*mfaChallenge{}}
+}
+
+func (c *challengeStore) add(u storedUser, now time.Time) (string, error) {
+	b := make([]byte, challengeTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, ch := range c.pending {
+		if !now.Before(ch.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = &mfaChallenge{userID: u.ID, username: u.Username, expires: now.Add(c.ttl)}
+	return token, nil
+}
+
+func (c *challengeStore) get(token string, now time.Time) (mfaChallenge, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.pending[token]
+	if !ok {
+		return mfaChallenge{}, false
+	}
+	if !now.Before(ch.expires) {
+		delete(c.pending, token)
+		return mfaChallenge{}, false
+	}
+	return *ch, true
+}
+
+// fail counts a wrong code, the challenge is dropped after too many
+func (c *challengeStore) fail(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, ok := c.pending[token]; ok {
+		ch.attempts++
+		if ch.attempts >= c.maxAttempts {
+			delete(c.pending, token)
+		}
+	}
+}
+
+func (c *challengeStore) remove(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, token)
+}
+
+// challenge returns the MFARequiredError for a user whose password matched
+func (s *dbSvc) challenge(u storedUser) error {
+	token, err := s.challenges.add(u, s.now())
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{Challenge: token}
+}
+
+func (s *dbSvc) storedUser(id uuid.UUID) (storedUser, error) {
+	var u storedUser
+	result := s.db.Where(&storedUser{ID: id}).First(&u)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedUser{}, ErrNotFound
+	}
+	return u, result.Error
+}
+
+func (s *dbSvc) CompleteMFA(ctx context.Context, challenge, code string) (Session, error) {
+	ch, ok := s.challenges.get(challenge, s.now())
+	if !ok {
+		return Session{}, ErrInvalidMFAChallenge
+	}
+	if wait := s.userLogins.Check(ch.username); wait > 0 {
+		return Session{}, &LockedError{RetryAfter: wait}
+	}
+
+	u, err := s.storedUser(ch.userID)
+	if err == ErrNotFound {
+		s.challenges.remove(challenge)
+		return Session{}, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	if !u.TOTPEnabled {
+		// disabled after the password was checked, nothing left to complete
+		s.challenges.remove(challenge)
+		return s.startSession(u.ToUser())
+	}
+
+	ok, err = s.verifySecondFactor(u, code)
+	if err != nil {
+		return Session{}, err
+	}
+	if !ok {
+		s.challenges.fail(challenge)
+		s.userLogins.Fail(ch.username)
+		return Session{}, ErrInvalidMFACode
+	}
+
+	s.challenges.remove(challenge)
+	s.userLogins.Reset(ch.username)
+	return s.startSession(u.ToUser())
+}
+
+// verifySecondFactor accepts a TOTP code newer than the last one used or an
+// unused recovery code, which is used up
+func (s *dbSvc) verifySecondFactor(u storedUser, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := totp.Encoding.DecodeString(u.TOTPSecret)
+		if err != nil {
+			return false, err
+		}
+		counter, ok := totp.Validate(secret, code, s.now(), s.mfa.Skew)
+		if !ok {
+			return false, nil
+		}
+		// the update only matches when no newer code was accepted meanwhile
+		result := s.db.Model(&storedUser{}).
+			Where("id = ? AND totp_counter < ?", u.ID, counter).
+			Update("totp_counter", counter)
+		return result.RowsAffected == 1, result.Error
+	}
+
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLen {
+		return false, nil
+	}
+	var candidates []storedRecoveryCode
+	err := s.db.Where(&storedRecoveryCode{UserID: u.ID, Lookup: code[:recoveryLookupLen]}).Find(&candidates).Error
+	if err != nil {
+		return false, err
+	}
+	for _, rc := range candidates {
+		match, err := argon2id.ComparePasswordAndHash(code, rc.Hash, s.hashParams.Pepper())
+		if err != nil {
+			return false, err
+		}
+		if match {
+			result := s.db.Unscoped().Delete(&rc)
+			return result.RowsAffected == 1, result.Error
+		}
+	}
+	return false, nil
+}
+
+func (s *dbSvc) EnrollTOTP(ctx context.Context, id uuid.UUID) (TOTPEnrollment, error) {
+	u, err := s.storedUser(id)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if u.TOTPEnabled {
+		return TOTPEnrollment{}, ErrMFAEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	encoded := totp.Encoding.EncodeToString(secret)
+	if err := s.db.Model(&u).Update("totp_secret", encoded).Error; err != nil {
+		return TOTPEnrollment{}, err
+	}
+	return TOTPEnrollment{Secret: encoded, URI: totp.URI(secret, s.mfa.Issuer, u.Username)}, nil
+}
+
+func (s *dbSvc) ConfirmTOTP(ctx context.Context, id uuid.UUID, code string) ([]string, error) {
+	u, err := s.storedUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, ErrMFAEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	secret, err := totp.Encoding.DecodeString(u.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := totp.Validate(secret, strings.TrimSpace(code), s.now(), s.mfa.Skew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, stored, err := s.newRecoveryCodes(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", u.ID).Delete(&storedRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&stored).Error; err != nil {
+			return err
+		}
+		return tx.Model(&u).Updates(map[string]interface{}{"totp_enabled": true, "totp_counter": counter}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *dbSvc) DisableMFA(ctx context.Context, id uuid.UUID, code string) error {
+	u, err := s.storedUser(id)
+	if err != nil {
+		return err
+	}
+	if code != "" {
+		if u.TOTPSecret == "" {
+			return ErrMFANotEnrolled
+		}
+		if wait := s.userLogins.Check(u.Username); wait > 0 {
+			return &LockedError{RetryAfter: wait}
+		}
+		ok, err := s.verifySecondFactor(u, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.userLogins.Fail(u.Username)
+			return ErrInvalidMFACode
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", u.ID).Delete(&storedRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&u).Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_counter": 0}).Error
+	})
+}
+
+// newRecoveryCodes returns RecoveryCodes codes formatted for the user and
+// their hashes to store
+func (s *dbSvc) newRecoveryCodes(userID uuid.UUID) ([]string, []storedRecoveryCode, error) {
+	codes := make([]string, RecoveryCodes)
+	stored := make([]storedRecoveryCode, RecoveryCodes)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:recoveryCodeLen]
+		hash, err := argon2id.HashPassword(code, s.hashParams)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = formatRecoveryCode(code)
+		stored[i] = storedRecoveryCode{UserID: userID, Lookup: code[:recoveryLookupLen], Hash: hash}
+	}
+	return codes, stored, nil
+}
+
+// formatRecoveryCode groups a code for reading, abcdefghijkl becomes abcd-efgh-ijkl
+func formatRecoveryCode(code string) string {
+	var groups []string
+	for len(code) > recoveryGroupLen {
+		groups = append(groups, code[:recoveryGroupLen])
+		code = code[recoveryGroupLen:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// normalizeRecoveryCode undoes formatRecoveryCode and the changes users make
+// typing a code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
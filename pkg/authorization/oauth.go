@@ -0,0 +1,632 @@
+package authorization
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthConfig sets the lifetimes of the codes and tokens of the OAuth2 server
+type OAuthConfig struct {
+	CodeTTL         time.Duration
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+var DefaultOAuthConfig = OAuthConfig{CodeTTL: 10 * time.Minute, AccessTokenTTL: time.Hour, RefreshTokenTTL: 30 * 24 * time.Hour}
+
+// WithOAuth sets the lifetimes of OAuth2 codes and tokens,
+// DefaultOAuthConfig is used otherwise
+func WithOAuth(cfg OAuthConfig) Option {
+	return func(s *dbSvc) {
+		s.oauth = cfg
+	}
+}
+
+const (
+	// AccessTokenPrefix starts every OAuth2 access token, see IsAccessToken
+	AccessTokenPrefix = "tda_"
+	// RefreshTokenPrefix starts every OAuth2 refresh token
+	RefreshTokenPrefix = "tdr_"
+
+	// GrantTypeAuthorizationCode exchanges an authorization code for tokens
+	GrantTypeAuthorizationCode = "authorization_code"
+	// GrantTypeRefreshToken exchanges a refresh token for new tokens
+	GrantTypeRefreshToken = "refresh_token"
+
+	// CodeChallengeS256 is the only PKCE method accepted, plain challenges
+	// would leak the verifier
+	CodeChallengeS256 = "S256"
+
+	kindAccess  = "access"
+	kindRefresh = "refresh"
+)
+
+// IsAccessToken reports whether token looks like an OAuth2 access token
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// OAuthClient is an application acting for users with their consent.
+// Public clients, like browser extensions, can't keep a secret and only
+// rely on PKCE. Secret is only returned by RegisterClient.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []Scope   `json:"scopes"`
+	Public       bool      `json:"public"`
+	OwnerID      uuid.UUID `json:"owner_id"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+// ClientCredentials authenticate a client at the token, revocation and
+// introspection endpoints. Public clients only send their ID.
+type ClientCredentials struct {
+	ID     uuid.UUID
+	Secret string
+}
+
+// AuthorizationRequest is the consent of a user to let a client act for it
+// with Scopes, Scopes defaults to those of the client. Nonce is copied into
+// the ID token of OpenID Connect requests.
+type AuthorizationRequest struct {
+	ClientID            uuid.UUID `json:"client_id"`
+	UserID              uuid.UUID `json:"user_id,omitempty"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scopes              []Scope   `json:"scopes,omitempty"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	Nonce               string    `json:"nonce,omitempty"`
+}
+
+// TokenRequest is a request to the token endpoint, see RFC 6749 section 4.1.3
+// and 6. Scopes can only narrow the scopes of a refreshed grant.
+type TokenRequest struct {
+	GrantType    string
+	Client       ClientCredentials
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scopes       []Scope
+}
+
+// OAuthToken is the response of the token endpoint, IDToken is only set
+// for grants with ScopeOpenID
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// AccessToken is a valid OAuth2 access token
+type AccessToken struct {
+	ClientID  uuid.UUID `json:"client_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Scopes    []Scope   `json:"scopes"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Has reports whether t grants scope
+func (t AccessToken) Has(scope Scope) bool {
+	return hasScope(t.Scopes, scope)
+}
+
+// Introspection describes a token as in RFC 7662, only Active is set for
+// unknown, expired and revoked tokens
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+}
+
+// JoinScopes formats scopes as an OAuth2 scope parameter
+func JoinScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, " ")
+}
+
+// SplitScopes parses an OAuth2 scope parameter
+func SplitScopes(s string) []Scope {
+	var scopes []Scope
+	for _, scope := range strings.Fields(s) {
+		scopes = append(scopes, Scope(scope))
+	}
+	return scopes
+}
+
+func hasScope(scopes []Scope, scope Scope) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// validScopes fails unless every scope is allowed
+func validScopes(scopes, allowed []Scope) error {
+	for _, s := range scopes {
+		if !hasScope(allowed, s) {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+type storedOAuthClient struct {
+	ID           uuid.UUID `gorm:"type:uuid;primarykey"`
+	Name         string
+	RedirectURIs string
+	Scopes       string
+	SecretHash   string
+	OwnerID      uuid.UUID `gorm:"type:uuid;index"`
+	CreatedAt    time.Time
+}
+
+func (storedOAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (c *storedOAuthClient) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+func (c storedOAuthClient) toOAuthClient() OAuthClient {
+	return OAuthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		RedirectURIs: strings.Fields(c.RedirectURIs),
+		Scopes:       SplitScopes(c.Scopes),
+		Public:       c.SecretHash == "",
+		OwnerID:      c.OwnerID,
+	}
+}
+
+// storedAuthCode is an authorization code waiting to be exchanged, codes
+// are single use
+type storedAuthCode struct {
+	Hash          string    `gorm:"primarykey"`
+	ClientID      uuid.UUID `gorm:"type:uuid"`
+	UserID        uuid.UUID `gorm:"type:uuid;index"`
+	RedirectURI   string
+	Scopes        string
+	CodeChallenge string
+	Nonce         string
+	ExpiresAt     time.Time
+}
+
+func (storedAuthCode) TableName() string {
+	return "oauth_codes"
+}
+
+// storedOAuthToken is an access or refresh token. The tokens issued for one
+// authorization share GrantID, revoking a refresh token revokes them all.
+type storedOAuthToken struct {
+	Hash      string    `gorm:"primarykey"`
+	Kind      string    `gorm:"index"`
+	GrantID   uuid.UUID `gorm:"type:uuid;index"`
+	ClientID  uuid.UUID `gorm:"type:uuid;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	Scopes    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (storedOAuthToken) TableName() string {
+	return "oauth_tokens"
+}
+
+func (s *dbSvc) RegisterClient(ctx context.Context, C OAuthClient, owner uuid.UUID) (OAuthClient, error) {
+	if C.Name == "" || len(C.RedirectURIs) == 0 {
+		return OAuthClient{}, ErrInvalidClientMetadata
+	}
+	for _, uri := range C.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return OAuthClient{}, ErrInvalidRedirectURI
+		}
+	}
+	if len(C.Scopes) == 0 {
+		C.Scopes = OAuthScopes
+	}
+	if err := validScopes(C.Scopes, OAuthScopes); err != nil {
+		return OAuthClient{}, err
+	}
+	if owner == uuid.Nil {
+		return OAuthClient{}, ErrIDMissing
+	}
+	if _, err := s.storedUser(owner); err != nil {
+		return OAuthClient{}, err
+	}
+
+	c := storedOAuthClient{
+		Name:         C.Name,
+		RedirectURIs: strings.Join(C.RedirectURIs, " "),
+		Scopes:       JoinScopes(C.Scopes),
+		OwnerID:      owner,
+		CreatedAt:    s.now(),
+	}
+	var secret string
+	if !C.Public {
+		var err error
+		if secret, err = randomToken(); err != nil {
+			return OAuthClient{}, err
+		}
+		c.SecretHash = hashToken(secret)
+	}
+	if err := s.db.Create(&c).Error; err != nil {
+		return OAuthClient{}, err
+	}
+	client := c.toOAuthClient()
+	client.Secret = secret
+	return client, nil
+}
+
+// validRedirectURI accepts https URIs without fragment, see RFC 6749
+// section 3.1.2, and http URIs on the loopback interface native apps
+// listen on, see RFC 8252 section 7.3
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.User != nil || u.Fragment != "" || strings.ContainsAny(uri, " \t\n") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		return loopback(u.Hostname())
+	default:
+		return false
+	}
+}
+
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+func (s *dbSvc) storedClient(tx *gorm.DB, id uuid.UUID) (storedOAuthClient, error) {
+	var c storedOAuthClient
+	result := tx.Where(&storedOAuthClient{ID: id}).First(&c)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedOAuthClient{}, ErrClientNotFound
+	}
+	return c, result.Error
+}
+
+func (s *dbSvc) GetClient(ctx context.Context, id uuid.UUID) (OAuthClient, error) {
+	c, err := s.storedClient(s.db, id)
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	return c.toOAuthClient(), nil
+}
+
+// authenticateClient returns the client of creds, confidential clients
+// need their secret
+func (s *dbSvc) authenticateClient(tx *gorm.DB, creds ClientCredentials) (storedOAuthClient, error) {
+	c, err := s.storedClient(tx, creds.ID)
+	if err == ErrClientNotFound {
+		return storedOAuthClient{}, ErrInvalidClient
+	}
+	if err != nil {
+		return storedOAuthClient{}, err
+	}
+	if c.SecretHash != "" && subtle.ConstantTimeCompare([]byte(hashToken(creds.Secret)), []byte(c.SecretHash)) != 1 {
+		return storedOAuthClient{}, ErrInvalidClient
+	}
+	return c, nil
+}
+
+func (s *dbSvc) Authorize(ctx context.Context, r AuthorizationRequest) (string, error) {
+	c, err := s.storedClient(s.db, r.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if !hasRedirectURI(c, r.RedirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+	if r.CodeChallenge == "" || r.CodeChallengeMethod != CodeChallengeS256 {
+		return "", ErrInvalidOAuthRequest
+	}
+	if len(r.Scopes) == 0 {
+		r.Scopes = SplitScopes(c.Scopes)
+	}
+	if err := validScopes(r.Scopes, SplitScopes(c.Scopes)); err != nil {
+		return "", err
+	}
+	if _, err := s.storedUser(r.UserID); err != nil {
+		return "", err
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.db.Create(&storedAuthCode{
+		Hash:          hashToken(code),
+		ClientID:      c.ID,
+		UserID:        r.UserID,
+		RedirectURI:   r.RedirectURI,
+		Scopes:        JoinScopes(r.Scopes),
+		CodeChallenge: r.CodeChallenge,
+		Nonce:         r.Nonce,
+		ExpiresAt:     s.now().Add(s.oauth.CodeTTL),
+	}).Error
+	return code, err
+}
+
+func hasRedirectURI(c storedOAuthClient, uri string) bool {
+	for _, registered := range strings.Fields(c.RedirectURIs) {
+		if uri == registered {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCodeChallenge checks the PKCE verifier against the S256 challenge,
+// see RFC 7636 section 4.6
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+func (s *dbSvc) ExchangeToken(ctx context.Context, r TokenRequest) (OAuthToken, error) {
+	var token OAuthToken
+	var grantErr error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		c, err := s.authenticateClient(tx, r.Client)
+		if err != nil {
+			return err
+		}
+		switch r.GrantType {
+		case GrantTypeAuthorizationCode:
+			token, err = s.exchangeCode(tx, c, r)
+		case GrantTypeRefreshToken:
+			token, err = s.refresh(tx, c, r)
+		default:
+			err = ErrUnsupportedGrantType
+		}
+		// a rejected grant is committed so a used code stays deleted
+		if err == ErrInvalidGrant {
+			grantErr = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	return token, grantErr
+}
+
+func (s *dbSvc) exchangeCode(tx *gorm.DB, c storedOAuthClient, r TokenRequest) (OAuthToken, error) {
+	var code storedAuthCode
+	result := tx.Where(&storedAuthCode{Hash: hashToken(r.Code)}).First(&code)
+	if result.Error == gorm.ErrRecordNotFound {
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	if result.Error != nil {
+		return OAuthToken{}, result.Error
+	}
+	// codes are single use, even when the exchange fails
+	if result := tx.Delete(&code); result.Error != nil || result.RowsAffected != 1 {
+		if result.Error != nil {
+			return OAuthToken{}, result.Error
+		}
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	if !s.now().Before(code.ExpiresAt) || code.ClientID != c.ID || code.RedirectURI != r.RedirectURI ||
+		!verifyCodeChallenge(code.CodeChallenge, r.CodeVerifier) {
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	u, ok := grantUser(tx, code.UserID)
+	if !ok || u.Disabled {
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	return s.issueOAuthTokens(tx, uuid.New(), c.ID, u, SplitScopes(code.Scopes), code.Nonce)
+}
+
+// grantUser looks up the user of a grant within a transaction, grants of
+// deleted users can't be exchanged
+func grantUser(tx *gorm.DB, id uuid.UUID) (storedUser, bool) {
+	var u storedUser
+	return u, tx.Where(&storedUser{ID: id}).First(&u).Error == nil
+}
+
+func (s *dbSvc) refresh(tx *gorm.DB, c storedOAuthClient, r TokenRequest) (OAuthToken, error) {
+	t, err := s.lookupOAuthToken(tx, r.RefreshToken)
+	if err != nil || t.Kind != kindRefresh || t.ClientID != c.ID {
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	u, ok := grantUser(tx, t.UserID)
+	if !ok || u.Disabled {
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	scopes := SplitScopes(t.Scopes)
+	if len(r.Scopes) > 0 {
+		if err := validScopes(r.Scopes, scopes); err != nil {
+			return OAuthToken{}, err
+		}
+		scopes = r.Scopes
+	}
+	// refresh tokens are rotated, the old one can't be used again
+	if result := tx.Delete(&t); result.Error != nil || result.RowsAffected != 1 {
+		if result.Error != nil {
+			return OAuthToken{}, result.Error
+		}
+		return OAuthToken{}, ErrInvalidGrant
+	}
+	return s.issueOAuthTokens(tx, t.GrantID, c.ID, u, scopes, "")
+}
+
+// issueOAuthTokens stores and returns a new access and refresh token, and
+// an ID token for OpenID Connect grants
+func (s *dbSvc) issueOAuthTokens(tx *gorm.DB, grant, clientID uuid.UUID, u storedUser, scopes []Scope, nonce string) (OAuthToken, error) {
+	access, err := randomToken()
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	refresh, err := randomToken()
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	access, refresh = AccessTokenPrefix+access, RefreshTokenPrefix+refresh
+
+	now := s.now()
+	tokens := []storedOAuthToken{
+		{Hash: hashToken(access), Kind: kindAccess, ExpiresAt: now.Add(s.oauth.AccessTokenTTL)},
+		{Hash: hashToken(refresh), Kind: kindRefresh, ExpiresAt: now.Add(s.oauth.RefreshTokenTTL)},
+	}
+	for i := range tokens {
+		tokens[i].GrantID, tokens[i].ClientID, tokens[i].UserID = grant, clientID, u.ID
+		tokens[i].Scopes, tokens[i].CreatedAt = JoinScopes(scopes), now
+	}
+	if err := tx.Create(&tokens).Error; err != nil {
+		return OAuthToken{}, err
+	}
+	token := OAuthToken{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.oauth.AccessTokenTTL / time.Second),
+		RefreshToken: refresh,
+		Scope:        JoinScopes(scopes),
+	}
+	if hasScope(scopes, ScopeOpenID) {
+		token.IDToken, err = s.idToken(tx, clientID, u, nonce)
+	}
+	return token, err
+}
+
+// lookupOAuthToken returns the unexpired access or refresh token
+func (s *dbSvc) lookupOAuthToken(tx *gorm.DB, token string) (storedOAuthToken, error) {
+	var t storedOAuthToken
+	result := tx.Where(&storedOAuthToken{Hash: hashToken(token)}).First(&t)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedOAuthToken{}, ErrInvalidToken
+	}
+	if result.Error != nil {
+		return storedOAuthToken{}, result.Error
+	}
+	if !s.now().Before(t.ExpiresAt) {
+		tx.Delete(&t)
+		return storedOAuthToken{}, ErrInvalidToken
+	}
+	return t, nil
+}
+
+func (s *dbSvc) RevokeToken(ctx context.Context, token string, client ClientCredentials) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		c, err := s.authenticateClient(tx, client)
+		if err != nil {
+			return err
+		}
+		t, err := s.lookupOAuthToken(tx, token)
+		// invalid tokens and the tokens of other clients are ignored,
+		// see RFC 7009 section 2.2
+		if err == ErrInvalidToken || (err == nil && t.ClientID != c.ID) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if t.Kind == kindRefresh {
+			return tx.Where(&storedOAuthToken{GrantID: t.GrantID}).Delete(&storedOAuthToken{}).Error
+		}
+		return tx.Delete(&t).Error
+	})
+}
+
+func (s *dbSvc) IntrospectToken(ctx context.Context, token string, client ClientCredentials) (Introspection, error) {
+	// only confidential clients, like resource servers, may introspect
+	c, err := s.authenticateClient(s.db, client)
+	if err != nil {
+		return Introspection{}, err
+	}
+	if c.SecretHash == "" {
+		return Introspection{}, ErrInvalidClient
+	}
+
+	t, err := s.lookupOAuthToken(s.db, token)
+	if err == ErrInvalidToken {
+		return Introspection{Active: false}, nil
+	}
+	if err != nil {
+		return Introspection{}, err
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound || (err == nil && u.Disabled) {
+		return Introspection{Active: false}, nil
+	}
+	if err != nil {
+		return Introspection{}, err
+	}
+	i := Introspection{
+		Active:   true,
+		Scope:    t.Scopes,
+		ClientID: t.ClientID.String(),
+		Username: u.Username,
+		Exp:      t.ExpiresAt.Unix(),
+		Iat:      t.CreatedAt.Unix(),
+		Sub:      u.ID.String(),
+	}
+	if t.Kind == kindAccess {
+		i.TokenType = "Bearer"
+	}
+	return i, nil
+}
+
+func (s *dbSvc) ValidateAccessToken(ctx context.Context, token string) (AccessToken, error) {
+	if !IsAccessToken(token) {
+		return AccessToken{}, ErrInvalidToken
+	}
+	t, err := s.lookupOAuthToken(s.db, token)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound || (err == nil && u.Disabled) {
+		return AccessToken{}, ErrInvalidToken
+	}
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return AccessToken{
+		ClientID:  t.ClientID,
+		UserID:    t.UserID,
+		Scopes:    SplitScopes(t.Scopes),
+		IssuedAt:  t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,231 @@
+package authorization
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/internal/jose"
+)
+
+// OIDCConfig configures OpenID Connect on top of the OAuth2 server. Issuer
+// is the public URL of the HTTP transport, it is the iss claim of ID tokens
+// and the base of the endpoints in the discovery document. A signing key
+// signs ID tokens for KeyRotation before it is replaced.
+type OIDCConfig struct {
+	Issuer      string
+	IDTokenTTL  time.Duration
+	KeyRotation time.Duration
+}
+
+var DefaultOIDCConfig = OIDCConfig{Issuer: "http://localhost:8082", IDTokenTTL: time.Hour, KeyRotation: 30 * 24 * time.Hour}
+
+// WithOIDC configures the issuer and signing keys of ID tokens,
+// DefaultOIDCConfig is used otherwise
+func WithOIDC(cfg OIDCConfig) Option {
+	return func(s *dbSvc) {
+		s.oidc = cfg
+	}
+}
+
+// ScopeOpenID makes an OAuth2 grant an OpenID Connect sign in, the token
+// response carries an ID token and the access token reads the userinfo
+const ScopeOpenID Scope = "openid"
+
+// OAuthScopes lists the scopes OAuth2 clients can be registered with
+var OAuthScopes = append([]Scope{ScopeOpenID}, Scopes...)
+
+// ProviderMetadata is the OpenID Connect discovery document, see OpenID
+// Connect Discovery 1.0 section 3
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfo holds the claims about a user, it is the response of the
+// userinfo endpoint and part of every ID token
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
+
+// IDToken are the claims of an ID token, see OpenID Connect Core 1.0
+// section 2
+type IDToken struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+	Nonce     string `json:"nonce,omitempty"`
+	UserInfo
+}
+
+func userInfo(u storedUser) UserInfo {
+	return UserInfo{Subject: u.ID.String(), PreferredUsername: u.Username}
+}
+
+// storedSigningKey signs ID tokens until a newer key replaces it. It is
+// published until ExpiresAt, when the last ID token it signed has expired.
+type storedSigningKey struct {
+	ID         string `gorm:"primarykey"`
+	PrivateKey []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+func (storedSigningKey) TableName() string {
+	return "signing_keys"
+}
+
+// signingKeys returns the published keys, newest first. Expired keys are
+// deleted.
+func (s *dbSvc) signingKeys(tx *gorm.DB) ([]storedSigningKey, error) {
+	var keys []storedSigningKey
+	if err := tx.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	published := keys[:0]
+	for _, k := range keys {
+		if !s.now().Before(k.ExpiresAt) {
+			if err := tx.Delete(&k).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		published = append(published, k)
+	}
+	sort.Slice(published, func(i, j int) bool { return published[i].CreatedAt.After(published[j].CreatedAt) })
+	return published, nil
+}
+
+// signingKey returns the key for new ID tokens, a new key is generated
+// once the newest one is KeyRotation old
+func (s *dbSvc) signingKey(tx *gorm.DB) (string, *rsa.PrivateKey, error) {
+	keys, err := s.signingKeys(tx)
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.now()
+	if len(keys) > 0 && now.Before(keys[0].CreatedAt.Add(s.oidc.KeyRotation)) {
+		key, err := x509.ParsePKCS1PrivateKey(keys[0].PrivateKey)
+		return keys[0].ID, key, err
+	}
+
+	key, err := jose.GenerateKey()
+	if err != nil {
+		return "", nil, err
+	}
+	k := storedSigningKey{
+		ID:         uuid.NewString(),
+		PrivateKey: x509.MarshalPKCS1PrivateKey(key),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.oidc.KeyRotation + s.oidc.IDTokenTTL),
+	}
+	if err := tx.Create(&k).Error; err != nil {
+		return "", nil, err
+	}
+	return k.ID, key, nil
+}
+
+// idToken returns a signed ID token of u for client
+func (s *dbSvc) idToken(tx *gorm.DB, client uuid.UUID, u storedUser, nonce string) (string, error) {
+	kid, key, err := s.signingKey(tx)
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	return jose.Sign(key, kid, IDToken{
+		Issuer:    s.oidc.Issuer,
+		Audience:  client.String(),
+		ExpiresAt: now.Add(s.oidc.IDTokenTTL).Unix(),
+		IssuedAt:  now.Unix(),
+		Nonce:     nonce,
+		UserInfo:  userInfo(u),
+	})
+}
+
+func (s *dbSvc) OpenIDConfiguration(ctx context.Context) (ProviderMetadata, error) {
+	issuer := strings.TrimSuffix(s.oidc.Issuer, "/")
+	scopes := make([]string, len(OAuthScopes))
+	for i, scope := range OAuthScopes {
+		scopes[i] = string(scope)
+	}
+	return ProviderMetadata{
+		Issuer:                            s.oidc.Issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/jwks.json",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		ScopesSupported:                   scopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jose.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeS256},
+		ClaimsSupported:                   []string{"iss", "aud", "exp", "iat", "nonce", "sub", "preferred_username"},
+	}, nil
+}
+
+func (s *dbSvc) GetJWKS(ctx context.Context) (jose.KeySet, error) {
+	var set jose.KeySet
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// the key of the next ID token is published before it is used
+		if _, _, err := s.signingKey(tx); err != nil {
+			return err
+		}
+		keys, err := s.signingKeys(tx)
+		if err != nil {
+			return err
+		}
+		set.Keys = make([]jose.Key, len(keys))
+		for i, k := range keys {
+			key, err := x509.ParsePKCS1PrivateKey(k.PrivateKey)
+			if err != nil {
+				return err
+			}
+			set.Keys[i] = jose.PublicKey(k.ID, &key.PublicKey)
+		}
+		return nil
+	})
+	return set, err
+}
+
+func (s *dbSvc) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	t, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	if !t.Has(ScopeOpenID) {
+		return UserInfo{}, ErrForbidden
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound {
+		return UserInfo{}, ErrInvalidToken
+	}
+	if err != nil {
+		return UserInfo{}, err
+	}
+	return userInfo(u), nil
+}
@@ -0,0 +1,5 @@
+// Package pb holds the protobuf messages and gRPC service definition of the
+// authorization service.
+package pb
+
+//go:generate buf generate
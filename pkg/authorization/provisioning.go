@@ -0,0 +1,126 @@
+package authorization
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProvisioningConfig enables provisioning by an external system, like an
+// HR system, which authenticates with Token as bearer token. Provisioning
+// is disabled when Token is empty. Provisioned users get one of Roles,
+// only RoleMember when it is empty.
+type ProvisioningConfig struct {
+	Token string
+	Roles []Role
+}
+
+// WithProvisioning configures the provisioning client, there is none
+// otherwise
+func WithProvisioning(cfg ProvisioningConfig) Option {
+	return func(s *dbSvc) {
+		s.provisioning = cfg
+	}
+}
+
+// Provisioner is the caller authenticated by the provisioning token
+var Provisioner = User{Username: "provisioning", Role: RoleProvisioner}
+
+func (s *dbSvc) ValidateProvisioningToken(ctx context.Context, token string) (User, error) {
+	if s.provisioning.Token == "" || token == "" {
+		return User{}, ErrInvalidToken
+	}
+	// comparing hashes doesn't leak the length of the token
+	want, got := sha256.Sum256([]byte(s.provisioning.Token)), sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(want[:], got[:]) != 1 {
+		return User{}, ErrInvalidToken
+	}
+	return Provisioner, nil
+}
+
+func (s *dbSvc) ProvisionUser(ctx context.Context, u User) (User, error) {
+	if u.Username == "" {
+		return User{}, ErrInvalidUserObject
+	}
+	if u.Password != "" {
+		if err := s.checkPassword(u.Password, u.Username); err != nil {
+			return User{}, err
+		}
+	}
+	if u.Role == "" {
+		u.Role = RoleMember
+	}
+	if !u.Role.Valid() {
+		return User{}, ErrInvalidRole
+	}
+	if !s.provisionable(u.Role) {
+		return User{}, ErrForbidden
+	}
+
+	newUser, err := newStoredUser(u, s.hashParams)
+	if err != nil {
+		return User{}, err
+	}
+	newUser.Disabled = u.Disabled
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// deleted users keep their name, provisioning clients expect a
+		// conflict rather than an internal error for it
+		var count int64
+		if err := tx.Unscoped().Model(&storedUser{}).Where("username = ?", u.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUsernameTaken
+		}
+		return tx.Create(&newUser).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return newUser.ToUser(), nil
+}
+
+func (s *dbSvc) SetUserActive(ctx context.Context, id uuid.UUID, active bool) (User, error) {
+	var u storedUser
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ok bool
+		if u, ok = grantUser(tx, id); !ok {
+			return ErrNotFound
+		}
+		u.Disabled = !active
+		if err := tx.Model(&u).Update("disabled", u.Disabled).Error; err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+		// sessions, password resets and OAuth2 grants end at once
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(&storedToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&storedOAuthToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", id).Delete(&storedAuthCode{}).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return u.ToUser(), nil
+}
+
+// provisionable reports whether provisioned users may get r
+func (s *dbSvc) provisionable(r Role) bool {
+	if len(s.provisioning.Roles) == 0 {
+		return r == RoleMember
+	}
+	for _, allowed := range s.provisioning.Roles {
+		if r == allowed {
+			return true
+		}
+	}
+	return false
+}
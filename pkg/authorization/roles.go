@@ -0,0 +1,127 @@
+package authorization
+
+import (
+	"context"
+)
+
+// Role grants a user a set of permissions
+type Role string
+
+const (
+	// RoleAdmin manages all users
+	RoleAdmin Role = "admin"
+	// RoleMember manages its own user, it is the role of new users
+	RoleMember Role = "member"
+	// RoleReadOnly can only read its own user
+	RoleReadOnly Role = "read-only"
+	// RoleProvisioner is the role of the provisioning client, it manages
+	// users over SCIM without being one. Users can't be given it.
+	RoleProvisioner Role = "provisioner"
+)
+
+// Roles lists the known roles
+var Roles = []Role{RoleAdmin, RoleMember, RoleReadOnly}
+
+// Valid reports whether r is one of Roles
+func (r Role) Valid() bool {
+	for _, known := range Roles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission is an action on users
+type Permission string
+
+const (
+	// PermReadSelf and PermWriteSelf allow reading and changing the own user
+	PermReadSelf  Permission = "self:read"
+	PermWriteSelf Permission = "self:write"
+	// PermReadUsers and PermWriteUsers allow reading and changing every user
+	PermReadUsers  Permission = "users:read"
+	PermWriteUsers Permission = "users:write"
+	// PermProvision allows the requests of SCIM provisioning, only on
+	// behalf of the provisioning client
+	PermProvision Permission = "users:provision"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:       {PermReadSelf, PermWriteSelf, PermReadUsers, PermWriteUsers},
+	RoleMember:      {PermReadSelf, PermWriteSelf},
+	RoleReadOnly:    {PermReadSelf},
+	RoleProvisioner: {PermProvision},
+}
+
+// Can reports whether r grants p
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+type sessionTokenKey struct{}
+
+// WithSessionToken returns a context carrying the session token of the
+// caller. Transports put the bearer token of a request here, clients send
+// it along with their requests.
+func WithSessionToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sessionTokenKey{}, token)
+}
+
+// SessionToken returns the session token set by WithSessionToken
+func SessionToken(ctx context.Context) string {
+	token, _ := ctx.Value(sessionTokenKey{}).(string)
+	return token
+}
+
+type provisioningTokenKey struct{}
+
+// WithProvisioningToken returns a context carrying the provisioning token,
+// see ProvisioningConfig. Only the SCIM transport puts the bearer token of
+// a request here, it is not accepted as session anywhere else.
+func WithProvisioningToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, provisioningTokenKey{}, token)
+}
+
+// ProvisioningToken returns the token set by WithProvisioningToken
+func ProvisioningToken(ctx context.Context) string {
+	token, _ := ctx.Value(provisioningTokenKey{}).(string)
+	return token
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the authenticated user making a request
+func WithCaller(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, callerKey{}, u)
+}
+
+// Caller returns the user set by WithCaller
+func Caller(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(callerKey{}).(User)
+	return u, ok
+}
+
+// BootstrapAdmin creates an admin with username and password unless s has
+// an admin already, it reports whether the admin was created. Servers call
+// it on start so a fresh service can be managed.
+func BootstrapAdmin(ctx context.Context, s Service, username, password string) (bool, error) {
+	users, err := s.GetUsers(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.Role == RoleAdmin {
+			return false, nil
+		}
+	}
+	// an existing member is not promoted, whoever signed up with the name
+	// would become admin
+	_, err = s.AddUser(ctx, User{Username: username, Password: password, Role: RoleAdmin})
+	return err == nil, err
+}
@@ -3,8 +3,12 @@ package authorization
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/jose"
 )
 
 // User presents a single user object
@@ -13,6 +17,103 @@ type User struct {
 	ID       uuid.UUID `json:"id" gorm:"type:uuid;primarykey"`
 	Username string    `json:"username" gorm:"unique;index"`
 	Password string    `json:"password,omitempty"`
+	// MFAEnabled is set once the user confirmed a TOTP second factor
+	MFAEnabled bool   `json:"mfa_enabled"`
+	Email      string `json:"email,omitempty"`
+	// EmailVerified is set by VerifyEmail and ResetPassword, it is cleared
+	// when the email address changes
+	EmailVerified bool `json:"email_verified"`
+	// Role is RoleMember unless set by an admin
+	Role Role `json:"role,omitempty"`
+	// Disabled users can't sign in, it is set by SetUserActive
+	Disabled bool `json:"disabled"`
+}
+
+// Session identifies a logged in user, Token is sent as bearer token
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// TOTPEnrollment is a TOTP secret waiting to be confirmed with a first code.
+// Secret is base32 encoded for manual entry, URI is the otpauth:// URI
+// authenticator apps scan as a QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Team is a workspace shared by its members, todos can be owned by a team.
+// Members is filled by the methods returning a single team and GetTeams.
+type Team struct {
+	ID      uuid.UUID    `json:"id"`
+	Name    string       `json:"name"`
+	Members []TeamMember `json:"members,omitempty"`
+}
+
+// TeamMember is a user in a team with its role there
+type TeamMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   TeamRole  `json:"role"`
+}
+
+// Role returns the role of the user in t and whether it is a member
+func (t Team) Role(userID uuid.UUID) (TeamRole, bool) {
+	for _, m := range t.Members {
+		if m.UserID == userID {
+			return m.Role, true
+		}
+	}
+	return "", false
+}
+
+// ImportedUser is a user migrated from another system with the password hash
+// created there, see legacyhash for the supported schemes.
+// ID is generated when empty.
+type ImportedUser struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+// ListQuery selects a page of users or teams. Name matches the username or
+// team name ignoring case, everything matches when it is empty. Offset
+// skips the first matches, at most Limit are returned, DefaultListLimit
+// when it is 0.
+type ListQuery struct {
+	Name   string `json:"name,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+func (q ListQuery) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultListLimit
+	case q.Limit > MaxListLimit:
+		return MaxListLimit
+	}
+	return q.Limit
+}
+
+// UserList is a page of users ordered by username, Total counts all
+// matching users
+type UserList struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+// TeamList is a page of teams ordered by name, Total counts all matching
+// teams
+type TeamList struct {
+	Teams []Team `json:"teams"`
+	Total int    `json:"total"`
 }
 
 // Service is a simple CRUD intreface for userObjects
@@ -21,9 +122,122 @@ type Service interface {
 	GetUser(ctx context.Context, id uuid.UUID) (User, error)
 	FindUser(ctx context.Context, username string) (User, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, u User) (User, error)
+	// AuthenticateUser only checks the credentials of u, Login starts a session
 	AuthenticateUser(ctx context.Context, u User) (User, error)
+	// Login checks the credentials of u and starts a session. Users with a
+	// second factor get an MFARequiredError and a session from CompleteMFA.
+	Login(ctx context.Context, u User) (Session, error)
+	// ValidateSession returns the user of a session token
+	ValidateSession(ctx context.Context, token string) (User, error)
+	// RevokeSession ends a session
+	RevokeSession(ctx context.Context, token string) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	GetUsers(ctx context.Context) ([]User, error)
+	// ListUsers returns a page of the users matching q
+	ListUsers(ctx context.Context, q ListQuery) (UserList, error)
+	// ImportUsers adds all users or none, their passwords are migrated to
+	// argon2id on their next successful login
+	ImportUsers(ctx context.Context, users []ImportedUser) ([]User, error)
+	// UnlockUser lifts the login lockout of a user after failed attempts,
+	// and of the client ips its failed logins came from
+	UnlockUser(ctx context.Context, id uuid.UUID) error
+	// CompleteMFA finishes a login answered with an MFARequiredError,
+	// code is a TOTP code or an unused recovery code
+	CompleteMFA(ctx context.Context, challenge, code string) (Session, error)
+	// EnrollTOTP starts the enrolment of a TOTP second factor,
+	// it is enabled by ConfirmTOTP
+	EnrollTOTP(ctx context.Context, id uuid.UUID) (TOTPEnrollment, error)
+	// ConfirmTOTP enables the enrolled second factor when code is valid and
+	// returns the recovery codes of the user, they are only shown once
+	ConfirmTOTP(ctx context.Context, id uuid.UUID, code string) ([]string, error)
+	// DisableMFA removes the second factor and recovery codes of a user.
+	// A non-empty code must be a current TOTP code or a recovery code,
+	// administrators may leave it empty.
+	DisableMFA(ctx context.Context, id uuid.UUID, code string) error
+	// ForgotPassword mails a password reset token to the users with email in
+	// the background. It succeeds for unknown addresses and failed mails so
+	// addresses can't be discovered.
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword sets the password of the user token was mailed to and
+	// revokes their sessions, API keys and OAuth2 grants. Tokens can only be
+	// used once.
+	ResetPassword(ctx context.Context, token, password string) error
+	// SendVerificationEmail mails a token to confirm the email address of a user
+	SendVerificationEmail(ctx context.Context, id uuid.UUID) error
+	// VerifyEmail marks the email address token was mailed to as verified
+	VerifyEmail(ctx context.Context, token string) (User, error)
+	// CreateTeam adds a team with owner as its first member, provisioned
+	// teams have no owner
+	CreateTeam(ctx context.Context, t Team, owner uuid.UUID) (Team, error)
+	GetTeam(ctx context.Context, id uuid.UUID) (Team, error)
+	// GetTeams returns the teams userID is a member of
+	GetTeams(ctx context.Context, userID uuid.UUID) ([]Team, error)
+	DeleteTeam(ctx context.Context, id uuid.UUID) error
+	// InviteMember returns an invitation token to join a team with role,
+	// it is accepted once with AcceptInvitation
+	InviteMember(ctx context.Context, teamID uuid.UUID, role TeamRole) (string, error)
+	AcceptInvitation(ctx context.Context, token string, userID uuid.UUID) (Team, error)
+	UpdateMember(ctx context.Context, teamID, userID uuid.UUID, role TeamRole) (Team, error)
+	// RemoveMember takes a user out of a team, a team keeps at least one owner
+	RemoveMember(ctx context.Context, teamID, userID uuid.UUID) error
+	// CreateAPIKey returns a new API key of a user, the key is only shown once
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, k APIKey) (APIKey, error)
+	GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	// ValidateAPIKey returns the unexpired API key and records its use
+	ValidateAPIKey(ctx context.Context, key string) (APIKey, error)
+	// RegisterClient registers an OAuth2 client owned by a user, the secret
+	// of confidential clients is only shown once
+	RegisterClient(ctx context.Context, c OAuthClient, owner uuid.UUID) (OAuthClient, error)
+	GetClient(ctx context.Context, id uuid.UUID) (OAuthClient, error)
+	// Authorize records the consent of a user and returns an authorization
+	// code for the client, codes need a PKCE challenge
+	Authorize(ctx context.Context, r AuthorizationRequest) (string, error)
+	// ExchangeToken implements the token endpoint of RFC 6749 for the
+	// authorization_code and refresh_token grants
+	ExchangeToken(ctx context.Context, r TokenRequest) (OAuthToken, error)
+	// RevokeToken revokes a token of the client as in RFC 7009, unknown
+	// tokens are not an error
+	RevokeToken(ctx context.Context, token string, client ClientCredentials) error
+	// IntrospectToken describes a token as in RFC 7662 to a confidential client
+	IntrospectToken(ctx context.Context, token string, client ClientCredentials) (Introspection, error)
+	// ValidateAccessToken returns the unexpired OAuth2 access token
+	ValidateAccessToken(ctx context.Context, token string) (AccessToken, error)
+	// OpenIDConfiguration returns the OpenID Connect discovery document
+	OpenIDConfiguration(ctx context.Context) (ProviderMetadata, error)
+	// GetJWKS returns the public keys ID tokens are signed with, retired keys
+	// are kept until the tokens they signed have expired
+	GetJWKS(ctx context.Context) (jose.KeySet, error)
+	// UserInfo returns the claims about the user of an access token with
+	// ScopeOpenID
+	UserInfo(ctx context.Context, accessToken string) (UserInfo, error)
+	// StartFederatedLogin returns the URL of an external identity provider
+	// the user signs in at. With userID the identity is linked to that user
+	// instead of signing in.
+	StartFederatedLogin(ctx context.Context, provider string, userID uuid.UUID) (string, error)
+	// CompleteFederatedLogin finishes a sign in with the state and code the
+	// provider redirected back with and starts a session of the linked user
+	CompleteFederatedLogin(ctx context.Context, provider, state, code string) (Session, error)
+	// GetIdentities returns the external identities linked to a user
+	GetIdentities(ctx context.Context, userID uuid.UUID) ([]Identity, error)
+	// ValidateProvisioningToken returns Provisioner for the configured
+	// provisioning token
+	ValidateProvisioningToken(ctx context.Context, token string) (User, error)
+	// ProvisionUser adds a user like AddUser, the password is optional:
+	// users without one sign in with an identity provider or directory.
+	// Usernames in use, also by deleted users, are ErrUsernameTaken.
+	// Roles outside ProvisioningConfig.Roles are ErrForbidden.
+	ProvisionUser(ctx context.Context, u User) (User, error)
+	// SetUserActive enables or disables a user, disabling revokes its
+	// sessions and OAuth2 tokens. Its API keys are refused while disabled.
+	SetUserActive(ctx context.Context, id uuid.UUID, active bool) (User, error)
+	// ListTeams returns a page of the teams matching q
+	ListTeams(ctx context.Context, q ListQuery) (TeamList, error)
+	// UpdateTeam renames a team
+	UpdateTeam(ctx context.Context, id uuid.UUID, t Team) (Team, error)
+	// SetMembers replaces the members of a team, a team with an owner keeps
+	// one
+	SetMembers(ctx context.Context, teamID uuid.UUID, members []TeamMember) (Team, error)
 	ServiceStatus(ctx context.Context) (int, error)
 }
 
@@ -34,4 +248,77 @@ var (
 	ErrInconsistentIDs        = errors.New("inconsistent ids")
 	ErrInconsistentIDUserName = errors.New("inconsistent id and username")
 	ErrNotFound               = errors.New("not found")
+	ErrInvalidUUID            = errors.New("invalid uuid")
+	ErrTooManyAttempts        = errors.New("too many failed login attempts")
+	ErrMFARequired            = errors.New("second factor required")
+	ErrInvalidMFAChallenge    = errors.New("invalid or expired mfa challenge")
+	ErrInvalidMFACode         = errors.New("invalid mfa code")
+	ErrMFANotEnrolled         = errors.New("no second factor enrolled")
+	ErrMFAEnabled             = errors.New("second factor already enabled")
+	ErrInvalidToken           = errors.New("invalid or expired token")
+	ErrEmailMissing           = errors.New("user has no email address")
+	ErrMailDisabled           = errors.New("sending mail is not configured")
+	ErrInvalidRole            = errors.New("unknown role")
+	ErrUnauthenticated        = errors.New("authentication required")
+	ErrForbidden              = errors.New("permission denied")
+	ErrTeamNotFound           = errors.New("team not found")
+	ErrInvalidTeam            = errors.New("invalid team")
+	ErrInvalidTeamRole        = errors.New("unknown team role")
+	ErrAlreadyMember          = errors.New("already a member of the team")
+	ErrNotMember              = errors.New("not a member of the team")
+	ErrLastOwner              = errors.New("team needs an owner")
+	ErrInvalidAPIKey          = errors.New("invalid api key")
+	ErrInvalidScope           = errors.New("unknown scope")
+	ErrAPIKeyNotFound         = errors.New("api key not found")
+	ErrInvalidClientMetadata  = errors.New("invalid client metadata")
+	ErrInvalidRedirectURI     = errors.New("invalid redirect uri")
+	ErrClientNotFound         = errors.New("oauth client not found")
+	ErrInvalidClient          = errors.New("client authentication failed")
+	ErrInvalidGrant           = errors.New("invalid or expired grant")
+	ErrUnsupportedGrantType   = errors.New("unsupported grant type")
+	ErrInvalidOAuthRequest    = errors.New("invalid oauth request")
+	ErrProviderNotFound       = errors.New("identity provider not found")
+	ErrInvalidState           = errors.New("invalid or expired login state")
+	ErrFederationFailed       = errors.New("sign in at identity provider failed")
+	ErrIdentityNotLinked      = errors.New("identity not linked to a user")
+	ErrIdentityLinked         = errors.New("identity already linked")
+	ErrUserDisabled           = errors.New("user is disabled")
+	ErrUsernameTaken          = errors.New("username already taken")
 )
+
+// MFARequiredError is returned by AuthenticateUser and Login when the password of a
+// user with a second factor matched. The login is completed by passing
+// Challenge to CompleteMFA. It wraps ErrMFARequired.
+type MFARequiredError struct {
+	Challenge string
+}
+
+func (e *MFARequiredError) Error() string { return ErrMFARequired.Error() }
+
+func (e *MFARequiredError) Unwrap() error { return ErrMFARequired }
+
+// LockedError is returned by AuthenticateUser while logins of the user or
+// the client are locked out. It wraps ErrTooManyAttempts.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error { return ErrTooManyAttempts }
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the ip address of the client,
+// logins are throttled per ip address as well as per user
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the ip address of the client set by WithClientIP
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,64 @@
+package authorization
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSessionTTL is the lifetime of a session unless set by WithSessionTTL
+const DefaultSessionTTL = 12 * time.Hour
+
+// WithSessionTTL sets how long sessions started by Login and CompleteMFA last
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(s *dbSvc) {
+		s.sessionTTL = ttl
+	}
+}
+
+func (s *dbSvc) Login(ctx context.Context, U User) (Session, error) {
+	u, err := s.AuthenticateUser(ctx, U)
+	if err != nil {
+		return Session{}, err
+	}
+	return s.startSession(u)
+}
+
+// startSession returns a new session of a user whose credentials were checked
+func (s *dbSvc) startSession(U User) (Session, error) {
+	u, err := s.storedUser(U.ID)
+	if err != nil {
+		return Session{}, err
+	}
+	if u.Disabled {
+		return Session{}, ErrUserDisabled
+	}
+	token, err := s.newToken(s.db, u, purposeSession, s.sessionTTL)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{Token: token, ExpiresAt: s.now().Add(s.sessionTTL), User: u.ToUser()}, nil
+}
+
+func (s *dbSvc) ValidateSession(ctx context.Context, token string) (User, error) {
+	t, err := s.lookupToken(token, purposeSession)
+	if err != nil {
+		return User{}, err
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound || u.Disabled {
+		return User{}, ErrInvalidToken
+	}
+	return u.ToUser(), err
+}
+
+func (s *dbSvc) RevokeSession(ctx context.Context, token string) error {
+	t, err := s.lookupToken(token, purposeSession)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return useToken(tx, t)
+	})
+}
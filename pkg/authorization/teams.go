@@ -0,0 +1,419 @@
+package authorization
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TeamRole is the role of a member in a team
+type TeamRole string
+
+const (
+	// TeamRoleOwner manages the team and its members, including other owners
+	TeamRoleOwner TeamRole = "owner"
+	// TeamRoleAdmin invites and removes members other than owners
+	TeamRoleAdmin TeamRole = "admin"
+	// TeamRoleMember reads and adds the todos of the team
+	TeamRoleMember TeamRole = "member"
+	// TeamRoleGuest only reads the todos of the team
+	TeamRoleGuest TeamRole = "guest"
+)
+
+// TeamRoles lists the known team roles
+var TeamRoles = []TeamRole{TeamRoleOwner, TeamRoleAdmin, TeamRoleMember, TeamRoleGuest}
+
+// Valid reports whether r is one of TeamRoles
+func (r TeamRole) Valid() bool {
+	for _, known := range TeamRoles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManage reports whether r may invite, change and remove members
+func (r TeamRole) CanManage() bool {
+	return r == TeamRoleOwner || r == TeamRoleAdmin
+}
+
+// CanWrite reports whether r may add and change the todos of the team
+func (r TeamRole) CanWrite() bool {
+	return r.Valid() && r != TeamRoleGuest
+}
+
+// InvitationTTL is how long an invitation to a team can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+type storedTeam struct {
+	gorm.Model
+	ID   uuid.UUID `gorm:"type:uuid;primarykey"`
+	Name string
+}
+
+func (storedTeam) TableName() string {
+	return "teams"
+}
+
+func (t *storedTeam) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// storedMembership is removed, not soft deleted, so access ends at once
+type storedMembership struct {
+	TeamID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	Role   string
+}
+
+func (storedMembership) TableName() string {
+	return "team_members"
+}
+
+// storedInvitation is an invitation token to join a team, only its SHA-256
+// hash is stored like the tokens of users
+type storedInvitation struct {
+	gorm.Model
+	TeamID    uuid.UUID `gorm:"type:uuid;index"`
+	Role      string
+	Hash      string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+}
+
+func (storedInvitation) TableName() string {
+	return "team_invitations"
+}
+
+// team returns t with its members
+func (s *dbSvc) team(tx *gorm.DB, t storedTeam) (Team, error) {
+	var members []storedMembership
+	if err := tx.Where(&storedMembership{TeamID: t.ID}).Order("user_id").Find(&members).Error; err != nil {
+		return Team{}, err
+	}
+	team := Team{ID: t.ID, Name: t.Name, Members: make([]TeamMember, len(members))}
+	for i, m := range members {
+		team.Members[i] = TeamMember{UserID: m.UserID, Role: TeamRole(m.Role)}
+	}
+	return team, nil
+}
+
+func (s *dbSvc) storedTeam(tx *gorm.DB, id uuid.UUID) (storedTeam, error) {
+	var t storedTeam
+	result := tx.Where(&storedTeam{ID: id}).First(&t)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedTeam{}, ErrTeamNotFound
+	}
+	return t, result.Error
+}
+
+func (s *dbSvc) CreateTeam(ctx context.Context, T Team, owner uuid.UUID) (Team, error) {
+	if T.Name == "" {
+		return Team{}, ErrInvalidTeam
+	}
+	// provisioned teams start without members, see SetMembers
+	if owner != uuid.Nil {
+		if _, err := s.storedUser(owner); err != nil {
+			return Team{}, err
+		}
+	}
+
+	t := storedTeam{ID: T.ID, Name: T.Name}
+	var team Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+		if owner != uuid.Nil {
+			if err := tx.Create(&storedMembership{TeamID: t.ID, UserID: owner, Role: string(TeamRoleOwner)}).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		team, err = s.team(tx, t)
+		return err
+	})
+	return team, err
+}
+
+func (s *dbSvc) GetTeam(ctx context.Context, id uuid.UUID) (Team, error) {
+	t, err := s.storedTeam(s.db, id)
+	if err != nil {
+		return Team{}, err
+	}
+	return s.team(s.db, t)
+}
+
+func (s *dbSvc) GetTeams(ctx context.Context, userID uuid.UUID) ([]Team, error) {
+	var stored []storedTeam
+	err := s.db.Where("id IN (?)", s.db.Model(&storedMembership{}).Select("team_id").Where("user_id = ?", userID)).
+		Order("name").Find(&stored).Error
+	if err != nil {
+		return []Team{}, err
+	}
+	teams := make([]Team, len(stored))
+	for i := range stored {
+		if teams[i], err = s.team(s.db, stored[i]); err != nil {
+			return []Team{}, err
+		}
+	}
+	return teams, nil
+}
+
+func (s *dbSvc) DeleteTeam(ctx context.Context, id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&storedTeam{}, "id = ?", id.String())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTeamNotFound
+		}
+		if err := tx.Where("team_id = ?", id).Delete(&storedMembership{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("team_id = ?", id).Delete(&storedInvitation{}).Error
+	})
+}
+
+func (s *dbSvc) InviteMember(ctx context.Context, teamID uuid.UUID, role TeamRole) (string, error) {
+	if !role.Valid() {
+		return "", ErrInvalidTeamRole
+	}
+	if _, err := s.storedTeam(s.db, teamID); err != nil {
+		return "", err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.db.Create(&storedInvitation{
+		TeamID:    teamID,
+		Role:      string(role),
+		Hash:      hashToken(token),
+		ExpiresAt: s.now().Add(InvitationTTL),
+	}).Error
+	return token, err
+}
+
+func (s *dbSvc) AcceptInvitation(ctx context.Context, token string, userID uuid.UUID) (Team, error) {
+	if _, err := s.storedUser(userID); err != nil {
+		return Team{}, err
+	}
+
+	var team Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var inv storedInvitation
+		result := tx.Where(&storedInvitation{Hash: hashToken(token)}).First(&inv)
+		if result.Error == gorm.ErrRecordNotFound {
+			return ErrInvalidToken
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if !s.now().Before(inv.ExpiresAt) {
+			tx.Unscoped().Delete(&inv)
+			return ErrInvalidToken
+		}
+
+		t, err := s.storedTeam(tx, inv.TeamID)
+		if err == ErrTeamNotFound {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		var existing int64
+		if err := tx.Model(&storedMembership{}).Where(&storedMembership{TeamID: t.ID, UserID: userID}).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyMember
+		}
+
+		// invitations are single use
+		if result := tx.Unscoped().Delete(&inv); result.Error != nil || result.RowsAffected != 1 {
+			if result.Error != nil {
+				return result.Error
+			}
+			return ErrInvalidToken
+		}
+		if err := tx.Create(&storedMembership{TeamID: t.ID, UserID: userID, Role: inv.Role}).Error; err != nil {
+			return err
+		}
+		team, err = s.team(tx, t)
+		return err
+	})
+	return team, err
+}
+
+// membership returns the membership of userID in teamID
+func membership(tx *gorm.DB, teamID, userID uuid.UUID) (storedMembership, error) {
+	var m storedMembership
+	result := tx.Where(&storedMembership{TeamID: teamID, UserID: userID}).First(&m)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedMembership{}, ErrNotMember
+	}
+	return m, result.Error
+}
+
+// keepOwner fails when the last owner of teamID stops being owner
+func keepOwner(tx *gorm.DB, m storedMembership) error {
+	if m.Role != string(TeamRoleOwner) {
+		return nil
+	}
+	var owners int64
+	err := tx.Model(&storedMembership{}).Where(&storedMembership{TeamID: m.TeamID, Role: string(TeamRoleOwner)}).Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *dbSvc) UpdateMember(ctx context.Context, teamID, userID uuid.UUID, role TeamRole) (Team, error) {
+	if !role.Valid() {
+		return Team{}, ErrInvalidTeamRole
+	}
+	var team Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		t, err := s.storedTeam(tx, teamID)
+		if err != nil {
+			return err
+		}
+		m, err := membership(tx, teamID, userID)
+		if err != nil {
+			return err
+		}
+		if role != TeamRoleOwner {
+			if err := keepOwner(tx, m); err != nil {
+				return err
+			}
+		}
+		err = tx.Model(&storedMembership{}).Where(&storedMembership{TeamID: teamID, UserID: userID}).Update("role", string(role)).Error
+		if err != nil {
+			return err
+		}
+		team, err = s.team(tx, t)
+		return err
+	})
+	return team, err
+}
+
+func (s *dbSvc) RemoveMember(ctx context.Context, teamID, userID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.storedTeam(tx, teamID); err != nil {
+			return err
+		}
+		m, err := membership(tx, teamID, userID)
+		if err != nil {
+			return err
+		}
+		if err := keepOwner(tx, m); err != nil {
+			return err
+		}
+		return tx.Where(&storedMembership{TeamID: teamID, UserID: userID}).Delete(&storedMembership{}).Error
+	})
+}
+
+func (s *dbSvc) ListTeams(ctx context.Context, q ListQuery) (TeamList, error) {
+	query := s.db.Model(&storedTeam{})
+	if q.Name != "" {
+		query = query.Where("LOWER(name) = LOWER(?)", q.Name)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return TeamList{}, err
+	}
+	var stored []storedTeam
+	if err := query.Order("name").Order("id").Offset(q.Offset).Limit(q.limit()).Find(&stored).Error; err != nil {
+		return TeamList{}, err
+	}
+	list := TeamList{Teams: make([]Team, len(stored)), Total: int(total)}
+	for i, t := range stored {
+		team, err := s.team(s.db, t)
+		if err != nil {
+			return TeamList{}, err
+		}
+		list.Teams[i] = team
+	}
+	return list, nil
+}
+
+func (s *dbSvc) UpdateTeam(ctx context.Context, id uuid.UUID, T Team) (Team, error) {
+	if T.Name == "" {
+		return Team{}, ErrInvalidTeam
+	}
+	var team Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		t, err := s.storedTeam(tx, id)
+		if err != nil {
+			return err
+		}
+		t.Name = T.Name
+		if err := tx.Model(&t).Update("name", t.Name).Error; err != nil {
+			return err
+		}
+		team, err = s.team(tx, t)
+		return err
+	})
+	return team, err
+}
+
+func (s *dbSvc) SetMembers(ctx context.Context, teamID uuid.UUID, members []TeamMember) (Team, error) {
+	seen := make(map[uuid.UUID]bool, len(members))
+	owners := 0
+	for _, m := range members {
+		if !m.Role.Valid() {
+			return Team{}, ErrInvalidTeamRole
+		}
+		if m.UserID == uuid.Nil || seen[m.UserID] {
+			return Team{}, ErrInvalidTeam
+		}
+		seen[m.UserID] = true
+		if m.Role == TeamRoleOwner {
+			owners++
+		}
+	}
+
+	var team Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		t, err := s.storedTeam(tx, teamID)
+		if err != nil {
+			return err
+		}
+		var current []storedMembership
+		if err := tx.Where(&storedMembership{TeamID: teamID}).Find(&current).Error; err != nil {
+			return err
+		}
+		for _, m := range current {
+			if m.Role == string(TeamRoleOwner) && owners == 0 {
+				return ErrLastOwner
+			}
+		}
+		for _, m := range members {
+			if _, ok := grantUser(tx, m.UserID); !ok {
+				return ErrNotFound
+			}
+		}
+
+		if err := tx.Where(&storedMembership{TeamID: teamID}).Delete(&storedMembership{}).Error; err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := tx.Create(&storedMembership{TeamID: teamID, UserID: m.UserID, Role: string(m.Role)}).Error; err != nil {
+				return err
+			}
+		}
+		team, err = s.team(tx, t)
+		return err
+	})
+	return team, err
+}
@@ -0,0 +1,299 @@
+package authorization
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/mailer"
+)
+
+// MailConfig configures the password reset and email verification mails.
+// ResetURL and VerifyURL are the pages the links in the mails point to, the
+// token is added as token query parameter. The mails hold the bare token
+// when they are empty.
+type MailConfig struct {
+	ResetURL  string
+	VerifyURL string
+	ResetTTL  time.Duration
+	VerifyTTL time.Duration
+}
+
+var DefaultMailConfig = MailConfig{ResetTTL: time.Hour, VerifyTTL: 24 * time.Hour}
+
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+	purposeSession           = "session"
+
+	tokenSize = 32
+)
+
+// storedToken is a token handed to a user, a single use token sent by mail
+// or a session. Only the SHA-256 hash is stored, the tokens are random so a
+// slow hash adds nothing. Email is the address a token was sent to.
+type storedToken struct {
+	gorm.Model
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	Purpose   string
+	Hash      string `gorm:"uniqueIndex"`
+	Email     string
+	ExpiresAt time.Time
+}
+
+func (storedToken) TableName() string {
+	return "tokens"
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueToken returns a new token for u, earlier tokens of u for purpose
+// are revoked
+func (s *dbSvc) issueToken(u storedUser, purpose string, ttl time.Duration) (token string, err error) {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeTokens(tx, u.ID, purpose); err != nil {
+			return err
+		}
+		token, err = s.newToken(tx, u, purpose, ttl)
+		return err
+	})
+	return token, err
+}
+
+// randomToken returns a new url safe token
+func randomToken() (string, error) {
+	b := make([]byte, tokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// newToken stores and returns a new token for u
+func (s *dbSvc) newToken(tx *gorm.DB, u storedUser, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Create(&storedToken{
+		UserID:    u.ID,
+		Purpose:   purpose,
+		Hash:      hashToken(token),
+		Email:     u.Email,
+		ExpiresAt: s.now().Add(ttl),
+	}).Error
+	return token, err
+}
+
+func revokeTokens(tx *gorm.DB, userID uuid.UUID, purpose string) error {
+	return tx.Unscoped().Where(&storedToken{UserID: userID, Purpose: purpose}).Delete(&storedToken{}).Error
+}
+
+// lookupToken returns the unexpired token for purpose without using it
+func (s *dbSvc) lookupToken(token, purpose string) (storedToken, error) {
+	var t storedToken
+	result := s.db.Where(&storedToken{Hash: hashToken(token), Purpose: purpose}).First(&t)
+	if result.Error == gorm.ErrRecordNotFound {
+		return storedToken{}, ErrInvalidToken
+	}
+	if result.Error != nil {
+		return storedToken{}, result.Error
+	}
+	if !s.now().Before(t.ExpiresAt) {
+		s.db.Unscoped().Delete(&t)
+		return storedToken{}, ErrInvalidToken
+	}
+	return t, nil
+}
+
+// useToken deletes t, it fails when t was used concurrently
+func useToken(tx *gorm.DB, t storedToken) error {
+	result := tx.Unscoped().Delete(&t)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// link returns the link to base with token, or the bare token without base
+func link(base, token string) string {
+	if base == "" {
+		return token
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (s *dbSvc) ForgotPassword(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMailDisabled
+	}
+
+	// known and unknown addresses answer alike and just as fast, failures
+	// are only logged
+	ctx = context.WithoutCancel(ctx)
+	s.mails.Add(1)
+	go func() {
+		defer s.mails.Done()
+		if err := s.sendResetMails(ctx, email); err != nil {
+			s.logger.Log("method", "ForgotPassword", "err", err)
+		}
+	}()
+	return nil
+}
+
+// sendResetMails mails a password reset token to the users with email
+func (s *dbSvc) sendResetMails(ctx context.Context, email string) error {
+	var users []storedUser
+	if err := s.db.Where(&storedUser{Email: email}).Find(&users).Error; err != nil {
+		return err
+	}
+	for _, u := range users {
+		token, err := s.issueToken(u, purposePasswordReset, s.mail.ResetTTL)
+		if err != nil {
+			return err
+		}
+		err = s.mailer.Send(ctx, mailer.Message{
+			To:      u.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+				"Use this within %s to choose a new password:\n\n%s\n\n"+
+				"You can ignore this mail if it wasn't you, your password is unchanged.\n",
+				u.Username, s.mail.ResetTTL, link(s.mail.ResetURL, token)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *dbSvc) ResetPassword(ctx context.Context, token, password string) error {
+	t, err := s.lookupToken(token, purposePasswordReset)
+	if err != nil {
+		return err
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// a rejected password leaves the token to try again
+	if err := s.checkPassword(password, u.Username); err != nil {
+		return err
+	}
+	hash, err := argon2id.HashPassword(password, s.hashParams)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := useToken(tx, t); err != nil {
+			return err
+		}
+		// whoever knew the old password is logged out and loses the
+		// credentials they made with it
+		if err := revokeTokens(tx, u.ID, purposeSession); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", u.ID).Delete(&storedAPIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", u.ID).Delete(&storedOAuthToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", u.ID).Delete(&storedAuthCode{}).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"password_hash": hash}
+		// the mail arrived, so the address belongs to the user
+		if t.Email != "" && t.Email == u.Email {
+			updates["email_verified"] = true
+		}
+		return tx.Model(&u).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.userLogins.Reset(u.Username)
+	return nil
+}
+
+func (s *dbSvc) SendVerificationEmail(ctx context.Context, id uuid.UUID) error {
+	if s.mailer == nil {
+		return ErrMailDisabled
+	}
+	u, err := s.storedUser(id)
+	if err != nil {
+		return err
+	}
+	if u.Email == "" {
+		return ErrEmailMissing
+	}
+
+	token, err := s.issueToken(u, purposeEmailVerification, s.mail.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this within %s to confirm %s is your email address:\n\n%s\n",
+			u.Username, s.mail.VerifyTTL, u.Email, link(s.mail.VerifyURL, token)),
+	})
+}
+
+func (s *dbSvc) VerifyEmail(ctx context.Context, token string) (User, error) {
+	t, err := s.lookupToken(token, purposeEmailVerification)
+	if err != nil {
+		return User{}, err
+	}
+	u, err := s.storedUser(t.UserID)
+	if err == ErrNotFound {
+		return User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	// the address changed after the mail was sent
+	if t.Email != u.Email {
+		return User{}, ErrInvalidToken
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := useToken(tx, t); err != nil {
+			return err
+		}
+		return tx.Model(&u).Update("email_verified", true).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+	u.EmailVerified = true
+	return u.ToUser(), nil
+}
@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// encodeRedirectResponse sends the user agent on to the identity provider
+func encodeRedirectResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	r := response.(ep.StartFederatedLoginResponse)
+	if r.Err != nil {
+		encodeError(ctx, r.Err, w)
+		return nil
+	}
+	w.Header().Set("Location", r.URL)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusSeeOther)
+	return nil
+}
+
+func DecodeHTTPStartFederatedLoginRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.StartFederatedLoginRequest{Provider: chi.URLParam(r, "provider")}, nil
+}
+
+func DecodeHTTPLinkIdentityRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.StartFederatedLoginRequest{Provider: chi.URLParam(r, "provider"), UserID: userId}, nil
+}
+
+// DecodeHTTPCompleteFederatedLoginRequest decodes the redirect back from the
+// provider, which carries an error instead of a code when the user didn't
+// sign in there
+func DecodeHTTPCompleteFederatedLoginRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return nil, fmt.Errorf("%w: %s", authorization.ErrFederationFailed, e)
+	}
+	return ep.CompleteFederatedLoginRequest{
+		Provider: chi.URLParam(r, "provider"),
+		State:    q.Get("state"),
+		Code:     q.Get("code"),
+	}, nil
+}
+
+func DecodeHTTPGetIdentitiesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetIdentitiesRequest{UserID: userId}, nil
+}
+
+// startFederatedLoginClient signs in at GET /login/{provider} without
+// following its redirect, and links identities at
+// POST /{id}/identities/{provider}
+func startFederatedLoginClient(tgt *url.URL, options []httptransport.ClientOption) endpoint.Endpoint {
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	login := httptransport.NewClient("GET", tgt, encodeHTTPStartFederatedLoginRequest, decodeHTTPStartFederatedLoginResponse,
+		append(options, httptransport.SetClient(noRedirect))...).Endpoint()
+	link := httptransport.NewClient("POST", tgt, encodeHTTPLinkIdentityRequest, decodeHTTPStartFederatedLoginResponse, options...).Endpoint()
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if request.(ep.StartFederatedLoginRequest).UserID == uuid.Nil {
+			return login(ctx, request)
+		}
+		return link(ctx, request)
+	}
+}
+
+// client functions
+// encode request for server
+
+func encodeHTTPStartFederatedLoginRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/login/{provider}", ...)
+	r := request.(ep.StartFederatedLoginRequest)
+	req.URL.Path += "/login/" + url.PathEscape(r.Provider)
+	return nil
+}
+
+func encodeHTTPLinkIdentityRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/identities/{provider}", ...)
+	r := request.(ep.StartFederatedLoginRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/identities/" + url.PathEscape(r.Provider)
+	return nil
+}
+
+func encodeHTTPCompleteFederatedLoginRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/login/{provider}/callback", ...)
+	r := request.(ep.CompleteFederatedLoginRequest)
+	req.URL.Path += "/login/" + url.PathEscape(r.Provider) + "/callback"
+	req.URL.RawQuery = url.Values{"state": {r.State}, "code": {r.Code}}.Encode()
+	return nil
+}
+
+func encodeHTTPGetIdentitiesRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/{id}/identities", ...)
+	r := request.(ep.GetIdentitiesRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/identities"
+	return nil
+}
+
+// client functions
+// decode response from server
+
+func decodeHTTPStartFederatedLoginResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.StartFederatedLoginResponse
+	if resp.StatusCode == http.StatusSeeOther {
+		response.URL = resp.Header.Get("Location")
+		return response, nil
+	}
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPCompleteFederatedLoginResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.CompleteFederatedLoginResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetIdentitiesResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetIdentitiesResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
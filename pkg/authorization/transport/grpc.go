@@ -0,0 +1,2903 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/demeesterdev/todo-service/internal/jose"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+	"github.com/demeesterdev/todo-service/pkg/authorization/pb"
+)
+
+const grpcServiceName = "authorization.Users"
+
+type grpcServer struct {
+	pb.UnimplementedUsersServer
+
+	addUser                grpctransport.Handler
+	getUser                grpctransport.Handler
+	findUser               grpctransport.Handler
+	updateUser             grpctransport.Handler
+	authenticateUser       grpctransport.Handler
+	login                  grpctransport.Handler
+	validateSession        grpctransport.Handler
+	revokeSession          grpctransport.Handler
+	deleteUser             grpctransport.Handler
+	getUsers               grpctransport.Handler
+	importUsers            grpctransport.Handler
+	unlockUser             grpctransport.Handler
+	completeMFA            grpctransport.Handler
+	enrollTOTP             grpctransport.Handler
+	confirmTOTP            grpctransport.Handler
+	disableMFA             grpctransport.Handler
+	forgotPassword         grpctransport.Handler
+	resetPassword          grpctransport.Handler
+	sendVerification       grpctransport.Handler
+	verifyEmail            grpctransport.Handler
+	createTeam             grpctransport.Handler
+	getTeam                grpctransport.Handler
+	getTeams               grpctransport.Handler
+	deleteTeam             grpctransport.Handler
+	inviteMember           grpctransport.Handler
+	acceptInvitation       grpctransport.Handler
+	updateMember           grpctransport.Handler
+	removeMember           grpctransport.Handler
+	createAPIKey           grpctransport.Handler
+	getAPIKeys             grpctransport.Handler
+	revokeAPIKey           grpctransport.Handler
+	validateAPIKey         grpctransport.Handler
+	registerClient         grpctransport.Handler
+	getClient              grpctransport.Handler
+	authorize              grpctransport.Handler
+	exchangeToken          grpctransport.Handler
+	revokeToken            grpctransport.Handler
+	introspectToken        grpctransport.Handler
+	validateAccessToken    grpctransport.Handler
+	openIDConfiguration    grpctransport.Handler
+	getJWKS                grpctransport.Handler
+	userInfo               grpctransport.Handler
+	startFederatedLogin    grpctransport.Handler
+	completeFederatedLogin grpctransport.Handler
+	getIdentities          grpctransport.Handler
+	listUsers              grpctransport.Handler
+	validateProvisioning   grpctransport.Handler
+	provisionUser          grpctransport.Handler
+	setUserActive          grpctransport.Handler
+	listTeams              grpctransport.Handler
+	updateTeam             grpctransport.Handler
+	setMembers             grpctransport.Handler
+	serviceStatus          grpctransport.Handler
+}
+
+// MakeGRPCServer makes the set of endpoints available as a gRPC UsersServer.
+func MakeGRPCServer(ep ep.Endpoints, logger log.Logger) pb.UsersServer {
+	options := []grpctransport.ServerOption{
+		grpctransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		grpctransport.ServerBefore(peerIPToContext, SessionTokenFromMD),
+	}
+
+	return &grpcServer{
+		addUser: grpctransport.NewServer(
+			ep.AddUserEndpoint,
+			decodeGRPCAddUserRequest,
+			encodeGRPCAddUserResponse,
+			options...,
+		),
+		getUser: grpctransport.NewServer(
+			ep.GetUserEndpoint,
+			decodeGRPCGetUserRequest,
+			encodeGRPCGetUserResponse,
+			options...,
+		),
+		findUser: grpctransport.NewServer(
+			ep.GetUserEndpoint,
+			decodeGRPCFindUserRequest,
+			encodeGRPCGetUserResponse,
+			options...,
+		),
+		updateUser: grpctransport.NewServer(
+			ep.UpdateUserEndpoint,
+			decodeGRPCUpdateUserRequest,
+			encodeGRPCUpdateUserResponse,
+			options...,
+		),
+		authenticateUser: grpctransport.NewServer(
+			ep.AuthenticateUserEndpoint,
+			decodeGRPCAuthenticateUserRequest,
+			encodeGRPCAuthenticateUserResponse,
+			options...,
+		),
+		login: grpctransport.NewServer(
+			ep.LoginEndpoint,
+			decodeGRPCLoginRequest,
+			encodeGRPCLoginResponse,
+			options...,
+		),
+		validateSession: grpctransport.NewServer(
+			ep.ValidateSessionEndpoint,
+			decodeGRPCValidateSessionRequest,
+			encodeGRPCValidateSessionResponse,
+			options...,
+		),
+		revokeSession: grpctransport.NewServer(
+			ep.RevokeSessionEndpoint,
+			decodeGRPCRevokeSessionRequest,
+			encodeGRPCRevokeSessionResponse,
+			options...,
+		),
+		deleteUser: grpctransport.NewServer(
+			ep.DeleteUserEndpoint,
+			decodeGRPCDeleteUserRequest,
+			encodeGRPCDeleteUserResponse,
+			options...,
+		),
+		getUsers: grpctransport.NewServer(
+			ep.GetUsersEndpoint,
+			decodeGRPCGetUsersRequest,
+			encodeGRPCGetUsersResponse,
+			options...,
+		),
+		importUsers: grpctransport.NewServer(
+			ep.ImportUsersEndpoint,
+			decodeGRPCImportUsersRequest,
+			encodeGRPCImportUsersResponse,
+			options...,
+		),
+		unlockUser: grpctransport.NewServer(
+			ep.UnlockUserEndpoint,
+			decodeGRPCUnlockUserRequest,
+			encodeGRPCUnlockUserResponse,
+			options...,
+		),
+		completeMFA: grpctransport.NewServer(
+			ep.CompleteMFAEndpoint,
+			decodeGRPCCompleteMFARequest,
+			encodeGRPCCompleteMFAResponse,
+			options...,
+		),
+		enrollTOTP: grpctransport.NewServer(
+			ep.EnrollTOTPEndpoint,
+			decodeGRPCEnrollTOTPRequest,
+			encodeGRPCEnrollTOTPResponse,
+			options...,
+		),
+		confirmTOTP: grpctransport.NewServer(
+			ep.ConfirmTOTPEndpoint,
+			decodeGRPCConfirmTOTPRequest,
+			encodeGRPCConfirmTOTPResponse,
+			options...,
+		),
+		disableMFA: grpctransport.NewServer(
+			ep.DisableMFAEndpoint,
+			decodeGRPCDisableMFARequest,
+			encodeGRPCDisableMFAResponse,
+			options...,
+		),
+		forgotPassword: grpctransport.NewServer(
+			ep.ForgotPasswordEndpoint,
+			decodeGRPCForgotPasswordRequest,
+			encodeGRPCForgotPasswordResponse,
+			options...,
+		),
+		resetPassword: grpctransport.NewServer(
+			ep.ResetPasswordEndpoint,
+			decodeGRPCResetPasswordRequest,
+			encodeGRPCResetPasswordResponse,
+			options...,
+		),
+		sendVerification: grpctransport.NewServer(
+			ep.SendVerificationEmailEndpoint,
+			decodeGRPCSendVerificationEmailRequest,
+			encodeGRPCSendVerificationEmailResponse,
+			options...,
+		),
+		verifyEmail: grpctransport.NewServer(
+			ep.VerifyEmailEndpoint,
+			decodeGRPCVerifyEmailRequest,
+			encodeGRPCVerifyEmailResponse,
+			options...,
+		),
+		createTeam: grpctransport.NewServer(
+			ep.CreateTeamEndpoint,
+			decodeGRPCCreateTeamRequest,
+			encodeGRPCCreateTeamResponse,
+			options...,
+		),
+		getTeam: grpctransport.NewServer(
+			ep.GetTeamEndpoint,
+			decodeGRPCGetTeamRequest,
+			encodeGRPCGetTeamResponse,
+			options...,
+		),
+		getTeams: grpctransport.NewServer(
+			ep.GetTeamsEndpoint,
+			decodeGRPCGetTeamsRequest,
+			encodeGRPCGetTeamsResponse,
+			options...,
+		),
+		deleteTeam: grpctransport.NewServer(
+			ep.DeleteTeamEndpoint,
+			decodeGRPCDeleteTeamRequest,
+			encodeGRPCDeleteTeamResponse,
+			options...,
+		),
+		inviteMember: grpctransport.NewServer(
+			ep.InviteMemberEndpoint,
+			decodeGRPCInviteMemberRequest,
+			encodeGRPCInviteMemberResponse,
+			options...,
+		),
+		acceptInvitation: grpctransport.NewServer(
+			ep.AcceptInvitationEndpoint,
+			decodeGRPCAcceptInvitationRequest,
+			encodeGRPCAcceptInvitationResponse,
+			options...,
+		),
+		updateMember: grpctransport.NewServer(
+			ep.UpdateMemberEndpoint,
+			decodeGRPCUpdateMemberRequest,
+			encodeGRPCUpdateMemberResponse,
+			options...,
+		),
+		removeMember: grpctransport.NewServer(
+			ep.RemoveMemberEndpoint,
+			decodeGRPCRemoveMemberRequest,
+			encodeGRPCRemoveMemberResponse,
+			options...,
+		),
+		createAPIKey: grpctransport.NewServer(
+			ep.CreateAPIKeyEndpoint,
+			decodeGRPCCreateAPIKeyRequest,
+			encodeGRPCCreateAPIKeyResponse,
+			options...,
+		),
+		getAPIKeys: grpctransport.NewServer(
+			ep.GetAPIKeysEndpoint,
+			decodeGRPCGetAPIKeysRequest,
+			encodeGRPCGetAPIKeysResponse,
+			options...,
+		),
+		revokeAPIKey: grpctransport.NewServer(
+			ep.RevokeAPIKeyEndpoint,
+			decodeGRPCRevokeAPIKeyRequest,
+			encodeGRPCRevokeAPIKeyResponse,
+			options...,
+		),
+		validateAPIKey: grpctransport.NewServer(
+			ep.ValidateAPIKeyEndpoint,
+			decodeGRPCValidateAPIKeyRequest,
+			encodeGRPCValidateAPIKeyResponse,
+			options...,
+		),
+		registerClient: grpctransport.NewServer(
+			ep.RegisterClientEndpoint,
+			decodeGRPCRegisterClientRequest,
+			encodeGRPCRegisterClientResponse,
+			options...,
+		),
+		getClient: grpctransport.NewServer(
+			ep.GetClientEndpoint,
+			decodeGRPCGetClientRequest,
+			encodeGRPCGetClientResponse,
+			options...,
+		),
+		authorize: grpctransport.NewServer(
+			ep.AuthorizeEndpoint,
+			decodeGRPCAuthorizeRequest,
+			encodeGRPCAuthorizeResponse,
+			options...,
+		),
+		exchangeToken: grpctransport.NewServer(
+			ep.ExchangeTokenEndpoint,
+			decodeGRPCExchangeTokenRequest,
+			encodeGRPCExchangeTokenResponse,
+			options...,
+		),
+		revokeToken: grpctransport.NewServer(
+			ep.RevokeTokenEndpoint,
+			decodeGRPCRevokeTokenRequest,
+			encodeGRPCRevokeTokenResponse,
+			options...,
+		),
+		introspectToken: grpctransport.NewServer(
+			ep.IntrospectTokenEndpoint,
+			decodeGRPCIntrospectTokenRequest,
+			encodeGRPCIntrospectTokenResponse,
+			options...,
+		),
+		validateAccessToken: grpctransport.NewServer(
+			ep.ValidateAccessTokenEndpoint,
+			decodeGRPCValidateAccessTokenRequest,
+			encodeGRPCValidateAccessTokenResponse,
+			options...,
+		),
+		openIDConfiguration: grpctransport.NewServer(
+			ep.OpenIDConfigurationEndpoint,
+			decodeGRPCOpenIDConfigurationRequest,
+			encodeGRPCOpenIDConfigurationResponse,
+			options...,
+		),
+		getJWKS: grpctransport.NewServer(
+			ep.GetJWKSEndpoint,
+			decodeGRPCGetJWKSRequest,
+			encodeGRPCGetJWKSResponse,
+			options...,
+		),
+		userInfo: grpctransport.NewServer(
+			ep.UserInfoEndpoint,
+			decodeGRPCUserInfoRequest,
+			encodeGRPCUserInfoResponse,
+			options...,
+		),
+		startFederatedLogin: grpctransport.NewServer(
+			ep.StartFederatedLoginEndpoint,
+			decodeGRPCStartFederatedLoginRequest,
+			encodeGRPCStartFederatedLoginResponse,
+			options...,
+		),
+		completeFederatedLogin: grpctransport.NewServer(
+			ep.CompleteFederatedLoginEndpoint,
+			decodeGRPCCompleteFederatedLoginRequest,
+			encodeGRPCCompleteFederatedLoginResponse,
+			options...,
+		),
+		getIdentities: grpctransport.NewServer(
+			ep.GetIdentitiesEndpoint,
+			decodeGRPCGetIdentitiesRequest,
+			encodeGRPCGetIdentitiesResponse,
+			options...,
+		),
+		listUsers: grpctransport.NewServer(
+			ep.ListUsersEndpoint,
+			decodeGRPCListUsersRequest,
+			encodeGRPCListUsersResponse,
+			options...,
+		),
+		validateProvisioning: grpctransport.NewServer(
+			ep.ValidateProvisioningTokenEndpoint,
+			decodeGRPCValidateProvisioningTokenRequest,
+			encodeGRPCValidateProvisioningTokenResponse,
+			options...,
+		),
+		provisionUser: grpctransport.NewServer(
+			ep.ProvisionUserEndpoint,
+			decodeGRPCProvisionUserRequest,
+			encodeGRPCProvisionUserResponse,
+			options...,
+		),
+		setUserActive: grpctransport.NewServer(
+			ep.SetUserActiveEndpoint,
+			decodeGRPCSetUserActiveRequest,
+			encodeGRPCSetUserActiveResponse,
+			options...,
+		),
+		listTeams: grpctransport.NewServer(
+			ep.ListTeamsEndpoint,
+			decodeGRPCListTeamsRequest,
+			encodeGRPCListTeamsResponse,
+			options...,
+		),
+		updateTeam: grpctransport.NewServer(
+			ep.UpdateTeamEndpoint,
+			decodeGRPCUpdateTeamRequest,
+			encodeGRPCUpdateTeamResponse,
+			options...,
+		),
+		setMembers: grpctransport.NewServer(
+			ep.SetMembersEndpoint,
+			decodeGRPCSetMembersRequest,
+			encodeGRPCSetMembersResponse,
+			options...,
+		),
+		serviceStatus: grpctransport.NewServer(
+			ep.ServiceStatusEndpoint,
+			decodeGRPCServiceStatusRequest,
+			encodeGRPCServiceStatusResponse,
+			options...,
+		),
+	}
+}
+
+func (s *grpcServer) AddUser(ctx context.Context, req *pb.AddUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.addUser, req)
+}
+
+func (s *grpcServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.getUser, req)
+}
+
+func (s *grpcServer) FindUser(ctx context.Context, req *pb.FindUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.findUser, req)
+}
+
+func (s *grpcServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.updateUser, req)
+}
+
+func (s *grpcServer) AuthenticateUser(ctx context.Context, req *pb.AuthenticateUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.authenticateUser, req)
+}
+
+func (s *grpcServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.SessionReply, error) {
+	return serveSession(ctx, s.login, req)
+}
+
+func (s *grpcServer) ValidateSession(ctx context.Context, req *pb.ValidateSessionRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.validateSession, req)
+}
+
+func (s *grpcServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionReply, error) {
+	_, rep, err := s.revokeSession.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.RevokeSessionReply), nil
+}
+
+func (s *grpcServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserReply, error) {
+	_, rep, err := s.deleteUser.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.DeleteUserReply), nil
+}
+
+func (s *grpcServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersReply, error) {
+	_, rep, err := s.getUsers.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetUsersReply), nil
+}
+
+func (s *grpcServer) ImportUsers(ctx context.Context, req *pb.ImportUsersRequest) (*pb.ImportUsersReply, error) {
+	_, rep, err := s.importUsers.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ImportUsersReply), nil
+}
+
+func (s *grpcServer) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserReply, error) {
+	_, rep, err := s.unlockUser.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.UnlockUserReply), nil
+}
+
+func (s *grpcServer) CompleteMFA(ctx context.Context, req *pb.CompleteMFARequest) (*pb.SessionReply, error) {
+	return serveSession(ctx, s.completeMFA, req)
+}
+
+func (s *grpcServer) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPReply, error) {
+	_, rep, err := s.enrollTOTP.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.EnrollTOTPReply), nil
+}
+
+func (s *grpcServer) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPReply, error) {
+	_, rep, err := s.confirmTOTP.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ConfirmTOTPReply), nil
+}
+
+func (s *grpcServer) DisableMFA(ctx context.Context, req *pb.DisableMFARequest) (*pb.DisableMFAReply, error) {
+	_, rep, err := s.disableMFA.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.DisableMFAReply), nil
+}
+
+func (s *grpcServer) ForgotPassword(ctx context.Context, req *pb.ForgotPasswordRequest) (*pb.ForgotPasswordReply, error) {
+	_, rep, err := s.forgotPassword.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ForgotPasswordReply), nil
+}
+
+func (s *grpcServer) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordReply, error) {
+	_, rep, err := s.resetPassword.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ResetPasswordReply), nil
+}
+
+func (s *grpcServer) SendVerificationEmail(ctx context.Context, req *pb.SendVerificationEmailRequest) (*pb.SendVerificationEmailReply, error) {
+	_, rep, err := s.sendVerification.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.SendVerificationEmailReply), nil
+}
+
+func (s *grpcServer) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.verifyEmail, req)
+}
+
+func (s *grpcServer) CreateTeam(ctx context.Context, req *pb.CreateTeamRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.createTeam, req)
+}
+
+func (s *grpcServer) GetTeam(ctx context.Context, req *pb.GetTeamRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.getTeam, req)
+}
+
+func (s *grpcServer) GetTeams(ctx context.Context, req *pb.GetTeamsRequest) (*pb.GetTeamsReply, error) {
+	_, rep, err := s.getTeams.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetTeamsReply), nil
+}
+
+func (s *grpcServer) DeleteTeam(ctx context.Context, req *pb.DeleteTeamRequest) (*pb.DeleteTeamReply, error) {
+	_, rep, err := s.deleteTeam.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.DeleteTeamReply), nil
+}
+
+func (s *grpcServer) InviteMember(ctx context.Context, req *pb.InviteMemberRequest) (*pb.InviteMemberReply, error) {
+	_, rep, err := s.inviteMember.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.InviteMemberReply), nil
+}
+
+func (s *grpcServer) AcceptInvitation(ctx context.Context, req *pb.AcceptInvitationRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.acceptInvitation, req)
+}
+
+func (s *grpcServer) UpdateMember(ctx context.Context, req *pb.UpdateMemberRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.updateMember, req)
+}
+
+func (s *grpcServer) RemoveMember(ctx context.Context, req *pb.RemoveMemberRequest) (*pb.RemoveMemberReply, error) {
+	_, rep, err := s.removeMember.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.RemoveMemberReply), nil
+}
+
+func (s *grpcServer) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.APIKeyReply, error) {
+	return serveAPIKey(ctx, s.createAPIKey, req)
+}
+
+func (s *grpcServer) GetAPIKeys(ctx context.Context, req *pb.GetAPIKeysRequest) (*pb.GetAPIKeysReply, error) {
+	_, rep, err := s.getAPIKeys.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetAPIKeysReply), nil
+}
+
+func (s *grpcServer) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyReply, error) {
+	_, rep, err := s.revokeAPIKey.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.RevokeAPIKeyReply), nil
+}
+
+func (s *grpcServer) ValidateAPIKey(ctx context.Context, req *pb.ValidateAPIKeyRequest) (*pb.APIKeyReply, error) {
+	return serveAPIKey(ctx, s.validateAPIKey, req)
+}
+
+func (s *grpcServer) RegisterClient(ctx context.Context, req *pb.RegisterClientRequest) (*pb.ClientReply, error) {
+	return serveClient(ctx, s.registerClient, req)
+}
+
+func (s *grpcServer) GetClient(ctx context.Context, req *pb.GetClientRequest) (*pb.ClientReply, error) {
+	return serveClient(ctx, s.getClient, req)
+}
+
+func (s *grpcServer) Authorize(ctx context.Context, req *pb.AuthorizeRequest) (*pb.AuthorizeReply, error) {
+	_, rep, err := s.authorize.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.AuthorizeReply), nil
+}
+
+func (s *grpcServer) ExchangeToken(ctx context.Context, req *pb.ExchangeTokenRequest) (*pb.TokenReply, error) {
+	_, rep, err := s.exchangeToken.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.TokenReply), nil
+}
+
+func (s *grpcServer) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenReply, error) {
+	_, rep, err := s.revokeToken.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.RevokeTokenReply), nil
+}
+
+func (s *grpcServer) IntrospectToken(ctx context.Context, req *pb.IntrospectTokenRequest) (*pb.IntrospectTokenReply, error) {
+	_, rep, err := s.introspectToken.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.IntrospectTokenReply), nil
+}
+
+func (s *grpcServer) ValidateAccessToken(ctx context.Context, req *pb.ValidateAccessTokenRequest) (*pb.AccessTokenReply, error) {
+	_, rep, err := s.validateAccessToken.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.AccessTokenReply), nil
+}
+
+func (s *grpcServer) OpenIDConfiguration(ctx context.Context, req *pb.OpenIDConfigurationRequest) (*pb.OpenIDConfigurationReply, error) {
+	_, rep, err := s.openIDConfiguration.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.OpenIDConfigurationReply), nil
+}
+
+func (s *grpcServer) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.JWKSReply, error) {
+	_, rep, err := s.getJWKS.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.JWKSReply), nil
+}
+
+func (s *grpcServer) UserInfo(ctx context.Context, req *pb.UserInfoRequest) (*pb.UserInfoReply, error) {
+	_, rep, err := s.userInfo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.UserInfoReply), nil
+}
+
+func (s *grpcServer) StartFederatedLogin(ctx context.Context, req *pb.StartFederatedLoginRequest) (*pb.StartFederatedLoginReply, error) {
+	_, rep, err := s.startFederatedLogin.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.StartFederatedLoginReply), nil
+}
+
+func (s *grpcServer) CompleteFederatedLogin(ctx context.Context, req *pb.CompleteFederatedLoginRequest) (*pb.SessionReply, error) {
+	return serveSession(ctx, s.completeFederatedLogin, req)
+}
+
+func (s *grpcServer) GetIdentities(ctx context.Context, req *pb.GetIdentitiesRequest) (*pb.GetIdentitiesReply, error) {
+	_, rep, err := s.getIdentities.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetIdentitiesReply), nil
+}
+
+func (s *grpcServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersReply, error) {
+	_, rep, err := s.listUsers.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ListUsersReply), nil
+}
+
+func (s *grpcServer) ValidateProvisioningToken(ctx context.Context, req *pb.ValidateProvisioningTokenRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.validateProvisioning, req)
+}
+
+func (s *grpcServer) ProvisionUser(ctx context.Context, req *pb.ProvisionUserRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.provisionUser, req)
+}
+
+func (s *grpcServer) SetUserActive(ctx context.Context, req *pb.SetUserActiveRequest) (*pb.UserReply, error) {
+	return serveUser(ctx, s.setUserActive, req)
+}
+
+func (s *grpcServer) ListTeams(ctx context.Context, req *pb.ListTeamsRequest) (*pb.ListTeamsReply, error) {
+	_, rep, err := s.listTeams.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ListTeamsReply), nil
+}
+
+func (s *grpcServer) UpdateTeam(ctx context.Context, req *pb.UpdateTeamRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.updateTeam, req)
+}
+
+func (s *grpcServer) SetMembers(ctx context.Context, req *pb.SetMembersRequest) (*pb.TeamReply, error) {
+	return serveTeam(ctx, s.setMembers, req)
+}
+
+func (s *grpcServer) ServiceStatus(ctx context.Context, req *pb.ServiceStatusRequest) (*pb.ServiceStatusReply, error) {
+	_, rep, err := s.serviceStatus.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ServiceStatusReply), nil
+}
+
+// serveUser serves all rpcs replying with a single user
+func serveUser(ctx context.Context, h grpctransport.Handler, req interface{}) (*pb.UserReply, error) {
+	_, rep, err := h.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.UserReply), nil
+}
+
+// serveSession serves all rpcs replying with a session
+func serveSession(ctx context.Context, h grpctransport.Handler, req interface{}) (*pb.SessionReply, error) {
+	_, rep, err := h.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.SessionReply), nil
+}
+
+// serveTeam serves all rpcs replying with a single team
+func serveTeam(ctx context.Context, h grpctransport.Handler, req interface{}) (*pb.TeamReply, error) {
+	_, rep, err := h.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.TeamReply), nil
+}
+
+// serveAPIKey serves all rpcs replying with a single API key
+func serveAPIKey(ctx context.Context, h grpctransport.Handler, req interface{}) (*pb.APIKeyReply, error) {
+	_, rep, err := h.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.APIKeyReply), nil
+}
+
+// serveClient serves all rpcs replying with a single OAuth2 client
+func serveClient(ctx context.Context, h grpctransport.Handler, req interface{}) (*pb.ClientReply, error) {
+	_, rep, err := h.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ClientReply), nil
+}
+
+// SessionTokenFromMD passes the bearer token in the authorization metadata
+// to the authorization middleware, servers of other services use it to
+// forward the token to the authorization service
+func SessionTokenFromMD(ctx context.Context, md metadata.MD) context.Context {
+	if v := md.Get("authorization"); len(v) > 0 {
+		if token := bearerToken(http.Header{"Authorization": v[:1]}); token != "" {
+			return authorization.WithSessionToken(ctx, token)
+		}
+	}
+	return ctx
+}
+
+// SessionTokenToMD sends the session token in the context as bearer token
+func SessionTokenToMD(ctx context.Context, md *metadata.MD) context.Context {
+	if token := authorization.SessionToken(ctx); token != "" {
+		md.Set("authorization", "Bearer "+token)
+	}
+	return ctx
+}
+
+// peerIPToContext passes the address of the client to the service to
+// throttle failed logins per ip address
+func peerIPToContext(ctx context.Context, _ metadata.MD) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return authorization.WithClientIP(ctx, host)
+}
+
+// MakeGRPCClientEndpoints returns an Endpoints struct where each endpoint invokes
+// the corresponding method on the remote instance, via a transport/grpc.Client.
+func MakeGRPCClientEndpoints(conn *grpc.ClientConn) ep.Endpoints {
+	options := []grpctransport.ClientOption{
+		grpctransport.ClientBefore(SessionTokenToMD),
+	}
+	var getUser = grpctransport.NewClient(conn, grpcServiceName, "GetUser", encodeGRPCGetUserRequest, decodeGRPCGetUserResponse, pb.UserReply{}, options...).Endpoint()
+	var findUser = grpctransport.NewClient(conn, grpcServiceName, "FindUser", encodeGRPCFindUserRequest, decodeGRPCGetUserResponse, pb.UserReply{}, options...).Endpoint()
+
+	return ep.Endpoints{
+		AddUserEndpoint: grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "AddUser", encodeGRPCAddUserRequest, decodeGRPCAddUserResponse, pb.UserReply{}, options...).Endpoint()),
+		// GetUserEndpoint serves both rpcs, just like it does for http
+		GetUserEndpoint: grpcClientErrors(func(ctx context.Context, request interface{}) (interface{}, error) {
+			if request.(ep.GetUserRequest).User.ID == uuid.Nil {
+				return findUser(ctx, request)
+			}
+			return getUser(ctx, request)
+		}),
+		UpdateUserEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UpdateUser", encodeGRPCUpdateUserRequest, decodeGRPCUpdateUserResponse, pb.UserReply{}, options...).Endpoint()),
+		AuthenticateUserEndpoint:          grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "AuthenticateUser", encodeGRPCAuthenticateUserRequest, decodeGRPCAuthenticateUserResponse, pb.UserReply{}, options...).Endpoint()),
+		LoginEndpoint:                     grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "Login", encodeGRPCLoginRequest, decodeGRPCLoginResponse, pb.SessionReply{}, options...).Endpoint()),
+		ValidateSessionEndpoint:           grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ValidateSession", encodeGRPCValidateSessionRequest, decodeGRPCValidateSessionResponse, pb.UserReply{}, options...).Endpoint()),
+		RevokeSessionEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RevokeSession", encodeGRPCRevokeSessionRequest, decodeGRPCRevokeSessionResponse, pb.RevokeSessionReply{}, options...).Endpoint()),
+		DeleteUserEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "DeleteUser", encodeGRPCDeleteUserRequest, decodeGRPCDeleteUserResponse, pb.DeleteUserReply{}, options...).Endpoint()),
+		GetUsersEndpoint:                  grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetUsers", encodeGRPCGetUsersRequest, decodeGRPCGetUsersResponse, pb.GetUsersReply{}, options...).Endpoint()),
+		ImportUsersEndpoint:               grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ImportUsers", encodeGRPCImportUsersRequest, decodeGRPCImportUsersResponse, pb.ImportUsersReply{}, options...).Endpoint()),
+		UnlockUserEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UnlockUser", encodeGRPCUnlockUserRequest, decodeGRPCUnlockUserResponse, pb.UnlockUserReply{}, options...).Endpoint()),
+		CompleteMFAEndpoint:               grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "CompleteMFA", encodeGRPCCompleteMFARequest, decodeGRPCCompleteMFAResponse, pb.SessionReply{}, options...).Endpoint()),
+		EnrollTOTPEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "EnrollTOTP", encodeGRPCEnrollTOTPRequest, decodeGRPCEnrollTOTPResponse, pb.EnrollTOTPReply{}, options...).Endpoint()),
+		ConfirmTOTPEndpoint:               grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ConfirmTOTP", encodeGRPCConfirmTOTPRequest, decodeGRPCConfirmTOTPResponse, pb.ConfirmTOTPReply{}, options...).Endpoint()),
+		DisableMFAEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "DisableMFA", encodeGRPCDisableMFARequest, decodeGRPCDisableMFAResponse, pb.DisableMFAReply{}, options...).Endpoint()),
+		ForgotPasswordEndpoint:            grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ForgotPassword", encodeGRPCForgotPasswordRequest, decodeGRPCForgotPasswordResponse, pb.ForgotPasswordReply{}, options...).Endpoint()),
+		ResetPasswordEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ResetPassword", encodeGRPCResetPasswordRequest, decodeGRPCResetPasswordResponse, pb.ResetPasswordReply{}, options...).Endpoint()),
+		SendVerificationEmailEndpoint:     grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "SendVerificationEmail", encodeGRPCSendVerificationEmailRequest, decodeGRPCSendVerificationEmailResponse, pb.SendVerificationEmailReply{}, options...).Endpoint()),
+		VerifyEmailEndpoint:               grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "VerifyEmail", encodeGRPCVerifyEmailRequest, decodeGRPCVerifyEmailResponse, pb.UserReply{}, options...).Endpoint()),
+		CreateTeamEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "CreateTeam", encodeGRPCCreateTeamRequest, decodeGRPCCreateTeamResponse, pb.TeamReply{}, options...).Endpoint()),
+		GetTeamEndpoint:                   grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetTeam", encodeGRPCGetTeamRequest, decodeGRPCGetTeamResponse, pb.TeamReply{}, options...).Endpoint()),
+		GetTeamsEndpoint:                  grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetTeams", encodeGRPCGetTeamsRequest, decodeGRPCGetTeamsResponse, pb.GetTeamsReply{}, options...).Endpoint()),
+		DeleteTeamEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "DeleteTeam", encodeGRPCDeleteTeamRequest, decodeGRPCDeleteTeamResponse, pb.DeleteTeamReply{}, options...).Endpoint()),
+		InviteMemberEndpoint:              grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "InviteMember", encodeGRPCInviteMemberRequest, decodeGRPCInviteMemberResponse, pb.InviteMemberReply{}, options...).Endpoint()),
+		AcceptInvitationEndpoint:          grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "AcceptInvitation", encodeGRPCAcceptInvitationRequest, decodeGRPCAcceptInvitationResponse, pb.TeamReply{}, options...).Endpoint()),
+		UpdateMemberEndpoint:              grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UpdateMember", encodeGRPCUpdateMemberRequest, decodeGRPCUpdateMemberResponse, pb.TeamReply{}, options...).Endpoint()),
+		RemoveMemberEndpoint:              grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RemoveMember", encodeGRPCRemoveMemberRequest, decodeGRPCRemoveMemberResponse, pb.RemoveMemberReply{}, options...).Endpoint()),
+		CreateAPIKeyEndpoint:              grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "CreateAPIKey", encodeGRPCCreateAPIKeyRequest, decodeGRPCCreateAPIKeyResponse, pb.APIKeyReply{}, options...).Endpoint()),
+		GetAPIKeysEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetAPIKeys", encodeGRPCGetAPIKeysRequest, decodeGRPCGetAPIKeysResponse, pb.GetAPIKeysReply{}, options...).Endpoint()),
+		RevokeAPIKeyEndpoint:              grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RevokeAPIKey", encodeGRPCRevokeAPIKeyRequest, decodeGRPCRevokeAPIKeyResponse, pb.RevokeAPIKeyReply{}, options...).Endpoint()),
+		ValidateAPIKeyEndpoint:            grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ValidateAPIKey", encodeGRPCValidateAPIKeyRequest, decodeGRPCValidateAPIKeyResponse, pb.APIKeyReply{}, options...).Endpoint()),
+		RegisterClientEndpoint:            grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RegisterClient", encodeGRPCRegisterClientRequest, decodeGRPCRegisterClientResponse, pb.ClientReply{}, options...).Endpoint()),
+		GetClientEndpoint:                 grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetClient", encodeGRPCGetClientRequest, decodeGRPCGetClientResponse, pb.ClientReply{}, options...).Endpoint()),
+		AuthorizeEndpoint:                 grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "Authorize", encodeGRPCAuthorizeRequest, decodeGRPCAuthorizeResponse, pb.AuthorizeReply{}, options...).Endpoint()),
+		ExchangeTokenEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ExchangeToken", encodeGRPCExchangeTokenRequest, decodeGRPCExchangeTokenResponse, pb.TokenReply{}, options...).Endpoint()),
+		RevokeTokenEndpoint:               grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RevokeToken", encodeGRPCRevokeTokenRequest, decodeGRPCRevokeTokenResponse, pb.RevokeTokenReply{}, options...).Endpoint()),
+		IntrospectTokenEndpoint:           grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "IntrospectToken", encodeGRPCIntrospectTokenRequest, decodeGRPCIntrospectTokenResponse, pb.IntrospectTokenReply{}, options...).Endpoint()),
+		ValidateAccessTokenEndpoint:       grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ValidateAccessToken", encodeGRPCValidateAccessTokenRequest, decodeGRPCValidateAccessTokenResponse, pb.AccessTokenReply{}, options...).Endpoint()),
+		OpenIDConfigurationEndpoint:       grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "OpenIDConfiguration", encodeGRPCOpenIDConfigurationRequest, decodeGRPCOpenIDConfigurationResponse, pb.OpenIDConfigurationReply{}, options...).Endpoint()),
+		GetJWKSEndpoint:                   grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetJWKS", encodeGRPCGetJWKSRequest, decodeGRPCGetJWKSResponse, pb.JWKSReply{}, options...).Endpoint()),
+		UserInfoEndpoint:                  grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UserInfo", encodeGRPCUserInfoRequest, decodeGRPCUserInfoResponse, pb.UserInfoReply{}, options...).Endpoint()),
+		StartFederatedLoginEndpoint:       grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "StartFederatedLogin", encodeGRPCStartFederatedLoginRequest, decodeGRPCStartFederatedLoginResponse, pb.StartFederatedLoginReply{}, options...).Endpoint()),
+		CompleteFederatedLoginEndpoint:    grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "CompleteFederatedLogin", encodeGRPCCompleteFederatedLoginRequest, decodeGRPCCompleteFederatedLoginResponse, pb.SessionReply{}, options...).Endpoint()),
+		GetIdentitiesEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetIdentities", encodeGRPCGetIdentitiesRequest, decodeGRPCGetIdentitiesResponse, pb.GetIdentitiesReply{}, options...).Endpoint()),
+		ListUsersEndpoint:                 grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ListUsers", encodeGRPCListUsersRequest, decodeGRPCListUsersResponse, pb.ListUsersReply{}, options...).Endpoint()),
+		ValidateProvisioningTokenEndpoint: grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ValidateProvisioningToken", encodeGRPCValidateProvisioningTokenRequest, decodeGRPCValidateProvisioningTokenResponse, pb.UserReply{}, options...).Endpoint()),
+		ProvisionUserEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ProvisionUser", encodeGRPCProvisionUserRequest, decodeGRPCProvisionUserResponse, pb.UserReply{}, options...).Endpoint()),
+		SetUserActiveEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "SetUserActive", encodeGRPCSetUserActiveRequest, decodeGRPCSetUserActiveResponse, pb.UserReply{}, options...).Endpoint()),
+		ListTeamsEndpoint:                 grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ListTeams", encodeGRPCListTeamsRequest, decodeGRPCListTeamsResponse, pb.ListTeamsReply{}, options...).Endpoint()),
+		UpdateTeamEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UpdateTeam", encodeGRPCUpdateTeamRequest, decodeGRPCUpdateTeamResponse, pb.TeamReply{}, options...).Endpoint()),
+		SetMembersEndpoint:                grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "SetMembers", encodeGRPCSetMembersRequest, decodeGRPCSetMembersResponse, pb.TeamReply{}, options...).Endpoint()),
+		ServiceStatusEndpoint:             grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ServiceStatus", encodeGRPCServiceStatusRequest, decodeGRPCServiceStatusResponse, pb.ServiceStatusReply{}, options...).Endpoint()),
+	}
+}
+
+// NewGRPCClient returns an authorization.Service backed by a remote
+// authorization service over gRPC.
+func NewGRPCClient(conn *grpc.ClientConn) authorization.Service {
+	return MakeGRPCClientEndpoints(conn)
+}
+
+// Server functions
+// decode requests from client
+
+func decodeGRPCAddUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AddUserRequest)
+	u, err := userFromPB(req.User)
+	if err != nil {
+		return nil, err
+	}
+	return ep.AddUserRequest{User: u}, nil
+}
+
+func decodeGRPCGetUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetUserRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetUserRequest{User: authorization.User{ID: id}}, nil
+}
+
+func decodeGRPCFindUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.FindUserRequest)
+	return ep.GetUserRequest{User: authorization.User{Username: req.Username}}, nil
+}
+
+func decodeGRPCUpdateUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UpdateUserRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	u, err := userFromPB(req.User)
+	if err != nil {
+		return nil, err
+	}
+	return ep.UpdateUserRequest{ID: id, User: u}, nil
+}
+
+func decodeGRPCAuthenticateUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AuthenticateUserRequest)
+	u, err := userFromPB(req.User)
+	if err != nil {
+		return nil, err
+	}
+	return ep.AuthenticateUserRequest{User: u}, nil
+}
+
+func decodeGRPCLoginRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.LoginRequest)
+	u, err := userFromPB(req.User)
+	if err != nil {
+		return nil, err
+	}
+	return ep.LoginRequest{User: u}, nil
+}
+
+func decodeGRPCValidateSessionRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ValidateSessionRequest)
+	return ep.ValidateSessionRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCRevokeSessionRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RevokeSessionRequest)
+	return ep.RevokeSessionRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCDeleteUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DeleteUserRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.DeleteUserRequest{ID: id}, nil
+}
+
+func decodeGRPCGetUsersRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.GetUsersRequest)
+	return ep.GetUsersRequest{}, nil
+}
+
+func decodeGRPCImportUsersRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ImportUsersRequest)
+	users := make([]authorization.ImportedUser, len(req.Users))
+	for i, u := range req.Users {
+		var id uuid.UUID
+		if u.Id != "" {
+			var err error
+			id, err = uuid.Parse(u.Id)
+			if err != nil {
+				return nil, authorization.ErrInvalidUUID
+			}
+		}
+		users[i] = authorization.ImportedUser{ID: id, Username: u.Username, PasswordHash: u.PasswordHash}
+	}
+	return ep.ImportUsersRequest{Users: users}, nil
+}
+
+func decodeGRPCUnlockUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UnlockUserRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.UnlockUserRequest{ID: id}, nil
+}
+
+func decodeGRPCCompleteMFARequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.CompleteMFARequest)
+	return ep.CompleteMFARequest{Challenge: req.MfaChallenge, Code: req.Code}, nil
+}
+
+func decodeGRPCEnrollTOTPRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.EnrollTOTPRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.EnrollTOTPRequest{ID: id}, nil
+}
+
+func decodeGRPCConfirmTOTPRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ConfirmTOTPRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.ConfirmTOTPRequest{ID: id, Code: req.Code}, nil
+}
+
+func decodeGRPCDisableMFARequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DisableMFARequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.DisableMFARequest{ID: id, Code: req.Code}, nil
+}
+
+func decodeGRPCForgotPasswordRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ForgotPasswordRequest)
+	return ep.ForgotPasswordRequest{Email: req.Email}, nil
+}
+
+func decodeGRPCResetPasswordRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ResetPasswordRequest)
+	return ep.ResetPasswordRequest{Token: req.Token, Password: req.Password}, nil
+}
+
+func decodeGRPCSendVerificationEmailRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SendVerificationEmailRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.SendVerificationEmailRequest{ID: id}, nil
+}
+
+func decodeGRPCVerifyEmailRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.VerifyEmailRequest)
+	return ep.VerifyEmailRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCCreateTeamRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.CreateTeamRequest)
+	t, err := teamFromPB(req.Team)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := optionalUUIDFromPB(req.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+	return ep.CreateTeamRequest{Team: t, OwnerID: owner}, nil
+}
+
+func decodeGRPCGetTeamRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetTeamRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetTeamRequest{ID: id}, nil
+}
+
+func decodeGRPCGetTeamsRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetTeamsRequest)
+	id, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetTeamsRequest{UserID: id}, nil
+}
+
+func decodeGRPCDeleteTeamRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DeleteTeamRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.DeleteTeamRequest{ID: id}, nil
+}
+
+func decodeGRPCInviteMemberRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.InviteMemberRequest)
+	id, err := uuid.Parse(req.TeamId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.InviteMemberRequest{TeamID: id, Role: authorization.TeamRole(req.Role)}, nil
+}
+
+func decodeGRPCAcceptInvitationRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AcceptInvitationRequest)
+	user, err := optionalUUIDFromPB(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return ep.AcceptInvitationRequest{Token: req.Token, UserID: user}, nil
+}
+
+func decodeGRPCUpdateMemberRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UpdateMemberRequest)
+	teamID, err := uuid.Parse(req.TeamId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.UpdateMemberRequest{TeamID: teamID, UserID: userID, Role: authorization.TeamRole(req.Role)}, nil
+}
+
+func decodeGRPCRemoveMemberRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RemoveMemberRequest)
+	teamID, err := uuid.Parse(req.TeamId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.RemoveMemberRequest{TeamID: teamID, UserID: userID}, nil
+}
+
+func decodeGRPCCreateAPIKeyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.CreateAPIKeyRequest)
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	k, err := apiKeyFromPB(req.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	return ep.CreateAPIKeyRequest{UserID: userID, APIKey: k}, nil
+}
+
+func decodeGRPCGetAPIKeysRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetAPIKeysRequest)
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetAPIKeysRequest{UserID: userID}, nil
+}
+
+func decodeGRPCRevokeAPIKeyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RevokeAPIKeyRequest)
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.RevokeAPIKeyRequest{UserID: userID, ID: id}, nil
+}
+
+func decodeGRPCValidateAPIKeyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ValidateAPIKeyRequest)
+	return ep.ValidateAPIKeyRequest{Key: req.Key}, nil
+}
+
+func decodeGRPCRegisterClientRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RegisterClientRequest)
+	ownerID, err := optionalUUIDFromPB(req.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+	c, err := clientFromPB(req.Client)
+	if err != nil {
+		return nil, err
+	}
+	return ep.RegisterClientRequest{Client: c, OwnerID: ownerID}, nil
+}
+
+func decodeGRPCGetClientRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetClientRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetClientRequest{ID: id}, nil
+}
+
+func decodeGRPCAuthorizeRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AuthorizeRequest)
+	clientID, err := uuid.Parse(req.ClientId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	userID, err := optionalUUIDFromPB(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return ep.AuthorizeRequest{Request: authorization.AuthorizationRequest{
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectUri,
+		Scopes:              scopesFromPB(req.Scopes),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	}}, nil
+}
+
+func decodeGRPCExchangeTokenRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ExchangeTokenRequest)
+	client, err := clientCredentialsFromPB(req.Client)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ExchangeTokenRequest{Request: authorization.TokenRequest{
+		GrantType:    req.GrantType,
+		Client:       client,
+		Code:         req.Code,
+		RedirectURI:  req.RedirectUri,
+		CodeVerifier: req.CodeVerifier,
+		RefreshToken: req.RefreshToken,
+		Scopes:       scopesFromPB(req.Scopes),
+	}}, nil
+}
+
+func decodeGRPCRevokeTokenRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RevokeTokenRequest)
+	client, err := clientCredentialsFromPB(req.Client)
+	if err != nil {
+		return nil, err
+	}
+	return ep.RevokeTokenRequest{Token: req.Token, Client: client}, nil
+}
+
+func decodeGRPCIntrospectTokenRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.IntrospectTokenRequest)
+	client, err := clientCredentialsFromPB(req.Client)
+	if err != nil {
+		return nil, err
+	}
+	return ep.IntrospectTokenRequest{Token: req.Token, Client: client}, nil
+}
+
+func decodeGRPCValidateAccessTokenRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ValidateAccessTokenRequest)
+	return ep.ValidateAccessTokenRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCOpenIDConfigurationRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.OpenIDConfigurationRequest)
+	return ep.OpenIDConfigurationRequest{}, nil
+}
+
+func decodeGRPCGetJWKSRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.GetJWKSRequest)
+	return ep.GetJWKSRequest{}, nil
+}
+
+func decodeGRPCUserInfoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UserInfoRequest)
+	return ep.UserInfoRequest{Token: req.AccessToken}, nil
+}
+
+func decodeGRPCStartFederatedLoginRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.StartFederatedLoginRequest)
+	userID, err := optionalUUIDFromPB(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return ep.StartFederatedLoginRequest{Provider: req.Provider, UserID: userID}, nil
+}
+
+func decodeGRPCCompleteFederatedLoginRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.CompleteFederatedLoginRequest)
+	return ep.CompleteFederatedLoginRequest{Provider: req.Provider, State: req.State, Code: req.Code}, nil
+}
+
+func decodeGRPCGetIdentitiesRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetIdentitiesRequest)
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetIdentitiesRequest{UserID: userID}, nil
+}
+
+func decodeGRPCListUsersRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ListUsersRequest)
+	return ep.ListUsersRequest{Query: listQueryFromPB(req.Query)}, nil
+}
+
+func decodeGRPCValidateProvisioningTokenRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ValidateProvisioningTokenRequest)
+	return ep.ValidateProvisioningTokenRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCProvisionUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ProvisionUserRequest)
+	u, err := userFromPB(req.User)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ProvisionUserRequest{User: u}, nil
+}
+
+func decodeGRPCSetUserActiveRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SetUserActiveRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.SetUserActiveRequest{ID: id, Active: req.Active}, nil
+}
+
+func decodeGRPCListTeamsRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ListTeamsRequest)
+	return ep.ListTeamsRequest{Query: listQueryFromPB(req.Query)}, nil
+}
+
+func decodeGRPCUpdateTeamRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UpdateTeamRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	t, err := teamFromPB(req.Team)
+	if err != nil {
+		return nil, err
+	}
+	return ep.UpdateTeamRequest{ID: id, Team: t}, nil
+}
+
+func decodeGRPCSetMembersRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SetMembersRequest)
+	teamID, err := uuid.Parse(req.TeamId)
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	t, err := teamFromPB(&pb.Team{Members: req.Members})
+	if err != nil {
+		return nil, err
+	}
+	return ep.SetMembersRequest{TeamID: teamID, Members: t.Members}, nil
+}
+
+func decodeGRPCServiceStatusRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.ServiceStatusRequest)
+	return ep.ServiceStatusRequest{}, nil
+}
+
+// Server functions
+// encode responses for client, errors in the response are returned as error
+// so they can be turned into a grpc status
+
+func encodeGRPCAddUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.AddUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCGetUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCUpdateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.UpdateUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCAuthenticateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.AuthenticateUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if resp.MFAChallenge != "" {
+		return &pb.UserReply{MfaChallenge: resp.MFAChallenge}, nil
+	}
+	return &pb.UserReply{User: userToPB(*resp.User)}, nil
+}
+
+func encodeGRPCLoginResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.LoginResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if resp.MFAChallenge != "" {
+		return &pb.SessionReply{MfaChallenge: resp.MFAChallenge}, nil
+	}
+	return &pb.SessionReply{Session: sessionToPB(*resp.Session)}, nil
+}
+
+func encodeGRPCValidateSessionResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ValidateSessionResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCRevokeSessionResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.RevokeSessionResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.RevokeSessionReply{}, nil
+}
+
+func encodeGRPCDeleteUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.DeleteUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.DeleteUserReply{}, nil
+}
+
+func encodeGRPCGetUsersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetUsersResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	users := make([]*pb.User, len(resp.Users))
+	for i := range resp.Users {
+		users[i] = userToPB(resp.Users[i])
+	}
+	return &pb.GetUsersReply{Users: users}, nil
+}
+
+func encodeGRPCImportUsersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ImportUsersResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	users := make([]*pb.User, len(resp.Users))
+	for i := range resp.Users {
+		users[i] = userToPB(resp.Users[i])
+	}
+	return &pb.ImportUsersReply{Users: users}, nil
+}
+
+func encodeGRPCUnlockUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.UnlockUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UnlockUserReply{}, nil
+}
+
+func encodeGRPCCompleteMFAResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.CompleteMFAResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.SessionReply{Session: sessionToPB(resp.Session)}, nil
+}
+
+func encodeGRPCEnrollTOTPResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.EnrollTOTPResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.EnrollTOTPReply{Secret: resp.Enrollment.Secret, Uri: resp.Enrollment.URI}, nil
+}
+
+func encodeGRPCConfirmTOTPResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ConfirmTOTPResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ConfirmTOTPReply{RecoveryCodes: resp.RecoveryCodes}, nil
+}
+
+func encodeGRPCDisableMFAResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.DisableMFAResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.DisableMFAReply{}, nil
+}
+
+func encodeGRPCForgotPasswordResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ForgotPasswordResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ForgotPasswordReply{}, nil
+}
+
+func encodeGRPCResetPasswordResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ResetPasswordResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ResetPasswordReply{}, nil
+}
+
+func encodeGRPCSendVerificationEmailResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.SendVerificationEmailResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.SendVerificationEmailReply{}, nil
+}
+
+func encodeGRPCVerifyEmailResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.VerifyEmailResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCCreateTeamResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.CreateTeamResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCGetTeamResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetTeamResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCGetTeamsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetTeamsResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	teams := make([]*pb.Team, len(resp.Teams))
+	for i := range resp.Teams {
+		teams[i] = teamToPB(resp.Teams[i])
+	}
+	return &pb.GetTeamsReply{Teams: teams}, nil
+}
+
+func encodeGRPCDeleteTeamResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.DeleteTeamResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.DeleteTeamReply{}, nil
+}
+
+func encodeGRPCInviteMemberResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.InviteMemberResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.InviteMemberReply{Token: resp.Token}, nil
+}
+
+func encodeGRPCAcceptInvitationResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.AcceptInvitationResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCUpdateMemberResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.UpdateMemberResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCRemoveMemberResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.RemoveMemberResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.RemoveMemberReply{}, nil
+}
+
+func encodeGRPCCreateAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.CreateAPIKeyResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.APIKeyReply{ApiKey: apiKeyToPB(resp.APIKey)}, nil
+}
+
+func encodeGRPCGetAPIKeysResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetAPIKeysResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	keys := make([]*pb.APIKey, len(resp.APIKeys))
+	for i, k := range resp.APIKeys {
+		keys[i] = apiKeyToPB(k)
+	}
+	return &pb.GetAPIKeysReply{ApiKeys: keys}, nil
+}
+
+func encodeGRPCRevokeAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.RevokeAPIKeyResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.RevokeAPIKeyReply{}, nil
+}
+
+func encodeGRPCValidateAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ValidateAPIKeyResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.APIKeyReply{ApiKey: apiKeyToPB(resp.APIKey)}, nil
+}
+
+func encodeGRPCRegisterClientResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.RegisterClientResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ClientReply{Client: clientToPB(resp.Client)}, nil
+}
+
+func encodeGRPCGetClientResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetClientResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ClientReply{Client: clientToPB(resp.Client)}, nil
+}
+
+func encodeGRPCAuthorizeResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.AuthorizeResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.AuthorizeReply{Code: resp.Code}, nil
+}
+
+func encodeGRPCExchangeTokenResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ExchangeTokenResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TokenReply{
+		AccessToken:  resp.Token.AccessToken,
+		TokenType:    resp.Token.TokenType,
+		ExpiresIn:    resp.Token.ExpiresIn,
+		RefreshToken: resp.Token.RefreshToken,
+		Scope:        resp.Token.Scope,
+		IdToken:      resp.Token.IDToken,
+	}, nil
+}
+
+func encodeGRPCRevokeTokenResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.RevokeTokenResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.RevokeTokenReply{}, nil
+}
+
+func encodeGRPCIntrospectTokenResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.IntrospectTokenResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	i := resp.Introspection
+	return &pb.IntrospectTokenReply{
+		Active:    i.Active,
+		Scope:     i.Scope,
+		ClientId:  i.ClientID,
+		Username:  i.Username,
+		TokenType: i.TokenType,
+		Exp:       i.Exp,
+		Iat:       i.Iat,
+		Sub:       i.Sub,
+	}, nil
+}
+
+func encodeGRPCValidateAccessTokenResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ValidateAccessTokenResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	t := resp.AccessToken
+	return &pb.AccessTokenReply{AccessToken: &pb.AccessToken{
+		ClientId:  optionalUUIDToPB(t.ClientID),
+		UserId:    optionalUUIDToPB(t.UserID),
+		Scopes:    scopesToPB(t.Scopes),
+		IssuedAt:  optionalUnix(t.IssuedAt),
+		ExpiresAt: optionalUnix(t.ExpiresAt),
+	}}, nil
+}
+
+func encodeGRPCOpenIDConfigurationResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.OpenIDConfigurationResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	m := resp.Metadata
+	return &pb.OpenIDConfigurationReply{
+		Issuer:                            m.Issuer,
+		AuthorizationEndpoint:             m.AuthorizationEndpoint,
+		TokenEndpoint:                     m.TokenEndpoint,
+		UserinfoEndpoint:                  m.UserinfoEndpoint,
+		JwksUri:                           m.JWKSURI,
+		RevocationEndpoint:                m.RevocationEndpoint,
+		IntrospectionEndpoint:             m.IntrospectionEndpoint,
+		ScopesSupported:                   m.ScopesSupported,
+		ResponseTypesSupported:            m.ResponseTypesSupported,
+		GrantTypesSupported:               m.GrantTypesSupported,
+		SubjectTypesSupported:             m.SubjectTypesSupported,
+		IdTokenSigningAlgValuesSupported:  m.IDTokenSigningAlgValuesSupported,
+		TokenEndpointAuthMethodsSupported: m.TokenEndpointAuthMethodsSupported,
+		CodeChallengeMethodsSupported:     m.CodeChallengeMethodsSupported,
+		ClaimsSupported:                   m.ClaimsSupported,
+	}, nil
+}
+
+func encodeGRPCGetJWKSResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetJWKSResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	keys := make([]*pb.JWK, len(resp.Keys.Keys))
+	for i, k := range resp.Keys.Keys {
+		keys[i] = &pb.JWK{Kty: k.KeyType, Use: k.Use, Alg: k.Algorithm, Kid: k.KeyID, N: k.N, E: k.E}
+	}
+	return &pb.JWKSReply{Keys: keys}, nil
+}
+
+func encodeGRPCUserInfoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.UserInfoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserInfoReply{Sub: resp.UserInfo.Subject, PreferredUsername: resp.UserInfo.PreferredUsername}, nil
+}
+
+func encodeGRPCStartFederatedLoginResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.StartFederatedLoginResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.StartFederatedLoginReply{Url: resp.URL}, nil
+}
+
+func encodeGRPCCompleteFederatedLoginResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.CompleteFederatedLoginResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if resp.MFAChallenge != "" {
+		return &pb.SessionReply{MfaChallenge: resp.MFAChallenge}, nil
+	}
+	return &pb.SessionReply{Session: sessionToPB(*resp.Session)}, nil
+}
+
+func encodeGRPCGetIdentitiesResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.GetIdentitiesResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	identities := make([]*pb.Identity, len(resp.Identities))
+	for i, id := range resp.Identities {
+		identities[i] = &pb.Identity{Provider: id.Provider, Subject: id.Subject, UserId: id.UserID.String(), CreatedAt: id.CreatedAt.Unix()}
+	}
+	return &pb.GetIdentitiesReply{Identities: identities}, nil
+}
+
+func encodeGRPCListUsersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ListUsersResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	users := make([]*pb.User, len(resp.Page.Users))
+	for i := range resp.Page.Users {
+		users[i] = userToPB(resp.Page.Users[i])
+	}
+	return &pb.ListUsersReply{Users: users, Total: int32(resp.Page.Total)}, nil
+}
+
+func encodeGRPCValidateProvisioningTokenResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ValidateProvisioningTokenResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCProvisionUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ProvisionUserResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCSetUserActiveResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.SetUserActiveResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGRPCListTeamsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ListTeamsResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	teams := make([]*pb.Team, len(resp.Page.Teams))
+	for i := range resp.Page.Teams {
+		teams[i] = teamToPB(resp.Page.Teams[i])
+	}
+	return &pb.ListTeamsReply{Teams: teams, Total: int32(resp.Page.Total)}, nil
+}
+
+func encodeGRPCUpdateTeamResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.UpdateTeamResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCSetMembersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.SetMembersResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.TeamReply{Team: teamToPB(resp.Team)}, nil
+}
+
+func encodeGRPCServiceStatusResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ep.ServiceStatusResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ServiceStatusReply{Code: int32(resp.Code)}, nil
+}
+
+// client functions
+// encode request for server
+
+func encodeGRPCAddUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.AddUserRequest)
+	return &pb.AddUserRequest{User: userToPB(req.User)}, nil
+}
+
+func encodeGRPCGetUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetUserRequest)
+	return &pb.GetUserRequest{Id: req.User.ID.String()}, nil
+}
+
+func encodeGRPCFindUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetUserRequest)
+	return &pb.FindUserRequest{Username: req.User.Username}, nil
+}
+
+func encodeGRPCUpdateUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.UpdateUserRequest)
+	return &pb.UpdateUserRequest{Id: req.ID.String(), User: userToPB(req.User)}, nil
+}
+
+func encodeGRPCAuthenticateUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.AuthenticateUserRequest)
+	return &pb.AuthenticateUserRequest{User: userToPB(req.User)}, nil
+}
+
+func encodeGRPCLoginRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.LoginRequest)
+	return &pb.LoginRequest{User: userToPB(req.User)}, nil
+}
+
+func encodeGRPCValidateSessionRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ValidateSessionRequest)
+	return &pb.ValidateSessionRequest{Token: req.Token}, nil
+}
+
+func encodeGRPCRevokeSessionRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.RevokeSessionRequest)
+	return &pb.RevokeSessionRequest{Token: req.Token}, nil
+}
+
+func encodeGRPCDeleteUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.DeleteUserRequest)
+	return &pb.DeleteUserRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCGetUsersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(ep.GetUsersRequest)
+	return &pb.GetUsersRequest{}, nil
+}
+
+func encodeGRPCImportUsersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ImportUsersRequest)
+	users := make([]*pb.ImportedUser, len(req.Users))
+	for i, u := range req.Users {
+		var id string
+		if u.ID != uuid.Nil {
+			id = u.ID.String()
+		}
+		users[i] = &pb.ImportedUser{Id: id, Username: u.Username, PasswordHash: u.PasswordHash}
+	}
+	return &pb.ImportUsersRequest{Users: users}, nil
+}
+
+func encodeGRPCUnlockUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.UnlockUserRequest)
+	return &pb.UnlockUserRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCCompleteMFARequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.CompleteMFARequest)
+	return &pb.CompleteMFARequest{MfaChallenge: req.Challenge, Code: req.Code}, nil
+}
+
+func encodeGRPCEnrollTOTPRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.EnrollTOTPRequest)
+	return &pb.EnrollTOTPRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCConfirmTOTPRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ConfirmTOTPRequest)
+	return &pb.ConfirmTOTPRequest{Id: req.ID.String(), Code: req.Code}, nil
+}
+
+func encodeGRPCDisableMFARequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.DisableMFARequest)
+	return &pb.DisableMFARequest{Id: req.ID.String(), Code: req.Code}, nil
+}
+
+func encodeGRPCForgotPasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ForgotPasswordRequest)
+	return &pb.ForgotPasswordRequest{Email: req.Email}, nil
+}
+
+func encodeGRPCResetPasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ResetPasswordRequest)
+	return &pb.ResetPasswordRequest{Token: req.Token, Password: req.Password}, nil
+}
+
+func encodeGRPCSendVerificationEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.SendVerificationEmailRequest)
+	return &pb.SendVerificationEmailRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCVerifyEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.VerifyEmailRequest)
+	return &pb.VerifyEmailRequest{Token: req.Token}, nil
+}
+
+func encodeGRPCCreateTeamRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.CreateTeamRequest)
+	return &pb.CreateTeamRequest{Team: teamToPB(req.Team), OwnerId: optionalUUIDToPB(req.OwnerID)}, nil
+}
+
+func encodeGRPCGetTeamRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetTeamRequest)
+	return &pb.GetTeamRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCGetTeamsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetTeamsRequest)
+	return &pb.GetTeamsRequest{UserId: req.UserID.String()}, nil
+}
+
+func encodeGRPCDeleteTeamRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.DeleteTeamRequest)
+	return &pb.DeleteTeamRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCInviteMemberRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.InviteMemberRequest)
+	return &pb.InviteMemberRequest{TeamId: req.TeamID.String(), Role: string(req.Role)}, nil
+}
+
+func encodeGRPCAcceptInvitationRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.AcceptInvitationRequest)
+	return &pb.AcceptInvitationRequest{Token: req.Token, UserId: optionalUUIDToPB(req.UserID)}, nil
+}
+
+func encodeGRPCUpdateMemberRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.UpdateMemberRequest)
+	return &pb.UpdateMemberRequest{TeamId: req.TeamID.String(), UserId: req.UserID.String(), Role: string(req.Role)}, nil
+}
+
+func encodeGRPCRemoveMemberRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.RemoveMemberRequest)
+	return &pb.RemoveMemberRequest{TeamId: req.TeamID.String(), UserId: req.UserID.String()}, nil
+}
+
+func encodeGRPCCreateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.CreateAPIKeyRequest)
+	return &pb.CreateAPIKeyRequest{UserId: req.UserID.String(), ApiKey: apiKeyToPB(req.APIKey)}, nil
+}
+
+func encodeGRPCGetAPIKeysRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetAPIKeysRequest)
+	return &pb.GetAPIKeysRequest{UserId: req.UserID.String()}, nil
+}
+
+func encodeGRPCRevokeAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.RevokeAPIKeyRequest)
+	return &pb.RevokeAPIKeyRequest{UserId: req.UserID.String(), Id: req.ID.String()}, nil
+}
+
+func encodeGRPCValidateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ValidateAPIKeyRequest)
+	return &pb.ValidateAPIKeyRequest{Key: req.Key}, nil
+}
+
+func encodeGRPCRegisterClientRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.RegisterClientRequest)
+	return &pb.RegisterClientRequest{Client: clientToPB(req.Client), OwnerId: optionalUUIDToPB(req.OwnerID)}, nil
+}
+
+func encodeGRPCGetClientRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetClientRequest)
+	return &pb.GetClientRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCAuthorizeRequest(_ context.Context, request interface{}) (interface{}, error) {
+	r := request.(ep.AuthorizeRequest).Request
+	return &pb.AuthorizeRequest{
+		ClientId:            r.ClientID.String(),
+		UserId:              optionalUUIDToPB(r.UserID),
+		RedirectUri:         r.RedirectURI,
+		Scopes:              scopesToPB(r.Scopes),
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		Nonce:               r.Nonce,
+	}, nil
+}
+
+func encodeGRPCExchangeTokenRequest(_ context.Context, request interface{}) (interface{}, error) {
+	r := request.(ep.ExchangeTokenRequest).Request
+	return &pb.ExchangeTokenRequest{
+		GrantType:    r.GrantType,
+		Client:       clientCredentialsToPB(r.Client),
+		Code:         r.Code,
+		RedirectUri:  r.RedirectURI,
+		CodeVerifier: r.CodeVerifier,
+		RefreshToken: r.RefreshToken,
+		Scopes:       scopesToPB(r.Scopes),
+	}, nil
+}
+
+func encodeGRPCRevokeTokenRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.RevokeTokenRequest)
+	return &pb.RevokeTokenRequest{Token: req.Token, Client: clientCredentialsToPB(req.Client)}, nil
+}
+
+func encodeGRPCIntrospectTokenRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.IntrospectTokenRequest)
+	return &pb.IntrospectTokenRequest{Token: req.Token, Client: clientCredentialsToPB(req.Client)}, nil
+}
+
+func encodeGRPCValidateAccessTokenRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ValidateAccessTokenRequest)
+	return &pb.ValidateAccessTokenRequest{Token: req.Token}, nil
+}
+
+func encodeGRPCOpenIDConfigurationRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(ep.OpenIDConfigurationRequest)
+	return &pb.OpenIDConfigurationRequest{}, nil
+}
+
+func encodeGRPCGetJWKSRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(ep.GetJWKSRequest)
+	return &pb.GetJWKSRequest{}, nil
+}
+
+func encodeGRPCUserInfoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.UserInfoRequest)
+	return &pb.UserInfoRequest{AccessToken: req.Token}, nil
+}
+
+func encodeGRPCStartFederatedLoginRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.StartFederatedLoginRequest)
+	return &pb.StartFederatedLoginRequest{Provider: req.Provider, UserId: optionalUUIDToPB(req.UserID)}, nil
+}
+
+func encodeGRPCCompleteFederatedLoginRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.CompleteFederatedLoginRequest)
+	return &pb.CompleteFederatedLoginRequest{Provider: req.Provider, State: req.State, Code: req.Code}, nil
+}
+
+func encodeGRPCGetIdentitiesRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.GetIdentitiesRequest)
+	return &pb.GetIdentitiesRequest{UserId: req.UserID.String()}, nil
+}
+
+func encodeGRPCListUsersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ListUsersRequest)
+	return &pb.ListUsersRequest{Query: listQueryToPB(req.Query)}, nil
+}
+
+func encodeGRPCValidateProvisioningTokenRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ValidateProvisioningTokenRequest)
+	return &pb.ValidateProvisioningTokenRequest{Token: req.Token}, nil
+}
+
+func encodeGRPCProvisionUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ProvisionUserRequest)
+	return &pb.ProvisionUserRequest{User: userToPB(req.User)}, nil
+}
+
+func encodeGRPCSetUserActiveRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.SetUserActiveRequest)
+	return &pb.SetUserActiveRequest{Id: req.ID.String(), Active: req.Active}, nil
+}
+
+func encodeGRPCListTeamsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.ListTeamsRequest)
+	return &pb.ListTeamsRequest{Query: listQueryToPB(req.Query)}, nil
+}
+
+func encodeGRPCUpdateTeamRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.UpdateTeamRequest)
+	return &pb.UpdateTeamRequest{Id: req.ID.String(), Team: teamToPB(req.Team)}, nil
+}
+
+func encodeGRPCSetMembersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(ep.SetMembersRequest)
+	return &pb.SetMembersRequest{TeamId: req.TeamID.String(), Members: teamToPB(authorization.Team{Members: req.Members}).Members}, nil
+}
+
+func encodeGRPCServiceStatusRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(ep.ServiceStatusRequest)
+	return &pb.ServiceStatusRequest{}, nil
+}
+
+// client functions
+// decode response from server
+
+func decodeGRPCAddUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.AddUserResponse{User: u}, err
+}
+
+func decodeGRPCGetUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.GetUserResponse{User: u}, err
+}
+
+func decodeGRPCUpdateUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.UpdateUserResponse{User: u}, err
+}
+
+func decodeGRPCAuthenticateUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	if reply.MfaChallenge != "" {
+		return ep.AuthenticateUserResponse{MFAChallenge: reply.MfaChallenge}, nil
+	}
+	u, err := userFromPB(reply.User)
+	return ep.AuthenticateUserResponse{User: &u}, err
+}
+
+func decodeGRPCLoginResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SessionReply)
+	if reply.MfaChallenge != "" {
+		return ep.LoginResponse{MFAChallenge: reply.MfaChallenge}, nil
+	}
+	session, err := sessionFromPB(reply.Session)
+	return ep.LoginResponse{Session: &session}, err
+}
+
+func decodeGRPCValidateSessionResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.ValidateSessionResponse{User: u}, err
+}
+
+func decodeGRPCRevokeSessionResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.RevokeSessionReply)
+	return ep.RevokeSessionResponse{}, nil
+}
+
+func decodeGRPCDeleteUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.DeleteUserReply)
+	return ep.DeleteUserResponse{}, nil
+}
+
+func decodeGRPCGetUsersResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetUsersReply)
+	users := make([]authorization.User, len(reply.Users))
+	for i := range reply.Users {
+		u, err := userFromPB(reply.Users[i])
+		if err != nil {
+			return nil, err
+		}
+		users[i] = u
+	}
+	return ep.GetUsersResponse{Users: users}, nil
+}
+
+func decodeGRPCImportUsersResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ImportUsersReply)
+	users := make([]authorization.User, len(reply.Users))
+	for i := range reply.Users {
+		u, err := userFromPB(reply.Users[i])
+		if err != nil {
+			return nil, err
+		}
+		users[i] = u
+	}
+	return ep.ImportUsersResponse{Users: users}, nil
+}
+
+func decodeGRPCUnlockUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.UnlockUserReply)
+	return ep.UnlockUserResponse{}, nil
+}
+
+func decodeGRPCCompleteMFAResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SessionReply)
+	session, err := sessionFromPB(reply.Session)
+	return ep.CompleteMFAResponse{Session: session}, err
+}
+
+func decodeGRPCEnrollTOTPResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.EnrollTOTPReply)
+	return ep.EnrollTOTPResponse{Enrollment: authorization.TOTPEnrollment{Secret: reply.Secret, URI: reply.Uri}}, nil
+}
+
+func decodeGRPCConfirmTOTPResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ConfirmTOTPReply)
+	return ep.ConfirmTOTPResponse{RecoveryCodes: reply.RecoveryCodes}, nil
+}
+
+func decodeGRPCDisableMFAResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.DisableMFAReply)
+	return ep.DisableMFAResponse{}, nil
+}
+
+func decodeGRPCForgotPasswordResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.ForgotPasswordReply)
+	return ep.ForgotPasswordResponse{}, nil
+}
+
+func decodeGRPCResetPasswordResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.ResetPasswordReply)
+	return ep.ResetPasswordResponse{}, nil
+}
+
+func decodeGRPCSendVerificationEmailResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.SendVerificationEmailReply)
+	return ep.SendVerificationEmailResponse{}, nil
+}
+
+func decodeGRPCVerifyEmailResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.VerifyEmailResponse{User: u}, err
+}
+
+func decodeGRPCCreateTeamResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.CreateTeamResponse{Team: t}, err
+}
+
+func decodeGRPCGetTeamResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.GetTeamResponse{Team: t}, err
+}
+
+func decodeGRPCGetTeamsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetTeamsReply)
+	teams := make([]authorization.Team, len(reply.Teams))
+	for i := range reply.Teams {
+		t, err := teamFromPB(reply.Teams[i])
+		if err != nil {
+			return nil, err
+		}
+		teams[i] = t
+	}
+	return ep.GetTeamsResponse{Teams: teams}, nil
+}
+
+func decodeGRPCDeleteTeamResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.DeleteTeamReply)
+	return ep.DeleteTeamResponse{}, nil
+}
+
+func decodeGRPCInviteMemberResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.InviteMemberReply)
+	return ep.InviteMemberResponse{Token: reply.Token}, nil
+}
+
+func decodeGRPCAcceptInvitationResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.AcceptInvitationResponse{Team: t}, err
+}
+
+func decodeGRPCUpdateMemberResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.UpdateMemberResponse{Team: t}, err
+}
+
+func decodeGRPCRemoveMemberResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.RemoveMemberReply)
+	return ep.RemoveMemberResponse{}, nil
+}
+
+func decodeGRPCCreateAPIKeyResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.APIKeyReply)
+	k, err := apiKeyFromPB(reply.ApiKey)
+	return ep.CreateAPIKeyResponse{APIKey: k}, err
+}
+
+func decodeGRPCGetAPIKeysResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetAPIKeysReply)
+	keys := make([]authorization.APIKey, len(reply.ApiKeys))
+	for i, k := range reply.ApiKeys {
+		var err error
+		if keys[i], err = apiKeyFromPB(k); err != nil {
+			return nil, err
+		}
+	}
+	return ep.GetAPIKeysResponse{APIKeys: keys}, nil
+}
+
+func decodeGRPCRevokeAPIKeyResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.RevokeAPIKeyReply)
+	return ep.RevokeAPIKeyResponse{}, nil
+}
+
+func decodeGRPCValidateAPIKeyResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.APIKeyReply)
+	k, err := apiKeyFromPB(reply.ApiKey)
+	return ep.ValidateAPIKeyResponse{APIKey: k}, err
+}
+
+func decodeGRPCRegisterClientResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ClientReply)
+	c, err := clientFromPB(reply.Client)
+	return ep.RegisterClientResponse{Client: c}, err
+}
+
+func decodeGRPCGetClientResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ClientReply)
+	c, err := clientFromPB(reply.Client)
+	return ep.GetClientResponse{Client: c}, err
+}
+
+func decodeGRPCAuthorizeResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.AuthorizeReply)
+	return ep.AuthorizeResponse{Code: reply.Code}, nil
+}
+
+func decodeGRPCExchangeTokenResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TokenReply)
+	return ep.ExchangeTokenResponse{Token: authorization.OAuthToken{
+		AccessToken:  reply.AccessToken,
+		TokenType:    reply.TokenType,
+		ExpiresIn:    reply.ExpiresIn,
+		RefreshToken: reply.RefreshToken,
+		Scope:        reply.Scope,
+		IDToken:      reply.IdToken,
+	}}, nil
+}
+
+func decodeGRPCRevokeTokenResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.RevokeTokenReply)
+	return ep.RevokeTokenResponse{}, nil
+}
+
+func decodeGRPCIntrospectTokenResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.IntrospectTokenReply)
+	return ep.IntrospectTokenResponse{Introspection: authorization.Introspection{
+		Active:    reply.Active,
+		Scope:     reply.Scope,
+		ClientID:  reply.ClientId,
+		Username:  reply.Username,
+		TokenType: reply.TokenType,
+		Exp:       reply.Exp,
+		Iat:       reply.Iat,
+		Sub:       reply.Sub,
+	}}, nil
+}
+
+func decodeGRPCValidateAccessTokenResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.AccessTokenReply)
+	t := reply.AccessToken
+	if t == nil {
+		return ep.ValidateAccessTokenResponse{}, nil
+	}
+	clientID, err := optionalUUIDFromPB(t.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := optionalUUIDFromPB(t.UserId)
+	if err != nil {
+		return nil, err
+	}
+	token := authorization.AccessToken{ClientID: clientID, UserID: userID, Scopes: scopesFromPB(t.Scopes)}
+	if t.IssuedAt != 0 {
+		token.IssuedAt = time.Unix(t.IssuedAt, 0)
+	}
+	if t.ExpiresAt != 0 {
+		token.ExpiresAt = time.Unix(t.ExpiresAt, 0)
+	}
+	return ep.ValidateAccessTokenResponse{AccessToken: token}, nil
+}
+
+func decodeGRPCOpenIDConfigurationResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.OpenIDConfigurationReply)
+	return ep.OpenIDConfigurationResponse{Metadata: authorization.ProviderMetadata{
+		Issuer:                            reply.Issuer,
+		AuthorizationEndpoint:             reply.AuthorizationEndpoint,
+		TokenEndpoint:                     reply.TokenEndpoint,
+		UserinfoEndpoint:                  reply.UserinfoEndpoint,
+		JWKSURI:                           reply.JwksUri,
+		RevocationEndpoint:                reply.RevocationEndpoint,
+		IntrospectionEndpoint:             reply.IntrospectionEndpoint,
+		ScopesSupported:                   reply.ScopesSupported,
+		ResponseTypesSupported:            reply.ResponseTypesSupported,
+		GrantTypesSupported:               reply.GrantTypesSupported,
+		SubjectTypesSupported:             reply.SubjectTypesSupported,
+		IDTokenSigningAlgValuesSupported:  reply.IdTokenSigningAlgValuesSupported,
+		TokenEndpointAuthMethodsSupported: reply.TokenEndpointAuthMethodsSupported,
+		CodeChallengeMethodsSupported:     reply.CodeChallengeMethodsSupported,
+		ClaimsSupported:                   reply.ClaimsSupported,
+	}}, nil
+}
+
+func decodeGRPCGetJWKSResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.JWKSReply)
+	keys := jose.KeySet{Keys: make([]jose.Key, len(reply.Keys))}
+	for i, k := range reply.Keys {
+		keys.Keys[i] = jose.Key{KeyType: k.Kty, Use: k.Use, Algorithm: k.Alg, KeyID: k.Kid, N: k.N, E: k.E}
+	}
+	return ep.GetJWKSResponse{Keys: keys}, nil
+}
+
+func decodeGRPCUserInfoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserInfoReply)
+	return ep.UserInfoResponse{UserInfo: authorization.UserInfo{Subject: reply.Sub, PreferredUsername: reply.PreferredUsername}}, nil
+}
+
+func decodeGRPCStartFederatedLoginResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.StartFederatedLoginReply)
+	return ep.StartFederatedLoginResponse{URL: reply.Url}, nil
+}
+
+func decodeGRPCCompleteFederatedLoginResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SessionReply)
+	if reply.MfaChallenge != "" {
+		return ep.CompleteFederatedLoginResponse{MFAChallenge: reply.MfaChallenge}, nil
+	}
+	session, err := sessionFromPB(reply.Session)
+	return ep.CompleteFederatedLoginResponse{Session: &session}, err
+}
+
+func decodeGRPCGetIdentitiesResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetIdentitiesReply)
+	identities := make([]authorization.Identity, len(reply.Identities))
+	for i, id := range reply.Identities {
+		userID, err := uuid.Parse(id.UserId)
+		if err != nil {
+			return nil, authorization.ErrInvalidUUID
+		}
+		identities[i] = authorization.Identity{Provider: id.Provider, Subject: id.Subject, UserID: userID, CreatedAt: time.Unix(id.CreatedAt, 0)}
+	}
+	return ep.GetIdentitiesResponse{Identities: identities}, nil
+}
+
+func decodeGRPCListUsersResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ListUsersReply)
+	users := make([]authorization.User, len(reply.Users))
+	for i := range reply.Users {
+		u, err := userFromPB(reply.Users[i])
+		if err != nil {
+			return nil, err
+		}
+		users[i] = u
+	}
+	return ep.ListUsersResponse{Page: authorization.UserList{Users: users, Total: int(reply.Total)}}, nil
+}
+
+func decodeGRPCValidateProvisioningTokenResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.ValidateProvisioningTokenResponse{User: u}, err
+}
+
+func decodeGRPCProvisionUserResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.ProvisionUserResponse{User: u}, err
+}
+
+func decodeGRPCSetUserActiveResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UserReply)
+	u, err := userFromPB(reply.User)
+	return ep.SetUserActiveResponse{User: u}, err
+}
+
+func decodeGRPCListTeamsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ListTeamsReply)
+	teams := make([]authorization.Team, len(reply.Teams))
+	for i := range reply.Teams {
+		t, err := teamFromPB(reply.Teams[i])
+		if err != nil {
+			return nil, err
+		}
+		teams[i] = t
+	}
+	return ep.ListTeamsResponse{Page: authorization.TeamList{Teams: teams, Total: int(reply.Total)}}, nil
+}
+
+func decodeGRPCUpdateTeamResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.UpdateTeamResponse{Team: t}, err
+}
+
+func decodeGRPCSetMembersResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.TeamReply)
+	t, err := teamFromPB(reply.Team)
+	return ep.SetMembersResponse{Team: t}, err
+}
+
+func decodeGRPCServiceStatusResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ServiceStatusReply)
+	return ep.ServiceStatusResponse{Code: int(reply.Code)}, nil
+}
+
+func userToPB(u authorization.User) *pb.User {
+	var id string
+	if u.ID != uuid.Nil {
+		id = u.ID.String()
+	}
+	return &pb.User{
+		Id:            id,
+		Username:      u.Username,
+		Password:      u.Password,
+		MfaEnabled:    u.MFAEnabled,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Role:          string(u.Role),
+		Disabled:      u.Disabled,
+	}
+}
+
+func userFromPB(u *pb.User) (authorization.User, error) {
+	if u == nil {
+		return authorization.User{}, nil
+	}
+	var id uuid.UUID
+	if u.Id != "" {
+		var err error
+		id, err = uuid.Parse(u.Id)
+		if err != nil {
+			return authorization.User{}, authorization.ErrInvalidUUID
+		}
+	}
+	return authorization.User{
+		ID:            id,
+		Username:      u.Username,
+		Password:      u.Password,
+		MFAEnabled:    u.MfaEnabled,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Role:          authorization.Role(u.Role),
+		Disabled:      u.Disabled,
+	}, nil
+}
+
+func sessionToPB(s authorization.Session) *pb.Session {
+	return &pb.Session{Token: s.Token, ExpiresAt: s.ExpiresAt.Unix(), User: userToPB(s.User)}
+}
+
+func sessionFromPB(s *pb.Session) (authorization.Session, error) {
+	if s == nil {
+		return authorization.Session{}, nil
+	}
+	u, err := userFromPB(s.User)
+	if err != nil {
+		return authorization.Session{}, err
+	}
+	return authorization.Session{Token: s.Token, ExpiresAt: time.Unix(s.ExpiresAt, 0), User: u}, nil
+}
+
+func teamToPB(t authorization.Team) *pb.Team {
+	members := make([]*pb.TeamMember, len(t.Members))
+	for i, m := range t.Members {
+		members[i] = &pb.TeamMember{UserId: m.UserID.String(), Role: string(m.Role)}
+	}
+	return &pb.Team{Id: optionalUUIDToPB(t.ID), Name: t.Name, Members: members}
+}
+
+func teamFromPB(t *pb.Team) (authorization.Team, error) {
+	if t == nil {
+		return authorization.Team{}, nil
+	}
+	id, err := optionalUUIDFromPB(t.Id)
+	if err != nil {
+		return authorization.Team{}, err
+	}
+	team := authorization.Team{ID: id, Name: t.Name}
+	for _, m := range t.Members {
+		userID, err := uuid.Parse(m.UserId)
+		if err != nil {
+			return authorization.Team{}, authorization.ErrInvalidUUID
+		}
+		team.Members = append(team.Members, authorization.TeamMember{UserID: userID, Role: authorization.TeamRole(m.Role)})
+	}
+	return team, nil
+}
+
+func listQueryToPB(q authorization.ListQuery) *pb.ListQuery {
+	return &pb.ListQuery{Name: q.Name, Offset: int32(q.Offset), Limit: int32(q.Limit)}
+}
+
+func listQueryFromPB(q *pb.ListQuery) authorization.ListQuery {
+	return authorization.ListQuery{Name: q.GetName(), Offset: int(q.GetOffset()), Limit: int(q.GetLimit())}
+}
+
+func apiKeyToPB(k authorization.APIKey) *pb.APIKey {
+	scopes := make([]string, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = string(s)
+	}
+	key := &pb.APIKey{
+		Id:        optionalUUIDToPB(k.ID),
+		UserId:    optionalUUIDToPB(k.UserID),
+		Name:      k.Name,
+		Scopes:    scopes,
+		CreatedAt: optionalUnix(k.CreatedAt),
+		ExpiresAt: optionalUnix(k.ExpiresAt),
+		Key:       k.Key,
+	}
+	if k.LastUsedAt != nil {
+		key.LastUsedAt = k.LastUsedAt.Unix()
+	}
+	return key
+}
+
+func apiKeyFromPB(k *pb.APIKey) (authorization.APIKey, error) {
+	if k == nil {
+		return authorization.APIKey{}, nil
+	}
+	id, err := optionalUUIDFromPB(k.Id)
+	if err != nil {
+		return authorization.APIKey{}, err
+	}
+	userID, err := optionalUUIDFromPB(k.UserId)
+	if err != nil {
+		return authorization.APIKey{}, err
+	}
+	key := authorization.APIKey{
+		ID:     id,
+		UserID: userID,
+		Name:   k.Name,
+		Scopes: make([]authorization.Scope, len(k.Scopes)),
+		Key:    k.Key,
+	}
+	for i, s := range k.Scopes {
+		key.Scopes[i] = authorization.Scope(s)
+	}
+	if k.CreatedAt != 0 {
+		key.CreatedAt = time.Unix(k.CreatedAt, 0)
+	}
+	if k.ExpiresAt != 0 {
+		key.ExpiresAt = time.Unix(k.ExpiresAt, 0)
+	}
+	if k.LastUsedAt != 0 {
+		lastUsed := time.Unix(k.LastUsedAt, 0)
+		key.LastUsedAt = &lastUsed
+	}
+	return key, nil
+}
+
+func clientToPB(c authorization.OAuthClient) *pb.OAuthClient {
+	return &pb.OAuthClient{
+		ClientId:     optionalUUIDToPB(c.ID),
+		Name:         c.Name,
+		RedirectUris: c.RedirectURIs,
+		Scopes:       scopesToPB(c.Scopes),
+		Public:       c.Public,
+		OwnerId:      optionalUUIDToPB(c.OwnerID),
+		ClientSecret: c.Secret,
+	}
+}
+
+func clientFromPB(c *pb.OAuthClient) (authorization.OAuthClient, error) {
+	if c == nil {
+		return authorization.OAuthClient{}, nil
+	}
+	id, err := optionalUUIDFromPB(c.ClientId)
+	if err != nil {
+		return authorization.OAuthClient{}, err
+	}
+	ownerID, err := optionalUUIDFromPB(c.OwnerId)
+	if err != nil {
+		return authorization.OAuthClient{}, err
+	}
+	return authorization.OAuthClient{
+		ID:           id,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       scopesFromPB(c.Scopes),
+		Public:       c.Public,
+		OwnerID:      ownerID,
+		Secret:       c.ClientSecret,
+	}, nil
+}
+
+func clientCredentialsToPB(c authorization.ClientCredentials) *pb.ClientCredentials {
+	return &pb.ClientCredentials{ClientId: c.ID.String(), ClientSecret: c.Secret}
+}
+
+// clientCredentialsFromPB fails like client authentication for malformed ids
+func clientCredentialsFromPB(c *pb.ClientCredentials) (authorization.ClientCredentials, error) {
+	if c == nil {
+		return authorization.ClientCredentials{}, authorization.ErrInvalidClient
+	}
+	id, err := uuid.Parse(c.ClientId)
+	if err != nil {
+		return authorization.ClientCredentials{}, authorization.ErrInvalidClient
+	}
+	return authorization.ClientCredentials{ID: id, Secret: c.ClientSecret}, nil
+}
+
+func scopesToPB(scopes []authorization.Scope) []string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return s
+}
+
+func scopesFromPB(scopes []string) []authorization.Scope {
+	var s []authorization.Scope
+	for _, scope := range scopes {
+		s = append(s, authorization.Scope(scope))
+	}
+	return s
+}
+
+// optionalUnix is the unix time of t, 0 for the zero time
+func optionalUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// optionalUUIDToPB leaves ids that are not set empty
+func optionalUUIDToPB(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optionalUUIDFromPB(id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, authorization.ErrInvalidUUID
+	}
+	return parsed, nil
+}
+
+// grpcErrors maps the errors of the authorization package to a grpc status code
+var grpcErrors = map[error]codes.Code{
+	authorization.ErrInvalidUserObject:      codes.InvalidArgument,
+	authorization.ErrAuthenticationFailed:   codes.Unauthenticated,
+	authorization.ErrIDMissing:              codes.InvalidArgument,
+	authorization.ErrInconsistentIDs:        codes.InvalidArgument,
+	authorization.ErrInconsistentIDUserName: codes.InvalidArgument,
+	authorization.ErrNotFound:               codes.NotFound,
+	authorization.ErrInvalidUUID:            codes.InvalidArgument,
+	authorization.ErrTooManyAttempts:        codes.ResourceExhausted,
+	authorization.ErrInvalidMFAChallenge:    codes.Unauthenticated,
+	authorization.ErrInvalidMFACode:         codes.Unauthenticated,
+	authorization.ErrMFANotEnrolled:         codes.FailedPrecondition,
+	authorization.ErrMFAEnabled:             codes.FailedPrecondition,
+	authorization.ErrInvalidToken:           codes.InvalidArgument,
+	authorization.ErrEmailMissing:           codes.FailedPrecondition,
+	authorization.ErrMailDisabled:           codes.Unavailable,
+	authorization.ErrInvalidRole:            codes.InvalidArgument,
+	authorization.ErrUnauthenticated:        codes.Unauthenticated,
+	authorization.ErrForbidden:              codes.PermissionDenied,
+	authorization.ErrTeamNotFound:           codes.NotFound,
+	authorization.ErrInvalidTeam:            codes.InvalidArgument,
+	authorization.ErrInvalidTeamRole:        codes.InvalidArgument,
+	authorization.ErrAlreadyMember:          codes.AlreadyExists,
+	authorization.ErrNotMember:              codes.NotFound,
+	authorization.ErrLastOwner:              codes.FailedPrecondition,
+	authorization.ErrInvalidAPIKey:          codes.InvalidArgument,
+	authorization.ErrInvalidScope:           codes.InvalidArgument,
+	authorization.ErrAPIKeyNotFound:         codes.NotFound,
+	authorization.ErrInvalidClientMetadata:  codes.InvalidArgument,
+	authorization.ErrInvalidRedirectURI:     codes.InvalidArgument,
+	authorization.ErrClientNotFound:         codes.NotFound,
+	authorization.ErrInvalidClient:          codes.Unauthenticated,
+	authorization.ErrInvalidGrant:           codes.InvalidArgument,
+	authorization.ErrUnsupportedGrantType:   codes.InvalidArgument,
+	authorization.ErrInvalidOAuthRequest:    codes.InvalidArgument,
+	authorization.ErrProviderNotFound:       codes.NotFound,
+	authorization.ErrInvalidState:           codes.InvalidArgument,
+	authorization.ErrFederationFailed:       codes.Unauthenticated,
+	authorization.ErrIdentityNotLinked:      codes.Unauthenticated,
+	authorization.ErrIdentityLinked:         codes.AlreadyExists,
+	authorization.ErrUserDisabled:           codes.PermissionDenied,
+	authorization.ErrUsernameTaken:          codes.AlreadyExists,
+}
+
+func encodeGRPCError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var fields problem.FieldErrors
+	if errors.As(err, &fields) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	code, ok := grpcErrors[err]
+	if !ok {
+		// lockouts carry the time to wait, the client only needs the sentinel
+		if errors.Is(err, authorization.ErrTooManyAttempts) {
+			return status.Error(codes.ResourceExhausted, authorization.ErrTooManyAttempts.Error())
+		}
+		// the details of a failure at the identity provider stay on the server
+		if errors.Is(err, authorization.ErrFederationFailed) {
+			return status.Error(codes.Unauthenticated, authorization.ErrFederationFailed.Error())
+		}
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcClientErrors turns a grpc status returned by the server back into
+// the authorization error it was created from.
+func grpcClientErrors(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := next(ctx, request)
+		if err == nil {
+			return response, nil
+		}
+
+		st, ok := status.FromError(err)
+		if !ok {
+			return nil, err
+		}
+		for known, code := range grpcErrors {
+			if st.Code() == code && st.Message() == known.Error() {
+				return nil, known
+			}
+		}
+		return nil, err
+	}
+}
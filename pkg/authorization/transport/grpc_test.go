@@ -0,0 +1,135 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+	"github.com/demeesterdev/todo-service/pkg/authorization/pb"
+)
+
+func TestGRPCClient(t *testing.T) {
+	s, _ := authorization.NewInMemService(argon2id.DefaultConfig)
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterUsersServer(server, MakeGRPCServer(endpoints.MakeServerEndpoints(s), log.NewNopLogger()))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := NewGRPCClient(conn)
+
+	added, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Empty(t, added.Password)
+
+	found, err := c.FindUser(ctx, "hanshandjes")
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, found.ID)
+
+	authenticated, err := c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, authenticated.ID)
+
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "wrong"})
+	assert.Equal(t, authorization.ErrAuthenticationFailed, err)
+
+	_, err = c.AddUser(ctx, authorization.User{Username: "no-password"})
+	assert.Equal(t, authorization.ErrInvalidUserObject, err)
+
+	_, err = c.ProvisionUser(ctx, authorization.User{Username: "hanshandjes"})
+	assert.Equal(t, authorization.ErrUsernameTaken, err)
+	disabled, err := c.SetUserActive(ctx, added.ID, false)
+	assert.NoError(t, err)
+	assert.True(t, disabled.Disabled)
+	page, err := c.ListUsers(ctx, authorization.ListQuery{Name: "hanshandjes"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	if assert.Len(t, page.Users, 1) {
+		assert.True(t, page.Users[0].Disabled)
+	}
+
+	assert.NoError(t, c.DeleteUser(ctx, added.ID))
+	_, err = c.GetUser(ctx, added.ID)
+	assert.Equal(t, authorization.ErrNotFound, err)
+}
+
+func TestGRPCSessions(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterUsersServer(server, MakeGRPCServer(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s)), log.NewNopLogger()))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := NewGRPCClient(conn)
+
+	added, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	_, err = c.GetUser(ctx, added.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	session, err := c.Login(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.RoleMember, session.User.Role)
+	assert.False(t, session.ExpiresAt.IsZero())
+
+	u, err := c.GetUser(authorization.WithSessionToken(ctx, session.Token), added.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, u.ID)
+	_, err = c.GetUsers(authorization.WithSessionToken(ctx, session.Token))
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	asHans := authorization.WithSessionToken(ctx, session.Token)
+	team, err := c.CreateTeam(asHans, authorization.Team{Name: "kitchen"}, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []authorization.TeamMember{{UserID: added.ID, Role: authorization.TeamRoleOwner}}, team.Members)
+	token, err := c.InviteMember(asHans, team.ID, authorization.TeamRoleGuest)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	_, err = c.AcceptInvitation(asHans, token, uuid.Nil)
+	assert.Equal(t, authorization.ErrAlreadyMember, err)
+	teams, err := c.GetTeams(asHans, added.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []authorization.Team{team}, teams)
+	assert.Equal(t, authorization.ErrLastOwner, c.RemoveMember(asHans, team.ID, added.ID))
+
+	key, err := c.CreateAPIKey(asHans, added.ID, authorization.APIKey{Name: "backup", Scopes: []authorization.Scope{authorization.ScopeTodosRead}})
+	assert.NoError(t, err)
+	validated, err := c.ValidateAPIKey(ctx, key.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, validated.ID)
+	assert.Equal(t, key.ExpiresAt.Unix(), validated.ExpiresAt.Unix())
+	assert.NotNil(t, validated.LastUsedAt)
+	keys, err := c.GetAPIKeys(asHans, added.ID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.NoError(t, c.RevokeAPIKey(asHans, added.ID, key.ID))
+	assert.Equal(t, authorization.ErrAPIKeyNotFound, c.RevokeAPIKey(asHans, added.ID, key.ID))
+}
@@ -1,10 +1,17 @@
 package transport
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-kit/kit/transport"
@@ -12,6 +19,8 @@ import (
 	"github.com/go-kit/log"
 	"github.com/google/uuid"
 
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/problem"
 	"github.com/demeesterdev/todo-service/pkg/authorization"
 	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
 )
@@ -22,6 +31,7 @@ func MakeHTTPHandler(ep ep.Endpoints, logger log.Logger) http.Handler {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
 		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(clientIPToContext, SessionTokenToContext),
 	}
 
 	r.Get("/status", httptransport.NewServer(
@@ -42,12 +52,84 @@ func MakeHTTPHandler(ep ep.Endpoints, logger log.Logger) http.Handler {
 		encodeResponse,
 		options...,
 	).ServeHTTP)
+	r.Get("/search", httptransport.NewServer(
+		ep.ListUsersEndpoint,
+		DecodeHTTPListUsersRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/provision", httptransport.NewServer(
+		ep.ProvisionUserEndpoint,
+		DecodeHTTPProvisionUserRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/import", httptransport.NewServer(
+		ep.ImportUsersEndpoint,
+		DecodeHTTPImportUsersRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
 	r.Post("/login", httptransport.NewServer(
 		ep.AuthenticateUserEndpoint,
 		DecodeHTTPAuthenticateUserRequest,
 		encodeResponse,
 		options...,
 	).ServeHTTP)
+	r.Post("/session", httptransport.NewServer(
+		ep.LoginEndpoint,
+		DecodeHTTPLoginRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/session", httptransport.NewServer(
+		ep.ValidateSessionEndpoint,
+		DecodeHTTPValidateSessionRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/session", httptransport.NewServer(
+		ep.RevokeSessionEndpoint,
+		DecodeHTTPRevokeSessionRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/provisioning-token", httptransport.NewServer(
+		ep.ValidateProvisioningTokenEndpoint,
+		DecodeHTTPValidateProvisioningTokenRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/api-key", httptransport.NewServer(
+		ep.ValidateAPIKeyEndpoint,
+		DecodeHTTPValidateAPIKeyRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/login/mfa", httptransport.NewServer(
+		ep.CompleteMFAEndpoint,
+		DecodeHTTPCompleteMFARequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/password/forgot", httptransport.NewServer(
+		ep.ForgotPasswordEndpoint,
+		DecodeHTTPForgotPasswordRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/password/reset", httptransport.NewServer(
+		ep.ResetPasswordEndpoint,
+		DecodeHTTPResetPasswordRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/email/verify", httptransport.NewServer(
+		ep.VerifyEmailEndpoint,
+		DecodeHTTPVerifyEmailRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
 	r.Get("/{id}", httptransport.NewServer(
 		ep.GetUserEndpoint,
 		DecodeHTTPGetUserRequest,
@@ -66,6 +148,231 @@ func MakeHTTPHandler(ep ep.Endpoints, logger log.Logger) http.Handler {
 		encodeResponse,
 		options...,
 	).ServeHTTP)
+	r.Post("/{id}/unlock", httptransport.NewServer(
+		ep.UnlockUserEndpoint,
+		DecodeHTTPUnlockUserRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Put("/{id}/active", httptransport.NewServer(
+		ep.SetUserActiveEndpoint,
+		DecodeHTTPSetUserActiveRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/{id}/mfa/totp", httptransport.NewServer(
+		ep.EnrollTOTPEndpoint,
+		DecodeHTTPEnrollTOTPRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/{id}/mfa/totp/confirm", httptransport.NewServer(
+		ep.ConfirmTOTPEndpoint,
+		DecodeHTTPConfirmTOTPRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/{id}/mfa", httptransport.NewServer(
+		ep.DisableMFAEndpoint,
+		DecodeHTTPDisableMFARequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/{id}/email/verification", httptransport.NewServer(
+		ep.SendVerificationEmailEndpoint,
+		DecodeHTTPSendVerificationEmailRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/teams", httptransport.NewServer(
+		ep.CreateTeamEndpoint,
+		DecodeHTTPCreateTeamRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/teams", httptransport.NewServer(
+		ep.ListTeamsEndpoint,
+		DecodeHTTPListTeamsRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/teams/invitations/accept", httptransport.NewServer(
+		ep.AcceptInvitationEndpoint,
+		DecodeHTTPAcceptInvitationRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/teams/{team}", httptransport.NewServer(
+		ep.GetTeamEndpoint,
+		DecodeHTTPGetTeamRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Put("/teams/{team}", httptransport.NewServer(
+		ep.UpdateTeamEndpoint,
+		DecodeHTTPUpdateTeamRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/teams/{team}", httptransport.NewServer(
+		ep.DeleteTeamEndpoint,
+		DecodeHTTPDeleteTeamRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/teams/{team}/invitations", httptransport.NewServer(
+		ep.InviteMemberEndpoint,
+		DecodeHTTPInviteMemberRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Put("/teams/{team}/members", httptransport.NewServer(
+		ep.SetMembersEndpoint,
+		DecodeHTTPSetMembersRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Put("/teams/{team}/members/{user}", httptransport.NewServer(
+		ep.UpdateMemberEndpoint,
+		DecodeHTTPUpdateMemberRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/teams/{team}/members/{user}", httptransport.NewServer(
+		ep.RemoveMemberEndpoint,
+		DecodeHTTPRemoveMemberRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/{id}/teams", httptransport.NewServer(
+		ep.GetTeamsEndpoint,
+		DecodeHTTPGetTeamsRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/{id}/api-keys", httptransport.NewServer(
+		ep.CreateAPIKeyEndpoint,
+		DecodeHTTPCreateAPIKeyRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/{id}/api-keys", httptransport.NewServer(
+		ep.GetAPIKeysEndpoint,
+		DecodeHTTPGetAPIKeysRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/{id}/api-keys/{key}", httptransport.NewServer(
+		ep.RevokeAPIKeyEndpoint,
+		DecodeHTTPRevokeAPIKeyRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+
+	r.Post("/oauth/clients", httptransport.NewServer(
+		ep.RegisterClientEndpoint,
+		DecodeHTTPRegisterClientRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/oauth/clients/{client}", httptransport.NewServer(
+		ep.GetClientEndpoint,
+		DecodeHTTPGetClientRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/oauth/consent", httptransport.NewServer(
+		ep.AuthorizeEndpoint,
+		DecodeHTTPAuthorizeRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/oauth/access-token", httptransport.NewServer(
+		ep.ValidateAccessTokenEndpoint,
+		DecodeHTTPValidateAccessTokenRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+
+	// the OAuth2 protocol endpoints answer errors as in RFC 6749
+	oauthOptions := []httptransport.ServerOption{
+		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		httptransport.ServerErrorEncoder(encodeOAuthError),
+	}
+	consent := consentHandler{eps: ep, logger: logger}
+	r.Get("/oauth/authorize", consent.ServeHTTP)
+	r.Post("/oauth/authorize", consent.ServeHTTP)
+	r.Post("/oauth/token", httptransport.NewServer(
+		ep.ExchangeTokenEndpoint,
+		DecodeHTTPExchangeTokenRequest,
+		encodeOAuthResponse,
+		oauthOptions...,
+	).ServeHTTP)
+	r.Post("/oauth/revoke", httptransport.NewServer(
+		ep.RevokeTokenEndpoint,
+		DecodeHTTPRevokeTokenRequest,
+		encodeOAuthResponse,
+		oauthOptions...,
+	).ServeHTTP)
+	r.Post("/oauth/introspect", httptransport.NewServer(
+		ep.IntrospectTokenEndpoint,
+		DecodeHTTPIntrospectTokenRequest,
+		encodeOAuthResponse,
+		oauthOptions...,
+	).ServeHTTP)
+
+	// OpenID Connect discovery, keys and userinfo are served unwrapped
+	r.Get("/.well-known/openid-configuration", httptransport.NewServer(
+		ep.OpenIDConfigurationEndpoint,
+		DecodeHTTPOpenIDConfigurationRequest,
+		encodeOIDCResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/jwks.json", httptransport.NewServer(
+		ep.GetJWKSEndpoint,
+		DecodeHTTPGetJWKSRequest,
+		encodeOIDCResponse,
+		options...,
+	).ServeHTTP)
+	userinfo := httptransport.NewServer(
+		ep.UserInfoEndpoint,
+		DecodeHTTPUserInfoRequest,
+		encodeOIDCResponse,
+		append(options, httptransport.ServerErrorEncoder(encodeBearerError))...,
+	)
+	r.Get("/userinfo", userinfo.ServeHTTP)
+	r.Post("/userinfo", userinfo.ServeHTTP)
+
+	// signing in with an external identity provider
+	r.Get("/login/{provider}", httptransport.NewServer(
+		ep.StartFederatedLoginEndpoint,
+		DecodeHTTPStartFederatedLoginRequest,
+		encodeRedirectResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/login/{provider}/callback", httptransport.NewServer(
+		ep.CompleteFederatedLoginEndpoint,
+		DecodeHTTPCompleteFederatedLoginRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/{id}/identities/{provider}", httptransport.NewServer(
+		ep.StartFederatedLoginEndpoint,
+		DecodeHTTPLinkIdentityRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/{id}/identities", httptransport.NewServer(
+		ep.GetIdentitiesEndpoint,
+		DecodeHTTPGetIdentitiesRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+
+	// SCIM provisioning answers errors as in RFC 7644
+	scimHandler{eps: ep, logger: logger}.routes(r)
+
+	openapi.Mount(r, OpenAPI())
 
 	return r
 }
@@ -79,7 +386,7 @@ func DecodeHTTPAddUserRequest(ctx context.Context, r *http.Request) (interface{}
 	var req ep.AddUserRequest
 	err := json.NewDecoder(r.Body).Decode(&req.User)
 	if err != nil {
-		return nil, err
+		return nil, problem.DecodeError(err)
 	}
 	return req, nil
 }
@@ -103,12 +410,11 @@ func DecodeHTTPUpdateUserRequest(ctx context.Context, r *http.Request) (interfac
 	var err error
 	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		return nil, err
+		return nil, authorization.ErrInvalidUUID
 	}
 	err = json.NewDecoder(r.Body).Decode(&req.User)
 	if err != nil {
-		fmt.Println("json error: ", err.Error())
-		return nil, err
+		return nil, problem.DecodeError(err)
 	}
 
 	return req, nil
@@ -118,7 +424,33 @@ func DecodeHTTPAuthenticateUserRequest(ctx context.Context, r *http.Request) (in
 	var req ep.AuthenticateUserRequest
 	err := json.NewDecoder(r.Body).Decode(&req.User)
 	if err != nil {
-		return nil, err
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPLoginRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.LoginRequest
+	err := json.NewDecoder(r.Body).Decode(&req.User)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPValidateSessionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.ValidateSessionRequest{Token: bearerToken(r.Header)}, nil
+}
+
+func DecodeHTTPRevokeSessionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.RevokeSessionRequest{Token: bearerToken(r.Header)}, nil
+}
+
+func DecodeHTTPImportUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.ImportUsersRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
 	}
 	return req, nil
 }
@@ -127,11 +459,788 @@ func DecodeHTTPDeleteUserRequest(ctx context.Context, r *http.Request) (interfac
 	userIdRaw := chi.URLParam(r, "id")
 	userId, err := uuid.Parse(userIdRaw)
 	if err != nil {
-		return nil, err
+		return nil, authorization.ErrInvalidUUID
 	}
 	return ep.DeleteUserRequest{ID: userId}, nil
 }
 
+func DecodeHTTPUnlockUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.UnlockUserRequest{ID: userId}, nil
+}
+
+func DecodeHTTPCompleteMFARequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.CompleteMFARequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPEnrollTOTPRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.EnrollTOTPRequest{ID: userId}, nil
+}
+
+func DecodeHTTPConfirmTOTPRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.ConfirmTOTPRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPDisableMFARequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.DisableMFARequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	// the body is optional, administrators don't send a code
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPForgotPasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPResetPasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPSendVerificationEmailRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.SendVerificationEmailRequest{ID: userId}, nil
+}
+
+func DecodeHTTPVerifyEmailRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.VerifyEmailRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPCreateTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.CreateTeamRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPGetTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	teamId, err := uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetTeamRequest{ID: teamId}, nil
+}
+
+func DecodeHTTPGetTeamsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetTeamsRequest{UserID: userId}, nil
+}
+
+func DecodeHTTPDeleteTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	teamId, err := uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.DeleteTeamRequest{ID: teamId}, nil
+}
+
+func DecodeHTTPInviteMemberRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.InviteMemberRequest
+	var err error
+	req.TeamID, err = uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPAcceptInvitationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.AcceptInvitationRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPUpdateMemberRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.UpdateMemberRequest
+	var err error
+	req.TeamID, err = uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	req.UserID, err = uuid.Parse(chi.URLParam(r, "user"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPRemoveMemberRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	teamId, err := uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	userId, err := uuid.Parse(chi.URLParam(r, "user"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.RemoveMemberRequest{TeamID: teamId, UserID: userId}, nil
+}
+
+func DecodeHTTPValidateAPIKeyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.ValidateAPIKeyRequest{Key: bearerToken(r.Header)}, nil
+}
+
+func DecodeHTTPCreateAPIKeyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.CreateAPIKeyRequest
+	var err error
+	req.UserID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPGetAPIKeysRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetAPIKeysRequest{UserID: userId}, nil
+}
+
+func DecodeHTTPRevokeAPIKeyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	keyId, err := uuid.Parse(chi.URLParam(r, "key"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.RevokeAPIKeyRequest{UserID: userId, ID: keyId}, nil
+}
+
+// clientIPToContext passes the address of the client to the service to
+// throttle failed logins per ip address
+func clientIPToContext(ctx context.Context, r *http.Request) context.Context {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return authorization.WithClientIP(ctx, host)
+}
+
+// SessionTokenToContext passes the bearer token of a request to the
+// authorization middleware, handlers of other services use it to forward
+// the token with their requests to the authorization service
+func SessionTokenToContext(ctx context.Context, r *http.Request) context.Context {
+	if token := bearerToken(r.Header); token != "" {
+		return authorization.WithSessionToken(ctx, token)
+	}
+	return ctx
+}
+
+// SessionTokenToHeader sends the session token in the context as bearer
+// token, unless the request sets one itself
+func SessionTokenToHeader(ctx context.Context, r *http.Request) context.Context {
+	if token := authorization.SessionToken(ctx); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return ctx
+}
+
+func bearerToken(h http.Header) string {
+	scheme, token, ok := strings.Cut(h.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// MakeClientEndpoints returns an Endpoints struct where each endpoint invokes
+// the corresponding method on the remote instance, via a transport/http.Client.
+func MakeClientEndpoints(instance string) (ep.Endpoints, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	tgt, err := url.Parse(instance)
+	if err != nil {
+		return ep.Endpoints{}, err
+	}
+	tgt.Path = strings.TrimSuffix(tgt.Path, "/")
+
+	options := []httptransport.ClientOption{
+		httptransport.ClientBefore(SessionTokenToHeader),
+	}
+
+	return ep.Endpoints{
+		AddUserEndpoint:                   httptransport.NewClient("POST", tgt, encodeHTTPAddUserRequest, decodeHTTPAddUserResponse, options...).Endpoint(),
+		GetUserEndpoint:                   httptransport.NewClient("GET", tgt, encodeHTTPGetUserRequest, decodeHTTPGetUserResponse, options...).Endpoint(),
+		UpdateUserEndpoint:                httptransport.NewClient("PUT", tgt, encodeHTTPUpdateUserRequest, decodeHTTPUpdateUserResponse, options...).Endpoint(),
+		AuthenticateUserEndpoint:          httptransport.NewClient("POST", tgt, encodeHTTPAuthenticateUserRequest, decodeHTTPAuthenticateUserResponse, options...).Endpoint(),
+		LoginEndpoint:                     httptransport.NewClient("POST", tgt, encodeHTTPLoginRequest, decodeHTTPLoginResponse, options...).Endpoint(),
+		ValidateSessionEndpoint:           httptransport.NewClient("GET", tgt, encodeHTTPValidateSessionRequest, decodeHTTPValidateSessionResponse, options...).Endpoint(),
+		RevokeSessionEndpoint:             httptransport.NewClient("DELETE", tgt, encodeHTTPRevokeSessionRequest, decodeHTTPRevokeSessionResponse, options...).Endpoint(),
+		DeleteUserEndpoint:                httptransport.NewClient("DELETE", tgt, encodeHTTPDeleteUserRequest, decodeHTTPDeleteUserResponse, options...).Endpoint(),
+		GetUsersEndpoint:                  httptransport.NewClient("GET", tgt, encodeHTTPGetUsersRequest, decodeHTTPGetUsersResponse, options...).Endpoint(),
+		ImportUsersEndpoint:               httptransport.NewClient("POST", tgt, encodeHTTPImportUsersRequest, decodeHTTPImportUsersResponse, options...).Endpoint(),
+		UnlockUserEndpoint:                httptransport.NewClient("POST", tgt, encodeHTTPUnlockUserRequest, decodeHTTPUnlockUserResponse, options...).Endpoint(),
+		CompleteMFAEndpoint:               httptransport.NewClient("POST", tgt, encodeHTTPCompleteMFARequest, decodeHTTPCompleteMFAResponse, options...).Endpoint(),
+		EnrollTOTPEndpoint:                httptransport.NewClient("POST", tgt, encodeHTTPEnrollTOTPRequest, decodeHTTPEnrollTOTPResponse, options...).Endpoint(),
+		ConfirmTOTPEndpoint:               httptransport.NewClient("POST", tgt, encodeHTTPConfirmTOTPRequest, decodeHTTPConfirmTOTPResponse, options...).Endpoint(),
+		DisableMFAEndpoint:                httptransport.NewClient("DELETE", tgt, encodeHTTPDisableMFARequest, decodeHTTPDisableMFAResponse, options...).Endpoint(),
+		ForgotPasswordEndpoint:            httptransport.NewClient("POST", tgt, encodeHTTPForgotPasswordRequest, decodeHTTPForgotPasswordResponse, options...).Endpoint(),
+		ResetPasswordEndpoint:             httptransport.NewClient("POST", tgt, encodeHTTPResetPasswordRequest, decodeHTTPResetPasswordResponse, options...).Endpoint(),
+		SendVerificationEmailEndpoint:     httptransport.NewClient("POST", tgt, encodeHTTPSendVerificationEmailRequest, decodeHTTPSendVerificationEmailResponse, options...).Endpoint(),
+		VerifyEmailEndpoint:               httptransport.NewClient("POST", tgt, encodeHTTPVerifyEmailRequest, decodeHTTPVerifyEmailResponse, options...).Endpoint(),
+		CreateTeamEndpoint:                httptransport.NewClient("POST", tgt, encodeHTTPCreateTeamRequest, decodeHTTPCreateTeamResponse, options...).Endpoint(),
+		AcceptInvitationEndpoint:          httptransport.NewClient("POST", tgt, encodeHTTPAcceptInvitationRequest, decodeHTTPAcceptInvitationResponse, options...).Endpoint(),
+		GetTeamEndpoint:                   httptransport.NewClient("GET", tgt, encodeHTTPGetTeamRequest, decodeHTTPGetTeamResponse, options...).Endpoint(),
+		DeleteTeamEndpoint:                httptransport.NewClient("DELETE", tgt, encodeHTTPDeleteTeamRequest, decodeHTTPDeleteTeamResponse, options...).Endpoint(),
+		InviteMemberEndpoint:              httptransport.NewClient("POST", tgt, encodeHTTPInviteMemberRequest, decodeHTTPInviteMemberResponse, options...).Endpoint(),
+		UpdateMemberEndpoint:              httptransport.NewClient("PUT", tgt, encodeHTTPUpdateMemberRequest, decodeHTTPUpdateMemberResponse, options...).Endpoint(),
+		RemoveMemberEndpoint:              httptransport.NewClient("DELETE", tgt, encodeHTTPRemoveMemberRequest, decodeHTTPRemoveMemberResponse, options...).Endpoint(),
+		GetTeamsEndpoint:                  httptransport.NewClient("GET", tgt, encodeHTTPGetTeamsRequest, decodeHTTPGetTeamsResponse, options...).Endpoint(),
+		CreateAPIKeyEndpoint:              httptransport.NewClient("POST", tgt, encodeHTTPCreateAPIKeyRequest, decodeHTTPCreateAPIKeyResponse, options...).Endpoint(),
+		GetAPIKeysEndpoint:                httptransport.NewClient("GET", tgt, encodeHTTPGetAPIKeysRequest, decodeHTTPGetAPIKeysResponse, options...).Endpoint(),
+		RevokeAPIKeyEndpoint:              httptransport.NewClient("DELETE", tgt, encodeHTTPRevokeAPIKeyRequest, decodeHTTPRevokeAPIKeyResponse, options...).Endpoint(),
+		ValidateAPIKeyEndpoint:            httptransport.NewClient("GET", tgt, encodeHTTPValidateAPIKeyRequest, decodeHTTPValidateAPIKeyResponse, options...).Endpoint(),
+		RegisterClientEndpoint:            httptransport.NewClient("POST", tgt, encodeHTTPRegisterClientRequest, decodeHTTPRegisterClientResponse, options...).Endpoint(),
+		GetClientEndpoint:                 httptransport.NewClient("GET", tgt, encodeHTTPGetClientRequest, decodeHTTPGetClientResponse, options...).Endpoint(),
+		AuthorizeEndpoint:                 httptransport.NewClient("POST", tgt, encodeHTTPAuthorizeRequest, decodeHTTPAuthorizeResponse, options...).Endpoint(),
+		ExchangeTokenEndpoint:             httptransport.NewClient("POST", tgt, encodeHTTPExchangeTokenRequest, decodeHTTPExchangeTokenResponse, options...).Endpoint(),
+		RevokeTokenEndpoint:               httptransport.NewClient("POST", tgt, encodeHTTPRevokeTokenRequest, decodeHTTPRevokeTokenResponse, options...).Endpoint(),
+		IntrospectTokenEndpoint:           httptransport.NewClient("POST", tgt, encodeHTTPIntrospectTokenRequest, decodeHTTPIntrospectTokenResponse, options...).Endpoint(),
+		ValidateAccessTokenEndpoint:       httptransport.NewClient("GET", tgt, encodeHTTPValidateAccessTokenRequest, decodeHTTPValidateAccessTokenResponse, options...).Endpoint(),
+		OpenIDConfigurationEndpoint:       httptransport.NewClient("GET", tgt, encodeHTTPOpenIDConfigurationRequest, decodeHTTPOpenIDConfigurationResponse, options...).Endpoint(),
+		GetJWKSEndpoint:                   httptransport.NewClient("GET", tgt, encodeHTTPGetJWKSRequest, decodeHTTPGetJWKSResponse, options...).Endpoint(),
+		UserInfoEndpoint:                  httptransport.NewClient("GET", tgt, encodeHTTPUserInfoRequest, decodeHTTPUserInfoResponse, options...).Endpoint(),
+		StartFederatedLoginEndpoint:       startFederatedLoginClient(tgt, options),
+		CompleteFederatedLoginEndpoint:    httptransport.NewClient("GET", tgt, encodeHTTPCompleteFederatedLoginRequest, decodeHTTPCompleteFederatedLoginResponse, options...).Endpoint(),
+		GetIdentitiesEndpoint:             httptransport.NewClient("GET", tgt, encodeHTTPGetIdentitiesRequest, decodeHTTPGetIdentitiesResponse, options...).Endpoint(),
+		ListUsersEndpoint:                 httptransport.NewClient("GET", tgt, encodeHTTPListUsersRequest, decodeHTTPListUsersResponse, options...).Endpoint(),
+		ValidateProvisioningTokenEndpoint: httptransport.NewClient("GET", tgt, encodeHTTPValidateProvisioningTokenRequest, decodeHTTPValidateProvisioningTokenResponse, options...).Endpoint(),
+		ProvisionUserEndpoint:             httptransport.NewClient("POST", tgt, encodeHTTPProvisionUserRequest, decodeHTTPProvisionUserResponse, options...).Endpoint(),
+		SetUserActiveEndpoint:             httptransport.NewClient("PUT", tgt, encodeHTTPSetUserActiveRequest, decodeHTTPSetUserActiveResponse, options...).Endpoint(),
+		ListTeamsEndpoint:                 httptransport.NewClient("GET", tgt, encodeHTTPListTeamsRequest, decodeHTTPListTeamsResponse, options...).Endpoint(),
+		UpdateTeamEndpoint:                httptransport.NewClient("PUT", tgt, encodeHTTPUpdateTeamRequest, decodeHTTPUpdateTeamResponse, options...).Endpoint(),
+		SetMembersEndpoint:                httptransport.NewClient("PUT", tgt, encodeHTTPSetMembersRequest, decodeHTTPSetMembersResponse, options...).Endpoint(),
+		ServiceStatusEndpoint:             httptransport.NewClient("GET", tgt, encodeHTTPServiceStatusRequest, decodeHTTPServiceStatusResponse, options...).Endpoint(),
+	}, nil
+}
+
+// client functions
+// encode request for server
+
+func encodeHTTPAddUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/", ...)
+	r := request.(ep.AddUserRequest)
+	req.URL.Path += "/"
+	return encodeRequest(ctx, req, r.User)
+}
+
+func encodeHTTPGetUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/{id}", ...) accepts an id or a username
+	r := request.(ep.GetUserRequest)
+	if r.User.ID != uuid.Nil {
+		req.URL.Path += "/" + r.User.ID.String()
+		return nil
+	}
+	if r.User.Username == "" {
+		return authorization.ErrIDMissing
+	}
+	req.URL.Path += "/" + url.PathEscape(r.User.Username)
+	return nil
+}
+
+func encodeHTTPUpdateUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Put("/{id}", ...)
+	r := request.(ep.UpdateUserRequest)
+	req.URL.Path += "/" + r.ID.String()
+	return encodeRequest(ctx, req, r.User)
+}
+
+func encodeHTTPAuthenticateUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/login", ...)
+	r := request.(ep.AuthenticateUserRequest)
+	req.URL.Path += "/login"
+	return encodeRequest(ctx, req, r.User)
+}
+
+func encodeHTTPLoginRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/session", ...)
+	r := request.(ep.LoginRequest)
+	req.URL.Path += "/session"
+	return encodeRequest(ctx, req, r.User)
+}
+
+func encodeHTTPValidateSessionRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/session", ...)
+	r := request.(ep.ValidateSessionRequest)
+	req.URL.Path += "/session"
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	return nil
+}
+
+func encodeHTTPRevokeSessionRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/session", ...)
+	r := request.(ep.RevokeSessionRequest)
+	req.URL.Path += "/session"
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	return nil
+}
+
+func encodeHTTPDeleteUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/{id}", ...)
+	r := request.(ep.DeleteUserRequest)
+	req.URL.Path += "/" + r.ID.String()
+	return nil
+}
+
+func encodeHTTPGetUsersRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/", ...)
+	req.URL.Path += "/"
+	return nil
+}
+
+func encodeHTTPImportUsersRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/import", ...)
+	r := request.(ep.ImportUsersRequest)
+	req.URL.Path += "/import"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPUnlockUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/unlock", ...)
+	r := request.(ep.UnlockUserRequest)
+	req.URL.Path += "/" + r.ID.String() + "/unlock"
+	return nil
+}
+
+func encodeHTTPCompleteMFARequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/login/mfa", ...)
+	r := request.(ep.CompleteMFARequest)
+	req.URL.Path += "/login/mfa"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPEnrollTOTPRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/mfa/totp", ...)
+	r := request.(ep.EnrollTOTPRequest)
+	req.URL.Path += "/" + r.ID.String() + "/mfa/totp"
+	return nil
+}
+
+func encodeHTTPConfirmTOTPRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/mfa/totp/confirm", ...)
+	r := request.(ep.ConfirmTOTPRequest)
+	req.URL.Path += "/" + r.ID.String() + "/mfa/totp/confirm"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPDisableMFARequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/{id}/mfa", ...)
+	r := request.(ep.DisableMFARequest)
+	req.URL.Path += "/" + r.ID.String() + "/mfa"
+	if r.Code == "" {
+		return nil
+	}
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPForgotPasswordRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/password/forgot", ...)
+	r := request.(ep.ForgotPasswordRequest)
+	req.URL.Path += "/password/forgot"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPResetPasswordRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/password/reset", ...)
+	r := request.(ep.ResetPasswordRequest)
+	req.URL.Path += "/password/reset"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPSendVerificationEmailRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/email/verification", ...)
+	r := request.(ep.SendVerificationEmailRequest)
+	req.URL.Path += "/" + r.ID.String() + "/email/verification"
+	return nil
+}
+
+func encodeHTTPVerifyEmailRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/email/verify", ...)
+	r := request.(ep.VerifyEmailRequest)
+	req.URL.Path += "/email/verify"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPCreateTeamRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/teams", ...)
+	r := request.(ep.CreateTeamRequest)
+	req.URL.Path += "/teams"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPAcceptInvitationRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/teams/invitations/accept", ...)
+	r := request.(ep.AcceptInvitationRequest)
+	req.URL.Path += "/teams/invitations/accept"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPGetTeamRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/teams/{team}", ...)
+	r := request.(ep.GetTeamRequest)
+	req.URL.Path += "/teams/" + r.ID.String()
+	return nil
+}
+
+func encodeHTTPDeleteTeamRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/teams/{team}", ...)
+	r := request.(ep.DeleteTeamRequest)
+	req.URL.Path += "/teams/" + r.ID.String()
+	return nil
+}
+
+func encodeHTTPInviteMemberRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/teams/{team}/invitations", ...)
+	r := request.(ep.InviteMemberRequest)
+	req.URL.Path += "/teams/" + r.TeamID.String() + "/invitations"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPUpdateMemberRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Put("/teams/{team}/members/{user}", ...)
+	r := request.(ep.UpdateMemberRequest)
+	req.URL.Path += "/teams/" + r.TeamID.String() + "/members/" + r.UserID.String()
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPRemoveMemberRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/teams/{team}/members/{user}", ...)
+	r := request.(ep.RemoveMemberRequest)
+	req.URL.Path += "/teams/" + r.TeamID.String() + "/members/" + r.UserID.String()
+	return nil
+}
+
+func encodeHTTPGetTeamsRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/{id}/teams", ...)
+	r := request.(ep.GetTeamsRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/teams"
+	return nil
+}
+
+func encodeHTTPCreateAPIKeyRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/api-keys", ...)
+	r := request.(ep.CreateAPIKeyRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/api-keys"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPGetAPIKeysRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/{id}/api-keys", ...)
+	r := request.(ep.GetAPIKeysRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/api-keys"
+	return nil
+}
+
+func encodeHTTPRevokeAPIKeyRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Delete("/{id}/api-keys/{key}", ...)
+	r := request.(ep.RevokeAPIKeyRequest)
+	req.URL.Path += "/" + r.UserID.String() + "/api-keys/" + r.ID.String()
+	return nil
+}
+
+func encodeHTTPValidateAPIKeyRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/api-key", ...)
+	r := request.(ep.ValidateAPIKeyRequest)
+	req.URL.Path += "/api-key"
+	req.Header.Set("Authorization", "Bearer "+r.Key)
+	return nil
+}
+
+func encodeHTTPServiceStatusRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/status", ...)
+	req.URL.Path += "/status"
+	return nil
+}
+
+// client functions
+// decode response from server
+
+func decodeHTTPAddUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.AddUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPUpdateUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.UpdateUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPAuthenticateUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.AuthenticateUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPLoginResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.LoginResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPValidateSessionResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ValidateSessionResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPRevokeSessionResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.RevokeSessionResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPDeleteUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.DeleteUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetUsersResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetUsersResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPImportUsersResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ImportUsersResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPUnlockUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.UnlockUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPCompleteMFAResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.CompleteMFAResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPEnrollTOTPResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.EnrollTOTPResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPConfirmTOTPResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ConfirmTOTPResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPDisableMFAResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.DisableMFAResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPForgotPasswordResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ForgotPasswordResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPResetPasswordResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ResetPasswordResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPSendVerificationEmailResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.SendVerificationEmailResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPVerifyEmailResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.VerifyEmailResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPCreateTeamResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.CreateTeamResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPAcceptInvitationResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.AcceptInvitationResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetTeamResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetTeamResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPDeleteTeamResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.DeleteTeamResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPInviteMemberResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.InviteMemberResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPUpdateMemberResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.UpdateMemberResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPRemoveMemberResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.RemoveMemberResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetTeamsResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetTeamsResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPCreateAPIKeyResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.CreateAPIKeyResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetAPIKeysResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetAPIKeysResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPRevokeAPIKeyResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.RevokeAPIKeyResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPValidateAPIKeyResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ValidateAPIKeyResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPServiceStatusResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ServiceStatusResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+// decodeResponse decodes a successful response body into response.
+// Problems are decoded into respErr so they reach the caller as the
+// sentinel error of the service method.
+func decodeResponse(resp *http.Response, response interface{}, respErr *error) error {
+	if err := problems.Decode(resp); err != nil {
+		*respErr = err
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// encodeRequest JSON-encodes the request to the HTTP request body.
+func encodeRequest(_ context.Context, req *http.Request, request interface{}) error {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(request)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(&buf)
+	return nil
+}
+
 // errorer is implemented by all concrete response types that may contain
 // errors.
 type errorer interface {
@@ -149,16 +1258,9 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 }
 
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	switch err {
-	case authorization.ErrInvalidUserObject:
-		w.WriteHeader(http.StatusBadRequest)
-	case authorization.ErrAuthenticationFailed:
-		w.WriteHeader(http.StatusUnauthorized)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
+	var locked *authorization.LockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	}
+	problems.Write(w, err)
 }
@@ -0,0 +1,630 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/internal/jose"
+	"github.com/demeesterdev/todo-service/internal/oidc"
+	"github.com/demeesterdev/todo-service/internal/oidc/oidctest"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/internal/throttle"
+	"github.com/demeesterdev/todo-service/internal/totp"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+func TestHTTPClient(t *testing.T) {
+	s, _ := authorization.NewInMemService(argon2id.DefaultConfig)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, err := MakeClientEndpoints(srv.URL)
+	assert.NoError(t, err)
+
+	added, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Empty(t, added.Password)
+
+	found, err := c.FindUser(ctx, "hanshandjes")
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, found.ID)
+
+	authenticated, err := c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, authenticated.ID)
+
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "wrong"})
+	assert.Equal(t, authorization.ErrAuthenticationFailed, err)
+
+	users, err := c.GetUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(users))
+
+	assert.NoError(t, c.DeleteUser(ctx, added.ID))
+	_, err = c.GetUser(ctx, added.ID)
+	assert.Equal(t, authorization.ErrNotFound, err)
+
+	code, err := c.ServiceStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+}
+
+func TestHTTPLockout(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params, authorization.WithLoginThrottle(
+		throttle.Config{Threshold: 1, BaseDelay: 90 * time.Second, MaxDelay: time.Hour, Window: time.Hour},
+		throttle.Config{},
+	))
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	added, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "wrong"})
+	assert.Equal(t, authorization.ErrAuthenticationFailed, err)
+
+	resp, err := http.Post(srv.URL+"/login", "application/json", strings.NewReader(`{"username": "hanshandjes", "password": "correct horse battery staple"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "90", resp.Header.Get("Retry-After"))
+
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.Equal(t, authorization.ErrTooManyAttempts, err)
+
+	assert.NoError(t, c.UnlockUser(ctx, added.ID))
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+}
+
+func TestHTTPMFA(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	added, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	enrollment, err := c.EnrollTOTP(ctx, added.ID)
+	assert.NoError(t, err)
+	secret, _ := totp.Encoding.DecodeString(enrollment.Secret)
+	recovery, err := c.ConfirmTOTP(ctx, added.ID, totp.Code(secret, totp.Counter(time.Now())))
+	assert.NoError(t, err)
+	assert.Len(t, recovery, authorization.RecoveryCodes)
+
+	// the first step returns a challenge and no user
+	resp, err := http.Post(srv.URL+"/login", "application/json", strings.NewReader(`{"username": "hanshandjes", "password": "correct horse battery staple"}`))
+	assert.NoError(t, err)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body, "user")
+	assert.NotEmpty(t, body["mfa_challenge"])
+
+	_, err = c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	var mfa *authorization.MFARequiredError
+	assert.True(t, errors.As(err, &mfa))
+
+	_, err = c.CompleteMFA(ctx, mfa.Challenge, "000000")
+	assert.Equal(t, authorization.ErrInvalidMFACode, err)
+	session, err := c.CompleteMFA(ctx, mfa.Challenge, recovery[0])
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, session.User.ID)
+	assert.True(t, session.User.MFAEnabled)
+
+	assert.NoError(t, c.DisableMFA(ctx, added.ID, ""))
+	u, err := c.AuthenticateUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.False(t, u.MFAEnabled)
+}
+
+func TestHTTPDisableOwnMFA(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	_, err := authorization.BootstrapAdmin(ctx, s, "admin", "correct horse battery staple")
+	assert.NoError(t, err)
+	hans, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	session, err := c.Login(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	asHans := authorization.WithSessionToken(ctx, session.Token)
+
+	enroll := func() []string {
+		enrollment, err := c.EnrollTOTP(asHans, hans.ID)
+		assert.NoError(t, err)
+		secret, _ := totp.Encoding.DecodeString(enrollment.Secret)
+		recovery, err := c.ConfirmTOTP(asHans, hans.ID, totp.Code(secret, totp.Counter(time.Now())))
+		assert.NoError(t, err)
+		return recovery
+	}
+	recovery := enroll()
+
+	// a stolen session alone can't remove the second factor
+	assert.Equal(t, authorization.ErrForbidden, c.DisableMFA(asHans, hans.ID, ""))
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/"+hans.ID.String()+"/mfa", nil)
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, authorization.ErrInvalidMFACode, c.DisableMFA(asHans, hans.ID, "000000"))
+	assert.NoError(t, c.DisableMFA(asHans, hans.ID, recovery[0]))
+	u, err := c.GetUser(asHans, hans.ID)
+	assert.NoError(t, err)
+	assert.False(t, u.MFAEnabled)
+
+	// administrators don't need the code
+	enroll()
+	adminSession, err := c.Login(ctx, authorization.User{Username: "admin", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DisableMFA(authorization.WithSessionToken(ctx, adminSession.Token), hans.ID, ""))
+	u, err = c.GetUser(asHans, hans.ID)
+	assert.NoError(t, err)
+	assert.False(t, u.MFAEnabled)
+}
+
+func TestHTTPPermissions(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	_, err := authorization.BootstrapAdmin(ctx, s, "admin", "correct horse battery staple")
+	assert.NoError(t, err)
+
+	// anyone can sign up, only as member
+	hans, err := c.AddUser(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.RoleMember, hans.Role)
+	_, err = c.AddUser(ctx, authorization.User{Username: "mallory", Password: "correct horse battery staple", Role: authorization.RoleAdmin})
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	jane, err := c.AddUser(ctx, authorization.User{Username: "jane", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	_, err = c.GetUsers(ctx)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	session, err := c.Login(ctx, authorization.User{Username: "hanshandjes", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	asHans := authorization.WithSessionToken(ctx, session.Token)
+
+	u, err := c.ValidateSession(ctx, session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, hans.ID, u.ID)
+
+	// members only reach their own record
+	_, err = c.GetUser(asHans, hans.ID)
+	assert.NoError(t, err)
+	_, err = c.FindUser(asHans, "hanshandjes")
+	assert.NoError(t, err)
+	_, err = c.GetUsers(asHans)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetUser(asHans, jane.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, c.DeleteUser(asHans, jane.ID))
+	_, err = c.UpdateUser(asHans, hans.ID, authorization.User{ID: hans.ID, Role: authorization.RoleAdmin})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.UpdateUser(asHans, hans.ID, authorization.User{ID: hans.ID, Email: "hans@example.com"})
+	assert.NoError(t, err)
+
+	// the raw request carries the bearer token itself
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/"+jane.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// admins manage every user
+	adminSession, err := c.Login(ctx, authorization.User{Username: "admin", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	asAdmin := authorization.WithSessionToken(ctx, adminSession.Token)
+	users, err := c.GetUsers(asAdmin)
+	assert.NoError(t, err)
+	assert.Len(t, users, 3)
+	_, err = c.UpdateUser(asAdmin, hans.ID, authorization.User{ID: hans.ID, Role: authorization.RoleReadOnly})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeleteUser(asAdmin, jane.ID))
+
+	// read-only users can no longer change their record
+	_, err = c.UpdateUser(asHans, hans.ID, authorization.User{ID: hans.ID, Email: "hans@example.org"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetUser(asHans, hans.ID)
+	assert.NoError(t, err)
+
+	// after logging out the token is worthless
+	assert.NoError(t, c.RevokeSession(ctx, session.Token))
+	_, err = c.GetUser(asHans, hans.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+}
+
+func TestHTTPTeamPermissions(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	login := func(username string) (authorization.User, context.Context) {
+		u, err := c.AddUser(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		session, err := c.Login(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		return u, authorization.WithSessionToken(ctx, session.Token)
+	}
+	owner, asOwner := login("owner")
+	admin, asAdmin := login("admin")
+	member, asMember := login("member")
+	_, asOutsider := login("outsider")
+
+	_, err := c.CreateTeam(ctx, authorization.Team{Name: "kitchen"}, uuid.Nil)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	team, err := c.CreateTeam(asOwner, authorization.Team{Name: "kitchen"}, uuid.Nil)
+	assert.NoError(t, err)
+
+	// only owners invite owners
+	token, err := c.InviteMember(asOwner, team.ID, authorization.TeamRoleAdmin)
+	assert.NoError(t, err)
+	_, err = c.AcceptInvitation(asAdmin, token, uuid.Nil)
+	assert.NoError(t, err)
+	_, err = c.InviteMember(asAdmin, team.ID, authorization.TeamRoleOwner)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	token, err = c.InviteMember(asAdmin, team.ID, authorization.TeamRoleMember)
+	assert.NoError(t, err)
+	_, err = c.AcceptInvitation(asMember, token, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = c.GetTeam(asOutsider, team.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetTeams(asOutsider, member.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	got, err := c.GetTeam(asMember, team.ID)
+	assert.NoError(t, err)
+	assert.Len(t, got.Members, 3)
+	_, err = c.InviteMember(asMember, team.ID, authorization.TeamRoleGuest)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	// admins don't touch owners
+	_, err = c.UpdateMember(asAdmin, team.ID, owner.ID, authorization.TeamRoleGuest)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, c.RemoveMember(asAdmin, team.ID, owner.ID))
+	_, err = c.UpdateMember(asAdmin, team.ID, member.ID, authorization.TeamRoleGuest)
+	assert.NoError(t, err)
+	_, err = c.UpdateMember(asOwner, team.ID, admin.ID, authorization.TeamRoleOwner)
+	assert.NoError(t, err)
+
+	// members can leave, only owners delete the team
+	assert.NoError(t, c.RemoveMember(asMember, team.ID, member.ID))
+	teams, err := c.GetTeams(asMember, member.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, teams)
+	assert.Equal(t, authorization.ErrForbidden, c.DeleteTeam(asMember, team.ID))
+	assert.NoError(t, c.DeleteTeam(asOwner, team.ID))
+	_, err = c.GetTeam(asOwner, team.ID)
+	assert.Equal(t, authorization.ErrTeamNotFound, err)
+}
+
+func TestHTTPAPIKeys(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s), endpoints.ValidationMiddleware(endpoints.DefaultLimits)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	login := func(username string) (authorization.User, context.Context) {
+		u, err := c.AddUser(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		session, err := c.Login(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		return u, authorization.WithSessionToken(ctx, session.Token)
+	}
+	user, asUser := login("scripter")
+	_, asOther := login("other")
+
+	read := authorization.APIKey{Name: "backup", Scopes: []authorization.Scope{authorization.ScopeTodosRead}}
+	_, err := c.CreateAPIKey(ctx, user.ID, read)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	_, err = c.CreateAPIKey(asOther, user.ID, read)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.CreateAPIKey(asUser, user.ID, authorization.APIKey{Name: "backup", Scopes: []authorization.Scope{"todos:admin"}})
+	var fields problem.FieldErrors
+	assert.ErrorAs(t, err, &fields)
+
+	created, err := c.CreateAPIKey(asUser, user.ID, read)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Key)
+
+	k, err := c.ValidateAPIKey(ctx, created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, k.UserID)
+	assert.Empty(t, k.Key)
+	assert.NotNil(t, k.LastUsedAt)
+
+	_, err = c.GetAPIKeys(asOther, user.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	keys, err := c.GetAPIKeys(asUser, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "backup", keys[0].Name)
+
+	// API keys are no sessions
+	_, err = c.GetAPIKeys(authorization.WithSessionToken(ctx, created.Key), user.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	// besides listing the teams of their user for the todo service
+	other, _ := c.FindUser(asOther, "other")
+	asKey := authorization.WithSessionToken(ctx, created.Key)
+	_, err = c.GetTeams(asKey, user.ID)
+	assert.NoError(t, err)
+	_, err = c.GetTeams(asKey, other.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	assert.Equal(t, authorization.ErrForbidden, c.RevokeAPIKey(asOther, user.ID, created.ID))
+	assert.NoError(t, c.RevokeAPIKey(asUser, user.ID, created.ID))
+	assert.Equal(t, authorization.ErrAPIKeyNotFound, c.RevokeAPIKey(asUser, user.ID, created.ID))
+	_, err = c.ValidateAPIKey(ctx, created.Key)
+	assert.Equal(t, authorization.ErrInvalidToken, err)
+}
+
+func TestHTTPOAuth(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s), endpoints.ValidationMiddleware(endpoints.DefaultLimits)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	dev, _ := c.AddUser(ctx, authorization.User{Username: "developer", Password: "correct horse battery staple"})
+	session, _ := c.Login(ctx, authorization.User{Username: "developer", Password: "correct horse battery staple"})
+	asDev := authorization.WithSessionToken(ctx, session.Token)
+	c.AddUser(ctx, authorization.User{Username: "planner", Password: "correct horse battery staple"})
+
+	_, err := c.RegisterClient(ctx, authorization.OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}}, uuid.Nil)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	client, err := c.RegisterClient(asDev, authorization.OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}, Scopes: []authorization.Scope{authorization.ScopeTodosRead}}, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, dev.ID, client.OwnerID)
+	assert.NotEmpty(t, client.Secret)
+
+	// the consent screen redirects back to the client, it isn't followed
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {"https://cal.example/cb"},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	authorize := func(method string, form url.Values) *http.Response {
+		var resp *http.Response
+		var err error
+		if method == http.MethodGet {
+			resp, err = browser.Get(srv.URL + "/oauth/authorize?" + form.Encode())
+		} else {
+			resp, err = browser.PostForm(srv.URL+"/oauth/authorize", form)
+		}
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	with := func(extra ...string) url.Values {
+		v := url.Values{}
+		for k, vs := range query {
+			v[k] = vs
+		}
+		for i := 0; i < len(extra); i += 2 {
+			v.Set(extra[i], extra[i+1])
+		}
+		return v
+	}
+
+	resp := authorize(http.MethodGet, query)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	// unregistered redirect uris aren't redirected to
+	resp = authorize(http.MethodGet, with("redirect_uri", "https://evil.example/cb"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = authorize(http.MethodGet, with("code_challenge_method", "plain"))
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "https://cal.example/cb?error=invalid_request&state=xyz", resp.Header.Get("Location"))
+	resp = authorize(http.MethodPost, with("action", "deny"))
+	assert.Equal(t, "https://cal.example/cb?error=access_denied&state=xyz", resp.Header.Get("Location"))
+	resp = authorize(http.MethodPost, with("action", "allow", "username", "planner", "password", "wrong"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authorize(http.MethodPost, with("action", "allow", "username", "planner", "password", "correct horse battery staple"))
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	assert.Equal(t, "cal.example", location.Host)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	assert.NotEmpty(t, code)
+
+	credentials := authorization.ClientCredentials{ID: client.ID, Secret: client.Secret}
+	exchange := authorization.TokenRequest{GrantType: authorization.GrantTypeAuthorizationCode, Client: credentials, Code: code, RedirectURI: "https://cal.example/cb", CodeVerifier: verifier}
+	_, err = c.ExchangeToken(ctx, authorization.TokenRequest{GrantType: exchange.GrantType, Client: authorization.ClientCredentials{ID: client.ID, Secret: "guess"}, Code: code, RedirectURI: exchange.RedirectURI, CodeVerifier: verifier})
+	assert.Equal(t, authorization.ErrInvalidClient, err)
+	token, err := c.ExchangeToken(ctx, exchange)
+	assert.NoError(t, err)
+	assert.Equal(t, "todos:read", token.Scope)
+	_, err = c.ExchangeToken(ctx, exchange)
+	assert.Equal(t, authorization.ErrInvalidGrant, err)
+
+	// token endpoint errors use the RFC 6749 format
+	form := url.Values{"grant_type": {"password"}, "client_id": {client.ID.String()}, "client_secret": {client.Secret}}
+	resp, err = http.PostForm(srv.URL+"/oauth/token", form)
+	assert.NoError(t, err)
+	var oauthErr struct{ Error string }
+	json.NewDecoder(resp.Body).Decode(&oauthErr)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "unsupported_grant_type", oauthErr.Error)
+
+	at, err := c.ValidateAccessToken(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, at.Has(authorization.ScopeTodosRead))
+
+	i, err := c.IntrospectToken(ctx, token.AccessToken, credentials)
+	assert.NoError(t, err)
+	assert.True(t, i.Active)
+	assert.Equal(t, "planner", i.Username)
+
+	refreshed, err := c.ExchangeToken(ctx, authorization.TokenRequest{GrantType: authorization.GrantTypeRefreshToken, Client: credentials, RefreshToken: token.RefreshToken})
+	assert.NoError(t, err)
+	assert.NoError(t, c.RevokeToken(ctx, refreshed.RefreshToken, credentials))
+	i, err = c.IntrospectToken(ctx, refreshed.AccessToken, credentials)
+	assert.NoError(t, err)
+	assert.False(t, i.Active)
+}
+
+func TestHTTPOIDC(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params)
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s), endpoints.ValidationMiddleware(endpoints.DefaultLimits)), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	m, err := c.OpenIDConfiguration(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.DefaultOIDCConfig.Issuer+"/userinfo", m.UserinfoEndpoint)
+
+	u, _ := s.AddUser(ctx, authorization.User{Username: "planner", Password: "correct horse battery staple"})
+	client, _ := s.RegisterClient(ctx, authorization.OAuthClient{Name: "wiki", RedirectURIs: []string{"https://wiki.example/cb"}, Public: true}, u.ID)
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	code, _ := s.Authorize(ctx, authorization.AuthorizationRequest{ClientID: client.ID, UserID: u.ID, RedirectURI: "https://wiki.example/cb", Scopes: []authorization.Scope{authorization.ScopeOpenID},
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(sum[:]), CodeChallengeMethod: authorization.CodeChallengeS256, Nonce: "abc"})
+	token, err := c.ExchangeToken(ctx, authorization.TokenRequest{GrantType: authorization.GrantTypeAuthorizationCode, Client: authorization.ClientCredentials{ID: client.ID},
+		Code: code, RedirectURI: "https://wiki.example/cb", CodeVerifier: verifier})
+	assert.NoError(t, err)
+
+	keys, err := c.GetJWKS(ctx)
+	assert.NoError(t, err)
+	var claims authorization.IDToken
+	assert.NoError(t, jose.Verify(token.IDToken, keys, &claims))
+	assert.Equal(t, "abc", claims.Nonce)
+	assert.Equal(t, "planner", claims.PreferredUsername)
+
+	info, err := c.UserInfo(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.UserInfo{Subject: u.ID.String(), PreferredUsername: "planner"}, info)
+	_, err = c.UserInfo(ctx, authorization.AccessTokenPrefix+"unknown")
+	assert.Equal(t, authorization.ErrInvalidToken, err)
+
+	// invalid tokens get a bearer challenge
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+authorization.AccessTokenPrefix+"unknown")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Bearer error="invalid_token"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func TestHTTPFederatedLogin(t *testing.T) {
+	var h http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { h.ServeHTTP(w, r) }))
+	defer srv.Close()
+	idp := oidctest.NewProvider(
+		oidctest.Client{ID: "todo", Secret: "s3cret", RedirectURL: srv.URL + "/login/corp/callback"},
+		oidc.Claims{Subject: "00u1", PreferredUsername: "alice"},
+	)
+	defer idp.Close()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params, authorization.WithFederation(authorization.FederationConfig{
+		Providers:       []authorization.IdentityProvider{{Name: "corp", Config: idp.Config()}},
+		JITProvisioning: true,
+		LoginTTL:        time.Minute,
+	}))
+	h = MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s), endpoints.ValidationMiddleware(endpoints.DefaultLimits)), log.NewNopLogger())
+
+	// a browser follows the redirects to the provider and back
+	resp, err := http.Get(srv.URL + "/login/corp")
+	assert.NoError(t, err)
+	var signedIn endpoints.CompleteFederatedLoginResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&signedIn))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "alice", signedIn.Session.User.Username)
+
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	asAlice := authorization.WithSessionToken(ctx, signedIn.Session.Token)
+	ids, err := c.GetIdentities(asAlice, signedIn.Session.User.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "00u1", ids[0].Subject)
+
+	// the client gets the url of the provider instead of following it
+	authURL, err := c.StartFederatedLogin(ctx, "corp", uuid.Nil)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, idp.URL+"/authorize?"))
+	_, err = c.StartFederatedLogin(ctx, "other", uuid.Nil)
+	assert.Equal(t, authorization.ErrProviderNotFound, err)
+
+	// only users themselves link identities
+	bob, _ := s.AddUser(ctx, authorization.User{Username: "bob", Password: "correct horse battery staple"})
+	_, err = c.StartFederatedLogin(ctx, "corp", bob.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	_, err = c.StartFederatedLogin(asAlice, "corp", bob.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetIdentities(asAlice, bob.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	bobSession, _ := s.Login(ctx, authorization.User{Username: "bob", Password: "correct horse battery staple"})
+	asBob := authorization.WithSessionToken(ctx, bobSession.Token)
+	authURL, err = c.StartFederatedLogin(asBob, "corp", bob.ID)
+	assert.NoError(t, err)
+	idp.SetClaims(oidc.Claims{Subject: "00u2"})
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirect.Get(authURL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	session, err := c.CompleteFederatedLogin(ctx, "corp", callback.Query().Get("state"), callback.Query().Get("code"))
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, session.User.ID)
+	_, err = c.CompleteFederatedLogin(ctx, "corp", callback.Query().Get("state"), callback.Query().Get("code"))
+	assert.Equal(t, authorization.ErrInvalidState, err)
+
+	// the provider reports a sign in the user declined
+	resp, err = http.Get(srv.URL + "/login/corp/callback?error=access_denied&state=x")
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.ErrFederationFailed, problems.Decode(resp))
+	resp.Body.Close()
+}
@@ -0,0 +1,593 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// oauthErrors are the errors of the token, revocation and introspection
+// endpoints, they are answered as in RFC 6749 section 5.2 instead of as
+// problem details
+var oauthErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{authorization.ErrInvalidClient, http.StatusUnauthorized, "invalid_client"},
+	{authorization.ErrInvalidGrant, http.StatusBadRequest, "invalid_grant"},
+	{authorization.ErrUnsupportedGrantType, http.StatusBadRequest, "unsupported_grant_type"},
+	{authorization.ErrInvalidScope, http.StatusBadRequest, "invalid_scope"},
+	{authorization.ErrInvalidOAuthRequest, http.StatusBadRequest, "invalid_request"},
+}
+
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// encodeOAuthError writes err as OAuth2 error response. Other client errors,
+// like invalid fields, are an invalid_request, anything else a server_error
+// without details.
+func encodeOAuthError(_ context.Context, err error, w http.ResponseWriter) {
+	status, body := http.StatusInternalServerError, oauthError{Error: "server_error"}
+	if p := problems.Problem(err); p.Status < http.StatusInternalServerError {
+		status, body = http.StatusBadRequest, oauthError{Error: "invalid_request", Description: err.Error()}
+	}
+	for _, e := range oauthErrors {
+		if errors.Is(err, e.err) {
+			status, body = e.status, oauthError{Error: e.code, Description: err.Error()}
+			break
+		}
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// encodeOAuthResponse writes the bare token or introspection, the OAuth2
+// endpoints don't wrap their responses
+func encodeOAuthResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.Error() != nil {
+		encodeOAuthError(ctx, e.Error(), w)
+		return nil
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	switch r := response.(type) {
+	case ep.ExchangeTokenResponse:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		return json.NewEncoder(w).Encode(r.Token)
+	case ep.IntrospectTokenResponse:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		return json.NewEncoder(w).Encode(r.Introspection)
+	default:
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+}
+
+// oauthForm parses the form encoded body of an OAuth2 request
+func oauthForm(r *http.Request) (url.Values, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return r.PostForm, nil
+}
+
+// clientCredentials reads the client from HTTP basic authentication or,
+// for public clients, from the client_id and client_secret fields.
+// Basic credentials are form encoded, see RFC 6749 section 2.3.1.
+func clientCredentials(r *http.Request, form url.Values) (authorization.ClientCredentials, error) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		var err error
+		if id, err = url.QueryUnescape(id); err != nil {
+			return authorization.ClientCredentials{}, authorization.ErrInvalidClient
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return authorization.ClientCredentials{}, authorization.ErrInvalidClient
+		}
+	} else {
+		id, secret = form.Get("client_id"), form.Get("client_secret")
+	}
+	clientID, err := uuid.Parse(id)
+	if err != nil {
+		return authorization.ClientCredentials{}, authorization.ErrInvalidClient
+	}
+	return authorization.ClientCredentials{ID: clientID, Secret: secret}, nil
+}
+
+func DecodeHTTPRegisterClientRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.RegisterClientRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPGetClientRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "client"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	return ep.GetClientRequest{ID: id}, nil
+}
+
+func DecodeHTTPAuthorizeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.AuthorizeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPExchangeTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	form, err := oauthForm(r)
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientCredentials(r, form)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ExchangeTokenRequest{Request: authorization.TokenRequest{
+		GrantType:    form.Get("grant_type"),
+		Client:       client,
+		Code:         form.Get("code"),
+		RedirectURI:  form.Get("redirect_uri"),
+		CodeVerifier: form.Get("code_verifier"),
+		RefreshToken: form.Get("refresh_token"),
+		Scopes:       authorization.SplitScopes(form.Get("scope")),
+	}}, nil
+}
+
+func DecodeHTTPRevokeTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	form, err := oauthForm(r)
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientCredentials(r, form)
+	if err != nil {
+		return nil, err
+	}
+	return ep.RevokeTokenRequest{Token: form.Get("token"), Client: client}, nil
+}
+
+func DecodeHTTPIntrospectTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	form, err := oauthForm(r)
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientCredentials(r, form)
+	if err != nil {
+		return nil, err
+	}
+	return ep.IntrospectTokenRequest{Token: form.Get("token"), Client: client}, nil
+}
+
+func DecodeHTTPValidateAccessTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.ValidateAccessTokenRequest{Token: bearerToken(r.Header)}, nil
+}
+
+// consentHandler serves the consent screen of the authorization endpoint,
+// see RFC 6749 section 4.1.1. Users log in with their password, and second
+// factor when enrolled, to allow the client. The session is only used to
+// consent and revoked right after.
+type consentHandler struct {
+	eps    ep.Endpoints
+	logger log.Logger
+}
+
+var scopeDescriptions = map[authorization.Scope]string{
+	authorization.ScopeOpenID:     "Sign you in with your user id and username",
+	authorization.ScopeTodosRead:  "Read your todos",
+	authorization.ScopeTodosWrite: "Add, change and delete your todos",
+}
+
+var consentTemplate = template.Must(template.New("consent").Funcs(template.FuncMap{
+	"describe": func(s authorization.Scope) string { return scopeDescriptions[s] },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Authorize {{.Client.Name}}</title></head>
+<body>
+<h1>Authorize {{.Client.Name}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<p>{{.Client.Name}} wants to:</p>
+<ul>{{range .Scopes}}<li>{{describe .}}</li>{{end}}</ul>
+<form method="post">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}{{if .MFAChallenge}}<input type="hidden" name="mfa_challenge" value="{{.MFAChallenge}}">
+<label>Authentication code <input name="code" autocomplete="one-time-code" required autofocus></label>
+{{else}}<label>Username <input name="username" value="{{.Username}}" autocomplete="username" required autofocus></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+{{end}}<button name="action" value="allow">Allow</button>
+<button name="action" value="deny" formnovalidate>Deny</button>
+</form>
+</body>
+</html>
+`))
+
+var consentErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Authorization failed</title></head>
+<body>
+<h1>Authorization failed</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+type consentPage struct {
+	Client       authorization.OAuthClient
+	Scopes       []authorization.Scope
+	Params       map[string]string
+	Username     string
+	MFAChallenge string
+	Error        string
+}
+
+// consentParams are passed on from the authorization request to the form
+var consentParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method", "nonce"}
+
+func (h consentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	ctx := clientIPToContext(r.Context(), r)
+	if err := r.ParseForm(); err != nil {
+		h.fail(w, http.StatusBadRequest, "The request could not be read.")
+		return
+	}
+
+	// errors about the client or redirect uri are shown to the user, the
+	// redirect uri can't be trusted yet
+	clientID, err := uuid.Parse(r.Form.Get("client_id"))
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, "The application is unknown.")
+		return
+	}
+	client, err := h.eps.GetClient(ctx, clientID)
+	if err == authorization.ErrClientNotFound {
+		h.fail(w, http.StatusBadRequest, "The application is unknown.")
+		return
+	}
+	if err != nil {
+		h.logger.Log("err", err)
+		h.fail(w, http.StatusInternalServerError, "Something went wrong, please try again later.")
+		return
+	}
+	redirectURI := r.Form.Get("redirect_uri")
+	if !hasString(client.RedirectURIs, redirectURI) {
+		h.fail(w, http.StatusBadRequest, "The redirect uri is not registered for the application.")
+		return
+	}
+
+	state := r.Form.Get("state")
+	req := authorization.AuthorizationRequest{
+		ClientID:            client.ID,
+		RedirectURI:         redirectURI,
+		Scopes:              authorization.SplitScopes(r.Form.Get("scope")),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+		Nonce:               r.Form.Get("nonce"),
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = client.Scopes
+	}
+	switch {
+	case r.Form.Get("response_type") != "code":
+		redirectError(w, r, redirectURI, state, "unsupported_response_type")
+		return
+	case req.CodeChallenge == "" || req.CodeChallengeMethod != authorization.CodeChallengeS256:
+		redirectError(w, r, redirectURI, state, "invalid_request")
+		return
+	case !allowedScopes(req.Scopes, client.Scopes):
+		redirectError(w, r, redirectURI, state, "invalid_scope")
+		return
+	}
+
+	page := consentPage{Client: client, Scopes: req.Scopes, Params: map[string]string{}}
+	for _, p := range consentParams {
+		page.Params[p] = r.Form.Get(p)
+	}
+	if r.Method != http.MethodPost {
+		h.render(w, page)
+		return
+	}
+	if r.PostForm.Get("action") != "allow" {
+		redirectError(w, r, redirectURI, state, "access_denied")
+		return
+	}
+
+	session, err := h.session(ctx, r.PostForm, &page)
+	if err != nil {
+		h.logger.Log("err", err)
+		h.fail(w, http.StatusInternalServerError, "Something went wrong, please try again later.")
+		return
+	}
+	if session.Token == "" {
+		h.render(w, page)
+		return
+	}
+
+	ctx = authorization.WithSessionToken(ctx, session.Token)
+	code, err := h.eps.Authorize(ctx, req)
+	h.eps.RevokeSession(ctx, session.Token)
+	switch {
+	case err == nil:
+	case errors.Is(err, authorization.ErrForbidden):
+		redirectError(w, r, redirectURI, state, "access_denied")
+		return
+	case errors.Is(err, authorization.ErrInvalidScope):
+		redirectError(w, r, redirectURI, state, "invalid_scope")
+		return
+	case problems.Problem(err).Status < http.StatusInternalServerError:
+		redirectError(w, r, redirectURI, state, "invalid_request")
+		return
+	default:
+		h.logger.Log("err", err)
+		redirectError(w, r, redirectURI, state, "server_error")
+		return
+	}
+	redirect(w, r, redirectURI, url.Values{"code": {code}, "state": {state}})
+}
+
+// session logs the user in with the password or second factor in form.
+// Failed logins return no session and set the error of page.
+func (h consentHandler) session(ctx context.Context, form url.Values, page *consentPage) (authorization.Session, error) {
+	var (
+		session authorization.Session
+		err     error
+	)
+	page.Username = form.Get("username")
+	if challenge := form.Get("mfa_challenge"); challenge != "" {
+		session, err = h.eps.CompleteMFA(ctx, challenge, form.Get("code"))
+	} else {
+		session, err = h.eps.Login(ctx, authorization.User{Username: page.Username, Password: form.Get("password")})
+	}
+
+	var mfa *authorization.MFARequiredError
+	var fields problem.FieldErrors
+	switch {
+	case err == nil:
+		return session, nil
+	case errors.As(err, &mfa):
+		page.MFAChallenge = mfa.Challenge
+	case errors.Is(err, authorization.ErrInvalidMFACode):
+		page.MFAChallenge = form.Get("mfa_challenge")
+		page.Error = "The authentication code is not valid."
+	case errors.Is(err, authorization.ErrInvalidMFAChallenge):
+		page.Error = "The login expired, please log in again."
+	case errors.Is(err, authorization.ErrTooManyAttempts):
+		page.Error = "Too many failed logins, please try again later."
+	case errors.Is(err, authorization.ErrAuthenticationFailed), errors.As(err, &fields):
+		page.Error = "The username or password is not valid."
+	default:
+		return authorization.Session{}, err
+	}
+	return authorization.Session{}, nil
+}
+
+func (h consentHandler) render(w http.ResponseWriter, page consentPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := consentTemplate.Execute(w, page); err != nil {
+		h.logger.Log("err", err)
+	}
+}
+
+func (h consentHandler) fail(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := consentErrorTemplate.Execute(w, message); err != nil {
+		h.logger.Log("err", err)
+	}
+}
+
+// redirect sends the user agent back to the client with params added to
+// the query of the registered redirect uri
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q[k] = v
+		}
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code string) {
+	redirect(w, r, redirectURI, url.Values{"error": {code}, "state": {state}})
+}
+
+func hasString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func allowedScopes(scopes, allowed []authorization.Scope) bool {
+	for _, s := range scopes {
+		found := false
+		for _, a := range allowed {
+			found = found || s == a
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// client functions
+// encode request for server
+
+func encodeHTTPRegisterClientRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/oauth/clients", ...)
+	req.URL.Path += "/oauth/clients"
+	return encodeRequest(ctx, req, request)
+}
+
+func encodeHTTPGetClientRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/oauth/clients/{client}", ...)
+	r := request.(ep.GetClientRequest)
+	req.URL.Path += "/oauth/clients/" + r.ID.String()
+	return nil
+}
+
+func encodeHTTPAuthorizeRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/oauth/consent", ...)
+	req.URL.Path += "/oauth/consent"
+	return encodeRequest(ctx, req, request)
+}
+
+func encodeHTTPExchangeTokenRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/oauth/token", ...)
+	r := request.(ep.ExchangeTokenRequest).Request
+	req.URL.Path += "/oauth/token"
+	form := url.Values{"grant_type": {r.GrantType}}
+	for k, v := range map[string]string{
+		"code":          r.Code,
+		"redirect_uri":  r.RedirectURI,
+		"code_verifier": r.CodeVerifier,
+		"refresh_token": r.RefreshToken,
+		"scope":         authorization.JoinScopes(r.Scopes),
+	} {
+		if v != "" {
+			form.Set(k, v)
+		}
+	}
+	encodeOAuthForm(req, form, r.Client)
+	return nil
+}
+
+func encodeHTTPRevokeTokenRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/oauth/revoke", ...)
+	r := request.(ep.RevokeTokenRequest)
+	req.URL.Path += "/oauth/revoke"
+	encodeOAuthForm(req, url.Values{"token": {r.Token}}, r.Client)
+	return nil
+}
+
+func encodeHTTPIntrospectTokenRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/oauth/introspect", ...)
+	r := request.(ep.IntrospectTokenRequest)
+	req.URL.Path += "/oauth/introspect"
+	encodeOAuthForm(req, url.Values{"token": {r.Token}}, r.Client)
+	return nil
+}
+
+func encodeHTTPValidateAccessTokenRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/oauth/access-token", ...)
+	r := request.(ep.ValidateAccessTokenRequest)
+	req.URL.Path += "/oauth/access-token"
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	return nil
+}
+
+// encodeOAuthForm sets form as body, confidential clients authenticate with
+// HTTP basic authentication
+func encodeOAuthForm(req *http.Request, form url.Values, client authorization.ClientCredentials) {
+	if client.Secret != "" {
+		req.SetBasicAuth(url.QueryEscape(client.ID.String()), url.QueryEscape(client.Secret))
+	} else {
+		form.Set("client_id", client.ID.String())
+	}
+	body := form.Encode()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+}
+
+// client functions
+// decode response from server
+
+func decodeHTTPRegisterClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.RegisterClientResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetClientResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPAuthorizeResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.AuthorizeResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPExchangeTokenResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ExchangeTokenResponse
+	err := decodeOAuthResponse(resp, &response.Token, &response.Err)
+	return response, err
+}
+
+func decodeHTTPRevokeTokenResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.RevokeTokenResponse
+	err := decodeOAuthResponse(resp, nil, &response.Err)
+	return response, err
+}
+
+func decodeHTTPIntrospectTokenResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.IntrospectTokenResponse
+	err := decodeOAuthResponse(resp, &response.Introspection, &response.Err)
+	return response, err
+}
+
+func decodeHTTPValidateAccessTokenResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ValidateAccessTokenResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+// decodeOAuthResponse is decodeResponse for the OAuth2 endpoints, their
+// errors are decoded into the sentinel registered in oauthErrors
+func decodeOAuthResponse(resp *http.Response, response interface{}, respErr *error) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		if response == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(response)
+	}
+	var e oauthError
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+		*respErr = &problem.Problem{Title: resp.Status, Status: resp.StatusCode}
+		return nil
+	}
+	for _, known := range oauthErrors {
+		if known.code == e.Error {
+			*respErr = known.err
+			return nil
+		}
+	}
+	*respErr = &problem.Problem{Title: e.Error, Status: resp.StatusCode, Detail: e.Description, Code: e.Error}
+	return nil
+}
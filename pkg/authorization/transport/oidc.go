@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// encodeOIDCResponse writes the bare discovery document, key set or
+// userinfo, OpenID Connect relying parties don't expect them wrapped
+func encodeOIDCResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.Error() != nil {
+		if _, ok := response.(ep.UserInfoResponse); ok {
+			encodeBearerError(ctx, e.Error(), w)
+		} else {
+			encodeError(ctx, e.Error(), w)
+		}
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch r := response.(type) {
+	case ep.OpenIDConfigurationResponse:
+		return json.NewEncoder(w).Encode(r.Metadata)
+	case ep.GetJWKSResponse:
+		return json.NewEncoder(w).Encode(r.Keys)
+	case ep.UserInfoResponse:
+		w.Header().Set("Cache-Control", "no-store")
+		return json.NewEncoder(w).Encode(r.UserInfo)
+	default:
+		return json.NewEncoder(w).Encode(response)
+	}
+}
+
+// encodeBearerError answers the errors of the userinfo endpoint as problem
+// with the WWW-Authenticate challenge of RFC 6750 section 3. Invalid tokens
+// are unauthorized there.
+func encodeBearerError(_ context.Context, err error, w http.ResponseWriter) {
+	p := problems.Problem(err)
+	var fields problem.FieldErrors
+	switch {
+	case errors.Is(err, authorization.ErrInvalidToken):
+		p.Status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	case errors.Is(err, authorization.ErrForbidden):
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+	case errors.As(err, &fields):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+	}
+	w.Header().Set("Content-Type", problem.ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func DecodeHTTPOpenIDConfigurationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.OpenIDConfigurationRequest{}, nil
+}
+
+func DecodeHTTPGetJWKSRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.GetJWKSRequest{}, nil
+}
+
+func DecodeHTTPUserInfoRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.UserInfoRequest{Token: bearerToken(r.Header)}, nil
+}
+
+// client functions
+// encode request for server
+
+func encodeHTTPOpenIDConfigurationRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/.well-known/openid-configuration", ...)
+	req.URL.Path += "/.well-known/openid-configuration"
+	return nil
+}
+
+func encodeHTTPGetJWKSRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/jwks.json", ...)
+	req.URL.Path += "/jwks.json"
+	return nil
+}
+
+func encodeHTTPUserInfoRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/userinfo", ...)
+	r := request.(ep.UserInfoRequest)
+	req.URL.Path += "/userinfo"
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	return nil
+}
+
+// client functions
+// decode response from server
+
+func decodeHTTPOpenIDConfigurationResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.OpenIDConfigurationResponse
+	err := decodeResponse(resp, &response.Metadata, &response.Err)
+	return response, err
+}
+
+func decodeHTTPGetJWKSResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.GetJWKSResponse
+	err := decodeResponse(resp, &response.Keys, &response.Err)
+	return response, err
+}
+
+func decodeHTTPUserInfoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.UserInfoResponse
+	err := decodeResponse(resp, &response.UserInfo, &response.Err)
+	return response, err
+}
@@ -0,0 +1,758 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/demeesterdev/todo-service/internal/jose"
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// OpenAPI returns the OpenAPI document of the routes served by MakeHTTPHandler
+func OpenAPI() openapi.Document {
+	doc := openapi.New("authorization", "1.0.0", "user management and authentication")
+
+	userSchema := openapi.SchemaOf(authorization.User{})
+	userSchema.Properties["password"].Description = "only accepted in requests, never returned"
+	user := doc.Component("User", userSchema)
+	userResponse := doc.Component("UserResponse", openapi.SchemaOf(ep.GetUserResponse{}))
+
+	id := openapi.PathParam("id", "id of the user", openapi.UUID)
+	teamResponse := doc.Component("TeamResponse", openapi.SchemaOf(ep.GetTeamResponse{}))
+	team := openapi.PathParam("team", "id of the team", openapi.UUID)
+	member := openapi.PathParam("user", "id of the member", openapi.UUID)
+	apiKey := openapi.PathParam("key", "id of the API key", openapi.UUID)
+	client := openapi.PathParam("client", "id of the OAuth2 client", openapi.UUID)
+	html := map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}
+	body := &openapi.RequestBody{Required: true, Content: openapi.JSON(user)}
+	ok := func(description string, s *openapi.Schema) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(s)}
+	}
+
+	doc.Add(http.MethodGet, "/status", openapi.Operation{
+		OperationID: "serviceStatus",
+		Summary:     "Status of the service",
+		Tags:        []string{"status"},
+		Responses: doc.Responses(problems,
+			ok("service is up", doc.Component("ServiceStatusResponse", openapi.SchemaOf(ep.ServiceStatusResponse{})))),
+	})
+	doc.Add(http.MethodGet, "/", openapi.Operation{
+		OperationID: "getUsers",
+		Summary:     "List users",
+		Tags:        []string{"users"},
+		Responses: doc.Responses(problems,
+			ok("the users", doc.Component("GetUsersResponse", openapi.SchemaOf(ep.GetUsersResponse{}))),
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/", openapi.Operation{
+		OperationID: "addUser",
+		Summary:     "Create a user",
+		Description: "Anyone can sign up as member, other roles need an admin session.",
+		Tags:        []string{"users"},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the created user", userResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUserObject, authorization.ErrInvalidRole,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/import", openapi.Operation{
+		OperationID: "importUsers",
+		Summary:     "Import users with password hashes from another system",
+		Description: "Accepts argon2id, bcrypt, scrypt and PBKDF2-SHA256 hashes. Either all users are imported or none, legacy hashes are replaced by argon2id on the next successful login.",
+		Tags:        []string{"users"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("ImportUsersRequest", openapi.SchemaOf(ep.ImportUsersRequest{})))},
+		Responses: doc.Responses(problems, ok("the imported users", doc.Component("ImportUsersResponse", openapi.SchemaOf(ep.ImportUsersResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/login", openapi.Operation{
+		OperationID: "authenticateUser",
+		Summary:     "Check the password of a user",
+		Description: "Users with a second factor get an mfa_challenge instead of the user, the login is completed by posting it to /login/mfa.",
+		Tags:        []string{"authentication"},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the authenticated user or an mfa challenge", doc.Component("AuthenticateUserResponse", openapi.SchemaOf(ep.AuthenticateUserResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUserObject, authorization.ErrAuthenticationFailed, authorization.ErrTooManyAttempts),
+	})
+	doc.Add(http.MethodPost, "/session", openapi.Operation{
+		OperationID: "login",
+		Summary:     "Log in and start a session",
+		Description: "The token of the session is sent as bearer token in the Authorization header of the other requests. Users with a second factor get an mfa_challenge instead, completed at /login/mfa.",
+		Tags:        []string{"authentication"},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the session or an mfa challenge", doc.Component("LoginResponse", openapi.SchemaOf(ep.LoginResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUserObject, authorization.ErrAuthenticationFailed, authorization.ErrTooManyAttempts),
+	})
+	doc.Add(http.MethodGet, "/session", openapi.Operation{
+		OperationID: "validateSession",
+		Summary:     "Get the user of the bearer token",
+		Tags:        []string{"authentication"},
+		Responses: doc.Responses(problems, ok("the user of the session", doc.Component("ValidateSessionResponse", openapi.SchemaOf(ep.ValidateSessionResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+	doc.Add(http.MethodDelete, "/session", openapi.Operation{
+		OperationID: "revokeSession",
+		Summary:     "Log out, ending the session of the bearer token",
+		Tags:        []string{"authentication"},
+		Responses: doc.Responses(problems, ok("the session is ended", &openapi.Schema{Type: "object"}),
+			problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+	doc.Add(http.MethodGet, "/api-key", openapi.Operation{
+		OperationID: "validateAPIKey",
+		Summary:     "Get the API key sent as bearer token",
+		Description: "Records the use of the key. Services accepting API keys check them here.",
+		Tags:        []string{"authentication"},
+		Responses: doc.Responses(problems, ok("the API key without its secret", doc.Component("ValidateAPIKeyResponse", openapi.SchemaOf(ep.ValidateAPIKeyResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+	doc.Add(http.MethodPost, "/login/mfa", openapi.Operation{
+		OperationID: "completeMFA",
+		Summary:     "Complete a login with a TOTP or recovery code",
+		Tags:        []string{"authentication"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("CompleteMFARequest", openapi.SchemaOf(ep.CompleteMFARequest{})))},
+		Responses: doc.Responses(problems, ok("the session of the user", doc.Component("CompleteMFAResponse", openapi.SchemaOf(ep.CompleteMFAResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidMFAChallenge, authorization.ErrInvalidMFACode, authorization.ErrTooManyAttempts),
+	})
+	doc.Add(http.MethodPost, "/password/forgot", openapi.Operation{
+		OperationID: "forgotPassword",
+		Summary:     "Mail a password reset token",
+		Description: "Succeeds for unknown addresses as well, so they can't be discovered.",
+		Tags:        []string{"password"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("ForgotPasswordRequest", openapi.SchemaOf(ep.ForgotPasswordRequest{})))},
+		Responses: doc.Responses(problems, ok("the mail is sent when the address is known", &openapi.Schema{Type: "object"}),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrMailDisabled),
+	})
+	doc.Add(http.MethodPost, "/password/reset", openapi.Operation{
+		OperationID: "resetPassword",
+		Summary:     "Set a new password with a mailed token",
+		Tags:        []string{"password"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("ResetPasswordRequest", openapi.SchemaOf(ep.ResetPasswordRequest{})))},
+		Responses: doc.Responses(problems, ok("the password is changed", &openapi.Schema{Type: "object"}),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+	doc.Add(http.MethodPost, "/email/verify", openapi.Operation{
+		OperationID: "verifyEmail",
+		Summary:     "Verify an email address with a mailed token",
+		Tags:        []string{"email"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("VerifyEmailRequest", openapi.SchemaOf(ep.VerifyEmailRequest{})))},
+		Responses: doc.Responses(problems, ok("the user with the verified address", userResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+	doc.Add(http.MethodGet, "/{id}", openapi.Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user by id or username",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "id or username of the user", &openapi.Schema{Type: "string"})},
+		Responses: doc.Responses(problems, ok("the user", userResponse),
+			authorization.ErrIDMissing, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/{id}", openapi.Operation{
+		OperationID: "updateUser",
+		Summary:     "Update a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the updated user", userResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInconsistentIDs, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/unlock", openapi.Operation{
+		OperationID: "unlockUser",
+		Summary:     "Lift the login lockout of a user",
+		Tags:        []string{"authentication"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the user is unlocked", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/mfa/totp", openapi.Operation{
+		OperationID: "enrollTOTP",
+		Summary:     "Start the enrolment of a TOTP second factor",
+		Description: "Returns a new secret and its otpauth:// URI. The second factor is only required after it is confirmed with a first code.",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the secret to enrol", doc.Component("EnrollTOTPResponse", openapi.SchemaOf(ep.EnrollTOTPResponse{}))),
+			authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrMFAEnabled, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/mfa/totp/confirm", openapi.Operation{
+		OperationID: "confirmTOTP",
+		Summary:     "Enable the enrolled TOTP second factor",
+		Description: "Returns the recovery codes of the user, they are not shown again.",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("ConfirmTOTPRequest", openapi.SchemaOf(ep.ConfirmTOTPRequest{})))},
+		Responses: doc.Responses(problems, ok("the recovery codes", doc.Component("ConfirmTOTPResponse", openapi.SchemaOf(ep.ConfirmTOTPResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrNotFound,
+			authorization.ErrMFAEnabled, authorization.ErrMFANotEnrolled, authorization.ErrInvalidMFACode, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/{id}/mfa", openapi.Operation{
+		OperationID: "disableMFA",
+		Summary:     "Remove the second factor and recovery codes of a user",
+		Description: "Users removing their own second factor send a current TOTP code or a recovery code, administrators may omit the body.",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Content: openapi.JSON(doc.Component("DisableMFARequest", openapi.SchemaOf(ep.DisableMFARequest{})))},
+		Responses: doc.Responses(problems, ok("the second factor is removed", &openapi.Schema{Type: "object"}),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrNotFound,
+			authorization.ErrMFANotEnrolled, authorization.ErrInvalidMFACode, authorization.ErrTooManyAttempts, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/email/verification", openapi.Operation{
+		OperationID: "sendVerificationEmail",
+		Summary:     "Mail a token to verify the email address of a user",
+		Tags:        []string{"email"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the mail is sent", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrEmailMissing, authorization.ErrMailDisabled, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/{id}", openapi.Operation{
+		OperationID: "deleteUser",
+		Summary:     "Delete a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the user is deleted", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/{id}/teams", openapi.Operation{
+		OperationID: "getTeams",
+		Summary:     "List the teams of a user",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the teams the user is a member of", doc.Component("GetTeamsResponse", openapi.SchemaOf(ep.GetTeamsResponse{}))),
+			authorization.ErrInvalidUUID, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/teams", openapi.Operation{
+		OperationID: "createTeam",
+		Summary:     "Create a team",
+		Description: "The owner defaults to the user of the session.",
+		Tags:        []string{"teams"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("CreateTeamRequest", openapi.SchemaOf(ep.CreateTeamRequest{})))},
+		Responses: doc.Responses(problems, ok("the created team", teamResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidTeam, authorization.ErrIDMissing, authorization.ErrNotFound,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/teams/invitations/accept", openapi.Operation{
+		OperationID: "acceptInvitation",
+		Summary:     "Join a team with an invitation token",
+		Description: "The user defaults to the user of the session. Invitations can be used once.",
+		Tags:        []string{"teams"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("AcceptInvitationRequest", openapi.SchemaOf(ep.AcceptInvitationRequest{})))},
+		Responses: doc.Responses(problems, ok("the joined team", doc.Component("AcceptInvitationResponse", openapi.SchemaOf(ep.AcceptInvitationResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidToken, authorization.ErrAlreadyMember, authorization.ErrNotFound,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/teams/{team}", openapi.Operation{
+		OperationID: "getTeam",
+		Summary:     "Get a team with its members",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team},
+		Responses: doc.Responses(problems, ok("the team", teamResponse),
+			authorization.ErrInvalidUUID, authorization.ErrTeamNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/teams/{team}", openapi.Operation{
+		OperationID: "deleteTeam",
+		Summary:     "Delete a team",
+		Description: "Only owners can delete a team.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team},
+		Responses: doc.Responses(problems, ok("the team is deleted", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrTeamNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/teams/{team}/invitations", openapi.Operation{
+		OperationID: "inviteMember",
+		Summary:     "Create an invitation token for a team",
+		Description: "Owners and admins invite members, only owners invite owners. The token is only returned once.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("InviteMemberRequest", openapi.SchemaOf(ep.InviteMemberRequest{})))},
+		Responses: doc.Responses(problems, ok("the invitation token", doc.Component("InviteMemberResponse", openapi.SchemaOf(ep.InviteMemberResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInvalidTeamRole, authorization.ErrTeamNotFound,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/teams/{team}/members/{user}", openapi.Operation{
+		OperationID: "updateMember",
+		Summary:     "Change the role of a member",
+		Description: "Owners change any member, admins can't change owners or make owners.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team, member},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("UpdateMemberRequest", openapi.SchemaOf(ep.UpdateMemberRequest{})))},
+		Responses: doc.Responses(problems, ok("the team", doc.Component("UpdateMemberResponse", openapi.SchemaOf(ep.UpdateMemberResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInvalidTeamRole, authorization.ErrTeamNotFound,
+			authorization.ErrNotMember, authorization.ErrLastOwner, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/teams/{team}/members/{user}", openapi.Operation{
+		OperationID: "removeMember",
+		Summary:     "Remove a member from a team",
+		Description: "Members can leave, owners remove anyone and admins remove members other than owners. The member loses access to the todos of the team at once.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team, member},
+		Responses: doc.Responses(problems, ok("the member is removed", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrTeamNotFound, authorization.ErrNotMember, authorization.ErrLastOwner,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/api-keys", openapi.Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Create an API key",
+		Description: "The key is only returned in this response, only its hash is stored. Keys expire after 90 days unless expires_at is set.",
+		Tags:        []string{"api keys"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("CreateAPIKeyRequest", openapi.SchemaOf(ep.CreateAPIKeyRequest{})))},
+		Responses: doc.Responses(problems, ok("the created API key", doc.Component("CreateAPIKeyResponse", openapi.SchemaOf(ep.CreateAPIKeyResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInvalidAPIKey, authorization.ErrInvalidScope,
+			authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/{id}/api-keys", openapi.Operation{
+		OperationID: "getAPIKeys",
+		Summary:     "List the API keys of a user",
+		Tags:        []string{"api keys"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the API keys without their secrets", doc.Component("GetAPIKeysResponse", openapi.SchemaOf(ep.GetAPIKeysResponse{}))),
+			authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/{id}/api-keys/{key}", openapi.Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Tags:        []string{"api keys"},
+		Parameters:  []openapi.Parameter{id, apiKey},
+		Responses: doc.Responses(problems, ok("the API key is revoked", &openapi.Schema{Type: "object"}),
+			authorization.ErrInvalidUUID, authorization.ErrAPIKeyNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+
+	doc.Add(http.MethodPost, "/oauth/clients", openapi.Operation{
+		OperationID: "registerClient",
+		Summary:     "Register an OAuth2 client",
+		Description: "The owner defaults to the user of the session. Confidential clients get a client_secret, it is only returned in this response. Public clients, like browser extensions, have no secret and rely on PKCE.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("RegisterClientRequest", openapi.SchemaOf(ep.RegisterClientRequest{})))},
+		Responses: doc.Responses(problems, ok("the registered client", doc.Component("RegisterClientResponse", openapi.SchemaOf(ep.RegisterClientResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidClientMetadata, authorization.ErrInvalidRedirectURI,
+			authorization.ErrInvalidScope, authorization.ErrIDMissing, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/oauth/clients/{client}", openapi.Operation{
+		OperationID: "getClient",
+		Summary:     "Get an OAuth2 client without its secret",
+		Tags:        []string{"oauth"},
+		Parameters:  []openapi.Parameter{client},
+		Responses: doc.Responses(problems, ok("the client", doc.Component("GetClientResponse", openapi.SchemaOf(ep.GetClientResponse{}))),
+			authorization.ErrInvalidUUID, authorization.ErrClientNotFound),
+	})
+	doc.Add(http.MethodPost, "/oauth/consent", openapi.Operation{
+		OperationID: "authorize",
+		Summary:     "Consent to a client and get an authorization code",
+		Description: "For first party apps showing their own consent screen to a user with a session, others use /oauth/authorize. The user defaults to the user of the session, nobody consents for other users.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("AuthorizeRequest", openapi.SchemaOf(ep.AuthorizeRequest{})))},
+		Responses: doc.Responses(problems, ok("the authorization code", doc.Component("AuthorizeResponse", openapi.SchemaOf(ep.AuthorizeResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrClientNotFound, authorization.ErrInvalidRedirectURI,
+			authorization.ErrInvalidOAuthRequest, authorization.ErrInvalidScope, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/oauth/access-token", openapi.Operation{
+		OperationID: "validateAccessToken",
+		Summary:     "Get the OAuth2 access token sent as bearer token",
+		Description: "Services accepting access tokens check them here.",
+		Tags:        []string{"oauth"},
+		Responses: doc.Responses(problems, ok("the access token", doc.Component("ValidateAccessTokenResponse", openapi.SchemaOf(ep.ValidateAccessTokenResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+
+	authorizeParams := []openapi.Parameter{
+		openapi.QueryParam("response_type", "must be code", &openapi.Schema{Type: "string", Enum: []string{"code"}}),
+		openapi.QueryParam("client_id", "id of the client", openapi.UUID),
+		openapi.QueryParam("redirect_uri", "one of the redirect uris of the client", &openapi.Schema{Type: "string"}),
+		openapi.QueryParam("scope", "scopes separated by spaces, defaults to the scopes of the client", &openapi.Schema{Type: "string"}),
+		openapi.QueryParam("state", "returned unchanged to the client", &openapi.Schema{Type: "string"}),
+		openapi.QueryParam("code_challenge", "PKCE challenge, see RFC 7636", &openapi.Schema{Type: "string"}),
+		openapi.QueryParam("code_challenge_method", "must be S256", &openapi.Schema{Type: "string", Enum: []string{authorization.CodeChallengeS256}}),
+		openapi.QueryParam("nonce", "copied into the ID token of openid requests", &openapi.Schema{Type: "string"}),
+	}
+	consentResponses := map[string]openapi.Response{
+		"200": {Description: "the consent screen", Content: html},
+		"303": {Description: "back to the redirect uri with a code or an error, and the state"},
+		"400": {Description: "the client or redirect uri is unknown, the user agent is not redirected", Content: html},
+		"500": {Description: "Internal Server Error", Content: html},
+	}
+	doc.Add(http.MethodGet, "/oauth/authorize", openapi.Operation{
+		OperationID: "authorizeForm",
+		Summary:     "Show the consent screen of the authorization code flow",
+		Description: "The authorization endpoint of RFC 6749 section 4.1.1. Only the code response type with a S256 PKCE challenge is supported.",
+		Tags:        []string{"oauth"},
+		Parameters:  authorizeParams,
+		Responses:   consentResponses,
+	})
+	doc.Add(http.MethodPost, "/oauth/authorize", openapi.Operation{
+		OperationID: "authorizeSubmit",
+		Summary:     "Log in and allow or deny the client",
+		Description: "Posted by the consent screen with the parameters of the authorization request. Users with a second factor are asked for a code before the client is allowed.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: form(map[string]*openapi.Schema{
+			"action":        {Type: "string", Enum: []string{"allow", "deny"}},
+			"username":      {Type: "string"},
+			"password":      {Type: "string"},
+			"mfa_challenge": {Type: "string"},
+			"code":          {Type: "string", Description: "TOTP or recovery code"},
+		}, "action")},
+		Responses: consentResponses,
+	})
+	doc.Add(http.MethodPost, "/oauth/token", openapi.Operation{
+		OperationID: "exchangeToken",
+		Summary:     "Exchange an authorization code or refresh token for tokens",
+		Description: "The token endpoint of RFC 6749. Confidential clients authenticate with HTTP basic authentication, public clients send their client_id. Refresh tokens are rotated, a used refresh token is revoked.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: form(map[string]*openapi.Schema{
+			"grant_type":    {Type: "string", Enum: []string{authorization.GrantTypeAuthorizationCode, authorization.GrantTypeRefreshToken}},
+			"client_id":     openapi.UUID,
+			"code":          {Type: "string"},
+			"redirect_uri":  {Type: "string"},
+			"code_verifier": {Type: "string"},
+			"refresh_token": {Type: "string"},
+			"scope":         {Type: "string", Description: "narrows the scopes of a refreshed grant"},
+		}, "grant_type")},
+		Responses: oauthResponses(&doc, ok("the tokens", doc.Component("OAuthToken", openapi.SchemaOf(authorization.OAuthToken{})))),
+	})
+	doc.Add(http.MethodPost, "/oauth/revoke", openapi.Operation{
+		OperationID: "revokeToken",
+		Summary:     "Revoke an access or refresh token",
+		Description: "The revocation endpoint of RFC 7009. Revoking a refresh token revokes the tokens of the whole grant. Unknown tokens are not an error.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: form(map[string]*openapi.Schema{
+			"token":     {Type: "string"},
+			"client_id": openapi.UUID,
+		}, "token")},
+		Responses: oauthResponses(&doc, openapi.Response{Description: "the token is revoked"}),
+	})
+	doc.Add(http.MethodPost, "/oauth/introspect", openapi.Operation{
+		OperationID: "introspectToken",
+		Summary:     "Describe an access or refresh token",
+		Description: "The introspection endpoint of RFC 7662, for confidential clients like resource servers. Unknown, expired and revoked tokens are inactive.",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: form(map[string]*openapi.Schema{
+			"token": {Type: "string"},
+		}, "token")},
+		Responses: oauthResponses(&doc, ok("the token", doc.Component("Introspection", openapi.SchemaOf(authorization.Introspection{})))),
+	})
+
+	doc.Add(http.MethodGet, "/.well-known/openid-configuration", openapi.Operation{
+		OperationID: "openIDConfiguration",
+		Summary:     "OpenID Connect discovery document",
+		Tags:        []string{"openid connect"},
+		Responses:   doc.Responses(problems, ok("the provider metadata", doc.Component("ProviderMetadata", openapi.SchemaOf(authorization.ProviderMetadata{})))),
+	})
+	doc.Add(http.MethodGet, "/jwks.json", openapi.Operation{
+		OperationID: "getJWKS",
+		Summary:     "Keys ID tokens are signed with",
+		Description: "Signing keys rotate, a replaced key stays published until the ID tokens it signed have expired.",
+		Tags:        []string{"openid connect"},
+		Responses:   doc.Responses(problems, ok("the JSON Web Key Set", doc.Component("JWKS", openapi.SchemaOf(jose.KeySet{})))),
+	})
+	userinfo := openapi.Operation{
+		OperationID: "userInfo",
+		Summary:     "Claims about the user of the access token sent as bearer token",
+		Description: "The access token needs the openid scope. Errors carry a WWW-Authenticate challenge as in RFC 6750.",
+		Tags:        []string{"openid connect"},
+		Responses: doc.Responses(problems, ok("the claims", doc.Component("UserInfo", openapi.SchemaOf(authorization.UserInfo{}))),
+			problem.ErrInvalidFields, authorization.ErrForbidden),
+	}
+	userinfo.Responses["401"] = openapi.Response{
+		Description: "Unauthorized: invalid_token",
+		Content:     map[string]openapi.MediaType{problem.ContentType: {Schema: openapi.Ref("Problem")}},
+	}
+	doc.Add(http.MethodGet, "/userinfo", userinfo)
+	userinfo.OperationID = "userInfoPost"
+	doc.Add(http.MethodPost, "/userinfo", userinfo)
+
+	provider := openapi.PathParam("provider", "name of the identity provider", &openapi.Schema{Type: "string"})
+	login := openapi.Operation{
+		OperationID: "startFederatedLogin",
+		Summary:     "Sign in with an external identity provider",
+		Description: "Redirects the user agent to the OpenID Connect provider, which redirects back to /login/{provider}/callback.",
+		Tags:        []string{"federation"},
+		Parameters:  []openapi.Parameter{provider},
+		Responses:   doc.Responses(problems, openapi.Response{Description: "the provider is at the Location header"}, problem.ErrInvalidFields, authorization.ErrProviderNotFound, authorization.ErrFederationFailed),
+	}
+	login.Responses["303"] = login.Responses["200"]
+	delete(login.Responses, "200")
+	doc.Add(http.MethodGet, "/login/{provider}", login)
+	doc.Add(http.MethodGet, "/login/{provider}/callback", openapi.Operation{
+		OperationID: "completeFederatedLogin",
+		Summary:     "Finish signing in with an external identity provider",
+		Description: "The identity is linked to a user by an earlier sign in or link, unknown identities get a new user without a password. Users with a second factor get an mfa_challenge instead, completed at /login/mfa.",
+		Tags:        []string{"federation"},
+		Parameters: []openapi.Parameter{provider,
+			openapi.QueryParam("state", "state sent to the provider", &openapi.Schema{Type: "string"}),
+			openapi.QueryParam("code", "authorization code of the provider", &openapi.Schema{Type: "string"}),
+			openapi.QueryParam("error", "set by the provider instead of code", &openapi.Schema{Type: "string"}),
+		},
+		Responses: doc.Responses(problems, ok("the session or an mfa challenge", doc.Component("CompleteFederatedLoginResponse", openapi.SchemaOf(ep.CompleteFederatedLoginResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrProviderNotFound, authorization.ErrInvalidState, authorization.ErrFederationFailed,
+			authorization.ErrIdentityNotLinked, authorization.ErrIdentityLinked),
+	})
+	doc.Add(http.MethodPost, "/{id}/identities/{provider}", openapi.Operation{
+		OperationID: "linkIdentity",
+		Summary:     "Link an identity at an external identity provider",
+		Description: "Only users themselves link identities. The user agent is sent to the returned url, the identity it signs in with is linked at /login/{provider}/callback.",
+		Tags:        []string{"federation"},
+		Parameters:  []openapi.Parameter{id, provider},
+		Responses: doc.Responses(problems, ok("the url of the provider", doc.Component("StartFederatedLoginResponse", openapi.SchemaOf(ep.StartFederatedLoginResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrProviderNotFound, authorization.ErrFederationFailed,
+			authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/{id}/identities", openapi.Operation{
+		OperationID: "getIdentities",
+		Summary:     "List the external identities linked to a user",
+		Tags:        []string{"federation"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the identities", doc.Component("GetIdentitiesResponse", openapi.SchemaOf(ep.GetIdentitiesResponse{}))),
+			authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+
+	listParams := []openapi.Parameter{
+		openapi.QueryParam("name", "only the exact name, ignoring case", &openapi.Schema{Type: "string"}),
+		openapi.QueryParam("offset", "number of matches to skip", &openapi.Schema{Type: "integer"}),
+		openapi.QueryParam("limit", "maximum number of matches, 100 by default and at most 1000", &openapi.Schema{Type: "integer"}),
+	}
+	doc.Add(http.MethodGet, "/search", openapi.Operation{
+		OperationID: "listUsers",
+		Summary:     "List a page of users",
+		Description: "Users are ordered by username, name matches the username.",
+		Tags:        []string{"users"},
+		Parameters:  listParams,
+		Responses: doc.Responses(problems, ok("the page and the number of matching users", doc.Component("ListUsersResponse", openapi.SchemaOf(ep.ListUsersResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/teams", openapi.Operation{
+		OperationID: "listTeams",
+		Summary:     "List a page of teams",
+		Description: "Teams are ordered by name, name matches the name of the team.",
+		Tags:        []string{"teams"},
+		Parameters:  listParams,
+		Responses: doc.Responses(problems, ok("the page and the number of matching teams", doc.Component("ListTeamsResponse", openapi.SchemaOf(ep.ListTeamsResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/teams/{team}", openapi.Operation{
+		OperationID: "updateTeam",
+		Summary:     "Rename a team",
+		Description: "Only owners can rename a team.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("Team", openapi.SchemaOf(authorization.Team{})))},
+		Responses: doc.Responses(problems, ok("the team", doc.Component("UpdateTeamResponse", openapi.SchemaOf(ep.UpdateTeamResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInvalidTeam, authorization.ErrTeamNotFound,
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/teams/{team}/members", openapi.Operation{
+		OperationID: "setMembers",
+		Summary:     "Replace the members of a team",
+		Description: "For admins and provisioning. A team with an owner keeps one, teams created by provisioning may have none.",
+		Tags:        []string{"teams"},
+		Parameters:  []openapi.Parameter{team},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("SetMembersRequest", openapi.SchemaOf(ep.SetMembersRequest{})))},
+		Responses: doc.Responses(problems, ok("the team", doc.Component("SetMembersResponse", openapi.SchemaOf(ep.SetMembersResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUUID, authorization.ErrInvalidTeam, authorization.ErrInvalidTeamRole,
+			authorization.ErrTeamNotFound, authorization.ErrNotFound, authorization.ErrLastOwner, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/provision", openapi.Operation{
+		OperationID: "provisionUser",
+		Summary:     "Create a user, the password is optional",
+		Description: "For admins and provisioning. Users without a password sign in with an identity provider or directory.",
+		Tags:        []string{"provisioning"},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the created user", doc.Component("ProvisionUserResponse", openapi.SchemaOf(ep.ProvisionUserResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, authorization.ErrInvalidUserObject, authorization.ErrInvalidRole,
+			authorization.ErrUsernameTaken, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/{id}/active", openapi.Operation{
+		OperationID: "setUserActive",
+		Summary:     "Enable or disable a user",
+		Description: "Disabled users can't sign in, their sessions and OAuth2 tokens are revoked and their API keys refused.",
+		Tags:        []string{"provisioning"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("SetUserActiveRequest", openapi.SchemaOf(ep.SetUserActiveRequest{})))},
+		Responses: doc.Responses(problems, ok("the user", doc.Component("SetUserActiveResponse", openapi.SchemaOf(ep.SetUserActiveResponse{}))),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, authorization.ErrInvalidUUID, authorization.ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/provisioning-token", openapi.Operation{
+		OperationID: "validateProvisioningToken",
+		Summary:     "Check the provisioning token sent as bearer token",
+		Tags:        []string{"provisioning"},
+		Responses: doc.Responses(problems, ok("the provisioning client", doc.Component("ValidateProvisioningTokenResponse", openapi.SchemaOf(ep.ValidateProvisioningTokenResponse{}))),
+			problem.ErrInvalidFields, authorization.ErrInvalidToken),
+	})
+
+	scimDocs(&doc)
+
+	return doc
+}
+
+// form returns the content of a form encoded request body
+func form(properties map[string]*openapi.Schema, required ...string) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{
+		"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: properties, Required: required}},
+	}
+}
+
+// oauthResponses documents the errors of the OAuth2 endpoints, see
+// encodeOAuthError
+func oauthResponses(doc *openapi.Document, ok openapi.Response) map[string]openapi.Response {
+	doc.Component("OAuthError", openapi.SchemaOf(oauthError{}))
+	errs := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(openapi.Ref("OAuthError"))}
+	}
+	return map[string]openapi.Response{
+		"200": ok,
+		"400": errs("Bad Request: invalid_request, invalid_grant, unsupported_grant_type, invalid_scope"),
+		"401": errs("Unauthorized: invalid_client"),
+		"500": errs("Internal Server Error: server_error"),
+	}
+}
+
+// scimDocs documents the SCIM routes, see scimHandler. Their bodies and
+// errors are SCIM messages instead of the JSON of the other routes.
+func scimDocs(doc *openapi.Document) {
+	scim := func(s *openapi.Schema) map[string]openapi.MediaType {
+		return map[string]openapi.MediaType{scimContentType: {Schema: s}}
+	}
+	user := doc.Component("SCIMUser", openapi.SchemaOf(scimUser{}))
+	group := doc.Component("SCIMGroup", openapi.SchemaOf(scimGroup{}))
+	patch := &openapi.RequestBody{Required: true, Content: scim(doc.Component("SCIMPatchOp", openapi.SchemaOf(scimPatchOp{})))}
+	scimErr := doc.Component("SCIMError", openapi.SchemaOf(scimErrorResponse{}))
+	responses := func(ok openapi.Response, statuses ...int) map[string]openapi.Response {
+		r := map[string]openapi.Response{
+			"401": {Description: "Unauthorized: the provisioning token is missing or invalid", Content: scim(scimErr)},
+			"500": {Description: "Internal Server Error", Content: scim(scimErr)},
+		}
+		for _, status := range statuses {
+			r[strconv.Itoa(status)] = openapi.Response{Description: http.StatusText(status), Content: scim(scimErr)}
+		}
+		if ok.Description == "" {
+			r["204"] = openapi.Response{Description: "No Content"}
+		} else {
+			r["200"] = ok
+		}
+		return r
+	}
+	list := func(resource string, s *openapi.Schema) openapi.Response {
+		l := openapi.SchemaOf(scimListResponse{})
+		l.Properties["Resources"] = &openapi.Schema{Type: "array", Items: s}
+		return openapi.Response{Description: "a page of " + resource, Content: scim(doc.Component("SCIM"+resource+"List", l))}
+	}
+	id := openapi.PathParam("id", "id of the resource", openapi.UUID)
+	page := func(filter string) []openapi.Parameter {
+		return []openapi.Parameter{
+			openapi.QueryParam("filter", filter+` eq "value", the only supported filter`, &openapi.Schema{Type: "string"}),
+			openapi.QueryParam("startIndex", "1-based index of the first result", &openapi.Schema{Type: "integer"}),
+			openapi.QueryParam("count", "maximum number of results, 0 only counts", &openapi.Schema{Type: "integer"}),
+		}
+	}
+	tags := []string{"scim"}
+
+	doc.Add(http.MethodGet, "/scim/v2/ServiceProviderConfig", openapi.Operation{
+		OperationID: "scimServiceProviderConfig",
+		Summary:     "SCIM features supported by the service",
+		Tags:        tags,
+		Responses: map[string]openapi.Response{
+			"200": {Description: "the configuration", Content: scim(doc.Component("SCIMServiceProviderConfig", openapi.SchemaOf(scimServiceProviderConfig{})))},
+		},
+	})
+	doc.Add(http.MethodGet, scimUsersPath, openapi.Operation{
+		OperationID: "scimListUsers",
+		Summary:     "List users",
+		Tags:        tags,
+		Parameters:  page("userName"),
+		Responses:   responses(list("Users", user), http.StatusBadRequest),
+	})
+	doc.Add(http.MethodPost, scimUsersPath, openapi.Operation{
+		OperationID: "scimCreateUser",
+		Summary:     "Provision a user",
+		Description: "The password is optional, users without one sign in with an identity provider or directory.",
+		Tags:        tags,
+		RequestBody: &openapi.RequestBody{Required: true, Content: scim(user)},
+		Responses: func() map[string]openapi.Response {
+			r := responses(openapi.Response{Description: "the created user", Content: scim(user)}, http.StatusBadRequest, http.StatusConflict)
+			r["201"] = r["200"]
+			delete(r, "200")
+			return r
+		}(),
+	})
+	doc.Add(http.MethodGet, scimUsersPath+"/{id}", openapi.Operation{
+		OperationID: "scimGetUser",
+		Summary:     "Get a user",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		Responses:   responses(openapi.Response{Description: "the user", Content: scim(user)}, http.StatusNotFound),
+	})
+	doc.Add(http.MethodPut, scimUsersPath+"/{id}", openapi.Operation{
+		OperationID: "scimReplaceUser",
+		Summary:     "Replace the attributes of a user",
+		Description: "Emails and the password are only changed when present. Setting active to false disables the user.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scim(user)},
+		Responses:   responses(openapi.Response{Description: "the user", Content: scim(user)}, http.StatusBadRequest, http.StatusNotFound),
+	})
+	doc.Add(http.MethodPatch, scimUsersPath+"/{id}", openapi.Operation{
+		OperationID: "scimPatchUser",
+		Summary:     "Change attributes of a user",
+		Description: "Supports userName, password, emails and active, other attributes are ignored.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: patch,
+		Responses:   responses(openapi.Response{Description: "the user", Content: scim(user)}, http.StatusBadRequest, http.StatusNotFound),
+	})
+	doc.Add(http.MethodDelete, scimUsersPath+"/{id}", openapi.Operation{
+		OperationID: "scimDeleteUser",
+		Summary:     "Deprovision a user",
+		Description: "The user is disabled and its sessions revoked, it is not deleted.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		Responses:   responses(openapi.Response{}, http.StatusNotFound),
+	})
+	doc.Add(http.MethodGet, scimGroupsPath, openapi.Operation{
+		OperationID: "scimListGroups",
+		Summary:     "List groups",
+		Tags:        tags,
+		Parameters:  page("displayName"),
+		Responses:   responses(list("Groups", group), http.StatusBadRequest),
+	})
+	doc.Add(http.MethodPost, scimGroupsPath, openapi.Operation{
+		OperationID: "scimCreateGroup",
+		Summary:     "Provision a group as team",
+		Description: "The team has no owner, members join as member.",
+		Tags:        tags,
+		RequestBody: &openapi.RequestBody{Required: true, Content: scim(group)},
+		Responses: func() map[string]openapi.Response {
+			r := responses(openapi.Response{Description: "the created group", Content: scim(group)}, http.StatusBadRequest, http.StatusNotFound)
+			r["201"] = r["200"]
+			delete(r, "200")
+			return r
+		}(),
+	})
+	doc.Add(http.MethodGet, scimGroupsPath+"/{id}", openapi.Operation{
+		OperationID: "scimGetGroup",
+		Summary:     "Get a group",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		Responses:   responses(openapi.Response{Description: "the group", Content: scim(group)}, http.StatusNotFound),
+	})
+	doc.Add(http.MethodPut, scimGroupsPath+"/{id}", openapi.Operation{
+		OperationID: "scimReplaceGroup",
+		Summary:     "Replace the name and members of a group",
+		Description: "Members keep their role in the team, new members join as member.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scim(group)},
+		Responses:   responses(openapi.Response{Description: "the group", Content: scim(group)}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	})
+	doc.Add(http.MethodPatch, scimGroupsPath+"/{id}", openapi.Operation{
+		OperationID: "scimPatchGroup",
+		Summary:     "Rename a group or add, remove and replace members",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: patch,
+		Responses:   responses(openapi.Response{Description: "the group", Content: scim(group)}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	})
+	doc.Add(http.MethodDelete, scimGroupsPath+"/{id}", openapi.Operation{
+		OperationID: "scimDeleteGroup",
+		Summary:     "Delete a group and its team",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{id},
+		Responses:   responses(openapi.Response{}, http.StatusNotFound),
+	})
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// problems presents the errors of the service as problem details
+var problems = problem.NewRegistry(
+	problem.Entry{Err: authorization.ErrInvalidUserObject, Status: http.StatusBadRequest, Code: "invalid_user", Title: "Invalid user"},
+	problem.Entry{Err: authorization.ErrAuthenticationFailed, Status: http.StatusUnauthorized, Code: "authentication_failed", Title: "Authentication failed"},
+	problem.Entry{Err: authorization.ErrIDMissing, Status: http.StatusBadRequest, Code: "id_missing", Title: "Id or username is missing", Field: "id"},
+	problem.Entry{Err: authorization.ErrInconsistentIDs, Status: http.StatusBadRequest, Code: "inconsistent_ids", Title: "Ids in path and body differ", Field: "id"},
+	problem.Entry{Err: authorization.ErrInconsistentIDUserName, Status: http.StatusBadRequest, Code: "inconsistent_id_username", Title: "Id and username belong to different users", Field: "username"},
+	problem.Entry{Err: authorization.ErrNotFound, Status: http.StatusNotFound, Code: "not_found", Title: "User not found"},
+	problem.Entry{Err: authorization.ErrTooManyAttempts, Status: http.StatusTooManyRequests, Code: "too_many_attempts", Title: "Too many failed login attempts"},
+	problem.Entry{Err: authorization.ErrInvalidMFAChallenge, Status: http.StatusUnauthorized, Code: "invalid_mfa_challenge", Title: "Invalid or expired MFA challenge", Field: "mfa_challenge"},
+	problem.Entry{Err: authorization.ErrInvalidMFACode, Status: http.StatusUnauthorized, Code: "invalid_mfa_code", Title: "Invalid MFA code", Field: "code"},
+	problem.Entry{Err: authorization.ErrMFANotEnrolled, Status: http.StatusConflict, Code: "mfa_not_enrolled", Title: "No second factor enrolled"},
+	problem.Entry{Err: authorization.ErrMFAEnabled, Status: http.StatusConflict, Code: "mfa_enabled", Title: "Second factor already enabled"},
+	problem.Entry{Err: authorization.ErrInvalidToken, Status: http.StatusBadRequest, Code: "invalid_token", Title: "Invalid or expired token", Field: "token"},
+	problem.Entry{Err: authorization.ErrEmailMissing, Status: http.StatusConflict, Code: "email_missing", Title: "User has no email address"},
+	problem.Entry{Err: authorization.ErrMailDisabled, Status: http.StatusServiceUnavailable, Code: "mail_disabled", Title: "Sending mail is not configured"},
+	problem.Entry{Err: authorization.ErrUnauthenticated, Status: http.StatusUnauthorized, Code: "unauthenticated", Title: "Authentication required"},
+	problem.Entry{Err: authorization.ErrForbidden, Status: http.StatusForbidden, Code: "forbidden", Title: "Permission denied"},
+	problem.Entry{Err: authorization.ErrInvalidRole, Status: http.StatusBadRequest, Code: "invalid_role", Title: "Unknown role", Field: "role"},
+	problem.Entry{Err: authorization.ErrTeamNotFound, Status: http.StatusNotFound, Code: "team_not_found", Title: "Team not found"},
+	problem.Entry{Err: authorization.ErrInvalidTeam, Status: http.StatusBadRequest, Code: "invalid_team", Title: "Invalid team", Field: "name"},
+	problem.Entry{Err: authorization.ErrInvalidTeamRole, Status: http.StatusBadRequest, Code: "invalid_team_role", Title: "Unknown team role", Field: "role"},
+	problem.Entry{Err: authorization.ErrAlreadyMember, Status: http.StatusConflict, Code: "already_member", Title: "User is already a member of the team"},
+	problem.Entry{Err: authorization.ErrNotMember, Status: http.StatusNotFound, Code: "not_member", Title: "User is not a member of the team"},
+	problem.Entry{Err: authorization.ErrLastOwner, Status: http.StatusConflict, Code: "last_owner", Title: "Team needs an owner"},
+	problem.Entry{Err: authorization.ErrInvalidAPIKey, Status: http.StatusBadRequest, Code: "invalid_api_key", Title: "Invalid API key"},
+	problem.Entry{Err: authorization.ErrInvalidScope, Status: http.StatusBadRequest, Code: "invalid_scope", Title: "Unknown scope", Field: "scopes"},
+	problem.Entry{Err: authorization.ErrAPIKeyNotFound, Status: http.StatusNotFound, Code: "api_key_not_found", Title: "API key not found"},
+	problem.Entry{Err: authorization.ErrInvalidClientMetadata, Status: http.StatusBadRequest, Code: "invalid_client_metadata", Title: "Invalid client metadata"},
+	problem.Entry{Err: authorization.ErrInvalidRedirectURI, Status: http.StatusBadRequest, Code: "invalid_redirect_uri", Title: "Invalid redirect uri", Field: "redirect_uri"},
+	problem.Entry{Err: authorization.ErrClientNotFound, Status: http.StatusNotFound, Code: "client_not_found", Title: "OAuth client not found"},
+	problem.Entry{Err: authorization.ErrInvalidClient, Status: http.StatusUnauthorized, Code: "invalid_client", Title: "Client authentication failed"},
+	problem.Entry{Err: authorization.ErrInvalidGrant, Status: http.StatusBadRequest, Code: "invalid_grant", Title: "Invalid or expired grant"},
+	problem.Entry{Err: authorization.ErrUnsupportedGrantType, Status: http.StatusBadRequest, Code: "unsupported_grant_type", Title: "Unsupported grant type", Field: "grant_type"},
+	problem.Entry{Err: authorization.ErrInvalidOAuthRequest, Status: http.StatusBadRequest, Code: "invalid_oauth_request", Title: "Invalid OAuth request"},
+	problem.Entry{Err: authorization.ErrProviderNotFound, Status: http.StatusNotFound, Code: "provider_not_found", Title: "Identity provider not found", Field: "provider"},
+	problem.Entry{Err: authorization.ErrInvalidState, Status: http.StatusBadRequest, Code: "invalid_state", Title: "Invalid or expired login state", Field: "state"},
+	problem.Entry{Err: authorization.ErrFederationFailed, Status: http.StatusUnauthorized, Code: "federation_failed", Title: "Sign in at identity provider failed"},
+	problem.Entry{Err: authorization.ErrIdentityNotLinked, Status: http.StatusUnauthorized, Code: "identity_not_linked", Title: "Identity not linked to a user"},
+	problem.Entry{Err: authorization.ErrIdentityLinked, Status: http.StatusConflict, Code: "identity_linked", Title: "Identity already linked"},
+	problem.Entry{Err: authorization.ErrUserDisabled, Status: http.StatusForbidden, Code: "user_disabled", Title: "User is disabled"},
+	problem.Entry{Err: authorization.ErrUsernameTaken, Status: http.StatusConflict, Code: "username_taken", Title: "Username already taken", Field: "username"},
+	problem.Entry{Err: authorization.ErrInvalidUUID, Status: http.StatusBadRequest, Code: "invalid_uuid", Title: "Invalid uuid", Field: "id"},
+)
@@ -0,0 +1,228 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// listQuery reads the name, offset and limit query parameters of the list
+// routes
+func listQuery(r *http.Request) (authorization.ListQuery, error) {
+	q := r.URL.Query()
+	lq := authorization.ListQuery{Name: q.Get("name")}
+	var errs problem.FieldErrors
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"offset", &lq.Offset}, {"limit", &lq.Limit}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, problem.FieldError{Field: p.name, Code: "invalid_format", Message: "must be a non-negative integer"})
+			continue
+		}
+		*p.dst = n
+	}
+	if errs != nil {
+		return authorization.ListQuery{}, errs
+	}
+	return lq, nil
+}
+
+func encodeListQuery(req *http.Request, q authorization.ListQuery) {
+	v := url.Values{}
+	if q.Name != "" {
+		v.Set("name", q.Name)
+	}
+	if q.Offset != 0 {
+		v.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.Limit != 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	req.URL.RawQuery = v.Encode()
+}
+
+func DecodeHTTPListUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q, err := listQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ListUsersRequest{Query: q}, nil
+}
+
+func DecodeHTTPValidateProvisioningTokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return ep.ValidateProvisioningTokenRequest{Token: bearerToken(r.Header)}, nil
+}
+
+func DecodeHTTPProvisionUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.ProvisionUserRequest
+	err := json.NewDecoder(r.Body).Decode(&req.User)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPSetUserActiveRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.SetUserActiveRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPListTeamsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q, err := listQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ListTeamsRequest{Query: q}, nil
+}
+
+func DecodeHTTPUpdateTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.UpdateTeamRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req.Team)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func DecodeHTTPSetMembersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req ep.SetMembersRequest
+	var err error
+	req.TeamID, err = uuid.Parse(chi.URLParam(r, "team"))
+	if err != nil {
+		return nil, authorization.ErrInvalidUUID
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+// client functions
+// encode request for server
+
+func encodeHTTPListUsersRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/search", ...)
+	r := request.(ep.ListUsersRequest)
+	req.URL.Path += "/search"
+	encodeListQuery(req, r.Query)
+	return nil
+}
+
+func encodeHTTPValidateProvisioningTokenRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/provisioning-token", ...)
+	r := request.(ep.ValidateProvisioningTokenRequest)
+	req.URL.Path += "/provisioning-token"
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	return nil
+}
+
+func encodeHTTPProvisionUserRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/provision", ...)
+	r := request.(ep.ProvisionUserRequest)
+	req.URL.Path += "/provision"
+	return encodeRequest(ctx, req, r.User)
+}
+
+func encodeHTTPSetUserActiveRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Put("/{id}/active", ...)
+	r := request.(ep.SetUserActiveRequest)
+	req.URL.Path += "/" + r.ID.String() + "/active"
+	return encodeRequest(ctx, req, r)
+}
+
+func encodeHTTPListTeamsRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Get("/teams", ...)
+	r := request.(ep.ListTeamsRequest)
+	req.URL.Path += "/teams"
+	encodeListQuery(req, r.Query)
+	return nil
+}
+
+func encodeHTTPUpdateTeamRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Put("/teams/{team}", ...)
+	r := request.(ep.UpdateTeamRequest)
+	req.URL.Path += "/teams/" + r.ID.String()
+	return encodeRequest(ctx, req, r.Team)
+}
+
+func encodeHTTPSetMembersRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Put("/teams/{team}/members", ...)
+	r := request.(ep.SetMembersRequest)
+	req.URL.Path += "/teams/" + r.TeamID.String() + "/members"
+	return encodeRequest(ctx, req, r)
+}
+
+// client functions
+// decode response from server
+
+func decodeHTTPListUsersResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ListUsersResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPValidateProvisioningTokenResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ValidateProvisioningTokenResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPProvisionUserResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ProvisionUserResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPSetUserActiveResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.SetUserActiveResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPListTeamsResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.ListTeamsResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPUpdateTeamResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.UpdateTeamResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+
+func decodeHTTPSetMembersResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response ep.SetMembersResponse
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
@@ -0,0 +1,834 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	ep "github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+// SCIM 2.0 provisioning, see RFC 7643 and RFC 7644. Users map onto
+// authorization.User and groups onto teams.
+const (
+	scimContentType = "application/scim+json"
+	scimUsersPath   = "/scim/v2/Users"
+	scimGroupsPath  = "/scim/v2/Groups"
+
+	scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema  = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimUser struct {
+	Schemas  []string    `json:"schemas"`
+	ID       string      `json:"id,omitempty"`
+	UserName string      `json:"userName"`
+	Password string      `json:"password,omitempty"`
+	Active   *bool       `json:"active,omitempty"`
+	Emails   []scimEmail `json:"emails,omitempty"`
+	Meta     *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimMember struct {
+	Value string `json:"value"`
+	Ref   string `json:"$ref,omitempty"`
+}
+
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type scimPatchOp struct {
+	Schemas    []string        `json:"schemas"`
+	Operations []scimOperation `json:"Operations"`
+}
+
+type scimOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type scimErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type scimSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type scimFilterConfig struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type scimBulkConfig struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type scimAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type scimServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 scimSupported              `json:"patch"`
+	Bulk                  scimBulkConfig             `json:"bulk"`
+	Filter                scimFilterConfig           `json:"filter"`
+	ChangePassword        scimSupported              `json:"changePassword"`
+	Sort                  scimSupported              `json:"sort"`
+	ETag                  scimSupported              `json:"etag"`
+	AuthenticationSchemes []scimAuthenticationScheme `json:"authenticationSchemes"`
+}
+
+var scimConfig = scimServiceProviderConfig{
+	Schemas:        []string{scimConfigSchema},
+	Patch:          scimSupported{Supported: true},
+	Filter:         scimFilterConfig{Supported: true, MaxResults: authorization.MaxListLimit},
+	ChangePassword: scimSupported{Supported: true},
+	AuthenticationSchemes: []scimAuthenticationScheme{{
+		Type:        "oauthbearertoken",
+		Name:        "Provisioning token",
+		Description: "The provisioning token of the service as bearer token",
+	}},
+}
+
+// scimError is an error of the SCIM protocol itself, like an unsupported
+// filter. Errors of the service are answered with the status of their
+// problem.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string { return e.detail }
+
+func errSCIM(status int, scimType, format string, args ...interface{}) error {
+	return &scimError{status: status, scimType: scimType, detail: fmt.Sprintf(format, args...)}
+}
+
+// scimHandler serves the SCIM endpoints to the provisioning client, see
+// authorization.ProvisioningConfig. Sessions of users, even admins, are not
+// accepted. Deleting a user disables it, its sessions end but the user and
+// its todos are kept.
+type scimHandler struct {
+	eps    ep.Endpoints
+	logger log.Logger
+}
+
+type scimHandlerFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+
+func (h scimHandler) routes(r chi.Router) {
+	r.Get("/scim/v2/ServiceProviderConfig", func(w http.ResponseWriter, r *http.Request) {
+		writeSCIM(w, http.StatusOK, scimConfig)
+	})
+	r.Get(scimUsersPath, h.authenticated(h.listUsers))
+	r.Post(scimUsersPath, h.authenticated(h.createUser))
+	r.Get(scimUsersPath+"/{id}", h.authenticated(h.getUser))
+	r.Put(scimUsersPath+"/{id}", h.authenticated(h.replaceUser))
+	r.Patch(scimUsersPath+"/{id}", h.authenticated(h.patchUser))
+	r.Delete(scimUsersPath+"/{id}", h.authenticated(h.deleteUser))
+	r.Get(scimGroupsPath, h.authenticated(h.listGroups))
+	r.Post(scimGroupsPath, h.authenticated(h.createGroup))
+	r.Get(scimGroupsPath+"/{id}", h.authenticated(h.getGroup))
+	r.Put(scimGroupsPath+"/{id}", h.authenticated(h.replaceGroup))
+	r.Patch(scimGroupsPath+"/{id}", h.authenticated(h.patchGroup))
+	r.Delete(scimGroupsPath+"/{id}", h.authenticated(h.deleteGroup))
+}
+
+// authenticated serves f when the request carries the provisioning token,
+// the token is passed on to the authorization middleware as provisioning
+// token, the other routes don't accept it
+func (h scimHandler) authenticated(f scimHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := clientIPToContext(r.Context(), r)
+		token := bearerToken(r.Header)
+		if _, err := h.eps.ValidateProvisioningToken(ctx, token); err != nil {
+			if problems.Problem(err).Status < http.StatusInternalServerError {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+				err = errSCIM(http.StatusUnauthorized, "", "provisioning token required")
+			}
+			h.fail(w, err)
+			return
+		}
+		if err := f(authorization.WithProvisioningToken(ctx, token), w, r); err != nil {
+			h.fail(w, err)
+		}
+	}
+}
+
+// fail answers err as SCIM error, see RFC 7644 section 3.12. Internal
+// errors are logged and answered without details.
+func (h scimHandler) fail(w http.ResponseWriter, err error) {
+	body := scimErrorResponse{Schemas: []string{scimErrorSchema}}
+	var status int
+	var se *scimError
+	if errors.As(err, &se) {
+		status, body.ScimType, body.Detail = se.status, se.scimType, se.detail
+	} else {
+		p := problems.Problem(err)
+		status, body.Detail = p.Status, p.Detail
+		switch {
+		case errors.Is(err, authorization.ErrUsernameTaken):
+			body.ScimType = "uniqueness"
+		case status == http.StatusBadRequest:
+			body.ScimType = "invalidValue"
+		case status >= http.StatusInternalServerError:
+			h.logger.Log("err", err)
+		}
+	}
+	body.Status = strconv.Itoa(status)
+	writeSCIM(w, status, body)
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeSCIM(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errSCIM(http.StatusBadRequest, "invalidSyntax", "malformed request body: %v", err)
+	}
+	return nil
+}
+
+// scimID parses the id in the path, ids that aren't uuids are not found
+func scimID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, errSCIM(http.StatusNotFound, "", "resource %s not found", chi.URLParam(r, "id"))
+	}
+	return id, nil
+}
+
+var scimEqFilter = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9:._-]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// scimQuery reads the filter and pagination of a list request, see RFC 7644
+// section 3.4.2. The only filter is attribute eq "value", startIndex counts
+// from 1. totalsOnly is set for a count of 0.
+func scimQuery(r *http.Request, attribute string) (q authorization.ListQuery, totalsOnly bool, err error) {
+	params := r.URL.Query()
+	if filter := params.Get("filter"); filter != "" {
+		m := scimEqFilter.FindStringSubmatch(filter)
+		if m == nil || !strings.EqualFold(scimAttribute(m[1]), attribute) {
+			return q, false, errSCIM(http.StatusBadRequest, "invalidFilter", "only %s eq \"value\" filters are supported", attribute)
+		}
+		if err := json.Unmarshal([]byte(m[2]), &q.Name); err != nil {
+			return q, false, errSCIM(http.StatusBadRequest, "invalidFilter", "invalid filter value %s", m[2])
+		}
+	}
+	if v := params.Get("startIndex"); v != "" {
+		start, err := strconv.Atoi(v)
+		if err != nil {
+			return q, false, errSCIM(http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+		}
+		if start > 1 {
+			q.Offset = start - 1
+		}
+	}
+	if v := params.Get("count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return q, false, errSCIM(http.StatusBadRequest, "invalidValue", "count must be an integer")
+		}
+		if count <= 0 {
+			// a limit of 0 is the default, one resource is read and dropped
+			return authorization.ListQuery{Name: q.Name, Offset: q.Offset, Limit: 1}, true, nil
+		}
+		q.Limit = count
+	}
+	return q, false, nil
+}
+
+// scimAttribute strips the schema from a fully qualified attribute name
+func scimAttribute(name string) string {
+	for _, schema := range []string{scimUserSchema, scimGroupSchema} {
+		if len(name) > len(schema) && strings.EqualFold(name[:len(schema)+1], schema+":") {
+			return name[len(schema)+1:]
+		}
+	}
+	return name
+}
+
+func scimList(total, offset int, resources interface{}, n int) scimListResponse {
+	return scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: n,
+		Resources:    resources,
+	}
+}
+
+func toSCIMUser(u authorization.User) scimUser {
+	active := !u.Disabled
+	su := scimUser{
+		Schemas:  []string{scimUserSchema},
+		ID:       u.ID.String(),
+		UserName: u.Username,
+		Active:   &active,
+		Meta:     &scimMeta{ResourceType: "User", Location: scimUsersPath + "/" + u.ID.String()},
+	}
+	if u.Email != "" {
+		su.Emails = []scimEmail{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return su
+}
+
+// primaryEmail returns the primary address, or the first without one
+func primaryEmail(emails []scimEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// scimBool accepts booleans and, as sent by some clients, "True" and "False"
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, errSCIM(http.StatusBadRequest, "invalidValue", "%s is not a boolean", value)
+}
+
+func (h scimHandler) listUsers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q, totalsOnly, err := scimQuery(r, "userName")
+	if err != nil {
+		return err
+	}
+	page, err := h.eps.ListUsers(ctx, q)
+	if err != nil {
+		return err
+	}
+	users := []scimUser{}
+	if !totalsOnly {
+		for _, u := range page.Users {
+			users = append(users, toSCIMUser(u))
+		}
+	}
+	return writeSCIM(w, http.StatusOK, scimList(page.Total, q.Offset, users, len(users)))
+}
+
+func (h scimHandler) createUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var su scimUser
+	if err := decodeSCIM(r, &su); err != nil {
+		return err
+	}
+	u, err := h.eps.ProvisionUser(ctx, authorization.User{
+		Username: su.UserName,
+		Password: su.Password,
+		Email:    primaryEmail(su.Emails),
+		Disabled: su.Active != nil && !*su.Active,
+	})
+	if err != nil {
+		return err
+	}
+	created := toSCIMUser(u)
+	w.Header().Set("Location", created.Meta.Location)
+	return writeSCIM(w, http.StatusCreated, created)
+}
+
+func (h scimHandler) getUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	u, err := h.eps.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMUser(u))
+}
+
+// replaceUser sets the attributes of a user, addresses and the password
+// can't be removed so they are only changed when present
+func (h scimHandler) replaceUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	var su scimUser
+	if err := decodeSCIM(r, &su); err != nil {
+		return err
+	}
+	change := userChange{
+		user:   authorization.User{Username: su.UserName, Password: su.Password, Email: primaryEmail(su.Emails)},
+		active: su.Active,
+	}
+	u, err := h.applyUser(ctx, id, change)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMUser(u))
+}
+
+// userChange collects the changes of a PUT or PATCH to a user
+type userChange struct {
+	user   authorization.User
+	active *bool
+}
+
+// set applies an add or replace of attr, attributes a user doesn't have,
+// like name or title, are ignored
+func (c *userChange) set(attr string, value json.RawMessage) error {
+	name, filter, _ := strings.Cut(scimAttribute(attr), "[")
+	var err error
+	switch strings.ToLower(name) {
+	case "username":
+		err = json.Unmarshal(value, &c.user.Username)
+	case "password":
+		err = json.Unmarshal(value, &c.user.Password)
+	case "active":
+		var active bool
+		active, err = scimBool(value)
+		c.active = &active
+	case "emails":
+		// emails[type eq "work"].value replaces a single address
+		if filter != "" {
+			err = json.Unmarshal(value, &c.user.Email)
+			break
+		}
+		var emails []scimEmail
+		err = json.Unmarshal(value, &emails)
+		c.user.Email = primaryEmail(emails)
+	}
+	if err != nil && !errors.As(err, new(*scimError)) {
+		err = errSCIM(http.StatusBadRequest, "invalidValue", "invalid value for %s: %v", attr, err)
+	}
+	return err
+}
+
+func (h scimHandler) patchUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	var patch scimPatchOp
+	if err := decodeSCIM(r, &patch); err != nil {
+		return err
+	}
+	var change userChange
+	for _, op := range patch.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path != "" {
+				if err := change.set(op.Path, op.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return errSCIM(http.StatusBadRequest, "invalidValue", "value without path must be an object")
+			}
+			for attr, value := range attrs {
+				if err := change.set(attr, value); err != nil {
+					return err
+				}
+			}
+		case "remove":
+			name, _, _ := strings.Cut(scimAttribute(op.Path), "[")
+			switch strings.ToLower(name) {
+			case "username", "password", "active", "emails":
+				return errSCIM(http.StatusBadRequest, "mutability", "%s can't be removed", op.Path)
+			}
+		default:
+			return errSCIM(http.StatusBadRequest, "invalidSyntax", "unknown operation %q", op.Op)
+		}
+	}
+	u, err := h.applyUser(ctx, id, change)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMUser(u))
+}
+
+// applyUser updates the attributes in c, then enables or disables the user
+func (h scimHandler) applyUser(ctx context.Context, id uuid.UUID, c userChange) (authorization.User, error) {
+	u, err := h.eps.GetUser(ctx, id)
+	if err != nil {
+		return authorization.User{}, err
+	}
+	if c.user.Username != "" || c.user.Password != "" || c.user.Email != "" {
+		if u, err = h.eps.UpdateUser(ctx, id, c.user); err != nil {
+			return authorization.User{}, err
+		}
+	}
+	if c.active != nil && *c.active == u.Disabled {
+		if u, err = h.eps.SetUserActive(ctx, id, *c.active); err != nil {
+			return authorization.User{}, err
+		}
+	}
+	return u, nil
+}
+
+// deleteUser deprovisions a user by disabling it
+func (h scimHandler) deleteUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	if _, err := h.eps.SetUserActive(ctx, id, false); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func toSCIMGroup(t authorization.Team) scimGroup {
+	g := scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          t.ID.String(),
+		DisplayName: t.Name,
+		Members:     []scimMember{},
+		Meta:        &scimMeta{ResourceType: "Group", Location: scimGroupsPath + "/" + t.ID.String()},
+	}
+	for _, m := range t.Members {
+		g.Members = append(g.Members, scimMember{Value: m.UserID.String(), Ref: scimUsersPath + "/" + m.UserID.String()})
+	}
+	return g
+}
+
+// groupChange collects the changes of a PUT or PATCH to a team. Members
+// keep their role in the team, new members join as TeamRoleMember.
+type groupChange struct {
+	team    authorization.Team
+	name    string
+	members []authorization.TeamMember
+	changed bool
+}
+
+func newGroupChange(t authorization.Team) *groupChange {
+	return &groupChange{team: t, members: append([]authorization.TeamMember{}, t.Members...)}
+}
+
+func scimMemberIDs(value json.RawMessage) ([]uuid.UUID, error) {
+	var members []scimMember
+	if err := json.Unmarshal(value, &members); err != nil {
+		return nil, errSCIM(http.StatusBadRequest, "invalidValue", "members must be a list of {\"value\": id}")
+	}
+	ids := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		id, err := uuid.Parse(m.Value)
+		if err != nil {
+			return nil, errSCIM(http.StatusBadRequest, "invalidValue", "member %q is not a user id", m.Value)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func (c *groupChange) index(id uuid.UUID) int {
+	for i, m := range c.members {
+		if m.UserID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *groupChange) add(ids []uuid.UUID) {
+	for _, id := range ids {
+		if c.index(id) < 0 {
+			c.members = append(c.members, authorization.TeamMember{UserID: id, Role: authorization.TeamRoleMember})
+			c.changed = true
+		}
+	}
+}
+
+func (c *groupChange) remove(ids []uuid.UUID) {
+	for _, id := range ids {
+		if i := c.index(id); i >= 0 {
+			c.members = append(c.members[:i], c.members[i+1:]...)
+			c.changed = true
+		}
+	}
+}
+
+func (c *groupChange) replace(ids []uuid.UUID) {
+	members := make([]authorization.TeamMember, 0, len(ids))
+	for _, id := range ids {
+		role, ok := c.team.Role(id)
+		if !ok {
+			role = authorization.TeamRoleMember
+		}
+		members = append(members, authorization.TeamMember{UserID: id, Role: role})
+	}
+	c.members, c.changed = members, true
+}
+
+// set applies an add or replace of attr, attributes a team doesn't have
+// are ignored
+func (c *groupChange) set(op, attr string, value json.RawMessage) error {
+	switch strings.ToLower(scimAttribute(attr)) {
+	case "displayname":
+		if err := json.Unmarshal(value, &c.name); err != nil {
+			return errSCIM(http.StatusBadRequest, "invalidValue", "displayName must be a string")
+		}
+	case "members":
+		ids, err := scimMemberIDs(value)
+		if err != nil {
+			return err
+		}
+		if op == "add" {
+			c.add(ids)
+		} else {
+			c.replace(ids)
+		}
+	}
+	return nil
+}
+
+var scimMemberPath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+("(?:[^"\\]|\\.)*")\s*\]$`)
+
+// unset applies a remove of path: all members, the members in value, or
+// the member selected by members[value eq "id"]
+func (c *groupChange) unset(path string, value json.RawMessage) error {
+	if m := scimMemberPath.FindStringSubmatch(path); m != nil {
+		var v string
+		json.Unmarshal([]byte(m[1]), &v)
+		if id, err := uuid.Parse(v); err == nil {
+			c.remove([]uuid.UUID{id})
+		}
+		return nil
+	}
+	if !strings.EqualFold(scimAttribute(path), "members") {
+		return errSCIM(http.StatusBadRequest, "invalidPath", "only members can be removed")
+	}
+	if len(value) == 0 {
+		c.replace(nil)
+		return nil
+	}
+	ids, err := scimMemberIDs(value)
+	if err != nil {
+		return err
+	}
+	c.remove(ids)
+	return nil
+}
+
+// applyGroup renames the team and replaces its members when they changed
+func (h scimHandler) applyGroup(ctx context.Context, c *groupChange) (authorization.Team, error) {
+	t := c.team
+	var err error
+	if c.name != "" && c.name != t.Name {
+		if t, err = h.eps.UpdateTeam(ctx, t.ID, authorization.Team{Name: c.name}); err != nil {
+			return authorization.Team{}, err
+		}
+	}
+	if c.changed {
+		if t, err = h.eps.SetMembers(ctx, t.ID, c.members); err != nil {
+			return authorization.Team{}, err
+		}
+	}
+	return t, nil
+}
+
+func (h scimHandler) listGroups(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q, totalsOnly, err := scimQuery(r, "displayName")
+	if err != nil {
+		return err
+	}
+	page, err := h.eps.ListTeams(ctx, q)
+	if err != nil {
+		return err
+	}
+	groups := []scimGroup{}
+	if !totalsOnly {
+		for _, t := range page.Teams {
+			groups = append(groups, toSCIMGroup(t))
+		}
+	}
+	return writeSCIM(w, http.StatusOK, scimList(page.Total, q.Offset, groups, len(groups)))
+}
+
+// createGroup creates a team without owner, it is removed again when its
+// members can't be set
+func (h scimHandler) createGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var g struct {
+		DisplayName string          `json:"displayName"`
+		Members     json.RawMessage `json:"members"`
+	}
+	if err := decodeSCIM(r, &g); err != nil {
+		return err
+	}
+	t, err := h.eps.CreateTeam(ctx, authorization.Team{Name: g.DisplayName}, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	if len(g.Members) > 0 {
+		c := newGroupChange(t)
+		err := c.set("replace", "members", g.Members)
+		if err == nil {
+			t, err = h.applyGroup(ctx, c)
+		}
+		if err != nil {
+			if derr := h.eps.DeleteTeam(ctx, t.ID); derr != nil {
+				h.logger.Log("err", derr)
+			}
+			return err
+		}
+	}
+	created := toSCIMGroup(t)
+	w.Header().Set("Location", created.Meta.Location)
+	return writeSCIM(w, http.StatusCreated, created)
+}
+
+func (h scimHandler) getGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	t, err := h.eps.GetTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMGroup(t))
+}
+
+func (h scimHandler) replaceGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	var g struct {
+		DisplayName string          `json:"displayName"`
+		Members     json.RawMessage `json:"members"`
+	}
+	if err := decodeSCIM(r, &g); err != nil {
+		return err
+	}
+	t, err := h.eps.GetTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+	c := newGroupChange(t)
+	c.name = g.DisplayName
+	members := g.Members
+	if len(members) == 0 {
+		members = json.RawMessage("[]")
+	}
+	if err := c.set("replace", "members", members); err != nil {
+		return err
+	}
+	if t, err = h.applyGroup(ctx, c); err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMGroup(t))
+}
+
+func (h scimHandler) patchGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	var patch scimPatchOp
+	if err := decodeSCIM(r, &patch); err != nil {
+		return err
+	}
+	t, err := h.eps.GetTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+	c := newGroupChange(t)
+	for _, op := range patch.Operations {
+		switch o := strings.ToLower(op.Op); o {
+		case "add", "replace":
+			if op.Path != "" {
+				if err := c.set(o, op.Path, op.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return errSCIM(http.StatusBadRequest, "invalidValue", "value without path must be an object")
+			}
+			for attr, value := range attrs {
+				if err := c.set(o, attr, value); err != nil {
+					return err
+				}
+			}
+		case "remove":
+			if err := c.unset(op.Path, op.Value); err != nil {
+				return err
+			}
+		default:
+			return errSCIM(http.StatusBadRequest, "invalidSyntax", "unknown operation %q", op.Op)
+		}
+	}
+	if t, err = h.applyGroup(ctx, c); err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, toSCIMGroup(t))
+}
+
+func (h scimHandler) deleteGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r)
+	if err != nil {
+		return err
+	}
+	if err := h.eps.DeleteTeam(ctx, id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
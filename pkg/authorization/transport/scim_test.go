@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/authorization/endpoints"
+)
+
+func TestSCIM(t *testing.T) {
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	s, _ := authorization.NewInMemService(params, authorization.WithProvisioning(authorization.ProvisioningConfig{Token: "s3cret"}))
+	srv := httptest.NewServer(MakeHTTPHandler(endpoints.MakeServerEndpoints(s, endpoints.AuthorizationMiddleware(s), endpoints.ValidationMiddleware(endpoints.DefaultLimits)), log.NewNopLogger()))
+	defer srv.Close()
+
+	do := func(token, method, path, body string, v interface{}) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", scimContentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp
+	}
+
+	resp := do("", http.MethodGet, "/scim/v2/Users", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	resp = do("wrong", http.MethodGet, "/scim/v2/Users", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// sessions of admins are no provisioning token
+	ctx := context.Background()
+	c, _ := MakeClientEndpoints(srv.URL)
+	_, err := authorization.BootstrapAdmin(ctx, s, "admin", "correct horse battery staple")
+	assert.NoError(t, err)
+	session, err := c.Login(ctx, authorization.User{Username: "admin", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	resp = do(session.Token, http.MethodGet, "/scim/v2/Users", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// nor is the provisioning token a session outside SCIM
+	admin, err := c.FindUser(authorization.WithSessionToken(ctx, session.Token), "admin")
+	assert.NoError(t, err)
+	provisioning := authorization.WithSessionToken(ctx, "s3cret")
+	_, err = c.GetUsers(provisioning)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	_, err = c.CreateAPIKey(provisioning, admin.ID, authorization.APIKey{Name: "provisioning"})
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	_, err = c.ProvisionUser(provisioning, authorization.User{Username: "mallory"})
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	// admins are managed by admins only
+	resp = do("s3cret", http.MethodDelete, "/scim/v2/Users/"+admin.ID.String(), "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = do("s3cret", http.MethodPatch, "/scim/v2/Users/"+admin.ID.String(), `{"Operations":[{"op":"replace","path":"password","value":"another horse battery staple"}]}`, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	var alice scimUser
+	resp = do("s3cret", http.MethodPost, "/scim/v2/Users", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"alice","password":"correct horse battery staple","emails":[{"value":"alice@example.com","primary":true}]}`, &alice)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "alice", alice.UserName)
+	if assert.NotNil(t, alice.Active) {
+		assert.True(t, *alice.Active)
+	}
+	var scimErr scimErrorResponse
+	resp = do("s3cret", http.MethodPost, "/scim/v2/Users", `{"userName":"alice"}`, &scimErr)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "uniqueness", scimErr.ScimType)
+	resp = do("s3cret", http.MethodPost, "/scim/v2/Users", `{"userName":"bob"}`, nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var list scimListResponse
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq "alice"`), "", &list)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, list.TotalResults)
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", "", &list)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, list.TotalResults)
+	assert.Equal(t, 2, list.StartIndex)
+	assert.Len(t, list.Resources, 1)
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName sw "a"`), "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	login, err := c.Login(ctx, authorization.User{Username: "alice", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	var patched scimUser
+	resp = do("s3cret", http.MethodPatch, "/scim/v2/Users/"+alice.ID, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","value":{"active":"False"}}]}`, &patched)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if assert.NotNil(t, patched.Active) {
+		assert.False(t, *patched.Active)
+	}
+	_, err = c.ValidateSession(ctx, login.Token)
+	assert.Equal(t, authorization.ErrInvalidToken, err)
+	_, err = c.Login(ctx, authorization.User{Username: "alice", Password: "correct horse battery staple"})
+	assert.Error(t, err)
+	resp = do("s3cret", http.MethodPatch, "/scim/v2/Users/"+alice.ID, `{"Operations":[{"op":"replace","path":"active","value":true}]}`, &patched)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, *patched.Active)
+
+	var group scimGroup
+	resp = do("s3cret", http.MethodPost, "/scim/v2/Groups", `{"displayName":"kitchen","members":[{"value":"`+alice.ID+`"}]}`, &group)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Len(t, group.Members, 1)
+	resp = do("s3cret", http.MethodPatch, "/scim/v2/Groups/"+group.ID, `{"Operations":[{"op":"remove","path":"members[value eq \"`+alice.ID+`\"]"},{"op":"replace","path":"displayName","value":"pantry"}]}`, &group)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, group.Members)
+	assert.Equal(t, "pantry", group.DisplayName)
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Groups?filter="+url.QueryEscape(`displayName eq "pantry"`), "", &list)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, list.TotalResults)
+	resp = do("s3cret", http.MethodDelete, "/scim/v2/Groups/"+group.ID, "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Groups/"+group.ID, "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// deprovisioned users are disabled, not deleted
+	resp = do("s3cret", http.MethodDelete, "/scim/v2/Users/"+alice.ID, "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	var gone scimUser
+	resp = do("s3cret", http.MethodGet, "/scim/v2/Users/"+alice.ID, "", &gone)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if assert.NotNil(t, gone.Active) {
+		assert.False(t, *gone.Active)
+	}
+}
@@ -0,0 +1,130 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+)
+
+// ErrUnknownUser is returned by a Verifier that doesn't know a username,
+// AuthenticateUser asks the next verifier then
+var ErrUnknownUser = errors.New("unknown user")
+
+// VerifiedUser is a user an external Verifier authenticated. The local
+// user with its username is created or updated with it.
+type VerifiedUser struct {
+	Username string
+	Email    string
+}
+
+// Verifier checks the password of a user. Verify returns ErrUnknownUser
+// for users it doesn't know and ErrAuthenticationFailed for a wrong
+// password. Name identifies the verifier in the local users it created,
+// a verifier only updates its own users.
+type Verifier interface {
+	Name() string
+	Verify(ctx context.Context, username, password string) (VerifiedUser, error)
+}
+
+// PasswordHashes is the Verifier of the argon2id password hashes stored
+// with the users, it knows users with a password
+var PasswordHashes Verifier = passwordHashes{}
+
+type passwordHashes struct{}
+
+func (passwordHashes) Name() string {
+	return ""
+}
+
+// Verify is never called, the service checks its own hashes
+func (passwordHashes) Verify(context.Context, string, string) (VerifiedUser, error) {
+	return VerifiedUser{}, ErrUnknownUser
+}
+
+// WithVerifiers sets the verifiers AuthenticateUser asks in order, the
+// first one knowing the user decides. Only PasswordHashes is asked
+// otherwise.
+func WithVerifiers(verifiers ...Verifier) Option {
+	return func(s *dbSvc) {
+		s.verifiers = verifiers
+	}
+}
+
+// verify returns the user authenticated by username and password
+func (s *dbSvc) verify(ctx context.Context, username, password string) (storedUser, error) {
+	for _, v := range s.verifiers {
+		var u storedUser
+		var err error
+		if v == PasswordHashes {
+			u, err = s.verifyHash(username, password)
+		} else {
+			u, err = s.verifyExternal(ctx, v, username, password)
+		}
+		if err != ErrUnknownUser {
+			return u, err
+		}
+	}
+	return storedUser{}, ErrAuthenticationFailed
+}
+
+func (s *dbSvc) verifyHash(username, password string) (storedUser, error) {
+	var u storedUser
+	result := s.db.Where(&storedUser{Username: username}).First(&u)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		return storedUser{}, result.Error
+	}
+	if result.Error == gorm.ErrRecordNotFound || u.PasswordHash == "" {
+		// unknown users fail like a wrong password and take as long
+		argon2id.ComparePasswordAndHash(password, s.dummy(), s.hashParams.Pepper())
+		return storedUser{}, ErrUnknownUser
+	}
+
+	match, err := u.ComparePassword(password, s.hashParams.Pepper())
+	if err != nil {
+		return storedUser{}, err
+	}
+	if !match {
+		return storedUser{}, ErrAuthenticationFailed
+	}
+	s.rehash(u, password)
+	return u, nil
+}
+
+// verifyExternal asks v and creates or updates the local user it
+// authenticated. Local users of another verifier are never taken over,
+// whoever controls the directory could sign in as them otherwise.
+func (s *dbSvc) verifyExternal(ctx context.Context, v Verifier, username, password string) (storedUser, error) {
+	verified, err := v.Verify(ctx, username, password)
+	if err != nil {
+		return storedUser{}, err
+	}
+	if verified.Username == "" {
+		verified.Username = username
+	}
+
+	var u storedUser
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where(&storedUser{Username: verified.Username}).First(&u)
+		if result.Error == gorm.ErrRecordNotFound {
+			// the directory manages the email addresses of its users
+			u = storedUser{Username: verified.Username, Email: verified.Email, EmailVerified: verified.Email != "",
+				Role: string(RoleMember), Directory: v.Name()}
+			return tx.Create(&u).Error
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if u.Directory != v.Name() || u.DeletedAt.Valid {
+			return ErrAuthenticationFailed
+		}
+		if u.Email != verified.Email {
+			u.Email, u.EmailVerified = verified.Email, verified.Email != ""
+			return tx.Model(&u).Select("Email", "EmailVerified").Updates(&u).Error
+		}
+		return nil
+	})
+	return u, err
+}
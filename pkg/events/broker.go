@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Publishers publishes every event to all of its publishers.
+type Publishers []Publisher
+
+// Publish implements Publisher. It returns the joined errors of all publishers.
+func (p Publishers) Publish(ctx context.Context, e Event) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// subscriberBuffer is the number of events a subscriber can fall behind
+// before events are dropped for it
+const subscriberBuffer = 16
+
+// Broker fans out published events to subscribers within the same process.
+// Slow subscribers miss events instead of blocking the publisher.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subs: map[chan Event]struct{}{},
+	}
+}
+
+// Publish implements Publisher
+func (b *Broker) Publish(ctx context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving all events published from now on.
+// The channel is closed when ctx is done.
+func (b *Broker) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+
+	return ch
+}
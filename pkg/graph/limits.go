@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Limits protects the service against expensive queries
+type Limits struct {
+	// MaxDepth is the maximum nesting of selections
+	MaxDepth int
+	// MaxComplexity is the maximum cost of a query. Every field costs 1,
+	// the cost of the selections of a list field is multiplied by ListCost.
+	MaxComplexity int
+	ListCost      int
+}
+
+var DefaultLimits = Limits{
+	MaxDepth:      8,
+	MaxComplexity: 1000,
+	ListCost:      10,
+}
+
+var ErrUnknownOperation = errors.New("unknown operation")
+
+// analysis is the result of checking a query against the schema and limits
+type analysis struct {
+	operation  ast.Operation
+	depth      int
+	complexity int
+}
+
+// analyze validates query against schema and enforces the limits
+func (l Limits) analyze(schema *ast.Schema, query, operationName string) (analysis, error) {
+	doc, errs := gqlparser.LoadQuery(schema, query)
+	if errs != nil {
+		return analysis{}, errs
+	}
+
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return analysis{}, ErrUnknownOperation
+	}
+
+	depth, complexity := l.measure(op.SelectionSet)
+	a := analysis{
+		operation:  op.Operation,
+		depth:      depth,
+		complexity: complexity,
+	}
+
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return a, fmt.Errorf("query depth %d exceeds the maximum of %d", depth, l.MaxDepth)
+	}
+	if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+		return a, fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, l.MaxComplexity)
+	}
+	return a, nil
+}
+
+// measure returns the depth and complexity of a selection set
+func (l Limits) measure(set ast.SelectionSet) (depth, complexity int) {
+	for _, selection := range set {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, c = l.measure(s.SelectionSet)
+			if s.Definition != nil && s.Definition.Type.Elem != nil && l.ListCost > 1 {
+				c *= l.ListCost
+			}
+			d, c = d+1, c+1
+		case *ast.FragmentSpread:
+			d, c = l.measure(s.Definition.SelectionSet)
+		case *ast.InlineFragment:
+			d, c = l.measure(s.SelectionSet)
+		}
+
+		complexity += c
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth, complexity
+}
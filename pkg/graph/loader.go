@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+// batchWait is how long a loader collects keys before fetching them at once
+const batchWait = 2 * time.Millisecond
+
+// batchFunc fetches the values for all keys in a single call.
+// keys missing in the result resolve to the zero value.
+type batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// loader batches and caches loads within a single request, resolvers
+// running in parallel share one fetch instead of calling the service each.
+type loader[K comparable, V any] struct {
+	fetch batchFunc[K, V]
+
+	mu      sync.Mutex
+	cache   map[K]*loadResult[V]
+	pending map[K]*loadResult[V]
+}
+
+func newLoader[K comparable, V any](fetch batchFunc[K, V]) *loader[K, V] {
+	return &loader[K, V]{
+		fetch: fetch,
+		cache: map[K]*loadResult[V]{},
+	}
+}
+
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		if l.pending == nil {
+			l.pending = map[K]*loadResult[V]{}
+			time.AfterFunc(batchWait, func() { l.dispatch(ctx) })
+		}
+		l.pending[key] = r
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	keys := make([]K, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+
+	values, err := l.fetch(ctx, keys)
+	for k, r := range pending {
+		r.value, r.err = values[k], err
+		close(r.done)
+	}
+}
+
+// loaders holds the request scoped loaders
+type loaders struct {
+	users      *loader[uuid.UUID, *authorization.User]
+	todosOwned *loader[uuid.UUID, []todo.Todo]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, todos todo.Service, users authorization.Service) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		users:      newLoader(fetchUsers(users)),
+		todosOwned: newLoader(fetchTodosOwned(todos)),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// fetchUsers gets a single user directly, multiple users with one call
+// listing all users
+func fetchUsers(s authorization.Service) batchFunc[uuid.UUID, *authorization.User] {
+	return func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*authorization.User, error) {
+		found := make(map[uuid.UUID]*authorization.User, len(ids))
+
+		if len(ids) == 1 {
+			u, err := s.GetUser(ctx, ids[0])
+			switch err {
+			case nil:
+				found[u.ID] = &u
+			case authorization.ErrNotFound:
+			default:
+				return nil, err
+			}
+			return found, nil
+		}
+
+		wanted := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			wanted[id] = true
+		}
+		users, err := s.GetUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			if wanted[users[i].ID] {
+				found[users[i].ID] = &users[i]
+			}
+		}
+		return found, nil
+	}
+}
+
+// fetchTodosOwned gets the todos of every owner from the todo service, the
+// todos of the teams of an owner are only known there
+func fetchTodosOwned(s todo.Service) batchFunc[uuid.UUID, []todo.Todo] {
+	return func(ctx context.Context, owners []uuid.UUID) (map[uuid.UUID][]todo.Todo, error) {
+		owned := make(map[uuid.UUID][]todo.Todo, len(owners))
+		for _, id := range owners {
+			todos, err := s.GetTodosOwned(ctx, authorization.User{ID: id})
+			if err != nil {
+				return nil, err
+			}
+			owned[id] = todos
+		}
+		return owned, nil
+	}
+}
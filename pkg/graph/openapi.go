@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/openapi"
+)
+
+// OpenAPI returns the OpenAPI document of the route served by
+// MakeHTTPHandler. The GraphQL schema itself is not described, it is
+// served by introspection.
+func OpenAPI() openapi.Document {
+	doc := openapi.New("graphql", "1.0.0", "GraphQL API over todos and users. Requests need a session, API key or OAuth2 access token as bearer token.")
+
+	result := doc.Component("GraphQLResult", &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"data":   {Type: "object", Description: "the result of the operation"},
+			"errors": {Type: "array", Items: &openapi.Schema{Type: "object"}},
+		},
+	})
+
+	doc.Add(http.MethodPost, "/", openapi.Operation{
+		OperationID: "graphql",
+		Summary:     "Run a GraphQL query, mutation or subscription",
+		Description: "Subscriptions are streamed as server-sent events, every result is a next event followed by a complete event at the end.",
+		Tags:        []string{"graphql"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.Component("GraphQLRequest", openapi.SchemaOf(graphqlRequest{})))},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "the result, errors of the operation included",
+				Content: map[string]openapi.MediaType{
+					"application/json":  {Schema: result},
+					"text/event-stream": {},
+				},
+			},
+			"400": {Description: "the request is malformed or exceeds the query limits", Content: openapi.JSON(result)},
+		},
+	})
+
+	return doc
+}
@@ -0,0 +1,357 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	gql "github.com/graph-gophers/graphql-go"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/events"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+// Subscriber is implemented by event sources the todoChanged subscription
+// can listen to, like events.Broker
+type Subscriber interface {
+	Subscribe(ctx context.Context) <-chan events.Event
+}
+
+// resolver is the root resolver for queries, mutations and subscriptions
+type resolver struct {
+	todos  todo.Service
+	users  authorization.Service
+	events Subscriber
+}
+
+func parseID(id gql.ID) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(string(id))
+	if err != nil {
+		return uuid.Nil, todo.ErrInvalidUUID
+	}
+	return parsed, nil
+}
+
+func parseOptionalID(id *gql.ID) (uuid.UUID, error) {
+	if id == nil {
+		return uuid.Nil, nil
+	}
+	return parseID(*id)
+}
+
+// queries
+
+func (r *resolver) Todo(ctx context.Context, args struct{ ID gql.ID }) (*todoResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.todos.GetTodo(ctx, id)
+	switch err {
+	case nil:
+		return &todoResolver{t}, nil
+	case todo.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *resolver) Todos(ctx context.Context, args struct{ Owner *gql.ID }) ([]*todoResolver, error) {
+	owner, err := parseOptionalID(args.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var todos []todo.Todo
+	if owner == uuid.Nil {
+		todos, err = r.todos.GetTodos(ctx)
+	} else {
+		todos, err = loadersFrom(ctx).todosOwned.Load(ctx, owner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return todoResolvers(todos), nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct {
+	ID       *gql.ID
+	Username *string
+}) (*userResolver, error) {
+	id, err := parseOptionalID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var u authorization.User
+	switch {
+	case id != uuid.Nil:
+		u, err = r.users.GetUser(ctx, id)
+	case args.Username != nil:
+		u, err = r.users.FindUser(ctx, *args.Username)
+	default:
+		return nil, authorization.ErrIDMissing
+	}
+
+	switch err {
+	case nil:
+		return &userResolver{u}, nil
+	case authorization.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *resolver) Users(ctx context.Context) ([]*userResolver, error) {
+	users, err := r.users.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*userResolver, len(users))
+	for i := range users {
+		resolvers[i] = &userResolver{users[i]}
+	}
+	return resolvers, nil
+}
+
+// mutations
+
+type todoInput struct {
+	Title       string
+	Description *string
+	Done        *bool
+	OwnerID     *gql.ID
+	TeamID      *gql.ID
+}
+
+func (in todoInput) toTodo() (todo.Todo, error) {
+	owner, err := parseOptionalID(in.OwnerID)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	team, err := parseOptionalID(in.TeamID)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	t := todo.Todo{Title: in.Title, OwnerID: owner, TeamID: team}
+	if in.Description != nil {
+		t.Description = *in.Description
+	}
+	if in.Done != nil {
+		t.Done = *in.Done
+	}
+	return t, nil
+}
+
+type userInput struct {
+	Username *string
+	Password *string
+}
+
+func (in userInput) toUser() authorization.User {
+	var u authorization.User
+	if in.Username != nil {
+		u.Username = *in.Username
+	}
+	if in.Password != nil {
+		u.Password = *in.Password
+	}
+	return u
+}
+
+func (r *resolver) AddTodo(ctx context.Context, args struct{ Input todoInput }) (*todoResolver, error) {
+	t, err := args.Input.toTodo()
+	if err != nil {
+		return nil, err
+	}
+	t, err = r.todos.AddTodo(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{t}, nil
+}
+
+func (r *resolver) UpdateTodo(ctx context.Context, args struct {
+	ID    gql.ID
+	Input todoInput
+}) (*todoResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := args.Input.toTodo()
+	if err != nil {
+		return nil, err
+	}
+	t, err = r.todos.UpdateTodo(ctx, id, t)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{t}, nil
+}
+
+func (r *resolver) DeleteTodo(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return "", err
+	}
+	return args.ID, r.todos.DeleteTodo(ctx, id)
+}
+
+func (r *resolver) RestoreTodo(ctx context.Context, args struct{ ID gql.ID }) (*todoResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.todos.RestoreTodo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{t}, nil
+}
+
+func (r *resolver) AddUser(ctx context.Context, args struct{ Input userInput }) (*userResolver, error) {
+	u, err := r.users.AddUser(ctx, args.Input.toUser())
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{u}, nil
+}
+
+func (r *resolver) UpdateUser(ctx context.Context, args struct {
+	ID    gql.ID
+	Input userInput
+}) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := r.users.UpdateUser(ctx, id, args.Input.toUser())
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{u}, nil
+}
+
+func (r *resolver) DeleteUser(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return "", err
+	}
+	return args.ID, r.users.DeleteUser(ctx, id)
+}
+
+// subscriptions
+
+func (r *resolver) TodoChanged(ctx context.Context, args struct{ Owner *gql.ID }) (<-chan *todoEventResolver, error) {
+	owner, err := parseOptionalID(args.Owner)
+	if err != nil {
+		return nil, err
+	}
+	caller, err := todo.Authenticate(ctx, r.users, authorization.ScopeTodosRead)
+	if err != nil {
+		return nil, err
+	}
+	if owner != uuid.Nil && owner != caller.ID && !caller.Role.Can(authorization.PermReadUsers) {
+		return nil, authorization.ErrForbidden
+	}
+
+	changes := make(chan *todoEventResolver)
+	go func() {
+		defer close(changes)
+		for e := range r.events.Subscribe(ctx) {
+			var t todo.Todo
+			if err := json.Unmarshal(e.Data, &t); err != nil {
+				continue
+			}
+			if owner != uuid.Nil && t.OwnerID != owner {
+				continue
+			}
+			// teams are looked up for every change so removed members stop
+			// receiving them at once
+			if todo.CanAccess(ctx, r.users, caller, t, false) != nil {
+				continue
+			}
+
+			select {
+			case changes <- &todoEventResolver{event: e, todo: t}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// type resolvers
+
+type todoResolver struct {
+	t todo.Todo
+}
+
+func todoResolvers(todos []todo.Todo) []*todoResolver {
+	resolvers := make([]*todoResolver, len(todos))
+	for i := range todos {
+		resolvers[i] = &todoResolver{todos[i]}
+	}
+	return resolvers
+}
+
+func (r *todoResolver) ID() gql.ID      { return gql.ID(r.t.ID.String()) }
+func (r *todoResolver) Title() string   { return r.t.Title }
+func (r *todoResolver) Done() bool      { return r.t.Done }
+func (r *todoResolver) OwnerID() gql.ID { return gql.ID(r.t.OwnerID.String()) }
+
+func (r *todoResolver) TeamID() *gql.ID {
+	if r.t.TeamID == uuid.Nil {
+		return nil
+	}
+	id := gql.ID(r.t.TeamID.String())
+	return &id
+}
+
+func (r *todoResolver) Description() *string {
+	if r.t.Description == "" {
+		return nil
+	}
+	return &r.t.Description
+}
+
+func (r *todoResolver) Owner(ctx context.Context) (*userResolver, error) {
+	u, err := loadersFrom(ctx).users.Load(ctx, r.t.OwnerID)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &userResolver{*u}, nil
+}
+
+type userResolver struct {
+	u authorization.User
+}
+
+func (r *userResolver) ID() gql.ID       { return gql.ID(r.u.ID.String()) }
+func (r *userResolver) Username() string { return r.u.Username }
+
+func (r *userResolver) Todos(ctx context.Context) ([]*todoResolver, error) {
+	todos, err := loadersFrom(ctx).todosOwned.Load(ctx, r.u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return todoResolvers(todos), nil
+}
+
+type todoEventResolver struct {
+	event events.Event
+	todo  todo.Todo
+}
+
+func (r *todoEventResolver) ID() gql.ID         { return gql.ID(r.event.ID.String()) }
+func (r *todoEventResolver) Type() string       { return r.event.Type }
+func (r *todoEventResolver) OccurredAt() string { return r.event.OccurredAt.Format(time.RFC3339Nano) }
+func (r *todoEventResolver) Todo() *todoResolver {
+	return &todoResolver{r.todo}
+}
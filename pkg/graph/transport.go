@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/log"
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type handler struct {
+	schema   *gql.Schema
+	analyzer *ast.Schema
+	limits   Limits
+	todos    todo.Service
+	users    authorization.Service
+	logger   log.Logger
+}
+
+// MakeHTTPHandler serves the GraphQL API over todos and users.
+// Queries and mutations are posted as JSON, subscriptions are streamed as
+// server-sent events when the request accepts text/event-stream. todos are
+// the endpoints of the todo service wrapped with the same middlewares as
+// the other transports, see todo.MakeServerEndpoints, so queries and
+// mutations are authorized alike. Subscriptions authenticate with users
+// and only stream the changes of todos the caller may read.
+func MakeHTTPHandler(todos todo.Service, users authorization.Service, events Subscriber, limits Limits, logger log.Logger) http.Handler {
+	r := &resolver{
+		todos:  todos,
+		users:  users,
+		events: events,
+	}
+
+	h := &handler{
+		schema:   gql.MustParseSchema(schemaSDL, r, gql.UseFieldResolvers()),
+		analyzer: gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: schemaSDL}),
+		limits:   limits,
+		todos:    todos,
+		users:    users,
+		logger:   logger,
+	}
+
+	// routed so the OpenAPI document can be compared with the routes, other
+	// methods are still answered with a GraphQL error
+	router := chi.NewRouter()
+	router.Post("/", h.ServeHTTP)
+	router.MethodNotAllowed(h.ServeHTTP)
+	return router
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeErrors(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+
+	a, err := h.limits.analyze(h.analyzer, req.Query, req.OperationName)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the session of the caller is forwarded to the authorization service
+	ctx := authorizationTrsp.SessionTokenToContext(r.Context(), r)
+	ctx = withLoaders(ctx, h.todos, h.users)
+
+	if a.operation == ast.Subscription {
+		h.serveSubscription(ctx, w, req)
+		return
+	}
+
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(response)
+}
+
+// serveSubscription streams every result of the subscription as an
+// server-sent event until the client goes away
+func (h *handler) serveSubscription(ctx context.Context, w http.ResponseWriter, req graphqlRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrors(w, http.StatusNotImplemented, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	results, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		writeErrors(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			h.logger.Log("during", "subscription", "err", err)
+			continue
+		}
+		fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	fmt.Fprint(w, "event: complete\ndata:\n\n")
+	flusher.Flush()
+}
+
+func writeErrors(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": err.Error()}},
+	})
+}
@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/events"
+	"github.com/demeesterdev/todo-service/pkg/todo"
+)
+
+type testServer struct {
+	*httptest.Server
+	todos  todo.Service
+	users  authorization.Service
+	broker *events.Broker
+}
+
+func newTestServer(t *testing.T, limits Limits) testServer {
+	todos, _ := todo.NewInMemService()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	broker := events.NewBroker()
+	eps := todo.MakeServerEndpoints(todos, todo.AuthorizationMiddleware(users), todo.ValidationMiddleware(todo.DefaultLimits))
+	s := httptest.NewServer(MakeHTTPHandler(eps, users, broker, limits, log.NewNopLogger()))
+	t.Cleanup(s.Close)
+	return testServer{Server: s, todos: todos, users: users, broker: broker}
+}
+
+// login adds a user and returns it with its session token
+func (s testServer) login(t *testing.T, username string) (authorization.User, string) {
+	ctx := context.Background()
+	u, err := s.users.AddUser(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	session, err := s.users.Login(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+	return u, session.Token
+}
+
+func (s testServer) request(query string, variables map[string]interface{}, token string) *http.Request {
+	body, _ := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	req, _ := http.NewRequest(http.MethodPost, s.URL, strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func (s testServer) post(t *testing.T, query string, variables map[string]interface{}, token string) (int, map[string]interface{}) {
+	resp, err := http.DefaultClient.Do(s.request(query, variables, token))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, DefaultLimits)
+
+	owner, token := s.login(t, "hanshandjes")
+	_, err := s.todos.AddTodo(ctx, todo.Todo{OwnerID: owner.ID, Title: "first"})
+	assert.NoError(t, err)
+	_, err = s.todos.AddTodo(ctx, todo.Todo{OwnerID: owner.ID, Title: "second"})
+	assert.NoError(t, err)
+
+	code, result := s.post(t, `query($owner: ID) { todos(owner: $owner) { title owner { username todos { title } } } }`,
+		map[string]interface{}{"owner": owner.ID.String()}, token)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, result["errors"])
+	todos := result["data"].(map[string]interface{})["todos"].([]interface{})
+	assert.Equal(t, 2, len(todos))
+	first := todos[0].(map[string]interface{})
+	assert.Equal(t, "hanshandjes", first["owner"].(map[string]interface{})["username"])
+	assert.Equal(t, 2, len(first["owner"].(map[string]interface{})["todos"].([]interface{})))
+
+	// queries are authorized like the other transports
+	other, otherToken := s.login(t, "peterpootjes")
+	private, _ := s.todos.AddTodo(ctx, todo.Todo{OwnerID: other.ID, Title: "private"})
+	_, result = s.post(t, `query($id: ID!) { todo(id: $id) { title } }`, map[string]interface{}{"id": private.ID.String()}, token)
+	assert.Equal(t, authorization.ErrForbidden.Error(), result["errors"].([]interface{})[0].(map[string]interface{})["message"])
+	_, result = s.post(t, `query($owner: ID) { todos(owner: $owner) { title } }`, map[string]interface{}{"owner": owner.ID.String()}, otherToken)
+	assert.Equal(t, authorization.ErrForbidden.Error(), result["errors"].([]interface{})[0].(map[string]interface{})["message"])
+	_, result = s.post(t, `{ todos { title } }`, nil, "")
+	assert.Equal(t, authorization.ErrUnauthenticated.Error(), result["errors"].([]interface{})[0].(map[string]interface{})["message"])
+	_, result = s.post(t, `{ todos { title } }`, nil, otherToken)
+	assert.Nil(t, result["errors"])
+	assert.Equal(t, 1, len(result["data"].(map[string]interface{})["todos"].([]interface{})))
+}
+
+func TestMutation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, DefaultLimits)
+	owner, token := s.login(t, "peterpootjes")
+
+	code, result := s.post(t, `mutation($owner: ID) { addTodo(input: {title: "new", ownerId: $owner}) { id title } }`,
+		map[string]interface{}{"owner": owner.ID.String()}, token)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, result["errors"])
+	todos, _ := s.todos.GetTodosOwned(ctx, owner)
+	assert.Equal(t, 1, len(todos))
+
+	// todos without an owner are added for the caller
+	_, result = s.post(t, `mutation { addTodo(input: {title: "no owner"}) { id } }`, nil, token)
+	assert.Nil(t, result["errors"])
+	todos, _ = s.todos.GetTodosOwned(ctx, owner)
+	assert.Equal(t, 2, len(todos))
+
+	_, result = s.post(t, `mutation { addTodo(input: {title: "anonymous"}) { id } }`, nil, "")
+	assert.Equal(t, authorization.ErrUnauthenticated.Error(), result["errors"].([]interface{})[0].(map[string]interface{})["message"])
+
+	// the middlewares of the todo service validate the input
+	_, result = s.post(t, `mutation($title: String!) { addTodo(input: {title: $title}) { id } }`,
+		map[string]interface{}{"title": strings.Repeat("x", todo.DefaultLimits.TitleMaxLength+1)}, token)
+	assert.NotNil(t, result["errors"])
+	todos, _ = s.todos.GetTodosOwned(ctx, owner)
+	assert.Equal(t, 2, len(todos))
+}
+
+func TestLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		limits Limits
+		query  string
+		code   int
+	}{
+		{
+			name:   "should allow queries within limits",
+			limits: DefaultLimits,
+			query:  `{ users { username todos { title } } }`,
+			code:   http.StatusOK,
+		},
+		{
+			name:   "should reject queries that are too deep",
+			limits: Limits{MaxDepth: 3},
+			query:  `{ users { todos { owner { username } } } }`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "should reject queries that are too complex",
+			limits: Limits{MaxComplexity: 100, ListCost: 10},
+			query:  `{ users { todos { owner { todos { title } } } } }`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "should reject invalid queries",
+			limits: DefaultLimits,
+			query:  `{ todos { unknown } }`,
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, tc.limits)
+
+			code, _ := s.post(t, tc.query, nil, "")
+
+			assert.Equal(t, tc.code, code)
+		})
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	s := newTestServer(t, DefaultLimits)
+	owner, token := s.login(t, "hanshandjes")
+
+	req := s.request(`subscription { todoChanged { type todo { title } } }`, nil, token)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the subscription is registered once the stream is open
+	time.Sleep(50 * time.Millisecond)
+	// only the changes of the todos of the caller are streamed
+	e, _ := events.New(todo.EventTodoCreated, todo.Todo{OwnerID: uuid.New(), Title: "not mine"})
+	s.broker.Publish(context.Background(), e)
+	e, _ = events.New(todo.EventTodoCreated, todo.Todo{OwnerID: owner.ID, Title: "streamed"})
+	s.broker.Publish(context.Background(), e)
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		data = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+		if !strings.HasPrefix(line, "data: ") {
+			data = ""
+		}
+	}
+	assert.JSONEq(t, `{"data":{"todoChanged":{"type":"todo.created","todo":{"title":"streamed"}}}}`, data)
+}
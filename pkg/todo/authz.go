@@ -0,0 +1,124 @@
+package todo
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// Credentials checks the sessions, API keys and OAuth2 access tokens of
+// users, it is implemented by authorization.Service
+type Credentials interface {
+	ValidateSession(ctx context.Context, token string) (authorization.User, error)
+	ValidateAPIKey(ctx context.Context, key string) (authorization.APIKey, error)
+	ValidateAccessToken(ctx context.Context, token string) (authorization.AccessToken, error)
+}
+
+// AuthorizationMiddleware authenticates every request but the service status
+// with the bearer token, see Authenticate, and adds the caller to the
+// context with authorization.WithCaller. The service then only lets the
+// caller reach the todos it owns and those of its teams, see CanAccess.
+// Todos are added for the caller and listing all todos lists the todos of
+// the caller, only callers managing users act for others and list all todos.
+func AuthorizationMiddleware(creds Credentials) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if _, ok := request.(serviceStatusRequest); ok {
+				return next(ctx, request)
+			}
+			caller, err := Authenticate(ctx, creds, requiredScope(request))
+			if err != nil {
+				return nil, err
+			}
+			request, err = actAs(caller, request)
+			if err != nil {
+				return nil, err
+			}
+			return next(authorization.WithCaller(ctx, caller), request)
+		}
+	}
+}
+
+// Authenticate returns the user of the bearer token in ctx, see
+// authorization.SessionToken. API keys and access tokens need scope and
+// act as their user without its role, sessions need the permission of
+// their role matching scope.
+func Authenticate(ctx context.Context, creds Credentials, scope authorization.Scope) (authorization.User, error) {
+	token := authorization.SessionToken(ctx)
+	var (
+		caller  authorization.User
+		allowed bool
+		err     error
+	)
+	switch {
+	case token == "":
+		return authorization.User{}, authorization.ErrUnauthenticated
+	case authorization.IsAPIKey(token):
+		var k authorization.APIKey
+		k, err = creds.ValidateAPIKey(ctx, token)
+		caller, allowed = authorization.User{ID: k.UserID}, k.Has(scope)
+	case authorization.IsAccessToken(token):
+		var t authorization.AccessToken
+		t, err = creds.ValidateAccessToken(ctx, token)
+		caller, allowed = authorization.User{ID: t.UserID}, t.Has(scope)
+	default:
+		caller, err = creds.ValidateSession(ctx, token)
+		p := authorization.PermWriteSelf
+		if scope == authorization.ScopeTodosRead {
+			p = authorization.PermReadSelf
+		}
+		allowed = caller.Role.Can(p)
+	}
+	if err == authorization.ErrInvalidToken {
+		return authorization.User{}, authorization.ErrUnauthenticated
+	}
+	if err != nil {
+		return authorization.User{}, err
+	}
+	if !allowed {
+		return authorization.User{}, authorization.ErrForbidden
+	}
+	return caller, nil
+}
+
+// requiredScope returns the scope a key or access token needs to make request
+func requiredScope(request interface{}) authorization.Scope {
+	switch request.(type) {
+	case getTodoRequest, getTodosRequest, serviceStatusRequest:
+		return authorization.ScopeTodosRead
+	default:
+		return authorization.ScopeTodosWrite
+	}
+}
+
+// actAs limits the todos added and listed by request to those of caller,
+// unless it manages users. Requests on a single todo are checked by the
+// service once the todo is loaded.
+func actAs(caller authorization.User, request interface{}) (interface{}, error) {
+	switch req := request.(type) {
+	case addTodoRequest:
+		if req.Todo.OwnerID == uuid.Nil {
+			req.Todo.OwnerID = caller.ID
+		}
+		if req.Todo.OwnerID != caller.ID && !caller.Role.Can(authorization.PermWriteUsers) {
+			return nil, authorization.ErrForbidden
+		}
+		return req, nil
+	case getTodosRequest:
+		if caller.Role.Can(authorization.PermReadUsers) {
+			return req, nil
+		}
+		if req.OwnerID == uuid.Nil {
+			req.OwnerID = caller.ID
+		}
+		if req.OwnerID != caller.ID {
+			return nil, authorization.ErrForbidden
+		}
+		return req, nil
+	default:
+		return request, nil
+	}
+}
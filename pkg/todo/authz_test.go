@@ -0,0 +1,191 @@
+package todo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+func TestHTTPAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	u, _ := users.AddUser(ctx, authorization.User{Username: "scripter", Password: "correct horse battery staple"})
+	newKey := func(scopes ...authorization.Scope) context.Context {
+		k, err := users.CreateAPIKey(ctx, u.ID, authorization.APIKey{Name: "test", Scopes: scopes})
+		assert.NoError(t, err)
+		return authorization.WithSessionToken(ctx, k.Key)
+	}
+	reader := newKey(authorization.ScopeTodosRead)
+	writer := newKey(authorization.ScopeTodosRead, authorization.ScopeTodosWrite)
+
+	s, _ := NewInMemService()
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s, AuthorizationMiddleware(users)), log.NewNopLogger()))
+	defer srv.Close()
+	c, _ := MakeClientEndpoints(srv.URL)
+
+	other, err := s.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "not mine"})
+	assert.NoError(t, err)
+
+	// todos are added for the user of the key
+	_, err = c.AddTodo(reader, Todo{Title: "groceries"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	added, err := c.AddTodo(writer, Todo{Title: "groceries"})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, added.OwnerID)
+	_, err = c.AddTodo(writer, Todo{Title: "groceries", OwnerID: other.OwnerID})
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	todos, err := c.GetTodos(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{added.ID}, ids(todos))
+	_, err = c.GetTodosOwned(reader, authorization.User{ID: other.OwnerID})
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	_, err = c.UpdateTodo(reader, added.ID, Todo{Title: "groceries", Done: true})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, c.DeleteTodo(reader, added.ID))
+	assert.NoError(t, c.DeleteTodo(writer, added.ID))
+
+	// the todos of others are out of reach
+	_, err = c.GetTodo(writer, other.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.UpdateTodo(writer, other.ID, Todo{Title: "mine now"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, c.DeleteTodo(writer, other.ID))
+	assert.NoError(t, s.DeleteTodo(ctx, other.ID))
+	_, err = c.RestoreTodo(writer, other.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	_, err = c.GetTodos(authorization.WithSessionToken(ctx, authorization.APIKeyPrefix+"unknown"))
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	// requests without a key are rejected, the status stays public
+	_, err = c.GetTodos(ctx)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	_, err = c.GetTodo(ctx, other.ID)
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+	code, err := c.ServiceStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+}
+
+func TestHTTPSessions(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	login := func(username string, role authorization.Role) (authorization.User, context.Context) {
+		u, err := users.AddUser(ctx, authorization.User{Username: username, Password: "correct horse battery staple", Role: role})
+		assert.NoError(t, err)
+		session, err := users.Login(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		return u, authorization.WithSessionToken(ctx, session.Token)
+	}
+	u, asUser := login("planner", authorization.RoleMember)
+	_, asReader := login("reader", authorization.RoleReadOnly)
+	_, asAdmin := login("admin", authorization.RoleAdmin)
+
+	s, _ := NewInMemService()
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s, AuthorizationMiddleware(users)), log.NewNopLogger()))
+	defer srv.Close()
+	c, _ := MakeClientEndpoints(srv.URL)
+
+	added, err := c.AddTodo(asUser, Todo{Title: "dentist"})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, added.OwnerID)
+	todos, err := c.GetTodos(asUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{added.ID}, ids(todos))
+
+	// sessions are checked with the authorization service
+	_, err = c.GetTodos(authorization.WithSessionToken(ctx, "forged"))
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+
+	// read-only users can't write, others don't see the todo
+	_, err = c.AddTodo(asReader, Todo{Title: "dentist"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetTodo(asReader, added.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.GetTodosOwned(asReader, u)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	// admins reach every todo
+	_, err = c.GetTodo(asAdmin, added.ID)
+	assert.NoError(t, err)
+	todos, err = c.GetTodos(asAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{added.ID}, ids(todos))
+	todos, err = c.GetTodosOwned(asAdmin, u)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{added.ID}, ids(todos))
+}
+
+func TestHTTPAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	u, _ := users.AddUser(ctx, authorization.User{Username: "planner", Password: "correct horse battery staple"})
+	client, _ := users.RegisterClient(ctx, authorization.OAuthClient{Name: "calendar", RedirectURIs: []string{"https://cal.example/cb"}, Public: true}, u.ID)
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	newToken := func(scopes ...authorization.Scope) context.Context {
+		code, err := users.Authorize(ctx, authorization.AuthorizationRequest{ClientID: client.ID, UserID: u.ID, RedirectURI: "https://cal.example/cb", Scopes: scopes,
+			CodeChallenge: base64.RawURLEncoding.EncodeToString(sum[:]), CodeChallengeMethod: authorization.CodeChallengeS256})
+		assert.NoError(t, err)
+		token, err := users.ExchangeToken(ctx, authorization.TokenRequest{GrantType: authorization.GrantTypeAuthorizationCode,
+			Client: authorization.ClientCredentials{ID: client.ID}, Code: code, RedirectURI: "https://cal.example/cb", CodeVerifier: verifier})
+		assert.NoError(t, err)
+		return authorization.WithSessionToken(ctx, token.AccessToken)
+	}
+	reader := newToken(authorization.ScopeTodosRead)
+	writer := newToken(authorization.ScopeTodosRead, authorization.ScopeTodosWrite)
+
+	s, _ := NewInMemService()
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s, AuthorizationMiddleware(users)), log.NewNopLogger()))
+	defer srv.Close()
+	c, _ := MakeClientEndpoints(srv.URL)
+
+	_, err := c.AddTodo(reader, Todo{Title: "dentist"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	added, err := c.AddTodo(writer, Todo{Title: "dentist"})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, added.OwnerID)
+
+	other, err := s.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "not mine"})
+	assert.NoError(t, err)
+	todos, err := c.GetTodos(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{added.ID}, ids(todos))
+
+	// tokens act as their user, like API keys
+	_, err = c.GetTodo(reader, other.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.UpdateTodo(writer, other.ID, Todo{Title: "mine now"})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, c.DeleteTodo(writer, other.ID))
+	_, err = c.GetTodosOwned(reader, authorization.User{ID: other.OwnerID})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = c.AddTodo(writer, Todo{Title: "dentist", OwnerID: other.OwnerID})
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	_, err = c.GetTodos(authorization.WithSessionToken(ctx, authorization.AccessTokenPrefix+"unknown"))
+	assert.Equal(t, authorization.ErrUnauthenticated, err)
+}
+
+func ids(todos []Todo) []uuid.UUID {
+	ids := make([]uuid.UUID, len(todos))
+	for i, t := range todos {
+		ids[i] = t.ID
+	}
+	return ids
+}
@@ -13,40 +13,79 @@ import (
 
 type dbSvc struct {
 	db *gorm.DB
+	// outbox is set when a relay publishes the events written with a
+	// change, see NewOutboxDBService. Without one no events are written.
+	outbox bool
+	// outboxWritten is signalled after a transaction added to the outbox
+	outboxWritten chan struct{}
+	teams         Teams
+}
+
+// Option configures the service created by NewDBService
+type Option func(*dbSvc)
+
+// WithTeams lets todos be owned by teams. Without it todos can't be added
+// to a team and only the todos of the owner are listed.
+func WithTeams(t Teams) Option {
+	return func(s *dbSvc) {
+		s.teams = t
+	}
 }
 
 // NewService create a new service based on an sqlite database with a persistent file
-func NewDBService(dbconnection gorm.Dialector) (Service, error) {
+func NewDBService(dbconnection gorm.Dialector, opts ...Option) (Service, error) {
+	return newDBService(dbconnection, opts...)
+}
+
+func newDBService(dbconnection gorm.Dialector, opts ...Option) (*dbSvc, error) {
 	db, err := gorm.Open(dbconnection, &gorm.Config{})
-	db.AutoMigrate(&Todo{})
+	if err != nil {
+		return &dbSvc{}, err
+	}
+	err = db.AutoMigrate(&Todo{}, &outboxMessage{})
 	if err != nil {
 		return &dbSvc{}, err
 	}
 
-	return &dbSvc{
-		db: db,
-	}, nil
+	s := &dbSvc{
+		db:            db,
+		outboxWritten: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
-func NewSqliteDBService(target string) (Service, error) {
-	return NewDBService(sqlite.Open(target))
+func NewSqliteDBService(target string, opts ...Option) (Service, error) {
+	return NewDBService(sqlite.Open(target), opts...)
 }
 
-func NewInMemService() (Service, error) {
-	return NewSqliteDBService(":memory:")
+func NewInMemService(opts ...Option) (Service, error) {
+	return NewSqliteDBService(":memory:", opts...)
 }
 
 func (s *dbSvc) AddTodo(ctx context.Context, t Todo) (Todo, error) {
 	if t.OwnerID == uuid.Nil {
 		return Todo{}, ErrOwnerMissing
 	}
+	if t.TeamID != uuid.Nil {
+		if err := s.canWriteTeam(ctx, t.OwnerID, t.TeamID); err != nil {
+			return Todo{}, err
+		}
+	}
 
-	result := s.db.Create(&t)
-
-	if result.Error != nil {
-		return Todo{}, result.Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+		return s.writeOutbox(tx, EventTodoCreated, t)
+	})
+	if err != nil {
+		return Todo{}, err
 	}
 
+	s.notifyOutbox()
 	return t, nil
 }
 
@@ -56,12 +95,17 @@ func (s *dbSvc) GetTodo(ctx context.Context, id uuid.UUID) (Todo, error) {
 	result := s.db.First(&t, "id = ?", id.String())
 
 	switch result.Error {
+	case nil:
 	case gorm.ErrRecordNotFound:
 		return Todo{}, ErrNotFound
 	default:
-		return t, result.Error
+		return Todo{}, result.Error
 	}
 
+	if err := s.access(ctx, t, false); err != nil {
+		return Todo{}, err
+	}
+	return t, nil
 }
 
 func (s *dbSvc) UpdateTodo(ctx context.Context, id uuid.UUID, t Todo) (Todo, error) {
@@ -77,30 +121,92 @@ func (s *dbSvc) UpdateTodo(ctx context.Context, id uuid.UUID, t Todo) (Todo, err
 	if err != nil {
 		return Todo{}, err
 	}
+	if err := s.access(ctx, current, true); err != nil {
+		return Todo{}, err
+	}
 
 	if t.OwnerID != uuid.Nil && current.OwnerID != t.OwnerID {
 		return Todo{}, ErrOwnerChanged
 	}
 
+	if t.TeamID != uuid.Nil && current.TeamID != t.TeamID {
+		return Todo{}, ErrTeamChanged
+	}
+
 	current.Title = t.Title
 	current.Description = t.Description
-
-	result := s.db.Model(&current).Updates(current)
-
-	if result.Error != nil {
-		return Todo{}, nil
+	current.Done = t.Done
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// select the fields so zero values, like reopening a todo, are written
+		if err := tx.Model(&current).Select("Title", "Description", "Done").Updates(current).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&t, "id = ?", id.String()).Error; err != nil {
+			return err
+		}
+		return s.writeOutbox(tx, EventTodoUpdated, t)
+	})
+	if err != nil {
+		return Todo{}, err
 	}
 
-	s.db.First(&t, "id = ?", id.String())
+	s.notifyOutbox()
 	return t, nil
 }
 
 func (s *dbSvc) DeleteTodo(ctx context.Context, id uuid.UUID) error {
 
-	t := Todo{}
-	result := s.db.Delete(&t, "id = ?", id.String())
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var t Todo
+		result := tx.Limit(1).Find(&t, "id = ?", id.String())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := s.access(ctx, t, true); err != nil {
+			return err
+		}
+		if err := tx.Delete(&t).Error; err != nil {
+			return err
+		}
+		// the event carries the whole todo, consumers filter on its owner
+		return s.writeOutbox(tx, EventTodoDeleted, t)
+	})
+	if err != nil {
+		return err
+	}
 
-	return result.Error
+	s.notifyOutbox()
+	return nil
+}
+
+// RestoreTodo brings back a deleted todo from the trash
+func (s *dbSvc) RestoreTodo(ctx context.Context, id uuid.UUID) (Todo, error) {
+	var t Todo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&t, "id = ?", id.String())
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := s.access(ctx, t, true); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&t).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		t.DeletedAt = gorm.DeletedAt{}
+		return s.writeOutbox(tx, EventTodoRestored, t)
+	})
+	switch err {
+	case nil:
+	case gorm.ErrRecordNotFound:
+		return Todo{}, ErrNotFound
+	default:
+		return Todo{}, err
+	}
+
+	s.notifyOutbox()
+	return t, nil
 }
 
 func (s *dbSvc) GetTodos(ctx context.Context) ([]Todo, error) {
@@ -115,10 +221,27 @@ func (s *dbSvc) GetTodos(ctx context.Context) ([]Todo, error) {
 	return todos, nil
 }
 
+// GetTodosOwned lists the todos of the user and of the teams the user is a
+// member of. Teams are looked up on every call so removed members lose
+// access at once.
 func (s *dbSvc) GetTodosOwned(ctx context.Context, user authorization.User) ([]Todo, error) {
+	query := s.db.Where(&Todo{OwnerID: user.ID})
+	if s.teams != nil {
+		teams, err := s.teams.GetTeams(ctx, user.ID)
+		if err != nil {
+			return []Todo{}, err
+		}
+		if len(teams) > 0 {
+			ids := make([]uuid.UUID, len(teams))
+			for i := range teams {
+				ids[i] = teams[i].ID
+			}
+			query = query.Or("team_id IN ?", ids)
+		}
+	}
 
 	var todos []Todo
-	result := s.db.Where(&Todo{OwnerID: user.ID}).Find(&todos)
+	result := query.Find(&todos)
 
 	if result.Error != nil {
 		return []Todo{}, nil
@@ -127,6 +250,70 @@ func (s *dbSvc) GetTodosOwned(ctx context.Context, user authorization.User) ([]T
 	return todos, nil
 }
 
+// access checks the caller in ctx may read t or, with write, change it, see
+// CanAccess. Without a caller, like for calls within the service, all todos
+// are accessible.
+func (s *dbSvc) access(ctx context.Context, t Todo, write bool) error {
+	caller, ok := authorization.Caller(ctx)
+	if !ok {
+		return nil
+	}
+	return CanAccess(ctx, s.teams, caller, t, write)
+}
+
+// CanAccess checks caller may read t or, with write, change it. Owners may
+// do both, members of the team of t may read it and change it when their
+// team role can write. Callers managing users may do both as well. teams
+// may be nil when todos have no teams.
+func CanAccess(ctx context.Context, teams Teams, caller authorization.User, t Todo, write bool) error {
+	if caller.ID == t.OwnerID {
+		return nil
+	}
+	manage := authorization.PermReadUsers
+	if write {
+		manage = authorization.PermWriteUsers
+	}
+	if caller.Role.Can(manage) {
+		return nil
+	}
+	if t.TeamID == uuid.Nil || teams == nil {
+		return authorization.ErrForbidden
+	}
+	member, err := teams.GetTeams(ctx, caller.ID)
+	if err != nil {
+		return err
+	}
+	for _, team := range member {
+		if team.ID != t.TeamID {
+			continue
+		}
+		if r, ok := team.Role(caller.ID); ok && (!write || r.CanWrite()) {
+			return nil
+		}
+	}
+	return authorization.ErrForbidden
+}
+
+// canWriteTeam checks the owner may add todos to the team
+func (s *dbSvc) canWriteTeam(ctx context.Context, owner, teamID uuid.UUID) error {
+	if s.teams == nil {
+		return ErrNotTeamMember
+	}
+	teams, err := s.teams.GetTeams(ctx, owner)
+	if err != nil {
+		return err
+	}
+	for _, t := range teams {
+		if t.ID != teamID {
+			continue
+		}
+		if r, ok := t.Role(owner); ok && r.CanWrite() {
+			return nil
+		}
+	}
+	return ErrNotTeamMember
+}
+
 func (s *dbSvc) ServiceStatus(ctx context.Context) (int, error) {
 	db, err := s.db.DB()
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/demeesterdev/todo-service/internal/argon2id"
 	"github.com/demeesterdev/todo-service/pkg/authorization"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -232,3 +233,125 @@ func TestGetTodosOwned(t *testing.T) {
 		})
 	}
 }
+
+func TestTeamTodos(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	owner, _ := users.AddUser(ctx, authorization.User{Username: "owner", Password: "correct horse battery staple"})
+	member, _ := users.AddUser(ctx, authorization.User{Username: "member", Password: "correct horse battery staple"})
+	guest, _ := users.AddUser(ctx, authorization.User{Username: "guest", Password: "correct horse battery staple"})
+	team, _ := users.CreateTeam(ctx, authorization.Team{Name: "kitchen"}, owner.ID)
+	for u, role := range map[uuid.UUID]authorization.TeamRole{member.ID: authorization.TeamRoleMember, guest.ID: authorization.TeamRoleGuest} {
+		token, _ := users.InviteMember(ctx, team.ID, role)
+		_, err := users.AcceptInvitation(ctx, token, u)
+		assert.NoError(t, err)
+	}
+
+	s, _ := NewInMemService(WithTeams(users))
+
+	shared, err := s.AddTodo(ctx, Todo{OwnerID: member.ID, TeamID: team.ID, Title: "dishes"})
+	assert.NoError(t, err)
+	_, err = s.AddTodo(ctx, Todo{OwnerID: guest.ID, TeamID: team.ID, Title: "groceries"})
+	assert.Equal(t, ErrNotTeamMember, err)
+	_, err = s.AddTodo(ctx, Todo{OwnerID: member.ID, TeamID: uuid.New(), Title: "groceries"})
+	assert.Equal(t, ErrNotTeamMember, err)
+	_, err = s.AddTodo(ctx, Todo{OwnerID: guest.ID, Title: "own"})
+	assert.NoError(t, err)
+
+	todos, err := s.GetTodosOwned(ctx, guest)
+	assert.NoError(t, err)
+	assert.Len(t, todos, 2)
+	todos, err = s.GetTodosOwned(ctx, owner)
+	assert.NoError(t, err)
+	assert.Len(t, todos, 1)
+	assert.Equal(t, shared.ID, todos[0].ID)
+
+	_, err = s.UpdateTodo(ctx, shared.ID, Todo{TeamID: uuid.New(), Title: "dishes"})
+	assert.Equal(t, ErrTeamChanged, err)
+
+	// members read the todos of their team, guests can't change them
+	asOwner := authorization.WithCaller(ctx, owner)
+	asMember := authorization.WithCaller(ctx, member)
+	asGuest := authorization.WithCaller(ctx, guest)
+	chores, err := s.AddTodo(asOwner, Todo{OwnerID: owner.ID, TeamID: team.ID, Title: "chores"})
+	assert.NoError(t, err)
+	_, err = s.GetTodo(asGuest, chores.ID)
+	assert.NoError(t, err)
+	_, err = s.UpdateTodo(asGuest, chores.ID, Todo{Title: "chores", Done: true})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, s.DeleteTodo(asGuest, chores.ID))
+	_, err = s.UpdateTodo(asMember, chores.ID, Todo{Title: "chores", Done: true})
+	assert.NoError(t, err)
+
+	// others can't reach them at all
+	outsider, _ := users.AddUser(ctx, authorization.User{Username: "outsider", Password: "correct horse battery staple"})
+	asOutsider := authorization.WithCaller(ctx, outsider)
+	_, err = s.GetTodo(asOutsider, chores.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	own, err := s.AddTodo(asOutsider, Todo{OwnerID: outsider.ID, Title: "own"})
+	assert.NoError(t, err)
+	_, err = s.GetTodo(asMember, own.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	// removed members lose access at once
+	assert.NoError(t, users.RemoveMember(ctx, team.ID, guest.ID))
+	todos, err = s.GetTodosOwned(ctx, guest)
+	assert.NoError(t, err)
+	assert.Len(t, todos, 1)
+	assert.Equal(t, "own", todos[0].Title)
+	_, err = s.GetTodo(asGuest, chores.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	assert.NoError(t, users.RemoveMember(ctx, team.ID, member.ID))
+	_, err = s.GetTodo(asMember, chores.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = s.UpdateTodo(asMember, chores.ID, Todo{Title: "chores", Done: false})
+	assert.Equal(t, authorization.ErrForbidden, err)
+	assert.Equal(t, authorization.ErrForbidden, s.DeleteTodo(asMember, chores.ID))
+	assert.NoError(t, s.DeleteTodo(asOwner, chores.ID))
+	_, err = s.RestoreTodo(asMember, chores.ID)
+	assert.Equal(t, authorization.ErrForbidden, err)
+	_, err = s.RestoreTodo(asOwner, chores.ID)
+	assert.NoError(t, err)
+
+	// teams that can't be looked up fail the listing
+	denied, _ := NewInMemService(WithTeams(teamsFunc(func(ctx context.Context, userID uuid.UUID) ([]authorization.Team, error) {
+		return nil, authorization.ErrForbidden
+	})))
+	_, err = denied.GetTodosOwned(ctx, guest)
+	assert.Equal(t, authorization.ErrForbidden, err)
+
+	// without teams only the own todos are listed
+	plain, _ := NewInMemService()
+	_, err = plain.AddTodo(ctx, Todo{OwnerID: member.ID, TeamID: team.ID, Title: "dishes"})
+	assert.Equal(t, ErrNotTeamMember, err)
+}
+
+type teamsFunc func(ctx context.Context, userID uuid.UUID) ([]authorization.Team, error)
+
+func (f teamsFunc) GetTeams(ctx context.Context, userID uuid.UUID) ([]authorization.Team, error) {
+	return f(ctx, userID)
+}
+
+func TestRestoreTodo(t *testing.T) {
+	ctx := context.Background()
+	s, _ := NewInMemService()
+	added, _ := s.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "New Item", Done: true})
+
+	_, err := s.RestoreTodo(ctx, added.ID)
+	assert.Equal(t, ErrNotFound, err, "only deleted todos can be restored")
+
+	assert.NoError(t, s.DeleteTodo(ctx, added.ID))
+	_, err = s.GetTodo(ctx, added.ID)
+	assert.Equal(t, ErrNotFound, err)
+
+	restored, err := s.RestoreTodo(ctx, added.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, added.ID, restored.ID)
+	assert.True(t, restored.Done)
+
+	reopened, err := s.UpdateTodo(ctx, added.ID, Todo{Title: "New Item", Done: false})
+	assert.NoError(t, err)
+	assert.False(t, reopened.Done)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/demeesterdev/todo-service/pkg/authorization"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
 )
 
 type Endpoints struct {
@@ -17,6 +18,7 @@ type Endpoints struct {
 	GetTodoEndpoint       endpoint.Endpoint
 	UpdateTodoEndpoint    endpoint.Endpoint
 	DeleteTodoEndpoint    endpoint.Endpoint
+	RestoreTodoEndpoint   endpoint.Endpoint
 	GetTodosEndpoint      endpoint.Endpoint
 	GetTodosOwnedEndpoint endpoint.Endpoint
 	ServiceStatusEndpoint endpoint.Endpoint
@@ -24,15 +26,22 @@ type Endpoints struct {
 
 // MakeServerEndpoints returns an Endpoints struct where each endpoint invokes
 // the corresponding method on the provided service. Useful in a todo svc
-// server.
-func MakeServerEndpoints(s Service) Endpoints {
+// server. The middlewares wrap every endpoint, the first one outermost.
+func MakeServerEndpoints(s Service, mws ...endpoint.Middleware) Endpoints {
+	mw := func(e endpoint.Endpoint) endpoint.Endpoint {
+		for i := len(mws) - 1; i >= 0; i-- {
+			e = mws[i](e)
+		}
+		return e
+	}
 	return Endpoints{
-		AddTodoEndpoint:       makeAddTodoEndpoint(s),
-		GetTodoEndpoint:       makeGetTodoEndpoint(s),
-		UpdateTodoEndpoint:    makeUpdateTodoEndpoint(s),
-		DeleteTodoEndpoint:    makeDeleteTodoEndpoint(s),
-		GetTodosEndpoint:      makeGetTodosEndpoint(s),
-		ServiceStatusEndpoint: makeServiceStatusEndpoint(s),
+		AddTodoEndpoint:       mw(makeAddTodoEndpoint(s)),
+		GetTodoEndpoint:       mw(makeGetTodoEndpoint(s)),
+		UpdateTodoEndpoint:    mw(makeUpdateTodoEndpoint(s)),
+		DeleteTodoEndpoint:    mw(makeDeleteTodoEndpoint(s)),
+		RestoreTodoEndpoint:   mw(makeRestoreTodoEndpoint(s)),
+		GetTodosEndpoint:      mw(makeGetTodosEndpoint(s)),
+		ServiceStatusEndpoint: mw(makeServiceStatusEndpoint(s)),
 	}
 }
 
@@ -49,7 +58,10 @@ func MakeClientEndpoints(instance string) (Endpoints, error) {
 	}
 	tgt.Path = ""
 
-	options := []httptransport.ClientOption{}
+	// the session token of the caller is forwarded to the authorization service
+	options := []httptransport.ClientOption{
+		httptransport.ClientBefore(authorizationTrsp.SessionTokenToHeader),
+	}
 
 	// Note that the request encoders need to modify the request URL, changing
 	// the path. That's fine: we simply need to provide specific encoders for
@@ -60,6 +72,7 @@ func MakeClientEndpoints(instance string) (Endpoints, error) {
 		GetTodoEndpoint:       httptransport.NewClient("GET", tgt, encodeHTTPGetTodoRequest, decodeHTTPGetTodoResponse, options...).Endpoint(),
 		UpdateTodoEndpoint:    httptransport.NewClient("PUT", tgt, encodeHTTPUpdateTodoRequest, decodeHTTPUpdateTodoResponse, options...).Endpoint(),
 		DeleteTodoEndpoint:    httptransport.NewClient("DELETE", tgt, encodeHTTPDeleteTodoRequest, decodeHTTPDeleteTodoResponse, options...).Endpoint(),
+		RestoreTodoEndpoint:   httptransport.NewClient("POST", tgt, encodeHTTPRestoreTodoRequest, decodeHTTPRestoreTodoResponse, options...).Endpoint(),
 		GetTodosEndpoint:      httptransport.NewClient("GET", tgt, encodeHTTPGetTodosRequest, decodeHTTPGetTodosResponse, options...).Endpoint(),
 		ServiceStatusEndpoint: httptransport.NewClient("GET", tgt, encodeHTTPServiceStatusRequest, decodeHTTPServiceStatusResponse, options...).Endpoint(),
 	}, nil
@@ -81,7 +94,7 @@ func (e Endpoints) GetTodo(ctx context.Context, id uuid.UUID) (Todo, error) {
 	request := getTodoRequest{ID: id}
 	response, err := e.GetTodoEndpoint(ctx, request)
 	if err != nil {
-		return Todo{}, nil
+		return Todo{}, err
 	}
 	resp := response.(getTodoResponse)
 	return resp.Todo, resp.Err
@@ -105,14 +118,25 @@ func (e Endpoints) DeleteTodo(ctx context.Context, id uuid.UUID) error {
 	if err != nil {
 		return err
 	}
-	resp := response.(updateTodoResponse)
+	resp := response.(deleteTodoResponse)
 	return resp.Err
 }
 
+// RestoreTodo implements Service interface. Primarily useful in a client.
+func (e Endpoints) RestoreTodo(ctx context.Context, id uuid.UUID) (Todo, error) {
+	request := restoreTodoRequest{ID: id}
+	response, err := e.RestoreTodoEndpoint(ctx, request)
+	if err != nil {
+		return Todo{}, err
+	}
+	resp := response.(restoreTodoResponse)
+	return resp.Todo, resp.Err
+}
+
 // GetTodos implements Service interface. Primarily useful in a client.
 func (e Endpoints) GetTodos(ctx context.Context) ([]Todo, error) {
 	request := getTodosRequest{}
-	response, err := e.UpdateTodoEndpoint(ctx, request)
+	response, err := e.GetTodosEndpoint(ctx, request)
 	if err != nil {
 		return []Todo{}, err
 	}
@@ -123,7 +147,7 @@ func (e Endpoints) GetTodos(ctx context.Context) ([]Todo, error) {
 // GetTodosOwned implements Service interface. Primarily useful in a client.
 func (e Endpoints) GetTodosOwned(ctx context.Context, user authorization.User) ([]Todo, error) {
 	request := getTodosRequest{OwnerID: user.ID}
-	response, err := e.UpdateTodoEndpoint(ctx, request)
+	response, err := e.GetTodosEndpoint(ctx, request)
 	if err != nil {
 		return []Todo{}, err
 	}
@@ -182,6 +206,16 @@ func makeDeleteTodoEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+// makeRestoreTodoEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeRestoreTodoEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(restoreTodoRequest)
+		t, e := s.RestoreTodo(ctx, req.ID)
+		return restoreTodoResponse{Todo: t, Err: e}, nil
+	}
+}
+
 // makeGetTodosEndpoint returns an enpoint via the passed service.
 // Primarily useful in a server
 func makeGetTodosEndpoint(s Service) endpoint.Endpoint {
@@ -0,0 +1,526 @@
+package todo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
+	"github.com/demeesterdev/todo-service/pkg/todo/pb"
+)
+
+const grpcServiceName = "todo.Todos"
+
+type grpcServer struct {
+	pb.UnimplementedTodosServer
+
+	addTodo       grpctransport.Handler
+	getTodo       grpctransport.Handler
+	updateTodo    grpctransport.Handler
+	deleteTodo    grpctransport.Handler
+	restoreTodo   grpctransport.Handler
+	getTodos      grpctransport.Handler
+	getTodosOwned grpctransport.Handler
+	serviceStatus grpctransport.Handler
+}
+
+// MakeGRPCServer makes the set of endpoints available as a gRPC TodosServer.
+func MakeGRPCServer(ep Endpoints, logger log.Logger) pb.TodosServer {
+	options := []grpctransport.ServerOption{
+		grpctransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		grpctransport.ServerBefore(authorizationTrsp.SessionTokenFromMD),
+	}
+
+	return &grpcServer{
+		addTodo: grpctransport.NewServer(
+			ep.AddTodoEndpoint,
+			decodeGRPCAddTodoRequest,
+			encodeGRPCAddTodoResponse,
+			options...,
+		),
+		getTodo: grpctransport.NewServer(
+			ep.GetTodoEndpoint,
+			decodeGRPCGetTodoRequest,
+			encodeGRPCGetTodoResponse,
+			options...,
+		),
+		updateTodo: grpctransport.NewServer(
+			ep.UpdateTodoEndpoint,
+			decodeGRPCUpdateTodoRequest,
+			encodeGRPCUpdateTodoResponse,
+			options...,
+		),
+		deleteTodo: grpctransport.NewServer(
+			ep.DeleteTodoEndpoint,
+			decodeGRPCDeleteTodoRequest,
+			encodeGRPCDeleteTodoResponse,
+			options...,
+		),
+		restoreTodo: grpctransport.NewServer(
+			ep.RestoreTodoEndpoint,
+			decodeGRPCRestoreTodoRequest,
+			encodeGRPCRestoreTodoResponse,
+			options...,
+		),
+		getTodos: grpctransport.NewServer(
+			ep.GetTodosEndpoint,
+			decodeGRPCGetTodosRequest,
+			encodeGRPCGetTodosResponse,
+			options...,
+		),
+		getTodosOwned: grpctransport.NewServer(
+			ep.GetTodosEndpoint,
+			decodeGRPCGetTodosOwnedRequest,
+			encodeGRPCGetTodosResponse,
+			options...,
+		),
+		serviceStatus: grpctransport.NewServer(
+			ep.ServiceStatusEndpoint,
+			decodeGRPCServiceStatusRequest,
+			encodeGRPCServiceStatusResponse,
+			options...,
+		),
+	}
+}
+
+func (s *grpcServer) AddTodo(ctx context.Context, req *pb.AddTodoRequest) (*pb.AddTodoReply, error) {
+	_, rep, err := s.addTodo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.AddTodoReply), nil
+}
+
+func (s *grpcServer) GetTodo(ctx context.Context, req *pb.GetTodoRequest) (*pb.GetTodoReply, error) {
+	_, rep, err := s.getTodo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetTodoReply), nil
+}
+
+func (s *grpcServer) UpdateTodo(ctx context.Context, req *pb.UpdateTodoRequest) (*pb.UpdateTodoReply, error) {
+	_, rep, err := s.updateTodo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.UpdateTodoReply), nil
+}
+
+func (s *grpcServer) DeleteTodo(ctx context.Context, req *pb.DeleteTodoRequest) (*pb.DeleteTodoReply, error) {
+	_, rep, err := s.deleteTodo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.DeleteTodoReply), nil
+}
+
+func (s *grpcServer) RestoreTodo(ctx context.Context, req *pb.RestoreTodoRequest) (*pb.RestoreTodoReply, error) {
+	_, rep, err := s.restoreTodo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.RestoreTodoReply), nil
+}
+
+func (s *grpcServer) GetTodos(ctx context.Context, req *pb.GetTodosRequest) (*pb.GetTodosReply, error) {
+	_, rep, err := s.getTodos.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetTodosReply), nil
+}
+
+func (s *grpcServer) GetTodosOwned(ctx context.Context, req *pb.GetTodosOwnedRequest) (*pb.GetTodosReply, error) {
+	_, rep, err := s.getTodosOwned.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.GetTodosReply), nil
+}
+
+func (s *grpcServer) ServiceStatus(ctx context.Context, req *pb.ServiceStatusRequest) (*pb.ServiceStatusReply, error) {
+	_, rep, err := s.serviceStatus.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return rep.(*pb.ServiceStatusReply), nil
+}
+
+// MakeGRPCClientEndpoints returns an Endpoints struct where each endpoint invokes
+// the corresponding method on the remote instance, via a transport/grpc.Client.
+func MakeGRPCClientEndpoints(conn *grpc.ClientConn) Endpoints {
+	options := []grpctransport.ClientOption{
+		grpctransport.ClientBefore(authorizationTrsp.SessionTokenToMD),
+	}
+	var getTodos = grpctransport.NewClient(conn, grpcServiceName, "GetTodos", encodeGRPCGetTodosRequest, decodeGRPCGetTodosResponse, pb.GetTodosReply{}, options...).Endpoint()
+	var getTodosOwned = grpctransport.NewClient(conn, grpcServiceName, "GetTodosOwned", encodeGRPCGetTodosOwnedRequest, decodeGRPCGetTodosResponse, pb.GetTodosReply{}, options...).Endpoint()
+
+	return Endpoints{
+		AddTodoEndpoint:     grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "AddTodo", encodeGRPCAddTodoRequest, decodeGRPCAddTodoResponse, pb.AddTodoReply{}, options...).Endpoint()),
+		GetTodoEndpoint:     grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "GetTodo", encodeGRPCGetTodoRequest, decodeGRPCGetTodoResponse, pb.GetTodoReply{}, options...).Endpoint()),
+		UpdateTodoEndpoint:  grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "UpdateTodo", encodeGRPCUpdateTodoRequest, decodeGRPCUpdateTodoResponse, pb.UpdateTodoReply{}, options...).Endpoint()),
+		DeleteTodoEndpoint:  grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "DeleteTodo", encodeGRPCDeleteTodoRequest, decodeGRPCDeleteTodoResponse, pb.DeleteTodoReply{}, options...).Endpoint()),
+		RestoreTodoEndpoint: grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "RestoreTodo", encodeGRPCRestoreTodoRequest, decodeGRPCRestoreTodoResponse, pb.RestoreTodoReply{}, options...).Endpoint()),
+		// GetTodosEndpoint serves both rpcs, just like it does for http
+		GetTodosEndpoint: grpcClientErrors(func(ctx context.Context, request interface{}) (interface{}, error) {
+			if request.(getTodosRequest).OwnerID == uuid.Nil {
+				return getTodos(ctx, request)
+			}
+			return getTodosOwned(ctx, request)
+		}),
+		ServiceStatusEndpoint: grpcClientErrors(grpctransport.NewClient(conn, grpcServiceName, "ServiceStatus", encodeGRPCServiceStatusRequest, decodeGRPCServiceStatusResponse, pb.ServiceStatusReply{}, options...).Endpoint()),
+	}
+}
+
+// NewGRPCClient returns a Service backed by a remote todo service over gRPC.
+func NewGRPCClient(conn *grpc.ClientConn) Service {
+	return MakeGRPCClientEndpoints(conn)
+}
+
+// Server functions
+// decode requests from client
+
+func decodeGRPCAddTodoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AddTodoRequest)
+	t, err := todoFromPB(req.Todo)
+	if err != nil {
+		return nil, err
+	}
+	return addTodoRequest{Todo: t}, nil
+}
+
+func decodeGRPCGetTodoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetTodoRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return getTodoRequest{ID: id}, nil
+}
+
+func decodeGRPCUpdateTodoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UpdateTodoRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	t, err := todoFromPB(req.Todo)
+	if err != nil {
+		return nil, err
+	}
+	return updateTodoRequest{ID: id, Todo: t}, nil
+}
+
+func decodeGRPCDeleteTodoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DeleteTodoRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return deleteTodoRequest{ID: id}, nil
+}
+
+func decodeGRPCRestoreTodoRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.RestoreTodoRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return restoreTodoRequest{ID: id}, nil
+}
+
+func decodeGRPCGetTodosRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.GetTodosRequest)
+	return getTodosRequest{}, nil
+}
+
+func decodeGRPCGetTodosOwnedRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetTodosOwnedRequest)
+	id, err := uuid.Parse(req.OwnerId)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return getTodosRequest{OwnerID: id}, nil
+}
+
+func decodeGRPCServiceStatusRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	_ = grpcReq.(*pb.ServiceStatusRequest)
+	return serviceStatusRequest{}, nil
+}
+
+// Server functions
+// encode responses for client, errors in the response are returned as error
+// so they can be turned into a grpc status
+
+func encodeGRPCAddTodoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(addTodoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.AddTodoReply{Todo: todoToPB(resp.Todo)}, nil
+}
+
+func encodeGRPCGetTodoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(getTodoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.GetTodoReply{Todo: todoToPB(resp.Todo)}, nil
+}
+
+func encodeGRPCUpdateTodoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(updateTodoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.UpdateTodoReply{Todo: todoToPB(resp.Todo)}, nil
+}
+
+func encodeGRPCDeleteTodoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(deleteTodoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.DeleteTodoReply{}, nil
+}
+
+func encodeGRPCRestoreTodoResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(restoreTodoResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.RestoreTodoReply{Todo: todoToPB(resp.Todo)}, nil
+}
+
+func encodeGRPCGetTodosResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(getTodosResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	todos := make([]*pb.Todo, len(resp.Todos))
+	for i := range resp.Todos {
+		todos[i] = todoToPB(resp.Todos[i])
+	}
+	return &pb.GetTodosReply{Todos: todos}, nil
+}
+
+func encodeGRPCServiceStatusResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(serviceStatusResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &pb.ServiceStatusReply{Code: int32(resp.Code)}, nil
+}
+
+// client functions
+// encode request for server
+
+func encodeGRPCAddTodoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(addTodoRequest)
+	return &pb.AddTodoRequest{Todo: todoToPB(req.Todo)}, nil
+}
+
+func encodeGRPCGetTodoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(getTodoRequest)
+	return &pb.GetTodoRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCUpdateTodoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(updateTodoRequest)
+	return &pb.UpdateTodoRequest{Id: req.ID.String(), Todo: todoToPB(req.Todo)}, nil
+}
+
+func encodeGRPCDeleteTodoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(deleteTodoRequest)
+	return &pb.DeleteTodoRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCRestoreTodoRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(restoreTodoRequest)
+	return &pb.RestoreTodoRequest{Id: req.ID.String()}, nil
+}
+
+func encodeGRPCGetTodosRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(getTodosRequest)
+	return &pb.GetTodosRequest{}, nil
+}
+
+func encodeGRPCGetTodosOwnedRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(getTodosRequest)
+	return &pb.GetTodosOwnedRequest{OwnerId: req.OwnerID.String()}, nil
+}
+
+func encodeGRPCServiceStatusRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_ = request.(serviceStatusRequest)
+	return &pb.ServiceStatusRequest{}, nil
+}
+
+// client functions
+// decode response from server
+
+func decodeGRPCAddTodoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.AddTodoReply)
+	t, err := todoFromPB(reply.Todo)
+	return addTodoResponse{Todo: t}, err
+}
+
+func decodeGRPCGetTodoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetTodoReply)
+	t, err := todoFromPB(reply.Todo)
+	return getTodoResponse{Todo: t}, err
+}
+
+func decodeGRPCUpdateTodoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.UpdateTodoReply)
+	t, err := todoFromPB(reply.Todo)
+	return updateTodoResponse{Todo: t}, err
+}
+
+func decodeGRPCDeleteTodoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	_ = grpcReply.(*pb.DeleteTodoReply)
+	return deleteTodoResponse{}, nil
+}
+
+func decodeGRPCRestoreTodoResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.RestoreTodoReply)
+	t, err := todoFromPB(reply.Todo)
+	return restoreTodoResponse{Todo: t}, err
+}
+
+func decodeGRPCGetTodosResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.GetTodosReply)
+	todos := make([]Todo, len(reply.Todos))
+	for i := range reply.Todos {
+		t, err := todoFromPB(reply.Todos[i])
+		if err != nil {
+			return nil, err
+		}
+		todos[i] = t
+	}
+	return getTodosResponse{Todos: todos}, nil
+}
+
+func decodeGRPCServiceStatusResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ServiceStatusReply)
+	return serviceStatusResponse{Code: int(reply.Code)}, nil
+}
+
+func todoToPB(t Todo) *pb.Todo {
+	return &pb.Todo{
+		Id:          uuidToPB(t.ID),
+		Title:       t.Title,
+		Description: t.Description,
+		OwnerId:     uuidToPB(t.OwnerID),
+		Done:        t.Done,
+		TeamId:      uuidToPB(t.TeamID),
+	}
+}
+
+func todoFromPB(t *pb.Todo) (Todo, error) {
+	if t == nil {
+		return Todo{}, nil
+	}
+	id, err := uuidFromPB(t.Id)
+	if err != nil {
+		return Todo{}, err
+	}
+	ownerID, err := uuidFromPB(t.OwnerId)
+	if err != nil {
+		return Todo{}, err
+	}
+	teamID, err := uuidFromPB(t.TeamId)
+	if err != nil {
+		return Todo{}, err
+	}
+	return Todo{
+		ID:          id,
+		Title:       t.Title,
+		Description: t.Description,
+		OwnerID:     ownerID,
+		Done:        t.Done,
+		TeamID:      teamID,
+	}, nil
+}
+
+// uuidToPB leaves empty ids empty instead of sending the nil uuid
+func uuidToPB(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+func uuidFromPB(id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, ErrInvalidUUID
+	}
+	return parsed, nil
+}
+
+// grpcErrors maps the errors of this package to a grpc status code
+var grpcErrors = map[error]codes.Code{
+	ErrNotFound:        codes.NotFound,
+	ErrAlreadyExists:   codes.AlreadyExists,
+	ErrPopulatedID:     codes.InvalidArgument,
+	ErrOwnerMissing:    codes.InvalidArgument,
+	ErrInconsistentIDs: codes.InvalidArgument,
+	ErrInvalidUUID:     codes.InvalidArgument,
+	ErrOwnerChanged:    codes.FailedPrecondition,
+	ErrTeamChanged:     codes.FailedPrecondition,
+	ErrNotTeamMember:   codes.PermissionDenied,
+
+	authorization.ErrUnauthenticated: codes.Unauthenticated,
+	authorization.ErrForbidden:       codes.PermissionDenied,
+}
+
+func encodeGRPCError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var fields problem.FieldErrors
+	if errors.As(err, &fields) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	code, ok := grpcErrors[err]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcClientErrors turns a grpc status returned by the server back into
+// the error of this package it was created from.
+func grpcClientErrors(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := next(ctx, request)
+		if err == nil {
+			return response, nil
+		}
+
+		st, ok := status.FromError(err)
+		if !ok {
+			return nil, err
+		}
+		for known, code := range grpcErrors {
+			if st.Code() == code && st.Message() == known.Error() {
+				return nil, known
+			}
+		}
+		return nil, err
+	}
+}
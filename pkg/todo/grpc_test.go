@@ -0,0 +1,106 @@
+package todo
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/todo/pb"
+)
+
+func newGRPCTestClient(t *testing.T) Service {
+	s, _ := NewInMemService()
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterTodosServer(server, MakeGRPCServer(MakeServerEndpoints(s), log.NewNopLogger()))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return NewGRPCClient(conn)
+}
+
+func TestGRPCClient(t *testing.T) {
+	ctx := context.Background()
+	c := newGRPCTestClient(t)
+	owner := authorization.User{ID: uuid.New()}
+
+	added, err := c.AddTodo(ctx, Todo{OwnerID: owner.ID, Title: "New Item"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, added.ID)
+
+	updated, err := c.UpdateTodo(ctx, added.ID, Todo{Title: "new Title"})
+	assert.NoError(t, err)
+	assert.Equal(t, "new Title", updated.Title)
+
+	owned, err := c.GetTodosOwned(ctx, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(owned))
+
+	assert.NoError(t, c.DeleteTodo(ctx, added.ID))
+
+	all, err := c.GetTodos(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	code, err := c.ServiceStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+}
+
+func TestGRPCErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		call func(ctx context.Context, c Service) error
+		err  error
+	}{
+		{
+			name: "should map not found",
+			call: func(ctx context.Context, c Service) error {
+				_, err := c.GetTodo(ctx, uuid.New())
+				return err
+			},
+			err: ErrNotFound,
+		},
+		{
+			name: "should map missing owner",
+			call: func(ctx context.Context, c Service) error {
+				_, err := c.AddTodo(ctx, Todo{Title: "no owner"})
+				return err
+			},
+			err: ErrOwnerMissing,
+		},
+		{
+			name: "should map inconsistent ids",
+			call: func(ctx context.Context, c Service) error {
+				_, err := c.UpdateTodo(ctx, uuid.New(), Todo{ID: uuid.New()})
+				return err
+			},
+			err: ErrInconsistentIDs,
+		},
+	}
+
+	c := newGRPCTestClient(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call(context.Background(), c)
+
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package todo
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// OpenAPI returns the OpenAPI document of the routes served by MakeHTTPHandler
+func OpenAPI() openapi.Document {
+	doc := openapi.New("todo", "1.0.0", "CRUD service for todos. Requests made with an API key or OAuth2 access token as bearer token need its todos:read or todos:write scope.")
+
+	todo := doc.Component("Todo", openapi.SchemaOf(Todo{}))
+	todoResponse := doc.Component("TodoResponse", openapi.SchemaOf(addTodoResponse{}))
+
+	id := openapi.PathParam("id", "id of the todo", openapi.UUID)
+	body := &openapi.RequestBody{Required: true, Content: openapi.JSON(todo)}
+	ok := func(description string, s *openapi.Schema) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(s)}
+	}
+
+	doc.Add(http.MethodGet, "/status", openapi.Operation{
+		OperationID: "serviceStatus",
+		Summary:     "Status of the service",
+		Tags:        []string{"status"},
+		Responses: doc.Responses(problems,
+			ok("service is up", doc.Component("ServiceStatusResponse", openapi.SchemaOf(serviceStatusResponse{}))),
+			authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/", openapi.Operation{
+		OperationID: "getTodos",
+		Summary:     "List todos",
+		Tags:        []string{"todos"},
+		Parameters:  []openapi.Parameter{openapi.QueryParam("owner", "only list todos of this owner", openapi.UUID)},
+		Responses: doc.Responses(problems,
+			ok("the todos", doc.Component("GetTodosResponse", openapi.SchemaOf(getTodosResponse{}))),
+			ErrInvalidUUID, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/", openapi.Operation{
+		OperationID: "addTodo",
+		Summary:     "Create a todo",
+		Tags:        []string{"todos"},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the created todo", todoResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, ErrOwnerMissing, ErrNotTeamMember, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodGet, "/{id}", openapi.Operation{
+		OperationID: "getTodo",
+		Summary:     "Get a todo",
+		Tags:        []string{"todos"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the todo", todoResponse),
+			ErrInvalidUUID, ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPut, "/{id}", openapi.Operation{
+		OperationID: "updateTodo",
+		Summary:     "Update a todo",
+		Tags:        []string{"todos"},
+		Parameters:  []openapi.Parameter{id},
+		RequestBody: body,
+		Responses: doc.Responses(problems, ok("the updated todo", todoResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, problem.ErrInvalidFields, ErrInvalidUUID, ErrInconsistentIDs, ErrNotFound, ErrOwnerChanged, ErrTeamChanged, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodDelete, "/{id}", openapi.Operation{
+		OperationID: "deleteTodo",
+		Summary:     "Delete a todo",
+		Tags:        []string{"todos"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the todo is deleted", &openapi.Schema{Type: "object"}),
+			ErrInvalidUUID, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+	doc.Add(http.MethodPost, "/{id}/restore", openapi.Operation{
+		OperationID: "restoreTodo",
+		Summary:     "Restore a deleted todo from the trash",
+		Tags:        []string{"todos"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the restored todo", todoResponse),
+			ErrInvalidUUID, ErrNotFound, authorization.ErrUnauthenticated, authorization.ErrForbidden),
+	})
+
+	return doc
+}
@@ -0,0 +1,33 @@
+package todo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/openapi"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	s, _ := NewInMemService()
+	h := MakeHTTPHandler(MakeServerEndpoints(s), log.NewNopLogger())
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + openapi.SpecPath)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var doc openapi.Document
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+	undocumented, unrouted, err := openapi.Diff(doc, h.(chi.Routes))
+	assert.NoError(t, err)
+	assert.Empty(t, undocumented, "routes missing from the OpenAPI document")
+	assert.Empty(t, unrouted, "OpenAPI operations without a route")
+}
@@ -0,0 +1,167 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/pkg/events"
+)
+
+// event types published for changes on todos
+const (
+	EventTodoCreated  = "todo.created"
+	EventTodoUpdated  = "todo.updated"
+	EventTodoDeleted  = "todo.deleted"
+	EventTodoRestored = "todo.restored"
+)
+
+// outboxMessage presents a single event waiting to be published.
+// It is written in the same transaction as the change it describes.
+type outboxMessage struct {
+	CreatedAt  time.Time
+	ID         uuid.UUID `gorm:"type:uuid;primarykey"`
+	EventType  string
+	OccurredAt time.Time
+	Data       []byte
+	SentAt     *time.Time `gorm:"index"`
+	Attempts   int
+	LastError  string
+}
+
+// TableName overrides the table name used by outboxMessage to `outbox` (GORM specifics)
+func (outboxMessage) TableName() string {
+	return "outbox"
+}
+
+func (m outboxMessage) toEvent() events.Event {
+	return events.Event{
+		ID:         m.ID,
+		Type:       m.EventType,
+		OccurredAt: m.OccurredAt,
+		Data:       m.Data,
+	}
+}
+
+// EventPublisher receives the events relayed from the outbox
+type EventPublisher = events.Publisher
+
+// writeOutbox adds an event to the outbox within transaction tx, unless no
+// relay would ever publish it
+func (s *dbSvc) writeOutbox(tx *gorm.DB, eventType string, data interface{}) error {
+	if !s.outbox {
+		return nil
+	}
+	e, err := events.New(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&outboxMessage{
+		ID:         e.ID,
+		EventType:  e.Type,
+		OccurredAt: e.OccurredAt,
+		Data:       e.Data,
+	}).Error
+}
+
+// notifyOutbox wakes up the relay without waiting for the next poll
+func (s *dbSvc) notifyOutbox() {
+	select {
+	case s.outboxWritten <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxRelay publishes the events in the outbox of a service
+// and marks them as sent. Events are delivered at least once, consumers
+// should use the event ID as idempotency key.
+type OutboxRelay struct {
+	db        *gorm.DB
+	written   <-chan struct{}
+	publisher EventPublisher
+	interval  time.Duration
+	logger    log.Logger
+}
+
+// NewOutboxDBService is NewDBService, it also returns the relay publishing
+// the events in the outbox of the service to p. The relay polls every
+// interval and runs right away after a change. Only services created here
+// write events, the others have no relay to drain them.
+func NewOutboxDBService(dbconnection gorm.Dialector, p EventPublisher, interval time.Duration, logger log.Logger, opts ...Option) (Service, *OutboxRelay, error) {
+	s, err := newDBService(dbconnection, opts...)
+	if err != nil {
+		return s, nil, err
+	}
+	s.outbox = true
+
+	// the relay runs concurrently with requests, a single connection keeps
+	// in memory databases shared and sqlite writes serialized
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return s, nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return s, &OutboxRelay{
+		db:        s.db,
+		written:   s.outboxWritten,
+		publisher: p,
+		interval:  interval,
+		logger:    logger,
+	}, nil
+}
+
+// Run relays events until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RelayPending(ctx); err != nil {
+			r.logger.Log("during", "relay", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.written:
+		}
+	}
+}
+
+// RelayPending publishes unsent events in the order they were written.
+// It stops at the first event that fails to publish so it is retried first
+// on the next run. It returns the number of events published.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	var pending []outboxMessage
+	result := r.db.Where("sent_at IS NULL").Order("created_at").Limit(100).Find(&pending)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	for i, m := range pending {
+		err := r.publisher.Publish(ctx, m.toEvent())
+		if err != nil {
+			r.db.Model(&m).Updates(map[string]interface{}{
+				"attempts":   m.Attempts + 1,
+				"last_error": err.Error(),
+			})
+			return i, err
+		}
+
+		// a crash before this update publishes the event again,
+		// which is why delivery is at least once
+		sentAt := time.Now().UTC()
+		result = r.db.Model(&m).Update("sent_at", &sentAt)
+		if result.Error != nil {
+			return i, result.Error
+		}
+	}
+
+	return len(pending), nil
+}
@@ -0,0 +1,91 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+
+	"github.com/demeesterdev/todo-service/pkg/events"
+)
+
+type recordingPublisher struct {
+	err    error
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e events.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	p := &recordingPublisher{}
+	s, relay, err := NewOutboxDBService(sqlite.Open(":memory:"), p, time.Second, log.NewNopLogger())
+	assert.NoError(t, err)
+
+	created, err := s.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "New Item"})
+	assert.NoError(t, err)
+	_, err = s.UpdateTodo(ctx, created.ID, Todo{Title: "new Title"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteTodo(ctx, created.ID))
+
+	// failing publisher keeps events in the outbox
+	p.err = errors.New("broker down")
+	n, err := relay.RelayPending(ctx)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, p.err, err)
+
+	p.err = nil
+	n, err = relay.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, EventTodoCreated, p.events[0].Type)
+	assert.Equal(t, EventTodoUpdated, p.events[1].Type)
+	assert.Equal(t, EventTodoDeleted, p.events[2].Type)
+
+	// sent events are not published again
+	n, err = relay.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestOutboxRollback(t *testing.T) {
+	ctx := context.Background()
+	p := &recordingPublisher{}
+	s, relay, _ := NewOutboxDBService(sqlite.Open(":memory:"), p, time.Second, log.NewNopLogger())
+
+	_, err := s.AddTodo(ctx, Todo{Title: "no owner"})
+	assert.Equal(t, ErrOwnerMissing, err)
+	_, err = s.UpdateTodo(ctx, uuid.New(), Todo{Title: "missing"})
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, s.DeleteTodo(ctx, uuid.New()))
+
+	n, err := relay.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestNoOutboxWithoutRelay(t *testing.T) {
+	ctx := context.Background()
+	s, _ := NewInMemService()
+
+	created, err := s.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "New Item"})
+	assert.NoError(t, err)
+	_, err = s.UpdateTodo(ctx, created.ID, Todo{Title: "new Title"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteTodo(ctx, created.ID))
+
+	var count int64
+	assert.NoError(t, s.(*dbSvc).db.Model(&outboxMessage{}).Count(&count).Error)
+	assert.Zero(t, count, "nothing drains the outbox")
+}
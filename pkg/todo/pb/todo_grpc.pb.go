@@ -0,0 +1,368 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: todo.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Todos_AddTodo_FullMethodName       = "/todo.Todos/AddTodo"
+	Todos_GetTodo_FullMethodName       = "/todo.Todos/GetTodo"
+	Todos_UpdateTodo_FullMethodName    = "/todo.Todos/UpdateTodo"
+	Todos_DeleteTodo_FullMethodName    = "/todo.Todos/DeleteTodo"
+	Todos_RestoreTodo_FullMethodName   = "/todo.Todos/RestoreTodo"
+	Todos_GetTodos_FullMethodName      = "/todo.Todos/GetTodos"
+	Todos_GetTodosOwned_FullMethodName = "/todo.Todos/GetTodosOwned"
+	Todos_ServiceStatus_FullMethodName = "/todo.Todos/ServiceStatus"
+)
+
+// TodosClient is the client API for Todos service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TodosClient interface {
+	AddTodo(ctx context.Context, in *AddTodoRequest, opts ...grpc.CallOption) (*AddTodoReply, error)
+	GetTodo(ctx context.Context, in *GetTodoRequest, opts ...grpc.CallOption) (*GetTodoReply, error)
+	UpdateTodo(ctx context.Context, in *UpdateTodoRequest, opts ...grpc.CallOption) (*UpdateTodoReply, error)
+	DeleteTodo(ctx context.Context, in *DeleteTodoRequest, opts ...grpc.CallOption) (*DeleteTodoReply, error)
+	RestoreTodo(ctx context.Context, in *RestoreTodoRequest, opts ...grpc.CallOption) (*RestoreTodoReply, error)
+	GetTodos(ctx context.Context, in *GetTodosRequest, opts ...grpc.CallOption) (*GetTodosReply, error)
+	GetTodosOwned(ctx context.Context, in *GetTodosOwnedRequest, opts ...grpc.CallOption) (*GetTodosReply, error)
+	ServiceStatus(ctx context.Context, in *ServiceStatusRequest, opts ...grpc.CallOption) (*ServiceStatusReply, error)
+}
+
+type todosClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTodosClient(cc grpc.ClientConnInterface) TodosClient {
+	return &todosClient{cc}
+}
+
+func (c *todosClient) AddTodo(ctx context.Context, in *AddTodoRequest, opts ...grpc.CallOption) (*AddTodoReply, error) {
+	out := new(AddTodoReply)
+	err := c.cc.Invoke(ctx, Todos_AddTodo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) GetTodo(ctx context.Context, in *GetTodoRequest, opts ...grpc.CallOption) (*GetTodoReply, error) {
+	out := new(GetTodoReply)
+	err := c.cc.Invoke(ctx, Todos_GetTodo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) UpdateTodo(ctx context.Context, in *UpdateTodoRequest, opts ...grpc.CallOption) (*UpdateTodoReply, error) {
+	out := new(UpdateTodoReply)
+	err := c.cc.Invoke(ctx, Todos_UpdateTodo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) DeleteTodo(ctx context.Context, in *DeleteTodoRequest, opts ...grpc.CallOption) (*DeleteTodoReply, error) {
+	out := new(DeleteTodoReply)
+	err := c.cc.Invoke(ctx, Todos_DeleteTodo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) RestoreTodo(ctx context.Context, in *RestoreTodoRequest, opts ...grpc.CallOption) (*RestoreTodoReply, error) {
+	out := new(RestoreTodoReply)
+	err := c.cc.Invoke(ctx, Todos_RestoreTodo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) GetTodos(ctx context.Context, in *GetTodosRequest, opts ...grpc.CallOption) (*GetTodosReply, error) {
+	out := new(GetTodosReply)
+	err := c.cc.Invoke(ctx, Todos_GetTodos_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) GetTodosOwned(ctx context.Context, in *GetTodosOwnedRequest, opts ...grpc.CallOption) (*GetTodosReply, error) {
+	out := new(GetTodosReply)
+	err := c.cc.Invoke(ctx, Todos_GetTodosOwned_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todosClient) ServiceStatus(ctx context.Context, in *ServiceStatusRequest, opts ...grpc.CallOption) (*ServiceStatusReply, error) {
+	out := new(ServiceStatusReply)
+	err := c.cc.Invoke(ctx, Todos_ServiceStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TodosServer is the server API for Todos service.
+// All implementations must embed UnimplementedTodosServer
+// for forward compatibility
+type TodosServer interface {
+	AddTodo(context.Context, *AddTodoRequest) (*AddTodoReply, error)
+	GetTodo(context.Context, *GetTodoRequest) (*GetTodoReply, error)
+	UpdateTodo(context.Context, *UpdateTodoRequest) (*UpdateTodoReply, error)
+	DeleteTodo(context.Context, *DeleteTodoRequest) (*DeleteTodoReply, error)
+	RestoreTodo(context.Context, *RestoreTodoRequest) (*RestoreTodoReply, error)
+	GetTodos(context.Context, *GetTodosRequest) (*GetTodosReply, error)
+	GetTodosOwned(context.Context, *GetTodosOwnedRequest) (*GetTodosReply, error)
+	ServiceStatus(context.Context, *ServiceStatusRequest) (*ServiceStatusReply, error)
+	mustEmbedUnimplementedTodosServer()
+}
+
+// UnimplementedTodosServer must be embedded to have forward compatible implementations.
+type UnimplementedTodosServer struct {
+}
+
+func (UnimplementedTodosServer) AddTodo(context.Context, *AddTodoRequest) (*AddTodoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddTodo not implemented")
+}
+func (UnimplementedTodosServer) GetTodo(context.Context, *GetTodoRequest) (*GetTodoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTodo not implemented")
+}
+func (UnimplementedTodosServer) UpdateTodo(context.Context, *UpdateTodoRequest) (*UpdateTodoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTodo not implemented")
+}
+func (UnimplementedTodosServer) DeleteTodo(context.Context, *DeleteTodoRequest) (*DeleteTodoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTodo not implemented")
+}
+func (UnimplementedTodosServer) RestoreTodo(context.Context, *RestoreTodoRequest) (*RestoreTodoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreTodo not implemented")
+}
+func (UnimplementedTodosServer) GetTodos(context.Context, *GetTodosRequest) (*GetTodosReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTodos not implemented")
+}
+func (UnimplementedTodosServer) GetTodosOwned(context.Context, *GetTodosOwnedRequest) (*GetTodosReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTodosOwned not implemented")
+}
+func (UnimplementedTodosServer) ServiceStatus(context.Context, *ServiceStatusRequest) (*ServiceStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ServiceStatus not implemented")
+}
+func (UnimplementedTodosServer) mustEmbedUnimplementedTodosServer() {}
+
+// UnsafeTodosServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TodosServer will
+// result in compilation errors.
+type UnsafeTodosServer interface {
+	mustEmbedUnimplementedTodosServer()
+}
+
+func RegisterTodosServer(s grpc.ServiceRegistrar, srv TodosServer) {
+	s.RegisterService(&Todos_ServiceDesc, srv)
+}
+
+func _Todos_AddTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).AddTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_AddTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).AddTodo(ctx, req.(*AddTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_GetTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).GetTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_GetTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).GetTodo(ctx, req.(*GetTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_UpdateTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).UpdateTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_UpdateTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).UpdateTodo(ctx, req.(*UpdateTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_DeleteTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).DeleteTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_DeleteTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).DeleteTodo(ctx, req.(*DeleteTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_RestoreTodo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreTodoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).RestoreTodo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_RestoreTodo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).RestoreTodo(ctx, req.(*RestoreTodoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_GetTodos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTodosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).GetTodos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_GetTodos_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).GetTodos(ctx, req.(*GetTodosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_GetTodosOwned_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTodosOwnedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).GetTodosOwned(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_GetTodosOwned_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).GetTodosOwned(ctx, req.(*GetTodosOwnedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Todos_ServiceStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodosServer).ServiceStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Todos_ServiceStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodosServer).ServiceStatus(ctx, req.(*ServiceStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Todos_ServiceDesc is the grpc.ServiceDesc for Todos service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Todos_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.Todos",
+	HandlerType: (*TodosServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddTodo",
+			Handler:    _Todos_AddTodo_Handler,
+		},
+		{
+			MethodName: "GetTodo",
+			Handler:    _Todos_GetTodo_Handler,
+		},
+		{
+			MethodName: "UpdateTodo",
+			Handler:    _Todos_UpdateTodo_Handler,
+		},
+		{
+			MethodName: "DeleteTodo",
+			Handler:    _Todos_DeleteTodo_Handler,
+		},
+		{
+			MethodName: "RestoreTodo",
+			Handler:    _Todos_RestoreTodo_Handler,
+		},
+		{
+			MethodName: "GetTodos",
+			Handler:    _Todos_GetTodos_Handler,
+		},
+		{
+			MethodName: "GetTodosOwned",
+			Handler:    _Todos_GetTodosOwned_Handler,
+		},
+		{
+			MethodName: "ServiceStatus",
+			Handler:    _Todos_ServiceStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "todo.proto",
+}
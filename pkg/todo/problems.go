@@ -0,0 +1,23 @@
+package todo
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// problems presents the errors of the service as problem details
+var problems = problem.NewRegistry(
+	problem.Entry{Err: ErrPopulatedID, Status: http.StatusBadRequest, Code: "id_populated", Title: "Id must be empty", Field: "id"},
+	problem.Entry{Err: ErrOwnerChanged, Status: http.StatusConflict, Code: "owner_changed", Title: "Owner can not be changed", Field: "owner_id"},
+	problem.Entry{Err: ErrOwnerMissing, Status: http.StatusBadRequest, Code: "owner_missing", Title: "Owner is missing", Field: "owner_id"},
+	problem.Entry{Err: ErrInconsistentIDs, Status: http.StatusBadRequest, Code: "inconsistent_ids", Title: "Ids in path and body differ", Field: "id"},
+	problem.Entry{Err: ErrAlreadyExists, Status: http.StatusConflict, Code: "already_exists", Title: "Todo already exists"},
+	problem.Entry{Err: ErrNotFound, Status: http.StatusNotFound, Code: "not_found", Title: "Todo not found"},
+	problem.Entry{Err: ErrInvalidUUID, Status: http.StatusBadRequest, Code: "invalid_uuid", Title: "Invalid uuid", Field: "id"},
+	problem.Entry{Err: ErrTeamChanged, Status: http.StatusConflict, Code: "team_changed", Title: "Team can not be changed", Field: "team_id"},
+	problem.Entry{Err: ErrNotTeamMember, Status: http.StatusForbidden, Code: "not_team_member", Title: "Owner can not add todos to the team", Field: "team_id"},
+	problem.Entry{Err: authorization.ErrUnauthenticated, Status: http.StatusUnauthorized, Code: "unauthenticated", Title: "Authentication required"},
+	problem.Entry{Err: authorization.ErrForbidden, Status: http.StatusForbidden, Code: "forbidden", Title: "Permission denied"},
+)
@@ -52,6 +52,18 @@ type deleteTodoResponse struct {
 //lint:ignore U1000 used to satisfy error interface in github.com/demeesterdev/todo-service/pkg/todo/transport
 func (r deleteTodoResponse) Error() error { return r.Err }
 
+type restoreTodoRequest struct {
+	ID uuid.UUID
+}
+
+type restoreTodoResponse struct {
+	Todo Todo  `json:"todo,omitempty"`
+	Err  error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in github.com/demeesterdev/todo-service/pkg/todo/transport
+func (r restoreTodoResponse) Error() error { return r.Err }
+
 type getTodosRequest struct {
 	OwnerID uuid.UUID
 }
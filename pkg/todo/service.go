@@ -19,7 +19,10 @@ type Todo struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primarykey"`
 	Title       string         `json:"title"`
 	Description string         `json:"description,omitempty"`
+	Done        bool           `json:"done"`
 	OwnerID     uuid.UUID      `json:"owner_id"`
+	// TeamID shares the todo with the members of a team, see Teams
+	TeamID uuid.UUID `json:"team_id,omitempty" gorm:"type:uuid;index"`
 }
 
 // Before create is a GORM hook
@@ -38,11 +41,18 @@ type Service interface {
 	GetTodo(ctx context.Context, id uuid.UUID) (Todo, error)
 	UpdateTodo(ctx context.Context, id uuid.UUID, t Todo) (Todo, error)
 	DeleteTodo(ctx context.Context, id uuid.UUID) error
+	RestoreTodo(ctx context.Context, id uuid.UUID) (Todo, error)
 	GetTodos(ctx context.Context) ([]Todo, error)
 	GetTodosOwned(ctx context.Context, user authorization.User) ([]Todo, error)
 	ServiceStatus(ctx context.Context) (int, error)
 }
 
+// Teams looks up the teams a user is a member of, it is implemented by
+// authorization.Service
+type Teams interface {
+	GetTeams(ctx context.Context, userID uuid.UUID) ([]authorization.Team, error)
+}
+
 var (
 	ErrPopulatedID     = errors.New("id filled")
 	ErrOwnerChanged    = errors.New("owner_id changed")
@@ -51,4 +61,6 @@ var (
 	ErrAlreadyExists   = errors.New("already exists")
 	ErrNotFound        = errors.New("not found")
 	ErrInvalidUUID     = errors.New("invalid uuid")
+	ErrTeamChanged     = errors.New("team_id changed")
+	ErrNotTeamMember   = errors.New("owner can not add todos to the team")
 )
@@ -13,6 +13,10 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
 	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
 )
 
 func MakeHTTPHandler(ep Endpoints, logger log.Logger) http.Handler {
@@ -21,6 +25,7 @@ func MakeHTTPHandler(ep Endpoints, logger log.Logger) http.Handler {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
 		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(authorizationTrsp.SessionTokenToContext),
 	}
 
 	r.Get("/status", httptransport.NewServer(
@@ -59,6 +64,14 @@ func MakeHTTPHandler(ep Endpoints, logger log.Logger) http.Handler {
 		encodeResponse,
 		options...,
 	).ServeHTTP)
+	r.Post("/{id}/restore", httptransport.NewServer(
+		ep.RestoreTodoEndpoint,
+		decodeHTTPRestoreTodoRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+
+	openapi.Mount(r, OpenAPI())
 
 	return r
 }
@@ -73,7 +86,7 @@ func decodeHTTPAddTodoRequest(ctx context.Context, r *http.Request) (interface{}
 	var req addTodoRequest
 	err := json.NewDecoder(r.Body).Decode(&req.Todo)
 	if err != nil {
-		return nil, err
+		return nil, problem.DecodeError(err)
 	}
 	return req, nil
 }
@@ -96,7 +109,7 @@ func decodeHTTPGetTodosRequest(ctx context.Context, r *http.Request) (interface{
 	if q.Has("owner") {
 		req.OwnerID, err = uuid.Parse(q.Get("owner"))
 		if err != nil {
-			return nil, ErrInvalidUUID
+			return nil, problem.WithField(ErrInvalidUUID, "owner")
 		}
 	}
 	return req, nil
@@ -107,11 +120,11 @@ func decodeHTTPUpdateTodoRequest(ctx context.Context, r *http.Request) (interfac
 	var err error
 	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidUUID
 	}
 	err = json.NewDecoder(r.Body).Decode(&req.Todo)
 	if err != nil {
-		return nil, err
+		return nil, problem.DecodeError(err)
 	}
 	return req, nil
 }
@@ -126,19 +139,35 @@ func decodeHTTPDeleteTodoRequest(ctx context.Context, r *http.Request) (interfac
 	return req, nil
 }
 
+func decodeHTTPRestoreTodoRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req restoreTodoRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return req, nil
+}
+
 // client functions
 // encode request for server
 
 func encodeHTTPGetTodosRequest(ctx context.Context, req *http.Request, request interface{}) error {
 	// r.Get("/", ...)
+	r := request.(getTodosRequest)
 	req.URL.Path = "/"
-	return encodeRequest(ctx, req, request)
+	if r.OwnerID != uuid.Nil {
+		q := req.URL.Query()
+		q.Set("owner", r.OwnerID.String())
+		req.URL.RawQuery = q.Encode()
+	}
+	return nil
 }
 
 func encodeHTTPServiceStatusRequest(ctx context.Context, req *http.Request, request interface{}) error {
 	// r.Get("/status", ...)
-	req.URL.Path = "/status/"
-	return encodeRequest(ctx, req, request)
+	req.URL.Path = "/status"
+	return nil
 }
 
 func encodeHTTPGetTodoRequest(ctx context.Context, req *http.Request, request interface{}) error {
@@ -146,13 +175,14 @@ func encodeHTTPGetTodoRequest(ctx context.Context, req *http.Request, request in
 	r := request.(getTodoRequest)
 	todoID := url.QueryEscape(r.ID.String())
 	req.URL.Path = "/" + todoID
-	return encodeRequest(ctx, req, request)
+	return nil
 }
 
 func encodeHTTPAddTodoRequest(ctx context.Context, req *http.Request, request interface{}) error {
 	// r.Post("/", ...)
+	r := request.(addTodoRequest)
 	req.URL.Path = "/"
-	return encodeRequest(ctx, req, request)
+	return encodeRequest(ctx, req, r.Todo)
 }
 
 func encodeHTTPUpdateTodoRequest(ctx context.Context, req *http.Request, request interface{}) error {
@@ -160,16 +190,23 @@ func encodeHTTPUpdateTodoRequest(ctx context.Context, req *http.Request, request
 	r := request.(updateTodoRequest)
 	todoID := url.QueryEscape(r.ID.String())
 	req.URL.Path = "/" + todoID
-	return encodeRequest(ctx, req, request)
+	return encodeRequest(ctx, req, r.Todo)
 }
 
 func encodeHTTPDeleteTodoRequest(ctx context.Context, req *http.Request, request interface{}) error {
 	// r.Delete("/{id}, ...)
-	req.URL.Path = "/status/"
-	r := request.(updateTodoRequest)
+	r := request.(deleteTodoRequest)
 	todoID := url.QueryEscape(r.ID.String())
 	req.URL.Path = "/" + todoID
-	return encodeRequest(ctx, req, request)
+	return nil
+}
+
+func encodeHTTPRestoreTodoRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	// r.Post("/{id}/restore", ...)
+	r := request.(restoreTodoRequest)
+	todoID := url.QueryEscape(r.ID.String())
+	req.URL.Path = "/" + todoID + "/restore"
+	return nil
 }
 
 // client functions
@@ -177,35 +214,51 @@ func encodeHTTPDeleteTodoRequest(ctx context.Context, req *http.Request, request
 
 func decodeHTTPAddTodoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response addTodoResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 func decodeHTTPGetTodoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response getTodoResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 func decodeHTTPUpdateTodoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response updateTodoResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 func decodeHTTPDeleteTodoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response deleteTodoResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
+	return response, err
+}
+func decodeHTTPRestoreTodoResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var response restoreTodoResponse
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 func decodeHTTPGetTodosResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response getTodosResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 func decodeHTTPServiceStatusResponse(_ context.Context, resp *http.Response) (interface{}, error) {
 	var response serviceStatusResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response, &response.Err)
 	return response, err
 }
 
+// decodeResponse decodes a successful response body into response.
+// Problems are decoded into respErr so they reach the caller as the
+// sentinel error of the service method.
+func decodeResponse(resp *http.Response, response interface{}, respErr *error) error {
+	if err := problems.Decode(resp); err != nil {
+		*respErr = err
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
 // errorer is implemented by all concrete response types that may contain
 // errors.
 type errorer interface {
@@ -236,18 +289,5 @@ func encodeRequest(_ context.Context, req *http.Request, request interface{}) er
 }
 
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	switch err {
-	case ErrNotFound:
-		w.WriteHeader(http.StatusNotFound)
-	case ErrInconsistentIDs:
-		w.WriteHeader(http.StatusBadRequest)
-	case ErrOwnerMissing:
-		w.WriteHeader(http.StatusBadRequest)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
+	problems.Write(w, err)
 }
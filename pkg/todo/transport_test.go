@@ -0,0 +1,132 @@
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+func TestHTTPClient(t *testing.T) {
+	s, _ := NewInMemService()
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s), log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, err := MakeClientEndpoints(srv.URL)
+	assert.NoError(t, err)
+	owner := authorization.User{ID: uuid.New()}
+
+	added, err := c.AddTodo(ctx, Todo{OwnerID: owner.ID, Title: "New Item"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, added.ID)
+
+	_, err = c.AddTodo(ctx, Todo{OwnerID: uuid.New(), Title: "Other Item"})
+	assert.NoError(t, err)
+
+	updated, err := c.UpdateTodo(ctx, added.ID, Todo{Title: "New Item", Done: true})
+	assert.NoError(t, err)
+	assert.True(t, updated.Done)
+
+	owned, err := c.GetTodosOwned(ctx, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(owned))
+
+	assert.NoError(t, c.DeleteTodo(ctx, added.ID))
+	_, err = c.GetTodo(ctx, added.ID)
+	assert.Equal(t, ErrNotFound, err)
+
+	restored, err := c.RestoreTodo(ctx, added.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "New Item", restored.Title)
+
+	code, err := c.ServiceStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+}
+
+func TestHTTPProblems(t *testing.T) {
+	s, _ := NewInMemService()
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s), log.NewNopLogger()))
+	defer srv.Close()
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{
+			name:   "should report invalid ids",
+			method: http.MethodGet,
+			path:   "/not-a-uuid",
+			status: http.StatusBadRequest,
+			code:   "invalid_uuid",
+			field:  "id",
+		},
+		{
+			name:   "should report the invalid owner filter",
+			method: http.MethodGet,
+			path:   "/?owner=not-a-uuid",
+			status: http.StatusBadRequest,
+			code:   "invalid_uuid",
+			field:  "owner",
+		},
+		{
+			name:   "should report missing owners",
+			method: http.MethodPost,
+			path:   "/",
+			body:   `{"title": "no owner"}`,
+			status: http.StatusBadRequest,
+			code:   "owner_missing",
+			field:  "owner_id",
+		},
+		{
+			name:   "should report malformed bodies",
+			method: http.MethodPost,
+			path:   "/",
+			body:   `{"title": `,
+			status: http.StatusBadRequest,
+			code:   "malformed_body",
+		},
+		{
+			name:   "should report missing todos",
+			method: http.MethodGet,
+			path:   "/" + uuid.NewString(),
+			status: http.StatusNotFound,
+			code:   "not_found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, srv.URL+tc.path, strings.NewReader(tc.body))
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			var p problem.Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			assert.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, tc.code, p.Code)
+			if tc.field == "" {
+				assert.Empty(t, p.Errors)
+			} else {
+				assert.Equal(t, tc.field, p.Errors[0].Field)
+			}
+		})
+	}
+}
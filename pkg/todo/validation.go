@@ -0,0 +1,46 @@
+package todo
+
+import (
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/demeesterdev/todo-service/internal/validate"
+)
+
+// Limits bounds the todos accepted by the service.
+// A limit of 0 disables it.
+type Limits struct {
+	TitleMaxLength      int
+	DescriptionMaxBytes int
+	// MaxBodyBytes is enforced by the http server, see validate.MaxBodyBytes
+	MaxBodyBytes int64
+}
+
+var DefaultLimits = Limits{
+	TitleMaxLength:      200,
+	DescriptionMaxBytes: 10 << 10,
+	MaxBodyBytes:        64 << 10,
+}
+
+// ValidationMiddleware rejects requests violating l before they reach the service
+func ValidationMiddleware(l Limits) endpoint.Middleware {
+	return validate.Middleware(l.validate)
+}
+
+func (l Limits) validate(request interface{}) error {
+	switch req := request.(type) {
+	case addTodoRequest:
+		return l.validateTodo(req.Todo)
+	case updateTodoRequest:
+		return l.validateTodo(req.Todo)
+	default:
+		// the other requests only carry ids, which are checked while decoding
+		return nil
+	}
+}
+
+func (l Limits) validateTodo(t Todo) error {
+	var c validate.Checker
+	c.String("title", t.Title, validate.Required(), validate.UTF8(), validate.MaxLength(l.TitleMaxLength))
+	c.Optional("description", t.Description, validate.UTF8(), validate.MaxBytes(l.DescriptionMaxBytes))
+	return c.Err()
+}
@@ -0,0 +1,82 @@
+package todo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/internal/validate"
+)
+
+func TestHTTPValidation(t *testing.T) {
+	s, _ := NewInMemService()
+	limits := Limits{TitleMaxLength: 10, DescriptionMaxBytes: 20, MaxBodyBytes: 256}
+	handler := MakeHTTPHandler(MakeServerEndpoints(s, ValidationMiddleware(limits)), log.NewNopLogger())
+	srv := httptest.NewServer(validate.MaxBodyBytes(limits.MaxBodyBytes)(handler))
+	defer srv.Close()
+
+	owner := uuid.NewString()
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		fields map[string]string
+	}{
+		{
+			name:   "should accept valid todos",
+			body:   `{"title": "groceries", "owner_id": "` + owner + `"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "should report missing titles",
+			body:   `{"description": "milk", "owner_id": "` + owner + `"}`,
+			status: http.StatusBadRequest,
+			code:   "invalid_fields",
+			fields: map[string]string{"title": "required"},
+		},
+		{
+			name:   "should report every invalid field",
+			body:   `{"title": "a title that is too long", "description": "a description that is too large", "owner_id": "` + owner + `"}`,
+			status: http.StatusBadRequest,
+			code:   "invalid_fields",
+			fields: map[string]string{"title": "too_long", "description": "too_large"},
+		},
+		{
+			name:   "should reject bodies over the limit",
+			body:   `{"title": "big", "description": "` + strings.Repeat("x", 512) + `"}`,
+			status: http.StatusRequestEntityTooLarge,
+			code:   "body_too_large",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/", "application/json", strings.NewReader(tc.body))
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.status, resp.StatusCode)
+			if tc.code == "" {
+				return
+			}
+
+			var p problem.Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			assert.Equal(t, tc.code, p.Code)
+			fields := map[string]string{}
+			for _, f := range p.Errors {
+				fields[f.Field] = f.Code
+			}
+			if tc.fields != nil {
+				assert.Equal(t, tc.fields, fields)
+			}
+		})
+	}
+}
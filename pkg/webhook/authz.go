@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// Sessions checks the session tokens of callers, it is implemented by
+// authorization.Service
+type Sessions interface {
+	ValidateSession(ctx context.Context, token string) (authorization.User, error)
+}
+
+// AuthorizationMiddleware only lets users with a session manage webhooks.
+// The caller is added to the context with authorization.WithCaller, the
+// service limits it to its own subscriptions and adds new ones for it.
+func AuthorizationMiddleware(s Sessions) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			token := authorization.SessionToken(ctx)
+			if token == "" {
+				return nil, authorization.ErrUnauthenticated
+			}
+			u, err := s.ValidateSession(ctx, token)
+			if err == authorization.ErrInvalidToken {
+				return nil, authorization.ErrUnauthenticated
+			}
+			if err != nil {
+				return nil, err
+			}
+			return next(authorization.WithCaller(ctx, u), request)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// Config holds the settings of the delivery dispatcher
+type Config struct {
+	// MaxAttempts before a delivery is moved to the dead letter queue
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt,
+	// it doubles for every following attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PollInterval is how often the dispatcher looks for due deliveries
+	PollInterval time.Duration
+	// Timeout for a single delivery request
+	Timeout time.Duration
+	// Workers is the number of deliveries sent concurrently
+	Workers int
+	// AllowInternalHosts lets subscriptions deliver to loopback, private
+	// and link-local addresses, only for development and tests
+	AllowInternalHosts bool
+}
+
+var DefaultConfig = Config{
+	MaxAttempts:    8,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     30 * time.Minute,
+	PollInterval:   time.Second,
+	Timeout:        10 * time.Second,
+	Workers:        4,
+}
+
+type dbSvc struct {
+	db     *gorm.DB
+	cfg    Config
+	client *http.Client
+	logger log.Logger
+	kick   chan struct{}
+	now    func() time.Time
+}
+
+// NewDBService creates a new webhook service based on a database connection.
+// It starts the dispatcher delivering pending deliveries in the background
+// until ctx is done.
+func NewDBService(ctx context.Context, dbconnection gorm.Dialector, cfg Config, logger log.Logger) (Service, error) {
+	db, err := gorm.Open(dbconnection, &gorm.Config{})
+	if err != nil {
+		return &dbSvc{}, err
+	}
+	err = db.AutoMigrate(&Subscription{}, &Delivery{})
+	if err != nil {
+		return &dbSvc{}, err
+	}
+
+	// the dispatcher runs concurrently with requests, a single connection
+	// keeps in memory databases shared and sqlite writes serialized
+	sqlDB, err := db.DB()
+	if err != nil {
+		return &dbSvc{}, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	s := &dbSvc{
+		db:     db,
+		cfg:    cfg,
+		client: newClient(cfg),
+		logger: logger,
+		kick:   make(chan struct{}, 1),
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+	go s.dispatch(ctx)
+
+	return s, nil
+}
+
+// NewSqliteDBService creates a new webhook service based on a sqlite database with a target file
+func NewSqliteDBService(ctx context.Context, target string, cfg Config, logger log.Logger) (Service, error) {
+	return NewDBService(ctx, sqlite.Open(target), cfg, logger)
+}
+
+func NewInMemService(ctx context.Context, cfg Config, logger log.Logger) (Service, error) {
+	return NewSqliteDBService(ctx, ":memory:", cfg, logger)
+}
+
+// owned limits db to the records of the caller in ctx, if there is one
+func owned(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if caller, ok := authorization.Caller(ctx); ok {
+		return db.Where("owner_id = ?", caller.ID)
+	}
+	return db
+}
+
+// ownedDeliveries is owned for deliveries, they belong to the owner of their
+// subscription. Deliveries of removed subscriptions stay with that owner.
+func ownedDeliveries(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if _, ok := authorization.Caller(ctx); ok {
+		return db.Where("subscription_id IN (?)", owned(ctx, db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Subscription{})).Select("id"))
+	}
+	return db
+}
+
+func (s *dbSvc) AddSubscription(ctx context.Context, sub Subscription) (Subscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+	if !s.cfg.AllowInternalHosts && internalHost(u.Hostname()) {
+		return Subscription{}, ErrInternalHost
+	}
+
+	if caller, ok := authorization.Caller(ctx); ok {
+		sub.OwnerID = caller.ID
+	}
+	if sub.OwnerID == uuid.Nil {
+		return Subscription{}, ErrOwnerMissing
+	}
+
+	if sub.Secret == "" {
+		sub.Secret, err = generateSecret()
+		if err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	result := s.db.Create(&sub)
+	if result.Error != nil {
+		return Subscription{}, result.Error
+	}
+
+	// the secret is only returned on creation
+	return sub, nil
+}
+
+func (s *dbSvc) GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	var sub Subscription
+	result := owned(ctx, s.db).First(&sub, "id = ?", id.String())
+
+	switch result.Error {
+	case gorm.ErrRecordNotFound:
+		return Subscription{}, ErrNotFound
+	default:
+		sub.Secret = ""
+		return sub, result.Error
+	}
+}
+
+func (s *dbSvc) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	result := owned(ctx, s.db).Delete(&Subscription{}, "id = ?", id.String())
+	return result.Error
+}
+
+func (s *dbSvc) GetSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	result := owned(ctx, s.db).Find(&subs)
+	if result.Error != nil {
+		return []Subscription{}, result.Error
+	}
+
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+func (s *dbSvc) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]Delivery, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return []Delivery{}, err
+	}
+
+	var deliveries []Delivery
+	result := s.db.Where("subscription_id = ?", subscriptionID.String()).Order("created_at desc").Find(&deliveries)
+	if result.Error != nil {
+		return []Delivery{}, result.Error
+	}
+	return deliveries, nil
+}
+
+func (s *dbSvc) GetDeadLetters(ctx context.Context) ([]Delivery, error) {
+	var deliveries []Delivery
+	result := ownedDeliveries(ctx, s.db).Where("status = ?", StatusDead).Order("updated_at desc").Find(&deliveries)
+	if result.Error != nil {
+		return []Delivery{}, result.Error
+	}
+	return deliveries, nil
+}
+
+func (s *dbSvc) Redeliver(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	var d Delivery
+	result := ownedDeliveries(ctx, s.db).First(&d, "id = ?", id.String())
+	switch {
+	case result.Error == gorm.ErrRecordNotFound:
+		return Delivery{}, ErrNotFound
+	case result.Error != nil:
+		return Delivery{}, result.Error
+	case d.Status == StatusPending:
+		return Delivery{}, ErrDeliveryPending
+	}
+
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = s.now()
+	d.LastError = ""
+	d.LastStatusCode = 0
+
+	result = s.db.Save(&d)
+	if result.Error != nil {
+		return Delivery{}, result.Error
+	}
+
+	s.wake()
+	return d, nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
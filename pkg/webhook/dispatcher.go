@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/demeesterdev/todo-service/pkg/events"
+)
+
+// headers set on every delivery
+const (
+	HeaderSignature = "X-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-ID"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Publish creates a pending delivery for every subscription interested in e.
+// Subscriptions only receive the events of their owner, the user in the
+// owner_id of the event data, events without owner are not delivered.
+// Delivery itself happens asynchronously.
+func (s *dbSvc) Publish(ctx context.Context, e events.Event) error {
+	var data struct {
+		OwnerID uuid.UUID `json:"owner_id"`
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil || data.OwnerID == uuid.Nil {
+		return nil
+	}
+
+	var subs []Subscription
+	result := s.db.Where("owner_id = ?", data.OwnerID).Find(&subs)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []Delivery
+	for _, sub := range subs {
+		if !sub.Events.Matches(e.Type) {
+			continue
+		}
+		deliveries = append(deliveries, Delivery{
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  s.now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// an event is delivered once per subscription, publishing it again is a no-op
+	result = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	s.wake()
+	return nil
+}
+
+// Sign returns the value of the X-Signature header for payload
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// wake triggers the dispatcher without waiting for the next poll
+func (s *dbSvc) wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch delivers due deliveries until ctx is done
+func (s *dbSvc) dispatch(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		s.deliverDue(ctx)
+	}
+}
+
+// deliverDue sends all deliveries that are due, using at most cfg.Workers
+// concurrent requests
+func (s *dbSvc) deliverDue(ctx context.Context) {
+	var due []Delivery
+	result := s.db.
+		Where("status = ? AND next_attempt_at <= ?", StatusPending, s.now()).
+		Order("next_attempt_at").
+		Limit(100).
+		Find(&due)
+	if result.Error != nil {
+		s.logger.Log("during", "dispatch", "err", result.Error)
+		return
+	}
+
+	workers := s.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d Delivery) {
+			defer wg.Done()
+			s.attempt(ctx, d)
+			<-sem
+		}(due[i])
+	}
+	wg.Wait()
+}
+
+func (s *dbSvc) attempt(ctx context.Context, d Delivery) {
+	var sub Subscription
+	result := s.db.First(&sub, "id = ?", d.SubscriptionID.String())
+
+	var err error
+	switch result.Error {
+	case nil:
+		d.LastStatusCode, err = s.send(ctx, sub, d)
+		if ctx.Err() != nil {
+			// shutting down, the attempt is made again on the next start
+			return
+		}
+	case gorm.ErrRecordNotFound:
+		// subscription was removed, no point in trying again
+		d.Attempts = s.cfg.MaxAttempts
+		err = ErrNotFound
+	default:
+		s.logger.Log("delivery", d.ID, "err", result.Error)
+		return
+	}
+
+	d.Attempts++
+	switch {
+	case err == nil:
+		d.Status = StatusSucceeded
+		d.LastError = ""
+	case d.Attempts >= s.cfg.MaxAttempts:
+		d.Status = StatusDead
+		d.LastError = err.Error()
+	default:
+		d.NextAttemptAt = s.now().Add(s.backoff(d.Attempts))
+		d.LastError = err.Error()
+	}
+
+	result = s.db.Save(&d)
+	if result.Error != nil {
+		s.logger.Log("delivery", d.ID, "err", result.Error)
+	}
+}
+
+// backoff returns the wait before the next attempt after n failed attempts
+func (s *dbSvc) backoff(n int) time.Duration {
+	wait := s.cfg.InitialBackoff
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if wait >= s.cfg.MaxBackoff {
+			return s.cfg.MaxBackoff
+		}
+	}
+	return wait
+}
+
+func (s *dbSvc) send(ctx context.Context, sub Subscription, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, d.Payload))
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderEventID, d.EventID.String())
+	req.Header.Set(HeaderDelivery, d.ID.String())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// blockedPrefixes are the ranges not covered by the net.IP methods in
+// internalIP that don't reach the internet either
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// internalIP reports whether ip is a loopback, private, link-local or
+// otherwise internal address deliveries must not go to
+func internalIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalHost reports whether the host of a subscription url is an
+// internal address or names the local machine. Other names are checked
+// when they are dialed, see newClient.
+func internalHost(host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return internalIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// newClient returns the client sending deliveries. Unless cfg allows
+// internal hosts it refuses to connect to internal addresses, whatever the
+// name of the host resolved to, also when following redirects.
+func newClient(cfg Config) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowInternalHosts {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if internalIP(ap.Addr()) {
+				return ErrInternalHost
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would dial the subscriber instead, unchecked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
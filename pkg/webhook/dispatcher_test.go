@@ -0,0 +1,345 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+	"github.com/demeesterdev/todo-service/pkg/events"
+)
+
+var testConfig = Config{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     40 * time.Millisecond,
+	PollInterval:   10 * time.Millisecond,
+	Timeout:        time.Second,
+	Workers:        2,
+	// the receivers in the tests listen on loopback
+	AllowInternalHosts: true,
+}
+
+func TestDelivery(t *testing.T) {
+	testCases := []struct {
+		name       string
+		failures   int32
+		filter     EventFilter
+		eventType  string
+		delivered  bool
+		status     string
+		statusCode int
+	}{
+		{
+			name:       "should deliver signed event",
+			eventType:  "todo.created",
+			delivered:  true,
+			status:     StatusSucceeded,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should retry failed deliveries",
+			failures:   2,
+			eventType:  "todo.created",
+			delivered:  true,
+			status:     StatusSucceeded,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should move delivery to dead letter queue",
+			failures:   3,
+			eventType:  "todo.created",
+			status:     StatusDead,
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:      "should skip events not matching the filter",
+			filter:    EventFilter{"todo.deleted"},
+			eventType: "todo.created",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			var verified int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tc.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get(HeaderSignature) == Sign("s3cr3t", body) {
+					atomic.StoreInt32(&verified, 1)
+				}
+			}))
+			defer receiver.Close()
+
+			ctx := context.Background()
+			s, _ := NewInMemService(testContext(t), testConfig, log.NewNopLogger())
+			owner := uuid.New()
+			sub, err := s.AddSubscription(ctx, Subscription{OwnerID: owner, URL: receiver.URL, Events: tc.filter, Secret: "s3cr3t"})
+			assert.NoError(t, err)
+
+			e, _ := events.New(tc.eventType, map[string]interface{}{"title": "new item", "owner_id": owner})
+			assert.NoError(t, s.Publish(ctx, e))
+
+			if tc.status == "" {
+				time.Sleep(5 * testConfig.PollInterval)
+				deliveries, err := s.GetDeliveries(ctx, sub.ID)
+				assert.NoError(t, err)
+				assert.Empty(t, deliveries)
+				assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+				return
+			}
+
+			var d Delivery
+			assert.Eventually(t, func() bool {
+				deliveries, _ := s.GetDeliveries(ctx, sub.ID)
+				if len(deliveries) != 1 {
+					return false
+				}
+				d = deliveries[0]
+				return d.Status != StatusPending
+			}, 2*time.Second, testConfig.PollInterval)
+
+			assert.Equal(t, tc.status, d.Status)
+			assert.Equal(t, tc.statusCode, d.LastStatusCode)
+			assert.Equal(t, e.ID, d.EventID)
+			assert.Equal(t, tc.delivered, atomic.LoadInt32(&verified) == 1)
+		})
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	var healthy int32
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	ctx := context.Background()
+	s, _ := NewInMemService(testContext(t), testConfig, log.NewNopLogger())
+	owner := uuid.New()
+	_, err := s.AddSubscription(ctx, Subscription{OwnerID: owner, URL: receiver.URL})
+	assert.NoError(t, err)
+
+	e, _ := events.New("todo.deleted", map[string]interface{}{"owner_id": owner})
+	assert.NoError(t, s.Publish(ctx, e))
+
+	var dead []Delivery
+	assert.Eventually(t, func() bool {
+		dead, _ = s.GetDeadLetters(ctx)
+		return len(dead) == 1
+	}, 2*time.Second, testConfig.PollInterval)
+	assert.Equal(t, int32(testConfig.MaxAttempts), atomic.LoadInt32(&calls))
+
+	// publishing the same event again does not create a new delivery
+	assert.NoError(t, s.Publish(ctx, e))
+
+	atomic.StoreInt32(&healthy, 1)
+	d, err := s.Redeliver(ctx, dead[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, d.Status)
+
+	_, err = s.Redeliver(ctx, dead[0].ID)
+	assert.Equal(t, ErrDeliveryPending, err)
+
+	assert.Eventually(t, func() bool {
+		dead, _ = s.GetDeadLetters(ctx)
+		return len(dead) == 0 && atomic.LoadInt32(&calls) == int32(testConfig.MaxAttempts)+1
+	}, 2*time.Second, testConfig.PollInterval)
+}
+
+func TestAddSubscription(t *testing.T) {
+	testCases := []struct {
+		name  string
+		url   string
+		owner uuid.UUID
+		err   error
+	}{
+		{
+			name: "should add subscription",
+			url:  "https://ci.example.com/hooks/todo",
+			err:  nil,
+		},
+		{
+			name: "should reject url without http scheme",
+			url:  "ftp://ci.example.com/hooks/todo",
+			err:  ErrInvalidURL,
+		},
+		{
+			name:  "should reject subscription without owner",
+			url:   "https://ci.example.com/hooks/todo",
+			owner: uuid.Nil,
+			err:   ErrOwnerMissing,
+		},
+		{
+			name: "should reject loopback address",
+			url:  "http://127.0.0.1:8080/hooks/todo",
+			err:  ErrInternalHost,
+		},
+		{
+			name: "should reject localhost",
+			url:  "http://localhost:8080/hooks/todo",
+			err:  ErrInternalHost,
+		},
+		{
+			name: "should reject private address",
+			url:  "http://10.1.2.3/hooks/todo",
+			err:  ErrInternalHost,
+		},
+		{
+			name: "should reject link-local address",
+			url:  "http://169.254.169.254/latest/meta-data",
+			err:  ErrInternalHost,
+		},
+		{
+			name: "should reject mapped ipv6 loopback",
+			url:  "http://[::ffff:127.0.0.1]/hooks/todo",
+			err:  ErrInternalHost,
+		},
+	}
+
+	cfg := testConfig
+	cfg.AllowInternalHosts = false
+	s, _ := NewInMemService(testContext(t), cfg, log.NewNopLogger())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			owner := tc.owner
+			if tc.err != ErrOwnerMissing {
+				owner = uuid.New()
+			}
+			sub, err := s.AddSubscription(context.Background(), Subscription{OwnerID: owner, URL: tc.url})
+
+			assert.Equal(t, tc.err, err)
+			if err == nil {
+				assert.NotEmpty(t, sub.Secret)
+			}
+		})
+	}
+}
+
+func TestInternalAddressAtDialTime(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	ctx := context.Background()
+	cfg := testConfig
+	cfg.AllowInternalHosts = false
+	s, _ := NewInMemService(testContext(t), cfg, log.NewNopLogger())
+
+	// names resolving to loopback pass registration, the dialer refuses them
+	owner := uuid.New()
+	sub := Subscription{OwnerID: owner, URL: receiver.URL, Secret: "s3cr3t"}
+	assert.NoError(t, s.(*dbSvc).db.Create(&sub).Error)
+	e, _ := events.New("todo.created", map[string]interface{}{"owner_id": owner})
+	assert.NoError(t, s.Publish(ctx, e))
+
+	var dead []Delivery
+	assert.Eventually(t, func() bool {
+		dead, _ = s.GetDeadLetters(ctx)
+		return len(dead) == 1
+	}, 2*time.Second, cfg.PollInterval)
+	assert.Contains(t, dead[0].LastError, ErrInternalHost.Error())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestSubscriptionOwners(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	s, _ := NewInMemService(testContext(t), testConfig, log.NewNopLogger())
+	alice := authorization.User{ID: uuid.New()}
+	bob := authorization.User{ID: uuid.New()}
+	asAlice := authorization.WithCaller(context.Background(), alice)
+	asBob := authorization.WithCaller(context.Background(), bob)
+
+	// subscriptions are added for the caller
+	sub, err := s.AddSubscription(asAlice, Subscription{OwnerID: bob.ID, URL: receiver.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, sub.OwnerID)
+
+	subs, err := s.GetSubscriptions(asBob)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	_, err = s.GetSubscription(asBob, sub.ID)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = s.GetDeliveries(asBob, sub.ID)
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, s.DeleteSubscription(asBob, sub.ID))
+	_, err = s.GetSubscription(asAlice, sub.ID)
+	assert.NoError(t, err)
+
+	// only the events of the owner are delivered
+	other, _ := events.New("todo.created", map[string]interface{}{"owner_id": bob.ID})
+	assert.NoError(t, s.Publish(asBob, other))
+	own, _ := events.New("todo.created", map[string]interface{}{"owner_id": alice.ID})
+	assert.NoError(t, s.Publish(asAlice, own))
+
+	var deliveries []Delivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ = s.GetDeliveries(asAlice, sub.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusSucceeded
+	}, 2*time.Second, testConfig.PollInterval)
+	assert.Equal(t, own.ID, deliveries[0].EventID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err = s.Redeliver(asBob, deliveries[0].ID)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = s.Redeliver(asAlice, deliveries[0].ID)
+	assert.NoError(t, err)
+}
+
+func TestDispatcherStops(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, _ := NewInMemService(ctx, testConfig, log.NewNopLogger())
+	owner := uuid.New()
+	sub, err := s.AddSubscription(context.Background(), Subscription{OwnerID: owner, URL: receiver.URL})
+	assert.NoError(t, err)
+
+	cancel()
+	time.Sleep(2 * testConfig.PollInterval)
+	e, _ := events.New("todo.created", map[string]interface{}{"owner_id": owner})
+	assert.NoError(t, s.Publish(context.Background(), e))
+	time.Sleep(5 * testConfig.PollInterval)
+
+	deliveries, err := s.GetDeliveries(context.Background(), sub.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, StatusPending, deliveries[0].Status)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+// testContext stops the dispatcher of a service when the test ends
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
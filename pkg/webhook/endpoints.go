@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+)
+
+type Endpoints struct {
+	AddSubscriptionEndpoint    endpoint.Endpoint
+	GetSubscriptionEndpoint    endpoint.Endpoint
+	DeleteSubscriptionEndpoint endpoint.Endpoint
+	GetSubscriptionsEndpoint   endpoint.Endpoint
+	GetDeliveriesEndpoint      endpoint.Endpoint
+	RedeliverEndpoint          endpoint.Endpoint
+}
+
+// MakeServerEndpoints returns an Endpoints struct where each endpoint invokes
+// the corresponding method on the provided service. Useful in a webhook svc
+// server. The middlewares wrap every endpoint, the first one outermost.
+func MakeServerEndpoints(s Service, mws ...endpoint.Middleware) Endpoints {
+	mw := func(e endpoint.Endpoint) endpoint.Endpoint {
+		for i := len(mws) - 1; i >= 0; i-- {
+			e = mws[i](e)
+		}
+		return e
+	}
+	return Endpoints{
+		AddSubscriptionEndpoint:    mw(makeAddSubscriptionEndpoint(s)),
+		GetSubscriptionEndpoint:    mw(makeGetSubscriptionEndpoint(s)),
+		DeleteSubscriptionEndpoint: mw(makeDeleteSubscriptionEndpoint(s)),
+		GetSubscriptionsEndpoint:   mw(makeGetSubscriptionsEndpoint(s)),
+		GetDeliveriesEndpoint:      mw(makeGetDeliveriesEndpoint(s)),
+		RedeliverEndpoint:          mw(makeRedeliverEndpoint(s)),
+	}
+}
+
+// makeAddSubscriptionEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeAddSubscriptionEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(addSubscriptionRequest)
+		sub, e := s.AddSubscription(ctx, req.Subscription)
+		return addSubscriptionResponse{Subscription: sub, Err: e}, nil
+	}
+}
+
+// makeGetSubscriptionEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeGetSubscriptionEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getSubscriptionRequest)
+		sub, e := s.GetSubscription(ctx, req.ID)
+		return getSubscriptionResponse{Subscription: sub, Err: e}, nil
+	}
+}
+
+// makeDeleteSubscriptionEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeDeleteSubscriptionEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(deleteSubscriptionRequest)
+		e := s.DeleteSubscription(ctx, req.ID)
+		return deleteSubscriptionResponse{Err: e}, nil
+	}
+}
+
+// makeGetSubscriptionsEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeGetSubscriptionsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		_ = request.(getSubscriptionsRequest)
+		subs, e := s.GetSubscriptions(ctx)
+		return getSubscriptionsResponse{Subscriptions: subs, Err: e}, nil
+	}
+}
+
+// makeGetDeliveriesEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeGetDeliveriesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getDeliveriesRequest)
+
+		// get the dead letter queue if no subscription is given
+		if req.SubscriptionID == uuid.Nil {
+			d, e := s.GetDeadLetters(ctx)
+			return getDeliveriesResponse{Deliveries: d, Err: e}, nil
+		}
+
+		d, e := s.GetDeliveries(ctx, req.SubscriptionID)
+		return getDeliveriesResponse{Deliveries: d, Err: e}, nil
+	}
+}
+
+// makeRedeliverEndpoint returns an enpoint via the passed service.
+// Primarily useful in a server
+func makeRedeliverEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(redeliverRequest)
+		d, e := s.Redeliver(ctx, req.ID)
+		return redeliverResponse{Delivery: d, Err: e}, nil
+	}
+}
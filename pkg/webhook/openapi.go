@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/openapi"
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// OpenAPI returns the OpenAPI document of the routes served by MakeHTTPHandler
+func OpenAPI() openapi.Document {
+	doc := openapi.New("webhook", "1.0.0", "Webhook subscriptions for the events of todos. Requests need a session token as bearer token, callers manage their own subscriptions.")
+
+	subscription := doc.Component("Subscription", openapi.SchemaOf(Subscription{}))
+	subscriptionResponse := doc.Component("SubscriptionResponse", openapi.SchemaOf(addSubscriptionResponse{}))
+	deliveriesResponse := doc.Component("DeliveriesResponse", openapi.SchemaOf(getDeliveriesResponse{}))
+
+	id := openapi.PathParam("id", "id of the subscription", openapi.UUID)
+	ok := func(description string, s *openapi.Schema) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(s)}
+	}
+
+	doc.Add(http.MethodGet, "/", openapi.Operation{
+		OperationID: "getSubscriptions",
+		Summary:     "List webhook subscriptions",
+		Tags:        []string{"webhooks"},
+		Responses: doc.Responses(problems,
+			ok("the subscriptions", doc.Component("GetSubscriptionsResponse", openapi.SchemaOf(getSubscriptionsResponse{}))),
+			authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodPost, "/", openapi.Operation{
+		OperationID: "addSubscription",
+		Summary:     "Subscribe a url to events",
+		Description: "The secret signs the deliveries, a random one is generated when it is empty.",
+		Tags:        []string{"webhooks"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(subscription)},
+		Responses: doc.Responses(problems, ok("the created subscription", subscriptionResponse),
+			problem.ErrMalformedBody, problem.ErrBodyTooLarge, ErrInvalidURL, ErrInternalHost, ErrOwnerMissing, authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodGet, "/{id}", openapi.Operation{
+		OperationID: "getSubscription",
+		Summary:     "Get a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the subscription", subscriptionResponse),
+			ErrInvalidUUID, ErrNotFound, authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodDelete, "/{id}", openapi.Operation{
+		OperationID: "deleteSubscription",
+		Summary:     "Delete a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the subscription is deleted", &openapi.Schema{Type: "object"}),
+			ErrInvalidUUID, ErrNotFound, authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodGet, "/{id}/deliveries", openapi.Operation{
+		OperationID: "getDeliveries",
+		Summary:     "List the deliveries of a subscription",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{id},
+		Responses: doc.Responses(problems, ok("the deliveries", deliveriesResponse),
+			ErrInvalidUUID, ErrNotFound, authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodGet, "/deliveries/dead", openapi.Operation{
+		OperationID: "getDeadLetters",
+		Summary:     "List the deliveries that ran out of attempts",
+		Tags:        []string{"webhooks"},
+		Responses: doc.Responses(problems, ok("the dead letters", deliveriesResponse),
+			authorization.ErrUnauthenticated),
+	})
+	doc.Add(http.MethodPost, "/deliveries/{id}/redeliver", openapi.Operation{
+		OperationID: "redeliver",
+		Summary:     "Deliver a failed delivery again",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "id of the delivery", openapi.UUID)},
+		Responses: doc.Responses(problems, ok("the delivery, queued again", doc.Component("RedeliverResponse", openapi.SchemaOf(redeliverResponse{}))),
+			ErrInvalidUUID, ErrNotFound, ErrDeliveryPending, authorization.ErrUnauthenticated),
+	})
+
+	return doc
+}
@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+// problems presents the errors of the service as problem details
+var problems = problem.NewRegistry(
+	problem.Entry{Err: ErrInvalidURL, Status: http.StatusBadRequest, Code: "invalid_url", Title: "Invalid url", Field: "url"},
+	problem.Entry{Err: ErrInternalHost, Status: http.StatusBadRequest, Code: "internal_host", Title: "Url points to an internal address", Field: "url"},
+	problem.Entry{Err: ErrOwnerMissing, Status: http.StatusBadRequest, Code: "owner_missing", Title: "Owner is missing", Field: "owner_id"},
+	problem.Entry{Err: ErrNotFound, Status: http.StatusNotFound, Code: "not_found", Title: "Not found"},
+	problem.Entry{Err: ErrInvalidUUID, Status: http.StatusBadRequest, Code: "invalid_uuid", Title: "Invalid uuid", Field: "id"},
+	problem.Entry{Err: ErrDeliveryPending, Status: http.StatusConflict, Code: "delivery_pending", Title: "Delivery still pending"},
+	problem.Entry{Err: authorization.ErrUnauthenticated, Status: http.StatusUnauthorized, Code: "unauthenticated", Title: "Authentication required"},
+)
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"github.com/google/uuid"
+)
+
+type addSubscriptionRequest struct {
+	Subscription Subscription
+}
+
+type addSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription,omitempty"`
+	Err          error        `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r addSubscriptionResponse) Error() error { return r.Err }
+
+type getSubscriptionRequest struct {
+	ID uuid.UUID
+}
+
+type getSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription,omitempty"`
+	Err          error        `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r getSubscriptionResponse) Error() error { return r.Err }
+
+type deleteSubscriptionRequest struct {
+	ID uuid.UUID
+}
+
+type deleteSubscriptionResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r deleteSubscriptionResponse) Error() error { return r.Err }
+
+type getSubscriptionsRequest struct{}
+
+type getSubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+	Err           error          `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r getSubscriptionsResponse) Error() error { return r.Err }
+
+// getDeliveriesRequest lists the dead letter queue if SubscriptionID is empty
+type getDeliveriesRequest struct {
+	SubscriptionID uuid.UUID
+}
+
+type getDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	Err        error      `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r getDeliveriesResponse) Error() error { return r.Err }
+
+type redeliverRequest struct {
+	ID uuid.UUID
+}
+
+type redeliverResponse struct {
+	Delivery Delivery `json:"delivery,omitempty"`
+	Err      error    `json:"err,omitempty"`
+}
+
+//lint:ignore U1000 used to satisfy error interface in transport
+func (r redeliverResponse) Error() error { return r.Err }
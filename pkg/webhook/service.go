@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/demeesterdev/todo-service/pkg/events"
+)
+
+// Subscription presents a single webhook subscription.
+// Events published that match the event filter and concern the owner are
+// delivered to URL and signed with Secret.
+type Subscription struct {
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"-"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primarykey"`
+	OwnerID   uuid.UUID      `json:"owner_id" gorm:"type:uuid;index"`
+	URL       string         `json:"url"`
+	Events    EventFilter    `json:"events"`
+	Secret    string         `json:"secret,omitempty"`
+}
+
+// Before create is a GORM hook
+// It makes shure a subscription has a valid uuid before creation
+func (s *Subscription) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// EventFilter is the list of event types a subscription is interested in.
+// An empty filter or a filter containing "*" matches every event.
+type EventFilter []string
+
+// Matches reports if eventType passes the filter
+func (f EventFilter) Matches(eventType string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, t := range f {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// GormDataType stores the filter as text (GORM specifics)
+func (EventFilter) GormDataType() string {
+	return "text"
+}
+
+// Value implements driver.Valuer
+func (f EventFilter) Value() (driver.Value, error) {
+	return strings.Join(f, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (f *EventFilter) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into EventFilter", value)
+	}
+
+	*f = EventFilter{}
+	if raw != "" {
+		*f = strings.Split(raw, ",")
+	}
+	return nil
+}
+
+// delivery states
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// Delivery presents a single attempt to get an event to a subscription.
+// Deliveries that keep failing end up with StatusDead in the dead letter queue.
+type Delivery struct {
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primarykey"`
+	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"type:uuid;uniqueIndex:idx_delivery_event"`
+	EventID        uuid.UUID `json:"event_id" gorm:"type:uuid;uniqueIndex:idx_delivery_event"`
+	EventType      string    `json:"event_type"`
+	Payload        []byte    `json:"-"`
+	Status         string    `json:"status" gorm:"index"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// Before create is a GORM hook
+// It makes shure a delivery has a valid uuid before creation
+func (d *Delivery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
+
+// Service manages webhook subscriptions and delivers published events to them.
+// With a caller in the context, see authorization.WithCaller, subscriptions
+// and deliveries of other users are not found.
+type Service interface {
+	AddSubscription(ctx context.Context, s Subscription) (Subscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	GetSubscriptions(ctx context.Context) ([]Subscription, error)
+	GetDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]Delivery, error)
+	GetDeadLetters(ctx context.Context) ([]Delivery, error)
+	Redeliver(ctx context.Context, id uuid.UUID) (Delivery, error)
+	Publish(ctx context.Context, e events.Event) error
+}
+
+var (
+	ErrInvalidURL      = errors.New("invalid url")
+	ErrInternalHost    = errors.New("url points to an internal address")
+	ErrOwnerMissing    = errors.New("owner_id missing")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidUUID     = errors.New("invalid uuid")
+	ErrDeliveryPending = errors.New("delivery still pending")
+)
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/kit/transport"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/demeesterdev/todo-service/internal/problem"
+	authorizationTrsp "github.com/demeesterdev/todo-service/pkg/authorization/transport"
+)
+
+func MakeHTTPHandler(ep Endpoints, logger log.Logger) http.Handler {
+	r := chi.NewRouter()
+
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(authorizationTrsp.SessionTokenToContext),
+	}
+
+	r.Get("/", httptransport.NewServer(
+		ep.GetSubscriptionsEndpoint,
+		decodeHTTPGetSubscriptionsRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/", httptransport.NewServer(
+		ep.AddSubscriptionEndpoint,
+		decodeHTTPAddSubscriptionRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/{id}", httptransport.NewServer(
+		ep.GetSubscriptionEndpoint,
+		decodeHTTPGetSubscriptionRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Delete("/{id}", httptransport.NewServer(
+		ep.DeleteSubscriptionEndpoint,
+		decodeHTTPDeleteSubscriptionRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/{id}/deliveries", httptransport.NewServer(
+		ep.GetDeliveriesEndpoint,
+		decodeHTTPGetDeliveriesRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Get("/deliveries/dead", httptransport.NewServer(
+		ep.GetDeliveriesEndpoint,
+		decodeHTTPGetDeadLettersRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+	r.Post("/deliveries/{id}/redeliver", httptransport.NewServer(
+		ep.RedeliverEndpoint,
+		decodeHTTPRedeliverRequest,
+		encodeResponse,
+		options...,
+	).ServeHTTP)
+
+	return r
+}
+
+func decodeHTTPGetSubscriptionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getSubscriptionsRequest
+	return req, nil
+}
+
+func decodeHTTPAddSubscriptionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req addSubscriptionRequest
+	err := json.NewDecoder(r.Body).Decode(&req.Subscription)
+	if err != nil {
+		return nil, problem.DecodeError(err)
+	}
+	return req, nil
+}
+
+func decodeHTTPGetSubscriptionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getSubscriptionRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return req, nil
+}
+
+func decodeHTTPDeleteSubscriptionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req deleteSubscriptionRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return req, nil
+}
+
+func decodeHTTPGetDeliveriesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getDeliveriesRequest
+	var err error
+	req.SubscriptionID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return req, nil
+}
+
+func decodeHTTPGetDeadLettersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getDeliveriesRequest
+	return req, nil
+}
+
+func decodeHTTPRedeliverRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req redeliverRequest
+	var err error
+	req.ID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+	return req, nil
+}
+
+// errorer is implemented by all concrete response types that may contain
+// errors.
+type errorer interface {
+	Error() error
+}
+
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	e, ok := response.(errorer)
+	if ok && e.Error() != nil {
+		encodeError(ctx, e.Error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	problems.Write(w, err)
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demeesterdev/todo-service/internal/argon2id"
+	"github.com/demeesterdev/todo-service/pkg/authorization"
+)
+
+func TestHTTPAuthorization(t *testing.T) {
+	ctx := context.Background()
+	params, _ := argon2id.NewParams(64, 1, 1, 16, 32)
+	users, _ := authorization.NewInMemService(params)
+	login := func(username string) (authorization.User, string) {
+		u, err := users.AddUser(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		session, err := users.Login(ctx, authorization.User{Username: username, Password: "correct horse battery staple"})
+		assert.NoError(t, err)
+		return u, session.Token
+	}
+	alice, asAlice := login("alice")
+	_, asBob := login("bob")
+
+	s, _ := NewInMemService(testContext(t), testConfig, log.NewNopLogger())
+	srv := httptest.NewServer(MakeHTTPHandler(MakeServerEndpoints(s, AuthorizationMiddleware(users)), log.NewNopLogger()))
+	defer srv.Close()
+
+	do := func(token, method, path, body string, v interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+		return resp.StatusCode
+	}
+
+	hook := `{"url": "https://ci.example.com/hooks/todo"}`
+	assert.Equal(t, http.StatusUnauthorized, do("", http.MethodPost, "/", hook, nil))
+	assert.Equal(t, http.StatusUnauthorized, do("unknown", http.MethodGet, "/", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, do("", http.MethodGet, "/deliveries/dead", "", nil))
+
+	var added addSubscriptionResponse
+	assert.Equal(t, http.StatusOK, do(asAlice, http.MethodPost, "/", hook, &added))
+	assert.Equal(t, alice.ID, added.Subscription.OwnerID)
+
+	var listed getSubscriptionsResponse
+	assert.Equal(t, http.StatusOK, do(asBob, http.MethodGet, "/", "", &listed))
+	assert.Empty(t, listed.Subscriptions)
+	assert.Equal(t, http.StatusNotFound, do(asBob, http.MethodGet, "/"+added.Subscription.ID.String(), "", nil))
+	assert.Equal(t, http.StatusOK, do(asAlice, http.MethodGet, "/"+added.Subscription.ID.String(), "", nil))
+}